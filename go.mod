@@ -12,13 +12,14 @@ require (
 	github.com/go-yaml/yaml v2.1.0+incompatible
 	github.com/jackc/pgx/v5 v5.4.3
 	github.com/magiconair/properties v1.8.7
+	github.com/opencontainers/go-digest v1.0.0
 	github.com/pkg/sftp v1.13.6
 	github.com/stretchr/testify v1.8.4
 	github.com/urfave/cli/v2 v2.25.7
 	github.com/xo/dburl v0.18.3
 	go.uber.org/mock v0.2.0
 	golang.org/x/crypto v0.16.0
-	golang.org/x/net v0.19.0
+	golang.org/x/mod v0.13.0
 	golang.org/x/term v0.15.0
 	gopkg.in/yaml.v2 v2.4.0
 )
@@ -71,7 +72,6 @@ require (
 	github.com/muesli/cancelreader v0.2.2 // indirect
 	github.com/muesli/reflow v0.3.0 // indirect
 	github.com/muesli/termenv v0.15.2 // indirect
-	github.com/opencontainers/go-digest v1.0.0 // indirect
 	github.com/opencontainers/image-spec v1.1.0-rc5 // indirect
 	github.com/pkg/errors v0.9.1 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
@@ -90,7 +90,7 @@ require (
 	github.com/tklauser/numcpus v0.6.1 // indirect
 	github.com/xrash/smetrics v0.0.0-20201216005158-039620a65673 // indirect
 	golang.org/x/exp v0.0.0-20230905200255-921286631fa9 // indirect
-	golang.org/x/mod v0.13.0 // indirect
+	golang.org/x/net v0.19.0 // indirect
 	golang.org/x/sync v0.4.0 // indirect
 	golang.org/x/sys v0.15.0 // indirect
 	golang.org/x/text v0.14.0 // indirect
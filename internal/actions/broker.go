@@ -4,11 +4,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strconv"
 	"strings"
 
+	"github.com/D8-X/d8x-cli/internal/components"
 	"github.com/D8-X/d8x-cli/internal/configs"
 	"github.com/D8-X/d8x-cli/internal/conn"
 	"github.com/D8-X/d8x-cli/internal/files"
@@ -135,6 +135,11 @@ func (c *Container) BrokerDeploy(ctx *cli.Context) error {
 	if err := c.CopyBrokerDeployConfigs(); err != nil {
 		return err
 	}
+	if ctx.Bool("pin-images") {
+		if err := c.pinImages(ctx, brokerDeployDockerCompose); err != nil {
+			return err
+		}
+	}
 
 	// Update chainConfig.json with referral executor address
 	fmt.Printf("Updating %s config...\n", brokerDeployChainConfig)
@@ -165,12 +170,16 @@ func (c *Container) BrokerDeploy(ctx *cli.Context) error {
 	if err != nil {
 		return fmt.Errorf("generating redis password: %w", err)
 	}
-	if err := c.FS.WriteFile(BROKER_SERVER_REDIS_PWD_FILE, []byte(redisPw)); err != nil {
-		return fmt.Errorf("storing password in %s file: %w", BROKER_SERVER_REDIS_PWD_FILE, err)
+	// With secrets vault, password is stored only in the vault (see
+	// BrokerServerConfig.RedisPassword below)
+	if c.Secrets == nil {
+		if err := c.FS.WriteFile(BROKER_SERVER_REDIS_PWD_FILE, []byte(redisPw)); err != nil {
+			return fmt.Errorf("storing password in %s file: %w", BROKER_SERVER_REDIS_PWD_FILE, err)
+		}
+		fmt.Println(
+			styles.SuccessText.Render("REDIS Password for broker-server was stored in " + BROKER_SERVER_REDIS_PWD_FILE + " file"),
+		)
 	}
-	fmt.Println(
-		styles.SuccessText.Render("REDIS Password for broker-server was stored in " + BROKER_SERVER_REDIS_PWD_FILE + " file"),
-	)
 
 	// Retrieve required information from user input
 	pk := c.Input.brokerDeployInput.privateKey
@@ -223,6 +232,8 @@ func (c *Container) BrokerDeploy(ctx *cli.Context) error {
 		return err
 	}
 
+	c.recordRelease(sshClient, brokerReleaseTarget(), "broker-deploy", 0)
+
 	// Store broker server setup details except pk
 	cfg.BrokerServerConfig = configs.D8XBrokerServerConfig{
 		FeeTBPS:       bsd.brokerFeeTBPS,
@@ -310,13 +321,15 @@ func (c *Container) BrokerServerNginxCertbotSetup(ctx *cli.Context) error {
 		// Run ansible-playbook for nginx setup on broker server
 		args := []string{
 			"--extra-vars", fmt.Sprintf(`ansible_ssh_private_key_file='%s'`, c.SshKeyPath),
-			"--extra-vars", "ansible_host_key_checking=false",
 			"--extra-vars", fmt.Sprintf(`ansible_become_pass='%s'`, password),
 			"-i", configs.DEFAULT_HOSTS_FILE,
 			"-u", c.DefaultClusterUserName,
 			"./playbooks/broker.ansible.yaml",
 		}
-		cmd := exec.Command("ansible-playbook", args...)
+		cmd, err := c.ansiblePlaybookCmd(args...)
+		if err != nil {
+			return err
+		}
 		connectCMDToCurrentTerm(cmd)
 		if err := c.RunCmd(cmd); err != nil {
 			return err
@@ -350,7 +363,7 @@ func (c *Container) BrokerServerNginxCertbotSetup(ctx *cli.Context) error {
 		fmt.Println(string(out))
 
 		if err != nil {
-			restart, err2 := c.TUI.NewPrompt("Certbot setup failed, do you want to restart the broker-nginx setup?", true)
+			restart, err2 := c.TUI.NewPrompt("Certbot setup failed, do you want to restart the broker-nginx setup?", true, components.PromptOptId("broker_nginx.restart_on_certbot_failure"))
 			if err2 != nil {
 				return err2
 			}
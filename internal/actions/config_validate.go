@@ -0,0 +1,163 @@
+package actions
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/D8-X/d8x-cli/internal/configs"
+	"github.com/D8-X/d8x-cli/internal/styles"
+	"github.com/jackc/pgx/v5"
+	"github.com/urfave/cli/v2"
+)
+
+// configSchemaVersion returns schema_version of stored d8x config file
+func (c *Container) configSchemaVersion() (int, error) {
+	contents, err := os.ReadFile(c.ConfigRWriter.GetPath())
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return configs.CurrentSchemaVersion, nil
+		}
+		return 0, err
+	}
+	_, version, err := configs.MigrateConfig(contents)
+	return version, err
+}
+
+// ConfigValidate reports missing and inconsistent fields of d8x config
+func (c *Container) ConfigValidate(ctx *cli.Context) error {
+	format, err := outputFormat(ctx)
+	if err != nil {
+		return err
+	}
+	version, err := c.configSchemaVersion()
+	if err != nil {
+		return err
+	}
+	cfg, err := c.ConfigRWriter.Read()
+	if err != nil {
+		return err
+	}
+	chainJson, err := c.LoadChainJson()
+	if err != nil {
+		return err
+	}
+
+	issues := c.validateConfig(cfg, chainJson)
+	if version < configs.CurrentSchemaVersion {
+		issues = append(issues, configs.ConfigIssue{
+			Severity: configs.ConfigIssueWarning,
+			Field:    "schema_version",
+			Message:  fmt.Sprintf("config uses schema version %d, run d8x config migrate to upgrade it to version %d", version, configs.CurrentSchemaVersion),
+		})
+	}
+
+	report := ConfigValidation{
+		Path:          c.ConfigRWriter.GetPath(),
+		SchemaVersion: version,
+		Valid:         true,
+		Issues:        issues,
+	}
+	numErrors := 0
+	for _, issue := range issues {
+		if issue.Severity == configs.ConfigIssueError {
+			numErrors++
+			report.Valid = false
+		}
+	}
+
+	if format != OutputText {
+		if err := printStructured(os.Stdout, format, report); err != nil {
+			return err
+		}
+	} else {
+		for _, issue := range issues {
+			line := fmt.Sprintf("%-8s %s: %s", issue.Severity, issue.Field, issue.Message)
+			if issue.Severity == configs.ConfigIssueError {
+				fmt.Println(styles.ErrorText.Render(line))
+			} else {
+				fmt.Println(styles.ItalicText.Render(line))
+			}
+		}
+		if report.Valid {
+			fmt.Println(styles.SuccessText.Render(fmt.Sprintf("Config %s is valid", report.Path)))
+		}
+	}
+
+	if !report.Valid {
+		return fmt.Errorf("config %s has %d error(s)", report.Path, numErrors)
+	}
+	return nil
+}
+
+// validateConfig performs checks of configs.D8XConfig.Validate and checks
+// which require chain.json and hosts.cfg
+func (c *Container) validateConfig(cfg *configs.D8XConfig, chainJson ChainJson) []configs.ConfigIssue {
+	issues := cfg.Validate()
+	if cfg.ServerProvider == "" {
+		return issues
+	}
+	addError := func(field, format string, args ...any) {
+		issues = append(issues, configs.ConfigIssue{
+			Severity: configs.ConfigIssueError,
+			Field:    field,
+			Message:  fmt.Sprintf(format, args...),
+		})
+	}
+
+	if cfg.ChainId != 0 {
+		if _, ok := chainJson[fmt.Sprintf("%d", cfg.ChainId)]; !ok {
+			addError("chain_id", "chain id %d is not supported, it was not found in chain.json", cfg.ChainId)
+		}
+	}
+
+	// Static provider ips are validated from config itself
+	if cfg.ServerProvider != configs.D8XServerProviderStatic {
+		if cfg.SwarmDeployed {
+			if ip, err := c.HostsCfg.GetMangerPublicIp(); err != nil || ip == "" {
+				addError("swarm_deployed", "swarm is deployed but manager ip was not found in %s", configs.DEFAULT_HOSTS_FILE)
+			}
+		}
+		if cfg.BrokerDeployed {
+			if ip, err := c.HostsCfg.GetBrokerPublicIp(); err != nil || ip == "" {
+				addError("broker_deployed", "broker server is deployed but broker ip was not found in %s", configs.DEFAULT_HOSTS_FILE)
+			}
+		}
+	}
+
+	if cfg.DatabaseDSN != "" {
+		if _, err := pgx.ParseConfig(cfg.DatabaseDSN); err != nil {
+			addError("database_dsn", "invalid database dsn")
+		}
+	}
+	if cfg.DbBackup != nil {
+		if err := validateDbBackupConfig(cfg.DbBackup); err != nil {
+			addError("db_backup", "%s", err.Error())
+		}
+	}
+
+	return issues
+}
+
+// ConfigMigrate upgrades stored d8x config to the current schema version
+func (c *Container) ConfigMigrate(ctx *cli.Context) error {
+	version, err := c.configSchemaVersion()
+	if err != nil {
+		return err
+	}
+	if version == configs.CurrentSchemaVersion {
+		fmt.Printf("Config %s is up to date (schema version %d)\n", c.ConfigRWriter.GetPath(), version)
+		return nil
+	}
+
+	cfg, err := c.ConfigRWriter.Read()
+	if err != nil {
+		return err
+	}
+	if err := c.ConfigRWriter.Write(cfg); err != nil {
+		return err
+	}
+	fmt.Println(styles.SuccessText.Render(fmt.Sprintf("Config was upgraded to schema version %d", configs.CurrentSchemaVersion)))
+
+	return nil
+}
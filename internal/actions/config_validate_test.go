@@ -0,0 +1,37 @@
+package actions
+
+import (
+	"testing"
+
+	"github.com/D8-X/d8x-cli/internal/configs"
+	"github.com/D8-X/d8x-cli/internal/mocks"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func TestValidateConfig(t *testing.T) {
+	ctl := gomock.NewController(t)
+	hosts := mocks.NewMockHostsFileInteractor(ctl)
+	hosts.EXPECT().GetMangerPublicIp().Return("", assert.AnError)
+	c := &Container{HostsCfg: hosts}
+
+	cfg := &configs.D8XConfig{
+		ServerProvider:     configs.D8XServerProviderLinode,
+		LinodeConfig:       &configs.D8XLinodeConfig{},
+		ChainId:            999999,
+		Services:           map[configs.D8XServiceName]configs.D8XService{},
+		HttpRpcList:        map[string][]string{"999999": {"https://rpc.example.com"}},
+		WsRpcList:          map[string][]string{"999999": {"wss://rpc.example.com"}},
+		SwarmDeployed:      true,
+		SwarmRedisPassword: "redis",
+		DatabaseDSN:        "postgres://d8x:pwd@db:notaport/d8x",
+		ConfigDetails:      configs.ConfigurationDetails{Done: true},
+	}
+
+	issues := c.validateConfig(cfg, ChainJson{"1101": {}})
+	assert.Equal(t, []configs.ConfigIssue{
+		{Severity: configs.ConfigIssueError, Field: "chain_id", Message: "chain id 999999 is not supported, it was not found in chain.json"},
+		{Severity: configs.ConfigIssueError, Field: "swarm_deployed", Message: "swarm is deployed but manager ip was not found in ./hosts.cfg"},
+		{Severity: configs.ConfigIssueError, Field: "database_dsn", Message: "invalid database dsn"},
+	}, issues)
+}
@@ -37,6 +37,12 @@ func (c *Container) CopyConfigs(ctx *cli.Context) error {
 			} else {
 				fmt.Println(styles.SuccessText.Render("Successfully copied Linode terraform files"))
 			}
+		case "tf-hetzner":
+			if err := c.CopyHetznerTFFiles(); err != nil {
+				return fmt.Errorf("failed to copy Hetzner terraform files: %w", err)
+			} else {
+				fmt.Println(styles.SuccessText.Render("Successfully copied Hetzner terraform files"))
+			}
 		default:
 			return fmt.Errorf("unknown argument: %s", arg)
 		}
@@ -4,9 +4,8 @@ import (
 	"crypto/rand"
 	"fmt"
 	"math/big"
-	"os"
-	"os/exec"
 
+	"github.com/D8-X/d8x-cli/internal/components"
 	"github.com/D8-X/d8x-cli/internal/configs"
 	"github.com/D8-X/d8x-cli/internal/files"
 	"github.com/D8-X/d8x-cli/internal/styles"
@@ -23,14 +22,51 @@ func (c *Container) Configure(ctx *cli.Context) error {
 		return err
 	}
 
-	// Update hosts.cfg for linode provider in case d8x config was changed
-	// manually
-	if cfg.ServerProvider == configs.D8XServerProviderLinode {
+	// Update hosts.cfg for linode, hetzner and static providers in case d8x
+	// config was changed manually
+	if cfg.HasPublicServers() {
 		if err := c.LinodeInventorySetUserVar(cfg.ConfigDetails.ConfiguredServers, c.DefaultClusterUserName); err != nil {
 			return fmt.Errorf("updating linode inventory file: %w", err)
 		}
 	}
 
+	// Generate password when not provided
+	if c.UserPassword == "" {
+		password, err := generatePassword(16)
+		if err != nil {
+			return err
+		}
+		c.UserPassword = password
+	}
+
+	// Prompt to save password
+	c.DisplayPasswordAlert()
+	if err := c.storeUserPassword(); err != nil {
+		return err
+	}
+
+	if err := c.runSetupPlaybook(cfg); err != nil {
+		return err
+	}
+
+	// Update configuration details
+	cfg.ConfigDetails.Done = true
+	cfg.ConfigDetails.ConfiguredServers = c.HostsCfg.GetAllPublicIps()
+
+	// Update hosts.cfg for linode, hetzner and static providers
+	if cfg.HasPublicServers() {
+		if err := c.LinodeInventorySetUserVar(cfg.ConfigDetails.ConfiguredServers, c.DefaultClusterUserName); err != nil {
+			return fmt.Errorf("updating linode inventory file: %w", err)
+		}
+	}
+
+	return c.ConfigRWriter.Write(cfg)
+}
+
+// runSetupPlaybook runs setup.ansible.yaml playbook on servers in hosts.cfg
+// with c.UserPassword as cluster user password. extraArgs are appended to
+// ansible-playbook arguments.
+func (c *Container) runSetupPlaybook(cfg *configs.D8XConfig, extraArgs ...string) error {
 	// Copy the playbooks file
 	if err := c.EmbedCopier.Copy(
 		configs.EmbededConfigs,
@@ -45,25 +81,6 @@ func (c *Container) Configure(ctx *cli.Context) error {
 	}
 	privKeyPath := c.SshKeyPath
 
-	// Generate password when not provided
-	if c.UserPassword == "" {
-		password, err := generatePassword(16)
-		if err != nil {
-			return err
-		}
-		c.UserPassword = password
-	}
-
-	// Prompt to save password
-	c.DisplayPasswordAlert()
-	// Legacy functionality to store password in txt
-	if err := c.FS.WriteFile("./password.txt", []byte(c.UserPassword)); err != nil {
-		return fmt.Errorf("storing password in ./password.txt file: %w", err)
-	}
-	fmt.Println(
-		styles.SuccessText.Render("Password was stored in ./password.txt file"),
-	)
-
 	configureUser := cfg.GetAnsibleUser()
 
 	// For linode when subsequent configuration is performed, we need to use the
@@ -81,7 +98,6 @@ func (c *Container) Configure(ctx *cli.Context) error {
 	// Generate ansible-playbook args
 	args := []string{
 		"--extra-vars", fmt.Sprintf(`ansible_ssh_private_key_file='%s'`, privKeyPath),
-		"--extra-vars", "ansible_host_key_checking=false",
 		"--extra-vars", fmt.Sprintf(`user_public_key='%s'`, pubKey),
 		"--extra-vars", fmt.Sprintf(`default_user_name=%s`, c.DefaultClusterUserName),
 		"--extra-vars", fmt.Sprintf(`default_user_password='%s'`, hashedPassword),
@@ -96,35 +112,69 @@ func (c *Container) Configure(ctx *cli.Context) error {
 		// AWS itself
 		args = append(args, "--extra-vars", "no_ufw=true")
 
-	case configs.D8XServerProviderLinode:
-		// For linode - pass become_pass for subsequent configuration runs.
+	case configs.D8XServerProviderLinode, configs.D8XServerProviderHetzner, configs.D8XServerProviderStatic:
+		// For linode, hetzner and static servers - pass become_pass for
+		// subsequent configuration runs.
 		if cfg.ConfigDetails.Done {
 			args = append(args,
 				"--extra-vars", fmt.Sprintf(`ansible_become_pass='%s'`, c.UserPassword),
 			)
+		} else if configureUser != "root" {
+			// Non-root user of static servers needs its sudo password for the
+			// initial configuration
+			fmt.Printf("Enter sudo password of %s user on your servers (leave empty for passwordless sudo):\n", configureUser)
+			becomePass, err := c.TUI.NewInput(
+				components.TextInputOptId("static.become_password"),
+				components.TextInputOptPlaceholder("<SUDO PASSWORD>"),
+				components.TextInputOptMasked(),
+			)
+			if err != nil {
+				return err
+			}
+			if becomePass != "" {
+				args = append(args,
+					"--extra-vars", fmt.Sprintf(`ansible_become_pass='%s'`, becomePass),
+				)
+			}
 		}
 	}
+	args = append(args, extraArgs...)
 
-	cmd := exec.Command("ansible-playbook", args...)
-	cmd.Env = os.Environ()
-	connectCMDToCurrentTerm(cmd)
-
-	if err := c.RunCmd(cmd); err != nil {
+	cmd, err := c.ansiblePlaybookCmd(args...)
+	if err != nil {
 		return err
 	}
+	connectCMDToCurrentTerm(cmd)
 
-	// Update configuration details
-	cfg.ConfigDetails.Done = true
-	cfg.ConfigDetails.ConfiguredServers = c.HostsCfg.GetAllPublicIps()
+	return c.RunCmd(cmd)
+}
 
-	// Update hosts.cfg for linode provider
-	if cfg.ServerProvider == configs.D8XServerProviderLinode {
-		if err := c.LinodeInventorySetUserVar(cfg.ConfigDetails.ConfiguredServers, c.DefaultClusterUserName); err != nil {
-			return fmt.Errorf("updating linode inventory file: %w", err)
+// storeUserPassword stores the user password in secrets vault or in legacy
+// ./password.txt file when vault is not used
+func (c *Container) storeUserPassword() error {
+	if c.Secrets != nil {
+		secrets, err := c.Secrets.Load()
+		if err != nil {
+			return err
 		}
+		secrets[configs.SecretUserPassword] = c.UserPassword
+		if err := c.Secrets.Save(secrets); err != nil {
+			return fmt.Errorf("storing password in secrets vault: %w", err)
+		}
+		fmt.Println(
+			styles.SuccessText.Render("Password was stored in secrets vault"),
+		)
+		return nil
 	}
 
-	return c.ConfigRWriter.Write(cfg)
+	// Legacy functionality to store password in txt
+	if err := c.FS.WriteFile(configs.DEFAULT_PASSWORD_FILE, []byte(c.UserPassword)); err != nil {
+		return fmt.Errorf("storing password in %s file: %w", configs.DEFAULT_PASSWORD_FILE, err)
+	}
+	fmt.Println(
+		styles.SuccessText.Render("Password was stored in " + configs.DEFAULT_PASSWORD_FILE + " file"),
+	)
+	return nil
 }
 
 func generatePassword(n int) (string, error) {
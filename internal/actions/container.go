@@ -5,6 +5,7 @@ import (
 	"os"
 	"os/exec"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/D8-X/d8x-cli/internal/components"
@@ -27,6 +28,13 @@ type Container struct {
 	// ConfigDir is the configuration directory path
 	ConfigDir string
 
+	// Workspace is the d8x working directory which holds profiles. Set by
+	// UseProfile.
+	Workspace *configs.Workspace
+
+	// Name of the profile in use. Empty when workspace directory is used.
+	Profile string
+
 	// Default ssh key pathname. Defaults to ./id_ed25519 for private key. For
 	// public key same name is used + .pub
 	SshKeyPath string
@@ -60,6 +68,9 @@ type Container struct {
 
 	ConfigRWriter configs.D8XConfigReadWriter
 
+	// Encrypted secrets store. Nil when legacy plain text secrets are used.
+	Secrets configs.SecretsStore
+
 	// terminal ui runner
 	TUI components.ComponentsRunner
 
@@ -68,6 +79,10 @@ type Container struct {
 
 	CreateSSHConn conn.SSHConnectionEstablisher
 
+	// CreateSSHConnWithBastion establishes connection via bastion (manager)
+	// server
+	CreateSSHConnWithBastion conn.SSHConnectionWithBastionEstablisher
+
 	// RunCmd runs the provided command
 	RunCmd func(*exec.Cmd) error
 
@@ -76,24 +91,61 @@ type Container struct {
 
 	// Global input state
 	Input *InputCollector
+
+	// Recorded plan of actions when running in --dry-run mode. Nil otherwise.
+	DryRun *conn.Plan
+
+	// d8x managed known_hosts file which all ssh connections are verified
+	// against
+	KnownHosts *conn.KnownHosts
+
+	// Pool of ssh connections established via CreateSSHConn and
+	// CreateSSHConnWithBastion. Nil when pooling is not enabled.
+	SSHPool *conn.Pool
+
+	// ssh transport used by all ssh connections, see InitSSHTransport
+	sshTransport   conn.Transport
+	sshPortOnce    sync.Once
+	sshPortDefault int
+	sshKeyPassMu   sync.Mutex
+	sshKeyPass     []byte
 }
 
 func NewDefaultContainer() (*Container, error) {
 
 	httpClient := http.DefaultClient
-	return &Container{
-		EmbedCopier:   files.NewEmbedFileCopier(),
-		FS:            files.NewFileSystemInteractor(),
-		HostsCfg:      files.NewFSHostsFileInteractor(configs.DEFAULT_HOSTS_FILE),
-		HttpClient:    httpClient,
-		TUI:           components.InteractiveRunner{},
-		GetPassword:   defaultPasswordGetter,
-		CreateSSHConn: conn.NewSSHConnection,
+	c := &Container{
+		EmbedCopier:              files.NewEmbedFileCopier(),
+		FS:                       files.NewFileSystemInteractor(),
+		HostsCfg:                 files.NewFSHostsFileInteractor(configs.DEFAULT_HOSTS_FILE),
+		HttpClient:               httpClient,
+		TUI:                      components.InteractiveRunner{},
+		CreateSSHConn:            conn.NewSSHConnection,
+		CreateSSHConnWithBastion: conn.NewSSHConnectionViaBastion,
 		RunCmd: func(c *exec.Cmd) error {
 			return c.Run()
 		},
 		ProvisioningTfDir: TF_FILES_DIR,
-	}, nil
+	}
+	c.GetPassword = c.defaultPasswordGetter
+
+	return c, nil
+}
+
+// EnableSSHPool makes CreateSSHConn and CreateSSHConnWithBastion reuse one
+// connection per server during the command run. Must be called after the
+// establishers are set up (i.e. after EnableDryRun).
+func (c *Container) EnableSSHPool() {
+	c.SSHPool = conn.NewPool(c.CreateSSHConn, c.CreateSSHConnWithBastion)
+	c.CreateSSHConn = c.SSHPool.Get
+	c.CreateSSHConnWithBastion = c.SSHPool.GetViaBastion
+}
+
+// CloseSSHConnections closes all pooled ssh connections
+func (c *Container) CloseSSHConnections() {
+	if c.SSHPool != nil {
+		c.SSHPool.Close()
+	}
 }
 
 // expandCMD expands input string to argument slice suitable for exec.Command
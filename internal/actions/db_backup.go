@@ -17,6 +17,7 @@ import (
 	"github.com/jackc/pgx/v5"
 	"github.com/pkg/sftp"
 	"github.com/urfave/cli/v2"
+	"golang.org/x/crypto/ssh"
 )
 
 // Latest available stable postgresql client version to use.
@@ -46,8 +47,13 @@ func (c *Container) BackupDb(ctx *cli.Context) error {
 		return fmt.Errorf("parsing database connection string: %w", err)
 	}
 
+	opts, err := backupOptionsFromFlags(ctx)
+	if err != nil {
+		return err
+	}
+
 	// SSH into the manager
-	managerConn, err := conn.NewSSHConnection(ip, c.DefaultClusterUserName, c.SshKeyPath)
+	managerConn, err := c.CreateSSHConn(ip, c.DefaultClusterUserName, c.SshKeyPath)
 	if err != nil {
 		return fmt.Errorf("creating ssh connection to manager: %w", err)
 	}
@@ -78,55 +84,68 @@ func (c *Container) BackupDb(ctx *cli.Context) error {
 	}
 	fmt.Printf("Postgres server at %s version: %s\n", pgCfg.Host, versionString)
 
-	// We default to maximum postgresq-client-x version available since it is
-	// backwards compatible.
-	cmd := "apt-cache search --names-only ^postgresql-client-* | awk '{print $1}'"
-	pgClientPackages, err := managerConn.ExecCommand(cmd)
-	maxPgVersion := CurrentMaximumPostgresVersion
-	if err != nil {
-		for _, pkgName := range strings.Split(string(pgClientPackages), "\n") {
-			versionStr := strings.TrimPrefix(
-				strings.TrimSpace(pkgName),
-				"postgresql-client-",
-			)
-			// Parse only whole int versions
-			if version, err := strconv.ParseInt(versionStr, 10, 64); err == nil && maxPgVersion < int(version) {
-				maxPgVersion = int(version)
-			}
-		}
+	if err := installPostgresClient(managerConn, pwd); err != nil {
+		return err
 	}
-	aptPgClientPackage := "postgresql-client-" + strconv.Itoa(maxPgVersion)
 
-	// Make sure pg dump (postgres 15) is installed on the manager. Let's use
-	// the public postgres apt repo and set it up. It contains all latest
-	// versions of postgres
-	fmt.Printf("Ensuring pg_dump is installed on manager server (%s)\n", aptPgClientPackage)
-	installScriptSteps := []string{
-		`echo "deb https://apt.postgresql.org/pub/repos/apt $(lsb_release -cs)-pgdg main" > /etc/apt/sources.list.d/pgdg.list`,
-		"wget --quiet -O - https://www.postgresql.org/media/keys/ACCC4CF8.asc | apt-key add -",
-		"apt-get update -y",
-		"apt-get -y install " + aptPgClientPackage,
+	backupFileName := opts.fileName(cfg.GetServersLabel(), time.Now())
+	fullBackupPath := backupFileName
+	if outDir := ctx.String("output-dir"); outDir != "" {
+		fullBackupPath = filepath.Join(outDir, fullBackupPath)
 	}
-	for _, s := range installScriptSteps {
-		cmd := fmt.Sprintf(`echo "%s" | sudo -S bash -c '%s'`, pwd, s)
-		if out, err := managerConn.ExecCommand(cmd); err != nil {
-			fmt.Println(string(out))
-			return fmt.Errorf("setting up pg_dump on manager server: %w", err)
+	fullBackupPath, err = filepath.Abs(fullBackupPath)
+	if err != nil {
+		return err
+	}
+
+	// TODO handle different versions and incompatible pg_dump and postgres
+
+	if opts.stream {
+		stopSpinner := make(chan struct{})
+		go c.TUI.NewSpinner(stopSpinner, fmt.Sprintf("Streaming database %s backup to local machine", pgCfg.Database))
+		err := streamBackup(managerConn, opts.pgDumpCommand(pgCfg, ""), fullBackupPath)
+		stopSpinner <- struct{}{}
+		if err != nil {
+			return err
 		}
+	} else if err := c.backupViaManagerFile(managerConn, opts, pgCfg, backupFileName, fullBackupPath); err != nil {
+		return err
 	}
 
-	// Run pg dump for the database. Create a normal SQL (non-archive) backup
-	// file which can be used directly with psql
-	backupFileName := fmt.Sprintf("backup-%s-%s.dump.sql", cfg.GetServersLabel(), time.Now().Format("2006-01-02-15-04-05"))
-	backupCmd := "PGPASSWORD=%s pg_dump -h %s -p %d -U %s -d %s -f %s"
-	backupCmd = fmt.Sprintf(backupCmd, pgCfg.Password, pgCfg.Host, pgCfg.Port, pgCfg.User, pgCfg.Database, backupFileName)
+	info := fmt.Sprintf("Database %s backup file was downloaded and copied to %s (checksum verified)", pgCfg.Database, fullBackupPath)
+	fmt.Println(styles.SuccessText.Render(info))
 
-	// TODO handle different versions and incompatible pg_dump and postgres
+	return nil
+}
+
+// backupViaManagerFile runs pg_dump into a file on manager, downloads it to
+// localPath and removes it from manager. Directory format backups are
+// downloaded as tar archive.
+func (c *Container) backupViaManagerFile(managerConn conn.SSHConnection, opts backupOptions, pgCfg *pgx.ConnConfig, backupFileName, localPath string) error {
+	remoteFile := backupFileName
+	dumpOutput := backupFileName
+	if opts.format == backupFormatDirectory {
+		dumpOutput = strings.TrimSuffix(backupFileName, ".tar")
+	}
+	// Remove everything created on manager, also when backup fails
+	defer func() {
+		fmt.Println("Removing backup file from server")
+		if out, err := managerConn.ExecCommand(fmt.Sprintf("rm -rf %s %s", dumpOutput, remoteFile)); err != nil {
+			fmt.Println(string(out))
+			fmt.Println(styles.ErrorText.Render(fmt.Sprintf("removing backup file from manager: %v", err)))
+		}
+	}()
 
 	fmt.Printf("Creating database %s backup\n", pgCfg.Database)
-	if err := managerConn.ExecCommandPiped(backupCmd); err != nil {
+	if err := managerConn.ExecCommandPiped(opts.pgDumpCommand(pgCfg, dumpOutput)); err != nil {
 		return fmt.Errorf("running pg_dump: %w", err)
 	}
+	if opts.format == backupFormatDirectory {
+		if out, err := managerConn.ExecCommand(fmt.Sprintf("tar -cf %s %s", remoteFile, dumpOutput)); err != nil {
+			fmt.Println(string(out))
+			return fmt.Errorf("archiving backup directory: %w", err)
+		}
+	}
 
 	// Use scp or similar library to copy the backup to given location on the
 	// local machine
@@ -134,57 +153,138 @@ func (c *Container) BackupDb(ctx *cli.Context) error {
 	if err != nil {
 		return err
 	}
-	f, err := scp.OpenFile(backupFileName, os.O_RDONLY)
+	fstat, err := scp.Stat(remoteFile)
+	scp.Close()
 	if err != nil {
 		return fmt.Errorf("opening backup file on manager: %w", err)
 	}
-	fstat, err := f.Stat()
-	if err != nil {
-		return err
-	}
 	sizeMb := float64(fstat.Size()) / float64(1024*1024)
 	fmt.Printf("Backup file size: %f MB\n", sizeMb)
 
+	out, err := managerConn.ExecCommand("sha256sum " + remoteFile)
+	if err != nil {
+		fmt.Println(string(out))
+		return fmt.Errorf("computing backup checksum on manager: %w", err)
+	}
+	remoteSum, _, _ := strings.Cut(strings.TrimSpace(string(out)), " ")
+
 	// Show a small download animation so user doesn't think that download
 	// process is stuck
 	stopDownloadSpinner := make(chan struct{})
 	go c.TUI.NewSpinner(stopDownloadSpinner, "Downloading backup file to local machine")
-
-	fullBackupPath := backupFileName
-	if outDir := ctx.String("output-dir"); outDir != "" {
-		fullBackupPath = filepath.Join(outDir, fullBackupPath)
-	}
-	fullBackupPath, err = filepath.Abs(fullBackupPath)
+	err = downloadFile(managerConn, remoteFile, localPath)
+	stopDownloadSpinner <- struct{}{}
 	if err != nil {
 		return err
 	}
 
-	// Create backup file in target path
-	fout, err := os.OpenFile(fullBackupPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0666)
+	localSum, err := fileSha256(localPath)
 	if err != nil {
 		return err
 	}
+	if localSum != remoteSum {
+		return fmt.Errorf("checksum of downloaded backup %s does not match the backup on manager (%s != %s)", localPath, localSum, remoteSum)
+	}
+	return nil
+}
 
-	if _, err := io.Copy(fout, f); err != nil {
+// Number of attempts to download a file when connection is lost
+var downloadAttempts = 3
+
+// downloadFile downloads remotePath over sftp to localPath. When connection is
+// lost during the download, sshConn is reconnected and the download is resumed
+// from the already downloaded offset.
+func downloadFile(sshConn conn.SSHConnection, remotePath, localPath string) error {
+	fout, err := os.OpenFile(localPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0666)
+	if err != nil {
 		return err
 	}
+	defer fout.Close()
 
-	stopDownloadSpinner <- struct{}{}
+	var downloaded int64
+	for attempt := 1; ; attempt++ {
+		n, err := copyRemoteFile(sshConn.GetClient(), remotePath, downloaded, fout)
+		downloaded += n
+		if err == nil {
+			return nil
+		}
+		if attempt >= downloadAttempts {
+			return fmt.Errorf("downloading %s: %w", remotePath, err)
+		}
 
-	info := fmt.Sprintf("Database %s backup file was downloaded and copied to %s", pgCfg.Database, fullBackupPath)
-	fmt.Println(styles.SuccessText.Render(info))
+		fmt.Printf("Download of %s was interrupted: %v. Reconnecting...\n", remotePath, err)
+		if err := sshConn.Reconnect(); err != nil {
+			return fmt.Errorf("reconnecting: %w", err)
+		}
+	}
+}
 
-	// Rm database backup from manager server
-	fmt.Println("Removing backup file from server")
-	if out, err := managerConn.ExecCommand("rm " + backupFileName); err != nil {
-		fmt.Println(string(out))
-		return fmt.Errorf("removing backup file from manager: %w", err)
+// copyRemoteFile copies remotePath starting at offset to w
+func copyRemoteFile(client *ssh.Client, remotePath string, offset int64, w io.Writer) (int64, error) {
+	s, err := sftp.NewClient(client)
+	if err != nil {
+		return 0, err
 	}
+	defer s.Close()
 
+	f, err := s.Open(remotePath)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return 0, err
+	}
+	return io.Copy(w, f)
+}
+
+// installPostgresClient installs the latest available postgresql-client-N
+// (pg_dump, psql) on the manager server from the public postgres apt repo
+func installPostgresClient(managerConn conn.SSHConnection, pwd string) error {
+	// We default to maximum postgresq-client-x version available since it is
+	// backwards compatible.
+	cmd := "apt-cache search --names-only ^postgresql-client-* | awk '{print $1}'"
+	pgClientPackages, err := managerConn.ExecCommand(cmd)
+	maxPgVersion := CurrentMaximumPostgresVersion
+	if err != nil {
+		for _, pkgName := range strings.Split(string(pgClientPackages), "\n") {
+			versionStr := strings.TrimPrefix(
+				strings.TrimSpace(pkgName),
+				"postgresql-client-",
+			)
+			// Parse only whole int versions
+			if version, err := strconv.ParseInt(versionStr, 10, 64); err == nil && maxPgVersion < int(version) {
+				maxPgVersion = int(version)
+			}
+		}
+	}
+	aptPgClientPackage := "postgresql-client-" + strconv.Itoa(maxPgVersion)
+
+	// Make sure postgres client is installed on the manager. Let's use the
+	// public postgres apt repo and set it up. It contains all latest versions
+	// of postgres
+	fmt.Printf("Ensuring postgres client is installed on manager server (%s)\n", aptPgClientPackage)
+	installScriptSteps := []string{
+		`echo "deb https://apt.postgresql.org/pub/repos/apt $(lsb_release -cs)-pgdg main" > /etc/apt/sources.list.d/pgdg.list`,
+		"wget --quiet -O - https://www.postgresql.org/media/keys/ACCC4CF8.asc | apt-key add -",
+		"apt-get update -y",
+		"apt-get -y install " + aptPgClientPackage,
+	}
+	for _, s := range installScriptSteps {
+		cmd := fmt.Sprintf(`echo "%s" | sudo -S bash -c '%s'`, pwd, s)
+		if out, err := managerConn.ExecCommand(cmd); err != nil {
+			fmt.Println(string(out))
+			return fmt.Errorf("setting up postgres client on manager server: %w", err)
+		}
+	}
 	return nil
 }
 
 func pgConnTunnel(manager conn.SSHConnection, pgCfg *pgx.ConnConfig) (*pgx.Conn, error) {
+	if manager.GetClient() == nil {
+		return nil, fmt.Errorf("ssh connection to manager is not established")
+	}
 	pgCfg.DialFunc = func(ctx context.Context, network, addr string) (net.Conn, error) {
 		return manager.GetClient().DialContext(ctx, network, addr)
 	}
@@ -0,0 +1,172 @@
+package actions
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/D8-X/d8x-cli/internal/conn"
+	"github.com/jackc/pgx/v5"
+	"github.com/urfave/cli/v2"
+)
+
+// Supported --format values of backup-db
+const (
+	backupFormatPlain     = "plain"
+	backupFormatCustom    = "custom"
+	backupFormatDirectory = "directory"
+)
+
+// --compress value is a pg_dump compression method with optional level
+var backupCompressRe = regexp.MustCompile(`^(none|gzip|zstd)(:[0-9]+)?$`)
+
+// Checksum line printed to stderr by the streamed backup command
+var backupChecksumRe = regexp.MustCompile(`(?m)^sha256:([0-9a-f]{64})$`)
+
+// backupOptions are the pg_dump options of backup-db command
+type backupOptions struct {
+	format string
+	// pg_dump --compress value, empty for the default compression of format
+	compress      string
+	tables        []string
+	excludeTables []string
+	// Whether pg_dump output is streamed directly to local file
+	stream bool
+}
+
+func backupOptionsFromFlags(ctx *cli.Context) (backupOptions, error) {
+	opts := backupOptions{
+		format:        ctx.String("format"),
+		compress:      ctx.String("compress"),
+		tables:        ctx.StringSlice("table"),
+		excludeTables: ctx.StringSlice("exclude-table"),
+		stream:        ctx.Bool("stream"),
+	}
+	if opts.format == "" {
+		opts.format = backupFormatPlain
+	}
+	return opts, opts.validate()
+}
+
+func (o backupOptions) validate() error {
+	switch o.format {
+	case backupFormatPlain, backupFormatCustom, backupFormatDirectory:
+	default:
+		return fmt.Errorf("unsupported backup format %s, supported values: plain, custom, directory", o.format)
+	}
+	if o.compress != "" && !backupCompressRe.MatchString(o.compress) {
+		return fmt.Errorf("unsupported compression %s, supported values: none, gzip, zstd with optional level (gzip:9)", o.compress)
+	}
+	if o.stream && o.format == backupFormatDirectory {
+		return fmt.Errorf("directory format backups can't be streamed, use plain or custom format")
+	}
+	return nil
+}
+
+// compressMethod returns the compression method without level
+func (o backupOptions) compressMethod() string {
+	method, _, _ := strings.Cut(o.compress, ":")
+	return method
+}
+
+// fileName returns the name of backup file. Extension reflects the format and
+// compression, so that restore-db knows how to load the backup.
+func (o backupOptions) fileName(label string, t time.Time) string {
+	base := fmt.Sprintf("backup-%s-%s", label, t.Format("2006-01-02-15-04-05"))
+	switch o.format {
+	case backupFormatCustom:
+		return base + ".dump"
+	case backupFormatDirectory:
+		return base + ".dir.tar"
+	}
+	switch o.compressMethod() {
+	case "gzip":
+		return base + ".dump.sql.gz"
+	case "zstd":
+		return base + ".dump.sql.zst"
+	}
+	return base + ".dump.sql"
+}
+
+// pgDumpCommand builds pg_dump command which writes the backup to output or
+// to stdout when output is empty
+func (o backupOptions) pgDumpCommand(pgCfg *pgx.ConnConfig, output string) string {
+	args := []string{
+		fmt.Sprintf("PGPASSWORD='%s' pg_dump", shellQuoteEscape(pgCfg.Password)),
+		fmt.Sprintf("-h %s -p %d -U %s -d '%s'", pgCfg.Host, pgCfg.Port, pgCfg.User, shellQuoteEscape(pgCfg.Database)),
+		"--format=" + o.format,
+	}
+	if o.compress != "" {
+		args = append(args, "--compress="+o.compress)
+	}
+	for _, t := range o.tables {
+		args = append(args, fmt.Sprintf("--table='%s'", shellQuoteEscape(t)))
+	}
+	for _, t := range o.excludeTables {
+		args = append(args, fmt.Sprintf("--exclude-table='%s'", shellQuoteEscape(t)))
+	}
+	if output != "" {
+		args = append(args, "-f "+output)
+	}
+	return strings.Join(args, " ")
+}
+
+// streamBackup runs dumpCmd on manager and writes its stdout directly to
+// localPath, nothing is stored on manager. Checksum of the stream is computed
+// on manager and compared with checksum of the received file.
+func streamBackup(managerConn conn.SSHConnection, dumpCmd, localPath string) error {
+	f, err := os.OpenFile(localPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0666)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	// tee copies the dump to ssh stdout (fd 3) and to sha256sum, which
+	// prints the checksum to stderr once the dump is complete
+	cmd := fmt.Sprintf(
+		`bash -c 'set -o pipefail; exec 3>&1; { %s; } | tee /dev/fd/3 | sha256sum | awk '\''{print "sha256:" $1}'\'' >&2'`,
+		shellQuoteEscape(dumpCmd),
+	)
+
+	h := sha256.New()
+	stderr := &bytes.Buffer{}
+	streamErr := managerConn.ExecCommandStream(context.Background(), cmd, io.MultiWriter(f, h), stderr)
+	if err := f.Close(); err != nil && streamErr == nil {
+		streamErr = err
+	}
+	if streamErr != nil {
+		os.Remove(localPath)
+		return fmt.Errorf("streaming pg_dump: %s: %w", strings.TrimSpace(backupChecksumRe.ReplaceAllString(stderr.String(), "")), streamErr)
+	}
+
+	matches := backupChecksumRe.FindStringSubmatch(stderr.String())
+	if matches == nil {
+		return fmt.Errorf("checksum of streamed backup was not received: %s", strings.TrimSpace(stderr.String()))
+	}
+	if localSum := hex.EncodeToString(h.Sum(nil)); localSum != matches[1] {
+		return fmt.Errorf("checksum of streamed backup %s does not match pg_dump output (%s != %s)", localPath, localSum, matches[1])
+	}
+	return nil
+}
+
+// fileSha256 returns hex encoded sha256 checksum of file contents
+func fileSha256(filePath string) (string, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("computing checksum of %s: %w", filePath, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,580 @@
+package actions
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/D8-X/d8x-cli/internal/components"
+	"github.com/D8-X/d8x-cli/internal/configs"
+	"github.com/D8-X/d8x-cli/internal/conn"
+	"github.com/D8-X/d8x-cli/internal/styles"
+	"github.com/jackc/pgx/v5"
+	"github.com/urfave/cli/v2"
+)
+
+// Locations of scheduled backup files on manager server
+const (
+	dbBackupScriptPath = "/usr/local/bin/d8x-db-backup"
+	dbBackupConfigDir  = "/etc/d8x-db-backup"
+	dbBackupDir        = "/var/backups/d8x-db"
+	dbBackupUnitName   = "d8x-db-backup"
+	// Remote directory where backup files are uploaded before they are
+	// installed with sudo
+	dbBackupStagingDir = "./d8x-db-backup"
+)
+
+// Defaults of scheduled backups
+const (
+	defaultDbBackupOnCalendar    = "daily"
+	defaultDbBackupKeepLocal     = 3
+	defaultDbBackupRetentionDays = 30
+	defaultDbBackupS3Region      = "us-east-1"
+)
+
+// Scheduled backups are encrypted pg_dump custom format archives
+var dbBackupNameRe = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*\.dump\.age$`)
+
+const dbBackupServiceUnit = `[Unit]
+Description=D8X database backup
+Wants=network-online.target
+After=network-online.target
+
+[Service]
+Type=oneshot
+ExecStart=` + dbBackupScriptPath + ` run
+`
+
+const dbBackupTimerUnit = `[Unit]
+Description=Scheduled D8X database backup
+
+[Timer]
+OnCalendar=%s
+Persistent=true
+RandomizedDelaySec=5min
+
+[Install]
+WantedBy=timers.target
+`
+
+// BackupDbSchedule installs systemd timer on manager which periodically
+// creates encrypted database backups, uploads them to S3 compatible bucket and
+// prunes old backups
+func (c *Container) BackupDbSchedule(ctx *cli.Context) error {
+	styles.PrintCommandTitle("Scheduling database backups...")
+
+	cfg, err := c.ConfigRWriter.Read()
+	if err != nil {
+		return err
+	}
+	if len(cfg.DatabaseDSN) == 0 {
+		return fmt.Errorf("database dsn is not set in config")
+	}
+	pgCfg, err := pgx.ParseConfig(cfg.DatabaseDSN)
+	if err != nil {
+		return fmt.Errorf("parsing database connection string: %w", err)
+	}
+
+	backupCfg, err := c.collectDbBackupConfig(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	envFile, err := dbBackupEnvFile(backupCfg, pgCfg, cfg.GetServersLabel())
+	if err != nil {
+		return err
+	}
+	if c.DryRun != nil {
+		c.DryRun.AddSecrets(pgCfg.Password, backupCfg.S3AccessKey, backupCfg.S3SecretKey)
+	}
+
+	ip, err := c.HostsCfg.GetMangerPublicIp()
+	if err != nil {
+		return fmt.Errorf("could not find manager ip: %w", err)
+	}
+	managerConn, err := c.CreateSSHConn(ip, c.DefaultClusterUserName, c.SshKeyPath)
+	if err != nil {
+		return fmt.Errorf("creating ssh connection to manager: %w", err)
+	}
+	pwd, err := c.GetPassword(ctx)
+	if err != nil {
+		return err
+	}
+
+	if out, err := managerConn.ExecCommand(
+		fmt.Sprintf("systemd-analyze calendar '%s'", shellQuoteEscape(backupCfg.OnCalendar)),
+	); err != nil {
+		return fmt.Errorf("invalid backup schedule %s: %s", backupCfg.OnCalendar, strings.TrimSpace(string(out)))
+	}
+
+	if err := installPostgresClient(managerConn, pwd); err != nil {
+		return err
+	}
+	fmt.Println("Ensuring age and rclone are installed on manager server")
+	if out, err := managerConn.ExecCommand(sudoCommand(pwd, "apt-get -y install age rclone")); err != nil {
+		fmt.Println(string(out))
+		return fmt.Errorf("installing age and rclone on manager server: %w", err)
+	}
+
+	script, err := configs.EmbededConfigs.ReadFile("embedded/db-backup/d8x-db-backup.sh")
+	if err != nil {
+		return err
+	}
+	files := map[string][]byte{
+		"d8x-db-backup":               script,
+		"env":                         envFile,
+		"recipients":                  []byte(backupCfg.PublicKey),
+		dbBackupUnitName + ".service": []byte(dbBackupServiceUnit),
+		dbBackupUnitName + ".timer":   []byte(fmt.Sprintf(dbBackupTimerUnit, backupCfg.OnCalendar)),
+	}
+
+	fmt.Println("Installing backup script and systemd timer")
+	if err := uploadDbBackupFiles(managerConn, files); err != nil {
+		return err
+	}
+	// Staged files contain the credentials, make sure they are removed
+	defer managerConn.ExecCommand("rm -rf " + dbBackupStagingDir)
+
+	installCmd := strings.Join([]string{
+		"install -d -m 700 " + dbBackupConfigDir,
+		fmt.Sprintf("install -m 755 %s/d8x-db-backup %s", dbBackupStagingDir, dbBackupScriptPath),
+		fmt.Sprintf("install -m 600 %s/env %s/env", dbBackupStagingDir, dbBackupConfigDir),
+		fmt.Sprintf("install -m 644 %s/recipients %s/recipients", dbBackupStagingDir, dbBackupConfigDir),
+		fmt.Sprintf("install -m 644 %[1]s/%[2]s.service %[1]s/%[2]s.timer /etc/systemd/system/", dbBackupStagingDir, dbBackupUnitName),
+		"systemctl daemon-reload",
+		fmt.Sprintf("systemctl enable --now %s.timer", dbBackupUnitName),
+	}, " && ")
+	if out, err := managerConn.ExecCommand(sudoCommand(pwd, installCmd)); err != nil {
+		fmt.Println(string(out))
+		return fmt.Errorf("installing backup timer: %w", err)
+	}
+
+	cfg.DbBackup = backupCfg
+	if err := c.ConfigRWriter.Write(cfg); err != nil {
+		return err
+	}
+
+	fmt.Println(styles.SuccessText.Render(
+		fmt.Sprintf("Database backups scheduled (%s), backups are uploaded to %s", backupCfg.OnCalendar, dbBackupRemote(backupCfg)),
+	))
+	if out, err := managerConn.ExecCommand(fmt.Sprintf("systemctl list-timers %s.timer --no-pager", dbBackupUnitName)); err == nil {
+		fmt.Println(string(out))
+	}
+
+	if ctx.Bool("now") {
+		fmt.Println("Running the first backup")
+		if err := managerConn.ExecCommandPiped(
+			sudoCommand(pwd, fmt.Sprintf("systemctl start %s.service", dbBackupUnitName)),
+		); err != nil {
+			out, _ := managerConn.ExecCommand(
+				sudoCommand(pwd, fmt.Sprintf("journalctl -u %s.service -n 30 --no-pager", dbBackupUnitName)),
+			)
+			fmt.Println(string(out))
+			return fmt.Errorf("running backup: %w", err)
+		}
+		fmt.Println(styles.SuccessText.Render("Backup was created and uploaded"))
+	}
+
+	return nil
+}
+
+// collectDbBackupConfig builds backup settings from flags, previously stored
+// settings and user input
+func (c *Container) collectDbBackupConfig(ctx *cli.Context, cfg *configs.D8XConfig) (*configs.D8XDbBackupConfig, error) {
+	backupCfg := &configs.D8XDbBackupConfig{
+		OnCalendar:    defaultDbBackupOnCalendar,
+		KeepLocal:     defaultDbBackupKeepLocal,
+		RetentionDays: defaultDbBackupRetentionDays,
+		S3Region:      defaultDbBackupS3Region,
+		S3Prefix:      cfg.GetServersLabel() + "/",
+	}
+	if cfg.DbBackup != nil {
+		*backupCfg = *cfg.DbBackup
+	}
+
+	for flag, field := range map[string]*string{
+		"on-calendar":   &backupCfg.OnCalendar,
+		"s3-endpoint":   &backupCfg.S3Endpoint,
+		"s3-region":     &backupCfg.S3Region,
+		"s3-bucket":     &backupCfg.S3Bucket,
+		"s3-prefix":     &backupCfg.S3Prefix,
+		"s3-access-key": &backupCfg.S3AccessKey,
+		"s3-secret-key": &backupCfg.S3SecretKey,
+	} {
+		if ctx.IsSet(flag) {
+			*field = ctx.String(flag)
+		}
+	}
+	if ctx.IsSet("keep-local") {
+		backupCfg.KeepLocal = ctx.Int("keep-local")
+	}
+	if ctx.IsSet("retention-days") {
+		backupCfg.RetentionDays = ctx.Int("retention-days")
+	}
+	if keyFile := ctx.String("public-key"); keyFile != "" {
+		key, err := os.ReadFile(keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading public key: %w", err)
+		}
+		backupCfg.PublicKey = string(key)
+	}
+
+	if backupCfg.PublicKey == "" {
+		fmt.Println("Enter path to the age recipients file (age or ssh public keys) which backups will be encrypted to:")
+		keyFile, err := c.TUI.NewInput(
+			components.TextInputOptId("db_backup.public_key"),
+			components.TextInputOptPlaceholder("./backup-recipients.txt"),
+			components.TextInputOptDenyEmpty(),
+		)
+		if err != nil {
+			return nil, err
+		}
+		key, err := os.ReadFile(keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading public key: %w", err)
+		}
+		backupCfg.PublicKey = string(key)
+	}
+
+	inputs := []struct {
+		field       *string
+		id          string
+		prompt      string
+		placeholder string
+		masked      bool
+	}{
+		{&backupCfg.S3Endpoint, "db_backup.s3_endpoint", "Enter S3 endpoint of backups bucket:", "https://s3.us-east-1.amazonaws.com", false},
+		{&backupCfg.S3Bucket, "db_backup.s3_bucket", "Enter S3 bucket name:", "d8x-backups", false},
+		{&backupCfg.S3AccessKey, "db_backup.s3_access_key", "Enter S3 access key:", "", false},
+		{&backupCfg.S3SecretKey, "db_backup.s3_secret_key", "Enter S3 secret key:", "", true},
+	}
+	for _, in := range inputs {
+		if *in.field != "" {
+			continue
+		}
+		fmt.Println(in.prompt)
+		opts := []components.TextInputOpt{
+			components.TextInputOptId(in.id),
+			components.TextInputOptPlaceholder(in.placeholder),
+			components.TextInputOptDenyEmpty(),
+		}
+		if in.masked {
+			opts = append(opts, components.TextInputOptMasked())
+		}
+		val, err := c.TUI.NewInput(opts...)
+		if err != nil {
+			return nil, err
+		}
+		*in.field = strings.TrimSpace(val)
+	}
+
+	if err := validateDbBackupConfig(backupCfg); err != nil {
+		return nil, err
+	}
+	return backupCfg, nil
+}
+
+func validateDbBackupConfig(backupCfg *configs.D8XDbBackupConfig) error {
+	if backupCfg.KeepLocal < 1 {
+		return fmt.Errorf("--keep-local must be at least 1")
+	}
+	if backupCfg.RetentionDays < 1 {
+		return fmt.Errorf("--retention-days must be at least 1")
+	}
+	if !ValidateHttp(backupCfg.S3Endpoint) {
+		return fmt.Errorf("s3 endpoint must start with http:// or https://")
+	}
+	if strings.ContainsAny(backupCfg.S3Bucket, "/ ") || backupCfg.S3Bucket == "" {
+		return fmt.Errorf("invalid s3 bucket name %q", backupCfg.S3Bucket)
+	}
+	if strings.ContainsAny(backupCfg.OnCalendar, "\n\r") {
+		return fmt.Errorf("invalid backup schedule %q", backupCfg.OnCalendar)
+	}
+	return validateAgeRecipients(backupCfg.PublicKey)
+}
+
+// validateAgeRecipients checks that recipients file contains at least one age
+// or ssh public key
+func validateAgeRecipients(contents string) error {
+	found := 0
+	for _, line := range strings.Split(contents, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if !strings.HasPrefix(line, "age1") && !strings.HasPrefix(line, "ssh-ed25519 ") && !strings.HasPrefix(line, "ssh-rsa ") {
+			return fmt.Errorf("unsupported public key %q, use age (age1...) or ssh-ed25519/ssh-rsa public keys", line)
+		}
+		found++
+	}
+	if found == 0 {
+		return fmt.Errorf("public key file does not contain any keys")
+	}
+	return nil
+}
+
+// dbBackupEnvFile renders the environment file which is sourced by backup
+// script. pg_dump and rclone are configured via their environment variables.
+func dbBackupEnvFile(backupCfg *configs.D8XDbBackupConfig, pgCfg *pgx.ConnConfig, label string) ([]byte, error) {
+	vars := [][2]string{
+		{"PGHOST", pgCfg.Host},
+		{"PGPORT", strconv.Itoa(int(pgCfg.Port))},
+		{"PGUSER", pgCfg.User},
+		{"PGPASSWORD", pgCfg.Password},
+		{"PGDATABASE", pgCfg.Database},
+		{"BACKUP_DIR", dbBackupDir},
+		{"BACKUP_NAME_PREFIX", "backup-" + label},
+		{"KEEP_LOCAL", strconv.Itoa(backupCfg.KeepLocal)},
+		{"RETENTION_DAYS", strconv.Itoa(backupCfg.RetentionDays)},
+		{"S3_BUCKET", backupCfg.S3Bucket},
+		{"S3_PREFIX", backupCfg.S3Prefix},
+		{"RCLONE_CONFIG_D8XBACKUP_TYPE", "s3"},
+		{"RCLONE_CONFIG_D8XBACKUP_PROVIDER", "Other"},
+		{"RCLONE_CONFIG_D8XBACKUP_ENDPOINT", backupCfg.S3Endpoint},
+		{"RCLONE_CONFIG_D8XBACKUP_REGION", backupCfg.S3Region},
+		{"RCLONE_CONFIG_D8XBACKUP_ACCESS_KEY_ID", backupCfg.S3AccessKey},
+		{"RCLONE_CONFIG_D8XBACKUP_SECRET_ACCESS_KEY", backupCfg.S3SecretKey},
+	}
+
+	b := strings.Builder{}
+	b.WriteString("# Managed by d8x-cli (d8x backup-db schedule), changes will be overwritten\n")
+	for _, v := range vars {
+		if strings.ContainsAny(v[1], "\n\r") {
+			return nil, fmt.Errorf("value of %s must not contain new lines", v[0])
+		}
+		fmt.Fprintf(&b, "%s=\"%s\"\n", v[0], envQuoteEscape(v[1]))
+	}
+	return []byte(b.String()), nil
+}
+
+// envQuoteEscape escapes s to be used inside double quotes of sourced
+// environment file
+func envQuoteEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, `$`, `\$`, "`", "\\`").Replace(s)
+}
+
+// dbBackupRemote returns the bucket location of backups
+func dbBackupRemote(backupCfg *configs.D8XDbBackupConfig) string {
+	return fmt.Sprintf("%s/%s/%s", strings.TrimSuffix(backupCfg.S3Endpoint, "/"), backupCfg.S3Bucket, backupCfg.S3Prefix)
+}
+
+// uploadDbBackupFiles writes files into a temporary local directory and
+// copies them into staging directory on manager. Staged files contain
+// credentials, so staging directory and files are accessible only by the
+// cluster user.
+func uploadDbBackupFiles(managerConn conn.SSHConnection, files map[string][]byte) error {
+	tmpDir, err := os.MkdirTemp("", "d8x-db-backup")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	if out, err := managerConn.ExecCommand(
+		fmt.Sprintf("rm -rf %[1]s && install -d -m 700 %[1]s", dbBackupStagingDir),
+	); err != nil {
+		fmt.Println(string(out))
+		return fmt.Errorf("creating staging directory on manager: %w", err)
+	}
+
+	copies := []conn.SftpCopySrcDest{}
+	for _, name := range sortedKeys(files) {
+		src := filepath.Join(tmpDir, name)
+		if err := os.WriteFile(src, files[name], 0600); err != nil {
+			return err
+		}
+		copies = append(copies, conn.SftpCopySrcDest{Src: src, Dst: dbBackupStagingDir + "/" + name, Mode: 0600})
+	}
+	if err := managerConn.CopyFilesOverSftp(copies...); err != nil {
+		return fmt.Errorf("uploading backup files to manager: %w", err)
+	}
+	return nil
+}
+
+// BackupDbList lists scheduled backups stored on manager and in the bucket
+func (c *Container) BackupDbList(ctx *cli.Context) error {
+	format, err := outputFormat(ctx)
+	if err != nil {
+		return err
+	}
+	managerConn, pwd, err := c.dbBackupManagerConn(ctx)
+	if err != nil {
+		return err
+	}
+
+	local, err := listDbBackups(managerConn, pwd, "local")
+	if err != nil {
+		return err
+	}
+	remote, err := listDbBackups(managerConn, pwd, "remote")
+	if err != nil {
+		return err
+	}
+	backups := mergeDbBackups(local, remote)
+
+	if format != OutputText {
+		return printStructured(os.Stdout, format, backups)
+	}
+	formatDbBackupsText(os.Stdout, backups)
+	return nil
+}
+
+// BackupDbFetch downloads scheduled backup to local machine
+func (c *Container) BackupDbFetch(ctx *cli.Context) error {
+	name := ctx.Args().First()
+	if !dbBackupNameRe.MatchString(name) {
+		return fmt.Errorf("backup name is required, see d8x backup-db list for available backups")
+	}
+
+	managerConn, pwd, err := c.dbBackupManagerConn(ctx)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Preparing backup %s on manager server\n", name)
+	remoteFile := "./" + name
+	if out, err := managerConn.ExecCommand(
+		sudoCommand(pwd, fmt.Sprintf("%s fetch %s $(pwd)/%s", dbBackupScriptPath, name, name)),
+	); err != nil {
+		fmt.Println(string(out))
+		return fmt.Errorf("retrieving backup %s: %w", name, err)
+	}
+	defer managerConn.ExecCommand("rm -f " + remoteFile)
+
+	localPath := name
+	if outDir := ctx.String("output-dir"); outDir != "" {
+		localPath = filepath.Join(outDir, localPath)
+	}
+	localPath, err = filepath.Abs(localPath)
+	if err != nil {
+		return err
+	}
+
+	stopDownloadSpinner := make(chan struct{})
+	go c.TUI.NewSpinner(stopDownloadSpinner, "Downloading backup file to local machine")
+	err = downloadFile(managerConn, remoteFile, localPath)
+	stopDownloadSpinner <- struct{}{}
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(styles.SuccessText.Render("Backup was downloaded to " + localPath))
+	fmt.Println("Decrypt it with your age identity and restore it with pg_restore:")
+	fmt.Printf("  age --decrypt -i <identity file> -o %s %s\n", strings.TrimSuffix(name, ".age"), name)
+
+	return nil
+}
+
+// dbBackupManagerConn connects to manager for backup-db list and fetch
+func (c *Container) dbBackupManagerConn(ctx *cli.Context) (conn.SSHConnection, string, error) {
+	cfg, err := c.ConfigRWriter.Read()
+	if err != nil {
+		return nil, "", err
+	}
+	if cfg.DbBackup == nil {
+		return nil, "", fmt.Errorf("database backups are not scheduled, run d8x backup-db schedule first")
+	}
+	ip, err := c.HostsCfg.GetMangerPublicIp()
+	if err != nil {
+		return nil, "", fmt.Errorf("could not find manager ip: %w", err)
+	}
+	managerConn, err := c.CreateSSHConn(ip, c.DefaultClusterUserName, c.SshKeyPath)
+	if err != nil {
+		return nil, "", fmt.Errorf("creating ssh connection to manager: %w", err)
+	}
+	pwd, err := c.GetPassword(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	return managerConn, pwd, nil
+}
+
+// rcloneEntry is a single entry of rclone lsjson output
+type rcloneEntry struct {
+	Name    string    `json:"Name"`
+	Size    int64     `json:"Size"`
+	ModTime time.Time `json:"ModTime"`
+}
+
+// listDbBackups lists backups stored on manager (local) or in the bucket
+// (remote)
+func listDbBackups(managerConn conn.SSHConnection, pwd, location string) ([]rcloneEntry, error) {
+	out, err := managerConn.ExecCommand(sudoCommand(pwd, dbBackupScriptPath+" list "+location))
+	if err != nil {
+		return nil, fmt.Errorf("listing %s backups: %s: %w", location, strings.TrimSpace(string(out)), err)
+	}
+	return parseRcloneList(out)
+}
+
+// parseRcloneList parses rclone lsjson output, log lines printed before the
+// json are skipped
+func parseRcloneList(out []byte) ([]rcloneEntry, error) {
+	start := strings.Index(string(out), "[")
+	if start < 0 {
+		return nil, fmt.Errorf("unexpected backups list output: %s", strings.TrimSpace(string(out)))
+	}
+	entries := []rcloneEntry{}
+	if err := json.NewDecoder(strings.NewReader(string(out[start:]))).Decode(&entries); err != nil {
+		return nil, fmt.Errorf("parsing backups list: %w", err)
+	}
+	return entries, nil
+}
+
+// mergeDbBackups merges local and remote backups by name, newest first
+func mergeDbBackups(local, remote []rcloneEntry) []DbBackup {
+	byName := map[string]*DbBackup{}
+	add := func(e rcloneEntry) *DbBackup {
+		b, ok := byName[e.Name]
+		if !ok {
+			b = &DbBackup{Name: e.Name, Size: e.Size, CreatedAt: e.ModTime.UTC()}
+			byName[e.Name] = b
+		}
+		return b
+	}
+	for _, e := range local {
+		add(e).Local = true
+	}
+	for _, e := range remote {
+		add(e).Remote = true
+	}
+
+	backups := []DbBackup{}
+	for _, b := range byName {
+		backups = append(backups, *b)
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		if backups[i].CreatedAt.Equal(backups[j].CreatedAt) {
+			return backups[i].Name > backups[j].Name
+		}
+		return backups[i].CreatedAt.After(backups[j].CreatedAt)
+	})
+	return backups
+}
+
+func formatDbBackupsText(w io.Writer, backups []DbBackup) {
+	if len(backups) == 0 {
+		fmt.Fprintln(w, "No backups were found")
+		return
+	}
+	fmt.Fprintf(w, "%-52s %-20s %10s %s\n", "NAME", "CREATED", "SIZE (MB)", "STORED")
+	for _, b := range backups {
+		stored := []string{}
+		if b.Local {
+			stored = append(stored, "manager")
+		}
+		if b.Remote {
+			stored = append(stored, "bucket")
+		}
+		fmt.Fprintf(w, "%-52s %-20s %10.2f %s\n",
+			b.Name,
+			b.CreatedAt.Local().Format("2006-01-02 15:04:05"),
+			float64(b.Size)/float64(1024*1024),
+			strings.Join(stored, ", "),
+		)
+	}
+}
@@ -0,0 +1,194 @@
+package actions
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/D8-X/d8x-cli/internal/configs"
+	"github.com/D8-X/d8x-cli/internal/conn"
+	"github.com/D8-X/d8x-cli/internal/mocks"
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func testDbBackupConfig() *configs.D8XDbBackupConfig {
+	return &configs.D8XDbBackupConfig{
+		OnCalendar:    "daily",
+		KeepLocal:     3,
+		RetentionDays: 30,
+		S3Endpoint:    "http://minio:9000",
+		S3Region:      "us-east-1",
+		S3Bucket:      "backups",
+		S3Prefix:      "d8x-cluster/",
+		S3AccessKey:   "access",
+		S3SecretKey:   `se"cr$et\`,
+		PublicKey:     "age1ql3z7hjy54pw3hyww5ayyfg7zqgvc7w3j2elw8zmrj2kg5sfn9aqmcac8p\n",
+	}
+}
+
+func TestDbBackupEnvFile(t *testing.T) {
+	pgCfg, err := pgx.ParseConfig("host=db.internal port=5433 user=d8x dbname=history password='pa\\'ss `w$(rm)'")
+	require.NoError(t, err)
+
+	env, err := dbBackupEnvFile(testDbBackupConfig(), pgCfg, "d8x-cluster")
+	require.NoError(t, err)
+	assert.Contains(t, string(env), "PGPORT=\"5433\"\n")
+	assert.Contains(t, string(env), "BACKUP_NAME_PREFIX=\"backup-d8x-cluster\"\n")
+
+	// Values are read back unchanged when the file is sourced by the backup
+	// script
+	bash, err := exec.LookPath("bash")
+	if err != nil {
+		t.Skip("bash is not available")
+	}
+	envPath := filepath.Join(t.TempDir(), "env")
+	require.NoError(t, os.WriteFile(envPath, env, 0600))
+	out, err := exec.Command(bash, "-c", `set -a; . "$0"; printf '%s\n%s' "$PGPASSWORD" "$RCLONE_CONFIG_D8XBACKUP_SECRET_ACCESS_KEY"`, envPath).CombinedOutput()
+	require.NoError(t, err, string(out))
+	assert.Equal(t, "pa'ss `w$(rm)\n"+`se"cr$et\`, string(out))
+
+	// New lines are rejected
+	backupCfg := testDbBackupConfig()
+	backupCfg.S3Bucket = "backups\nPGHOST=evil"
+	_, err = dbBackupEnvFile(backupCfg, pgCfg, "d8x-cluster")
+	assert.EqualError(t, err, "value of S3_BUCKET must not contain new lines")
+}
+
+func TestValidateDbBackupConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(*configs.D8XDbBackupConfig)
+		wantErr string
+	}{
+		{
+			name:   "valid",
+			modify: func(*configs.D8XDbBackupConfig) {},
+		},
+		{
+			name: "ssh public keys and comments",
+			modify: func(c *configs.D8XDbBackupConfig) {
+				c.PublicKey = "# ops team\nssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIH ops@example.com\n\n"
+			},
+		},
+		{
+			name:    "keep local",
+			modify:  func(c *configs.D8XDbBackupConfig) { c.KeepLocal = 0 },
+			wantErr: "--keep-local must be at least 1",
+		},
+		{
+			name:    "retention",
+			modify:  func(c *configs.D8XDbBackupConfig) { c.RetentionDays = 0 },
+			wantErr: "--retention-days must be at least 1",
+		},
+		{
+			name:    "endpoint",
+			modify:  func(c *configs.D8XDbBackupConfig) { c.S3Endpoint = "minio:9000" },
+			wantErr: "s3 endpoint must start with http:// or https://",
+		},
+		{
+			name:    "bucket",
+			modify:  func(c *configs.D8XDbBackupConfig) { c.S3Bucket = "a/b" },
+			wantErr: `invalid s3 bucket name "a/b"`,
+		},
+		{
+			name:    "private key",
+			modify:  func(c *configs.D8XDbBackupConfig) { c.PublicKey = "AGE-SECRET-KEY-1XYZ" },
+			wantErr: `unsupported public key "AGE-SECRET-KEY-1XYZ", use age (age1...) or ssh-ed25519/ssh-rsa public keys`,
+		},
+		{
+			name:    "empty key file",
+			modify:  func(c *configs.D8XDbBackupConfig) { c.PublicKey = "# nothing\n" },
+			wantErr: "public key file does not contain any keys",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backupCfg := testDbBackupConfig()
+			tt.modify(backupCfg)
+			err := validateDbBackupConfig(backupCfg)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestListDbBackups(t *testing.T) {
+	local, err := parseRcloneList([]byte(`[
+{"Path":"backup-d8x-2024-01-02-03-00-00.dump.age","Name":"backup-d8x-2024-01-02-03-00-00.dump.age","Size":2097152,"MimeType":"application/octet-stream","ModTime":"2024-01-02T03:00:05Z","IsDir":false}
+]`))
+	require.NoError(t, err)
+	remote, err := parseRcloneList([]byte(`2024/01/03 10:00:00 NOTICE: Config file "/root/.config/rclone/rclone.conf" not found - using defaults
+[
+{"Path":"backup-d8x-2024-01-01-03-00-00.dump.age","Name":"backup-d8x-2024-01-01-03-00-00.dump.age","Size":1048576,"ModTime":"2024-01-01T03:00:07Z","IsDir":false},
+{"Path":"backup-d8x-2024-01-02-03-00-00.dump.age","Name":"backup-d8x-2024-01-02-03-00-00.dump.age","Size":2097152,"ModTime":"2024-01-02T03:00:09Z","IsDir":false}
+]`))
+	require.NoError(t, err)
+
+	_, err = parseRcloneList([]byte("Failed to create file system"))
+	assert.Error(t, err)
+
+	backups := mergeDbBackups(local, remote)
+	assert.Equal(t, []DbBackup{
+		{
+			Name:      "backup-d8x-2024-01-02-03-00-00.dump.age",
+			Size:      2097152,
+			CreatedAt: time.Date(2024, 1, 2, 3, 0, 5, 0, time.UTC),
+			Local:     true,
+			Remote:    true,
+		},
+		{
+			Name:      "backup-d8x-2024-01-01-03-00-00.dump.age",
+			Size:      1048576,
+			CreatedAt: time.Date(2024, 1, 1, 3, 0, 7, 0, time.UTC),
+			Remote:    true,
+		},
+	}, backups)
+
+	buf := &bytes.Buffer{}
+	formatDbBackupsText(buf, backups)
+	assert.Contains(t, buf.String(), "backup-d8x-2024-01-02-03-00-00.dump.age")
+	assert.Contains(t, buf.String(), "2.00 manager, bucket\n")
+	assert.Contains(t, buf.String(), "1.00 bucket\n")
+}
+
+func TestDbBackupNameRe(t *testing.T) {
+	assert.True(t, dbBackupNameRe.MatchString("backup-d8x-2024-01-02-03-00-00.dump.age"))
+	assert.False(t, dbBackupNameRe.MatchString("../etc/shadow.dump.age"))
+	assert.False(t, dbBackupNameRe.MatchString("backup; rm -rf /.dump.age"))
+	assert.False(t, dbBackupNameRe.MatchString("backup.dump.sql"))
+}
+
+func TestUploadDbBackupFiles(t *testing.T) {
+	ctl := gomock.NewController(t)
+	sshConn := mocks.NewMockSSHConnection(ctl)
+
+	gomock.InOrder(
+		sshConn.EXPECT().ExecCommand("rm -rf ./d8x-db-backup && install -d -m 700 ./d8x-db-backup").Return(nil, nil),
+		sshConn.EXPECT().CopyFilesOverSftp(gomock.Any()).DoAndReturn(func(copies ...conn.SftpCopySrcDest) error {
+			require.Len(t, copies, 2)
+			for _, cp := range copies {
+				assert.Equal(t, os.FileMode(0600), cp.Mode)
+			}
+			assert.Equal(t, "./d8x-db-backup/env", copies[0].Dst)
+			contents, err := os.ReadFile(copies[0].Src)
+			require.NoError(t, err)
+			assert.Equal(t, "PGPASSWORD=secret\n", string(contents))
+			return nil
+		}),
+	)
+
+	require.NoError(t, uploadDbBackupFiles(sshConn, map[string][]byte{
+		"env":        []byte("PGPASSWORD=secret\n"),
+		"recipients": []byte("age1"),
+	}))
+}
@@ -0,0 +1,282 @@
+package actions
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/D8-X/d8x-cli/internal/components"
+	"github.com/D8-X/d8x-cli/internal/conn"
+	"github.com/D8-X/d8x-cli/internal/styles"
+	"github.com/jackc/pgx/v5"
+	"github.com/urfave/cli/v2"
+)
+
+// Swarm services which use the database and are scaled down during restore
+// with --stop-services
+var restoreDbServices = []string{"history", "referral"}
+
+// Database used to drop and create the target database
+const postgresMaintenanceDb = "postgres"
+
+// RestoreDb restores backup created by backup-db into the configured database
+// or a new database
+func (c *Container) RestoreDb(ctx *cli.Context) error {
+	styles.PrintCommandTitle("Restoring database...")
+
+	if ctx.Args().Len() != 1 {
+		return fmt.Errorf("backup file is required, usage: d8x restore-db <file>")
+	}
+	backupFile, err := filepath.Abs(ctx.Args().First())
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(backupFile); err != nil {
+		return fmt.Errorf("reading backup file: %w", err)
+	}
+	ext, err := backupFileExt(backupFile)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := c.ConfigRWriter.Read()
+	if err != nil {
+		return err
+	}
+
+	ip, err := c.HostsCfg.GetMangerPublicIp()
+	if err != nil {
+		return fmt.Errorf("could not find manager ip: %w", err)
+	}
+
+	if len(cfg.DatabaseDSN) == 0 {
+		return fmt.Errorf("database dsn is not set in config")
+	}
+
+	pgCfg, err := pgx.ParseConfig(cfg.DatabaseDSN)
+	if err != nil {
+		return fmt.Errorf("parsing database connection string: %w", err)
+	}
+	targetDb := pgCfg.Database
+	if db := ctx.String("database"); db != "" {
+		targetDb = db
+	}
+
+	managerConn, err := c.CreateSSHConn(ip, c.DefaultClusterUserName, c.SshKeyPath)
+	if err != nil {
+		return fmt.Errorf("creating ssh connection to manager: %w", err)
+	}
+
+	pwd, err := c.GetPassword(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Databases are dropped and created from the maintenance database, since
+	// target database can't be dropped while connected to it
+	maintenanceCfg := pgCfg.Copy()
+	maintenanceCfg.Database = postgresMaintenanceDb
+	pgConn, err := pgConnTunnel(managerConn, maintenanceCfg)
+	if err != nil {
+		return fmt.Errorf("connecting to postgres database via manager tunnel: %w", err)
+	}
+	defer pgConn.Close(context.Background())
+
+	exists := false
+	if err := pgConn.QueryRow(
+		context.Background(),
+		"select exists(select 1 from pg_database where datname = $1)",
+		targetDb,
+	).Scan(&exists); err != nil {
+		return fmt.Errorf("checking whether database %s exists: %w", targetDb, err)
+	}
+
+	if exists {
+		ok, err := c.TUI.NewPrompt(
+			fmt.Sprintf("Database %s on %s already exists. All of its data will be deleted and replaced with %s. Do you want to continue?", targetDb, pgCfg.Host, filepath.Base(backupFile)),
+			false,
+			components.PromptOptId("restore_db.confirm"),
+		)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			fmt.Println("Database was not restored")
+			return nil
+		}
+	}
+
+	remoteFile := fmt.Sprintf("restore-%s%s", time.Now().Format("2006-01-02-15-04-05"), ext)
+	fmt.Printf("Uploading %s to manager server\n", filepath.Base(backupFile))
+	if err := managerConn.CopyFilesOverSftp(
+		conn.SftpCopySrcDest{Src: backupFile, Dst: remoteFile},
+	); err != nil {
+		return fmt.Errorf("uploading backup file to manager: %w", err)
+	}
+	defer func() {
+		fmt.Println("Removing backup file from server")
+		if out, err := managerConn.ExecCommand(fmt.Sprintf("rm -rf %s %s", remoteFile, restoreDirName(remoteFile))); err != nil {
+			fmt.Println(string(out))
+			fmt.Println(styles.ErrorText.Render(fmt.Sprintf("removing backup file from manager: %v", err)))
+		}
+	}()
+
+	if err := installPostgresClient(managerConn, pwd); err != nil {
+		return err
+	}
+	if ext == ".dump.sql.zst" {
+		if out, err := managerConn.ExecCommand(sudoCommand(pwd, "apt-get -y install zstd")); err != nil {
+			fmt.Println(string(out))
+			return fmt.Errorf("installing zstd on manager server: %w", err)
+		}
+	}
+
+	if ctx.Bool("stop-services") {
+		replicas, err := scaleDownSwarmServices(managerConn, restoreDbServices)
+		// Scale back up whatever was scaled down, also when restore fails
+		defer func() {
+			if len(replicas) == 0 {
+				return
+			}
+			if err := scaleSwarmServices(managerConn, replicas); err != nil {
+				fmt.Println(styles.ErrorText.Render(fmt.Sprintf("scaling services back up: %v", err)))
+			} else {
+				fmt.Println("Services were scaled back up")
+			}
+		}()
+		if err != nil {
+			return err
+		}
+	}
+
+	db := pgx.Identifier{targetDb}.Sanitize()
+	if exists {
+		fmt.Printf("Dropping database %s\n", targetDb)
+		if _, err := pgConn.Exec(
+			context.Background(),
+			"select pg_terminate_backend(pid) from pg_stat_activity where datname = $1 and pid <> pg_backend_pid()",
+			targetDb,
+		); err != nil {
+			return fmt.Errorf("terminating connections to database %s: %w", targetDb, err)
+		}
+		if _, err := pgConn.Exec(context.Background(), "drop database "+db); err != nil {
+			return fmt.Errorf("dropping database %s: %w", targetDb, err)
+		}
+	}
+	fmt.Printf("Creating database %s\n", targetDb)
+	if _, err := pgConn.Exec(context.Background(), "create database "+db); err != nil {
+		return fmt.Errorf("creating database %s: %w", targetDb, err)
+	}
+
+	fmt.Printf("Restoring database %s from %s\n", targetDb, filepath.Base(backupFile))
+	if err := managerConn.ExecCommandPiped(restoreCommand(pgCfg, targetDb, remoteFile, ext)); err != nil {
+		return fmt.Errorf("loading backup: %w", err)
+	}
+
+	fmt.Println(styles.SuccessText.Render(fmt.Sprintf("Database %s was restored from %s", targetDb, backupFile)))
+	if targetDb != pgCfg.Database {
+		fmt.Printf("Services still use database %s, update database dsn in your configuration to switch them to %s\n", pgCfg.Database, targetDb)
+	}
+
+	return nil
+}
+
+// scaleDownSwarmServices scales given swarm services to zero replicas and
+// returns their replicas before scaling. Services which were scaled down are
+// returned also on error.
+func scaleDownSwarmServices(managerConn conn.SSHConnection, services []string) (map[string]int, error) {
+	replicas := map[string]int{}
+	for _, svc := range services {
+		svcStackName := dockerStackName + "_" + svc
+		out, err := managerConn.ExecCommand(
+			fmt.Sprintf("docker service inspect --format '{{.Spec.Mode.Replicated.Replicas}}' %s", svcStackName),
+		)
+		if err != nil {
+			return replicas, fmt.Errorf("retrieving replicas of service %s: %s: %w", svc, strings.TrimSpace(string(out)), err)
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(string(out)))
+		if err != nil {
+			return replicas, fmt.Errorf("parsing replicas of service %s: %w", svc, err)
+		}
+
+		fmt.Printf("Scaling down service %s\n", svc)
+		if out, err := managerConn.ExecCommand(fmt.Sprintf("docker service scale %s=0", svcStackName)); err != nil {
+			return replicas, fmt.Errorf("scaling down service %s: %s: %w", svc, strings.TrimSpace(string(out)), err)
+		}
+		replicas[svc] = n
+	}
+	return replicas, nil
+}
+
+// scaleSwarmServices scales swarm services to given number of replicas
+func scaleSwarmServices(managerConn conn.SSHConnection, replicas map[string]int) error {
+	if len(replicas) == 0 {
+		return nil
+	}
+	args := []string{}
+	for _, svc := range sortedKeys(replicas) {
+		args = append(args, fmt.Sprintf("%s_%s=%d", dockerStackName, svc, replicas[svc]))
+	}
+	if out, err := managerConn.ExecCommand("docker service scale " + strings.Join(args, " ")); err != nil {
+		return fmt.Errorf("%s: %w", strings.TrimSpace(string(out)), err)
+	}
+	return nil
+}
+
+// Extensions of backups created by backup-db, see backupOptions.fileName
+var restoreBackupExts = []string{".dump.sql", ".dump.sql.gz", ".dump.sql.zst", ".dump", ".dir.tar"}
+
+// backupFileExt returns the backup-db extension of backup file
+func backupFileExt(backupFile string) (string, error) {
+	for _, ext := range restoreBackupExts {
+		if strings.HasSuffix(backupFile, ext) {
+			return ext, nil
+		}
+	}
+	// Plain sql files created elsewhere
+	if strings.HasSuffix(backupFile, ".sql") {
+		return ".dump.sql", nil
+	}
+	return "", fmt.Errorf("unsupported backup file %s, supported extensions: %s", filepath.Base(backupFile), strings.Join(restoreBackupExts, ", "))
+}
+
+// restoreDirName returns the directory where directory format backup is
+// extracted
+func restoreDirName(remoteFile string) string {
+	return strings.TrimSuffix(remoteFile, ".tar")
+}
+
+// restoreCommand builds the command which loads remoteFile into targetDb.
+// Plain SQL backups are loaded with psql, archives with pg_restore.
+func restoreCommand(pgCfg *pgx.ConnConfig, targetDb, remoteFile, ext string) string {
+	target := fmt.Sprintf(
+		"-h %s -p %d -U %s -d '%s'",
+		pgCfg.Host,
+		pgCfg.Port,
+		pgCfg.User,
+		shellQuoteEscape(targetDb),
+	)
+	env := fmt.Sprintf("PGPASSWORD='%s'", shellQuoteEscape(pgCfg.Password))
+	psql := env + " psql -q -v ON_ERROR_STOP=1 " + target
+	pgRestore := env + " pg_restore --exit-on-error --no-owner " + target
+
+	var cmd string
+	switch ext {
+	case ".dump.sql.gz":
+		cmd = fmt.Sprintf("gunzip -c %s | %s", remoteFile, psql)
+	case ".dump.sql.zst":
+		cmd = fmt.Sprintf("zstd -dc %s | %s", remoteFile, psql)
+	case ".dump":
+		cmd = fmt.Sprintf("%s %s", pgRestore, remoteFile)
+	case ".dir.tar":
+		dir := restoreDirName(remoteFile)
+		cmd = fmt.Sprintf("mkdir -p %[1]s && tar -xf %[2]s -C %[1]s --strip-components=1 && %[3]s %[1]s", dir, remoteFile, pgRestore)
+	default:
+		cmd = fmt.Sprintf("%s -f %s", psql, remoteFile)
+	}
+	return fmt.Sprintf("bash -c 'set -o pipefail; %s'", shellQuoteEscape(cmd))
+}
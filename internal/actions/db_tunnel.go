@@ -6,7 +6,6 @@ import (
 	"net"
 	"strconv"
 
-	"github.com/D8-X/d8x-cli/internal/conn"
 	"github.com/D8-X/d8x-cli/internal/styles"
 	"github.com/jackc/pgx/v5"
 	"github.com/urfave/cli/v2"
@@ -54,7 +53,7 @@ func (c *Container) DbTunnel(ctx *cli.Context) error {
 	}
 
 	// SSH into the manager
-	managerConn, err := conn.NewSSHConnection(ip, c.DefaultClusterUserName, c.SshKeyPath)
+	managerConn, err := c.CreateSSHConn(ip, c.DefaultClusterUserName, c.SshKeyPath)
 	if err != nil {
 		return fmt.Errorf("creating ssh connection to manager: %w", err)
 	}
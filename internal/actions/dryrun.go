@@ -0,0 +1,109 @@
+package actions
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/D8-X/d8x-cli/internal/configs"
+	"github.com/D8-X/d8x-cli/internal/conn"
+	"github.com/D8-X/d8x-cli/internal/files"
+)
+
+// EnableDryRun swaps ssh connections, local command runner, config writes and
+// file writes with recording implementations. Nothing is executed on remote
+// servers, recorded plan can be printed with PrintDryRunPlan. Local
+// configuration files are still generated so they can be reviewed.
+func (c *Container) EnableDryRun() {
+	plan := conn.NewPlan()
+	c.DryRun = plan
+	c.CreateSSHConn = plan.SSHConnectionEstablisher()
+	c.CreateSSHConnWithBastion = plan.SSHConnectionWithBastionEstablisher()
+	c.RunCmd = plan.RunCmd
+	c.FS = &dryRunFS{FSInteractor: c.FS, plan: plan}
+	c.ConfigRWriter = &dryRunConfigRW{D8XConfigReadWriter: c.ConfigRWriter, plan: plan}
+}
+
+// PrintDryRunPlan prints the recorded dry run plan with all known secrets
+// masked
+func (c *Container) PrintDryRunPlan() {
+	if c.DryRun == nil {
+		return
+	}
+
+	c.DryRun.AddSecrets(c.UserPassword)
+	if c.Input != nil {
+		c.DryRun.AddSecrets(
+			c.Input.brokerDeployInput.privateKey,
+			c.Input.swarmDeployInput.referralPaymentExecutorPrivateKey,
+		)
+	}
+	if c.ConfigRWriter != nil {
+		if cfg, err := c.ConfigRWriter.Read(); err == nil {
+			c.DryRun.AddSecrets(cfg.SecretValues()...)
+		}
+	}
+
+	fmt.Println()
+	c.DryRun.Print(os.Stdout)
+}
+
+var _ (files.FSInteractor) = (*dryRunFS)(nil)
+
+// dryRunFS records file writes (passwords, etc) instead of writing them
+type dryRunFS struct {
+	files.FSInteractor
+	plan *conn.Plan
+}
+
+func (d *dryRunFS) WriteFile(fileName string, contents []byte) error {
+	d.plan.AddSecrets(string(contents))
+	d.plan.Record("localhost", conn.PlanStepLocal, "write file "+fileName)
+	return nil
+}
+
+var _ (configs.D8XConfigReadWriter) = (*dryRunConfigRW)(nil)
+
+// dryRunConfigRW records config writes instead of persisting them, so dry run
+// does not change deployment state. Written config is kept in memory and
+// returned by subsequent reads.
+type dryRunConfigRW struct {
+	configs.D8XConfigReadWriter
+	plan *conn.Plan
+
+	written []byte
+}
+
+func (d *dryRunConfigRW) Read() (*configs.D8XConfig, error) {
+	if d.written == nil {
+		return d.D8XConfigReadWriter.Read()
+	}
+	cfg := configs.NewD8XConfig()
+	if err := json.Unmarshal(d.written, cfg); err != nil {
+		return nil, err
+	}
+	if cfg.HttpRpcList == nil {
+		cfg.HttpRpcList = map[string][]string{}
+	}
+	if cfg.WsRpcList == nil {
+		cfg.WsRpcList = map[string][]string{}
+	}
+	return cfg, nil
+}
+
+func (d *dryRunConfigRW) Write(cfg *configs.D8XConfig) error {
+	written, err := json.Marshal(cfg)
+	if err != nil {
+		return err
+	}
+	d.written = written
+	d.plan.AddSecrets(cfg.SecretValues()...)
+	d.plan.Record("localhost", conn.PlanStepLocal, "update "+d.GetPath())
+	return nil
+}
+
+func (d *dryRunConfigRW) WriteTo(filePath string, cfg *configs.D8XConfig) error {
+	d.plan.AddSecrets(cfg.SecretValues()...)
+	d.plan.Record("localhost", conn.PlanStepLocal, "write config to "+filePath)
+	return nil
+}
@@ -0,0 +1,233 @@
+package actions
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/D8-X/d8x-cli/internal/configs"
+	"github.com/D8-X/d8x-cli/internal/conn"
+	"github.com/D8-X/d8x-cli/internal/styles"
+	"github.com/urfave/cli/v2"
+	"golang.org/x/crypto/ssh"
+)
+
+// Supported --target values of exec command
+const (
+	ExecTargetManager = "manager"
+	ExecTargetWorkers = "workers"
+	ExecTargetBroker  = "broker"
+	ExecTargetAll     = "all"
+)
+
+// execTarget is a single node on which exec command runs
+type execTarget struct {
+	node   string
+	ip     string
+	worker bool
+}
+
+// Exec runs the command on selected nodes in parallel and prints the output
+// of each node
+func (c *Container) Exec(ctx *cli.Context) error {
+	format, err := outputFormat(ctx)
+	if err != nil {
+		return err
+	}
+
+	cmd := strings.TrimSpace(strings.Join(ctx.Args().Slice(), " "))
+	if cmd == "" {
+		return fmt.Errorf("command is required, usage: d8x exec --target <target> -- <cmd>")
+	}
+
+	cfg, err := c.ConfigRWriter.Read()
+	if err != nil {
+		return err
+	}
+
+	targets, err := c.execTargets(ctx.String("target"))
+	if err != nil {
+		return err
+	}
+
+	if ctx.Bool("sudo") {
+		pwd, err := c.GetPassword(ctx)
+		if err != nil {
+			return err
+		}
+		if c.DryRun != nil {
+			c.DryRun.AddSecrets(pwd)
+		}
+		cmd = sudoCommand(pwd, cmd)
+	}
+
+	results := c.runExec(cfg, targets, cmd)
+
+	if format != OutputText {
+		if err := printStructured(os.Stdout, format, results); err != nil {
+			return err
+		}
+	} else {
+		formatExecResultsText(os.Stdout, results)
+	}
+
+	failed := 0
+	for _, r := range results {
+		if r.ExitStatus != 0 {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("command failed on %d of %d nodes", failed, len(results))
+	}
+	return nil
+}
+
+// execTargets resolves the --target value to the list of nodes. Broker is
+// included in "all" only when broker server exists.
+func (c *Container) execTargets(target string) ([]execTarget, error) {
+	targets := []execTarget{}
+
+	addManager := func() error {
+		ip, err := c.HostsCfg.GetMangerPublicIp()
+		if err != nil {
+			return err
+		}
+		targets = append(targets, execTarget{node: "manager", ip: ip})
+		return nil
+	}
+	addWorkers := func() error {
+		ips, err := c.HostsCfg.GetWorkerIps()
+		if err != nil {
+			return err
+		}
+		for i, ip := range ips {
+			targets = append(targets, execTarget{node: fmt.Sprintf("worker-%d", i+1), ip: ip, worker: true})
+		}
+		return nil
+	}
+	addBroker := func() error {
+		ip, err := c.HostsCfg.GetBrokerPublicIp()
+		if err != nil {
+			return err
+		}
+		targets = append(targets, execTarget{node: "broker", ip: ip})
+		return nil
+	}
+
+	var err error
+	switch target {
+	case ExecTargetManager:
+		err = addManager()
+	case ExecTargetWorkers:
+		err = addWorkers()
+	case ExecTargetBroker:
+		err = addBroker()
+	case ExecTargetAll:
+		if err = addManager(); err == nil {
+			err = addWorkers()
+		}
+		if err == nil {
+			// Broker server is optional
+			_ = addBroker()
+		}
+	default:
+		return nil, fmt.Errorf("unknown target %s, supported values: manager, workers, broker, all", target)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("no nodes found for target %s", target)
+	}
+	return targets, nil
+}
+
+// sudoCommand wraps cmd to be run with sudo with the provided user password
+func sudoCommand(pwd, cmd string) string {
+	return fmt.Sprintf(`echo '%s' | sudo -S -p '' bash -c '%s'`, shellQuoteEscape(pwd), shellQuoteEscape(cmd))
+}
+
+// shellQuoteEscape escapes s to be used inside single quotes
+func shellQuoteEscape(s string) string {
+	return strings.ReplaceAll(s, `'`, `'\''`)
+}
+
+// runExec runs cmd on all targets in parallel. Results are returned in the
+// order of targets.
+func (c *Container) runExec(cfg *configs.D8XConfig, targets []execTarget, cmd string) []ExecResult {
+	results := make([]ExecResult, len(targets))
+
+	wg := sync.WaitGroup{}
+	for i, t := range targets {
+		wg.Add(1)
+		go func(i int, t execTarget) {
+			defer wg.Done()
+			results[i] = c.execOnTarget(cfg, t, cmd)
+		}(i, t)
+	}
+	wg.Wait()
+
+	return results
+}
+
+func (c *Container) execOnTarget(cfg *configs.D8XConfig, t execTarget, cmd string) ExecResult {
+	result := ExecResult{Node: t.node, Host: t.ip}
+
+	var (
+		sshConn conn.SSHConnection
+		err     error
+	)
+	if t.worker {
+		sshConn, err = c.GetWorkerConnection(t.ip, cfg)
+	} else {
+		sshConn, err = c.CreateSSHConn(t.ip, c.DefaultClusterUserName, c.SshKeyPath)
+	}
+	if err != nil {
+		result.ExitStatus = -1
+		result.Error = fmt.Sprintf("connecting to %s: %v", t.node, err)
+		return result
+	}
+
+	out, err := sshConn.ExecCommand(cmd)
+	result.Output = string(out)
+
+	var exitErr *ssh.ExitError
+	switch {
+	case err == nil:
+	case errors.As(err, &exitErr):
+		result.ExitStatus = exitErr.ExitStatus()
+	default:
+		result.ExitStatus = -1
+		result.Error = err.Error()
+	}
+
+	return result
+}
+
+// formatExecResultsText writes output of each node prefixed with the node name
+// followed by its exit status
+func formatExecResultsText(w io.Writer, results []ExecResult) {
+	for _, r := range results {
+		prefix := fmt.Sprintf("[%s]", r.Node)
+		if out := strings.TrimRight(r.Output, "\n"); out != "" {
+			for _, line := range strings.Split(out, "\n") {
+				fmt.Fprintf(w, "%s %s\n", prefix, line)
+			}
+		}
+		if r.Error != "" {
+			fmt.Fprintf(w, "%s %s\n", prefix, styles.ErrorText.Render("error: "+r.Error))
+		}
+
+		status := fmt.Sprintf("exit status %d", r.ExitStatus)
+		if r.ExitStatus == 0 {
+			status = styles.SuccessText.Render(status)
+		} else {
+			status = styles.ErrorText.Render(status)
+		}
+		fmt.Fprintf(w, "%s %s\n", prefix, status)
+	}
+}
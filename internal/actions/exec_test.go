@@ -0,0 +1,151 @@
+package actions
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/D8-X/d8x-cli/internal/configs"
+	"github.com/D8-X/d8x-cli/internal/conn"
+	"github.com/D8-X/d8x-cli/internal/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestExecTargets(t *testing.T) {
+	tests := []struct {
+		name      string
+		target    string
+		hasBroker bool
+		want      []execTarget
+		wantErr   string
+	}{
+		{
+			name:   "manager",
+			target: ExecTargetManager,
+			want:   []execTarget{{node: "manager", ip: "1.1.1.1"}},
+		},
+		{
+			name:   "workers",
+			target: ExecTargetWorkers,
+			want: []execTarget{
+				{node: "worker-1", ip: "10.0.0.1", worker: true},
+				{node: "worker-2", ip: "10.0.0.2", worker: true},
+			},
+		},
+		{
+			name:    "missing broker",
+			target:  ExecTargetBroker,
+			wantErr: assert.AnError.Error(),
+		},
+		{
+			name:   "all without broker",
+			target: ExecTargetAll,
+			want: []execTarget{
+				{node: "manager", ip: "1.1.1.1"},
+				{node: "worker-1", ip: "10.0.0.1", worker: true},
+				{node: "worker-2", ip: "10.0.0.2", worker: true},
+			},
+		},
+		{
+			name:      "all with broker",
+			target:    ExecTargetAll,
+			hasBroker: true,
+			want: []execTarget{
+				{node: "manager", ip: "1.1.1.1"},
+				{node: "worker-1", ip: "10.0.0.1", worker: true},
+				{node: "worker-2", ip: "10.0.0.2", worker: true},
+				{node: "broker", ip: "2.2.2.2"},
+			},
+		},
+		{
+			name:    "unknown target",
+			target:  "db",
+			wantErr: "unknown target db, supported values: manager, workers, broker, all",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctl := gomock.NewController(t)
+			hosts := mocks.NewMockHostsFileInteractor(ctl)
+			hosts.EXPECT().GetMangerPublicIp().Return("1.1.1.1", nil).AnyTimes()
+			hosts.EXPECT().GetWorkerIps().Return([]string{"10.0.0.1", "10.0.0.2"}, nil).AnyTimes()
+			if tt.hasBroker {
+				hosts.EXPECT().GetBrokerPublicIp().Return("2.2.2.2", nil).AnyTimes()
+			} else {
+				hosts.EXPECT().GetBrokerPublicIp().Return("", assert.AnError).AnyTimes()
+			}
+
+			c := &Container{HostsCfg: hosts}
+			got, err := c.execTargets(tt.target)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestRunExecViaBastion(t *testing.T) {
+	ctl := gomock.NewController(t)
+	hosts := mocks.NewMockHostsFileInteractor(ctl)
+	hosts.EXPECT().GetMangerPublicIp().Return("1.1.1.1", nil).AnyTimes()
+
+	manager := mocks.NewMockSSHConnection(ctl)
+	worker1 := mocks.NewMockSSHConnection(ctl)
+	worker2 := mocks.NewMockSSHConnection(ctl)
+
+	manager.EXPECT().ExecCommand("uptime").Return([]byte("manager up\n"), nil)
+	worker1.EXPECT().ExecCommand("uptime").Return([]byte("worker 1 up\nline 2\n"), nil)
+
+	c := &Container{
+		HostsCfg:               hosts,
+		DefaultClusterUserName: "d8xtrader",
+		SshKeyPath:             "id_ed25519",
+		CreateSSHConn: func(serverIp, user, idFilePath string) (conn.SSHConnection, error) {
+			return manager, nil
+		},
+		CreateSSHConnWithBastion: func(bastion conn.SSHConnection, serverIp, user, idFilePath string) (conn.SSHConnection, error) {
+			assert.Same(t, manager, bastion)
+			switch serverIp {
+			case "10.0.0.1":
+				return worker1, nil
+			case "10.0.0.2":
+				return worker2, assert.AnError
+			}
+			return nil, nil
+		},
+	}
+
+	targets := []execTarget{
+		{node: "manager", ip: "1.1.1.1"},
+		{node: "worker-1", ip: "10.0.0.1", worker: true},
+		{node: "worker-2", ip: "10.0.0.2", worker: true},
+	}
+	results := c.runExec(&configs.D8XConfig{ServerProvider: configs.D8XServerProviderAWS}, targets, "uptime")
+
+	assert.Equal(t, []ExecResult{
+		{Node: "manager", Host: "1.1.1.1", Output: "manager up\n"},
+		{Node: "worker-1", Host: "10.0.0.1", Output: "worker 1 up\nline 2\n"},
+		{Node: "worker-2", Host: "10.0.0.2", ExitStatus: -1, Error: "connecting to worker-2: " + assert.AnError.Error()},
+	}, results)
+
+	buf := &bytes.Buffer{}
+	formatExecResultsText(buf, results[:2])
+	assert.Equal(t, `[manager] manager up
+[manager] exit status 0
+[worker-1] worker 1 up
+[worker-1] line 2
+[worker-1] exit status 0
+`, buf.String())
+}
+
+func TestSudoCommand(t *testing.T) {
+	assert.Equal(t,
+		`echo 'pa'\''ss' | sudo -S -p '' bash -c 'echo '\''hi'\'''`,
+		sudoCommand("pa'ss", "echo 'hi'"),
+	)
+}
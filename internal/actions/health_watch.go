@@ -0,0 +1,545 @@
+package actions
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/D8-X/d8x-cli/internal/configs"
+	"github.com/D8-X/d8x-cli/internal/conn"
+	"github.com/D8-X/d8x-cli/internal/styles"
+	"github.com/charmbracelet/bubbles/spinner"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/urfave/cli/v2"
+)
+
+const (
+	// Request timeout of a single endpoint check in watch mode
+	healthWatchRequestTimeout = time.Second * 10
+	// Number of failed tasks of a service within restartLoopWindow which is
+	// considered to be a restart loop
+	restartLoopThreshold = 3
+	restartLoopWindow    = time.Minute * 10
+	// Number of latest events displayed in watch mode
+	healthWatchDisplayedEvents = 10
+)
+
+type healthEventKind string
+
+const (
+	healthEventServiceDown       healthEventKind = "service_down"
+	healthEventServiceUp         healthEventKind = "service_up"
+	healthEventReplicaShortfall  healthEventKind = "replica_shortfall"
+	healthEventReplicasRecovered healthEventKind = "replicas_recovered"
+	healthEventRestartLoop       healthEventKind = "restart_loop"
+	healthEventRestartLoopEnded  healthEventKind = "restart_loop_ended"
+)
+
+// healthEvent is a state change detected in watch mode
+type healthEvent struct {
+	Time time.Time       `json:"time"`
+	Kind healthEventKind `json:"kind"`
+	// HTTP service or swarm service name
+	Target  string `json:"target"`
+	Message string `json:"message"`
+}
+
+func (e healthEvent) String() string {
+	icon := ok
+	switch e.Kind {
+	case healthEventServiceDown, healthEventRestartLoop:
+		icon = notok
+	case healthEventReplicaShortfall:
+		icon = warning
+	}
+	return fmt.Sprintf("%s [%s] %s: %s", icon, e.Time.Format(time.RFC3339), e.Target, e.Message)
+}
+
+// serviceHealthy reports whether service was reached and did not respond
+// with server error
+func serviceHealthy(h ServiceHealth) bool {
+	return h.Reachable && h.HTTPStatus < 500
+}
+
+// swarmTaskFailed reports whether swarm task has failed
+func swarmTaskFailed(t SwarmTaskStatus) bool {
+	return t.Error != "" ||
+		strings.HasPrefix(t.State, "Failed") ||
+		strings.HasPrefix(t.State, "Rejected")
+}
+
+// healthWatcher keeps the last observed health state and detects its changes
+type healthWatcher struct {
+	// Service name -> service was healthy
+	healthy map[string]bool
+	// Swarm service name -> running replicas were below desired
+	shortfall map[string]bool
+	// Swarm service name -> failed task id -> time the failure was observed.
+	// Failures found in the first observation have zero time.
+	failedTasks map[string]map[string]time.Time
+	// Swarm service name -> restart loop was reported
+	looping map[string]bool
+	// Whether at least one report was observed
+	initialized bool
+}
+
+func newHealthWatcher() *healthWatcher {
+	return &healthWatcher{
+		healthy:     map[string]bool{},
+		shortfall:   map[string]bool{},
+		failedTasks: map[string]map[string]time.Time{},
+		looping:     map[string]bool{},
+	}
+}
+
+// observe updates the watcher state with report and returns detected state
+// changes. Services are assumed to be healthy before the first observation,
+// so only problems are reported initially.
+func (w *healthWatcher) observe(report HealthReport, now time.Time) []healthEvent {
+	events := []healthEvent{}
+
+	for _, svc := range report.Services {
+		healthy := serviceHealthy(svc)
+		wasHealthy, seen := w.healthy[svc.Service]
+		if !seen {
+			wasHealthy = true
+		}
+		w.healthy[svc.Service] = healthy
+
+		if wasHealthy && !healthy {
+			msg := "service is unreachable at " + svc.Hostname
+			if svc.Reachable {
+				msg = fmt.Sprintf("service responded with HTTP status %d at %s", svc.HTTPStatus, svc.Hostname)
+			}
+			events = append(events, healthEvent{Time: now, Kind: healthEventServiceDown, Target: svc.Service, Message: msg})
+		}
+		if !wasHealthy && healthy {
+			events = append(events, healthEvent{
+				Time:    now,
+				Kind:    healthEventServiceUp,
+				Target:  svc.Service,
+				Message: fmt.Sprintf("service is up again with HTTP status %d at %s", svc.HTTPStatus, svc.Hostname),
+			})
+		}
+	}
+
+	for _, svc := range report.SwarmServices {
+		shortfall := svc.RunningReplicas < svc.DesiredReplicas
+		if shortfall && !w.shortfall[svc.Name] {
+			events = append(events, healthEvent{
+				Time:    now,
+				Kind:    healthEventReplicaShortfall,
+				Target:  svc.Name,
+				Message: fmt.Sprintf("only %d of %d replicas are running", svc.RunningReplicas, svc.DesiredReplicas),
+			})
+		}
+		if !shortfall && w.shortfall[svc.Name] {
+			events = append(events, healthEvent{
+				Time:    now,
+				Kind:    healthEventReplicasRecovered,
+				Target:  svc.Name,
+				Message: fmt.Sprintf("all %d replicas are running", svc.DesiredReplicas),
+			})
+		}
+		w.shortfall[svc.Name] = shortfall
+
+		// Count task failures observed within restart loop window
+		failed, ok := w.failedTasks[svc.Name]
+		if !ok {
+			failed = map[string]time.Time{}
+			w.failedTasks[svc.Name] = failed
+		}
+		lastError := ""
+		for _, task := range svc.Tasks {
+			if task.Id == "" || !swarmTaskFailed(task) {
+				continue
+			}
+			if _, seen := failed[task.Id]; !seen {
+				observedAt := now
+				if !w.initialized {
+					observedAt = time.Time{}
+				}
+				failed[task.Id] = observedAt
+				lastError = task.Error
+			}
+		}
+		recentFailures := 0
+		for id, observedAt := range failed {
+			if now.Sub(observedAt) > restartLoopWindow {
+				if !observedAt.IsZero() {
+					delete(failed, id)
+				}
+				continue
+			}
+			recentFailures++
+		}
+
+		looping := recentFailures >= restartLoopThreshold
+		if looping && !w.looping[svc.Name] {
+			msg := fmt.Sprintf("%d tasks failed within %s", recentFailures, restartLoopWindow)
+			if lastError != "" {
+				msg += ", last error: " + lastError
+			}
+			events = append(events, healthEvent{Time: now, Kind: healthEventRestartLoop, Target: svc.Name, Message: msg})
+		}
+		if !looping && w.looping[svc.Name] {
+			events = append(events, healthEvent{
+				Time:    now,
+				Kind:    healthEventRestartLoopEnded,
+				Target:  svc.Name,
+				Message: fmt.Sprintf("no more than %d tasks failed within %s", recentFailures, restartLoopWindow),
+			})
+		}
+		w.looping[svc.Name] = looping
+	}
+
+	w.initialized = true
+
+	return events
+}
+
+// healthNotifier delivers health events to a webhook
+type healthNotifier struct {
+	name string
+	url  string
+	// payload builds the json request body for events
+	payload func(events []healthEvent) any
+}
+
+// formatHealthEventsText renders events as plain text message
+func formatHealthEventsText(events []healthEvent) string {
+	lines := make([]string, len(events))
+	for i, e := range events {
+		lines[i] = e.String()
+	}
+	return "D8X health:\n" + strings.Join(lines, "\n")
+}
+
+// healthNotifiersFromFlags creates notifiers for the webhook flags of health
+// command
+func healthNotifiersFromFlags(ctx *cli.Context) ([]healthNotifier, error) {
+	notifiers := []healthNotifier{}
+
+	if url := ctx.String("webhook-url"); url != "" {
+		notifiers = append(notifiers, healthNotifier{
+			name: "webhook",
+			url:  url,
+			payload: func(events []healthEvent) any {
+				return map[string]any{"events": events}
+			},
+		})
+	}
+
+	if url := ctx.String("slack-webhook-url"); url != "" {
+		notifiers = append(notifiers, healthNotifier{
+			name: "slack",
+			url:  url,
+			payload: func(events []healthEvent) any {
+				return map[string]any{"text": formatHealthEventsText(events)}
+			},
+		})
+	}
+
+	token, chatId := ctx.String("telegram-bot-token"), ctx.String("telegram-chat-id")
+	if (token == "") != (chatId == "") {
+		return nil, fmt.Errorf("both --telegram-bot-token and --telegram-chat-id must be provided")
+	}
+	if token != "" {
+		notifiers = append(notifiers, healthNotifier{
+			name: "telegram",
+			url:  fmt.Sprintf("https://api.telegram.org/bot%s/sendMessage", token),
+			payload: func(events []healthEvent) any {
+				return map[string]any{"chat_id": chatId, "text": formatHealthEventsText(events)}
+			},
+		})
+	}
+
+	return notifiers, nil
+}
+
+// notifyHealthEvents sends events to all notifiers and returns delivery errors
+func (c *Container) notifyHealthEvents(notifiers []healthNotifier, events []healthEvent) []error {
+	errs := []error{}
+	for _, n := range notifiers {
+		body, err := json.Marshal(n.payload(events))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s notification: %w", n.name, err))
+			continue
+		}
+		resp, err := c.HttpClient.Post(n.url, "application/json", bytes.NewReader(body))
+		if err != nil {
+			// Notifier urls contain secrets (telegram bot token, slack
+			// webhook path), report only the cause
+			var urlErr *url.Error
+			if errors.As(err, &urlErr) {
+				err = urlErr.Err
+			}
+			errs = append(errs, fmt.Errorf("%s notification: %w", n.name, err))
+			continue
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 300 {
+			errs = append(errs, fmt.Errorf("%s notification: HTTP status %d", n.name, resp.StatusCode))
+		}
+	}
+	return errs
+}
+
+// checkServiceOnce performs a single health check request of svc
+func (c *Container) checkServiceOnce(svc configs.D8XService) ServiceHealth {
+	prefix := "http://"
+	if svc.UsesHTTPS {
+		prefix = "https://"
+	}
+	h := ServiceHealth{Service: string(svc.Name), Hostname: prefix + svc.HostName}
+
+	ctx, cancel := context.WithTimeout(context.Background(), healthWatchRequestTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, h.Hostname, nil)
+	if err != nil {
+		return h
+	}
+	resp, err := c.HttpClient.Do(req)
+	if err != nil {
+		return h
+	}
+	resp.Body.Close()
+	h.Reachable = true
+	h.HTTPStatus = resp.StatusCode
+	return h
+}
+
+// healthWatchPoller polls services health, keeping the manager connection
+// between polls
+type healthWatchPoller struct {
+	c           *Container
+	cfg         *configs.D8XConfig
+	managerConn conn.SSHConnection
+}
+
+func (p *healthWatchPoller) poll() (HealthReport, error) {
+	services := []configs.D8XService{}
+	for _, svc := range p.cfg.Services {
+		services = append(services, svc)
+	}
+	sort.Slice(services, func(i, j int) bool {
+		return services[i].Name < services[j].Name
+	})
+	report := HealthReport{Services: make([]ServiceHealth, len(services))}
+
+	wg := sync.WaitGroup{}
+	for i, svc := range services {
+		wg.Add(1)
+		go func(i int, svc configs.D8XService) {
+			defer wg.Done()
+			report.Services[i] = p.c.checkServiceOnce(svc)
+		}(i, svc)
+	}
+	wg.Wait()
+
+	if !p.cfg.SwarmDeployed {
+		return report, nil
+	}
+
+	if p.managerConn == nil {
+		ip, err := p.c.HostsCfg.GetMangerPublicIp()
+		if err != nil {
+			return report, err
+		}
+		managerConn, err := p.c.CreateSSHConn(ip, p.c.DefaultClusterUserName, p.c.SshKeyPath)
+		if err != nil {
+			return report, fmt.Errorf("establishing ssh connection to manager node: %w", err)
+		}
+		p.managerConn = managerConn
+	}
+	swarmServices, err := healthChecksSwarmServices(p.managerConn)
+	if err != nil {
+		// Reconnect on the next poll
+		p.managerConn = nil
+		return report, fmt.Errorf("retrieving docker swarm info: %w", err)
+	}
+	report.SwarmServices = swarmServices
+
+	return report, nil
+}
+
+// healthWatch polls services health every interval, displays the live status
+// and sends state change notifications
+func (c *Container) healthWatch(ctx *cli.Context, cfg *configs.D8XConfig) error {
+	interval := ctx.Duration("interval")
+	if interval <= 0 {
+		return fmt.Errorf("interval must be positive")
+	}
+	notifiers, err := healthNotifiersFromFlags(ctx)
+	if err != nil {
+		return err
+	}
+
+	poller := &healthWatchPoller{c: c, cfg: cfg}
+	watcher := newHealthWatcher()
+
+	program := tea.NewProgram(initHealthWatchModel(healthWatchModel{
+		interval:  interval,
+		notifiers: len(notifiers),
+	}))
+
+	done := make(chan struct{})
+	go func() {
+		for {
+			report, err := poller.poll()
+			now := time.Now()
+			msg := healthWatchReportMsg{report: report, err: err, time: now}
+			// Do not report swarm state changes when swarm info could not be
+			// retrieved
+			if err == nil {
+				msg.events = watcher.observe(report, now)
+			} else {
+				msg.events = watcher.observe(HealthReport{Services: report.Services}, now)
+			}
+			if len(msg.events) > 0 {
+				msg.notifyErrs = c.notifyHealthEvents(notifiers, msg.events)
+			}
+			program.Send(msg)
+
+			select {
+			case <-done:
+				return
+			case <-time.After(interval):
+			}
+		}
+	}()
+
+	_, err = program.Run()
+	close(done)
+	return err
+}
+
+type healthWatchReportMsg struct {
+	report     HealthReport
+	events     []healthEvent
+	err        error
+	notifyErrs []error
+	time       time.Time
+}
+
+var _ (tea.Model) = (*healthWatchModel)(nil)
+
+type healthWatchModel struct {
+	interval  time.Duration
+	notifiers int
+
+	report   *HealthReport
+	lastPoll time.Time
+	err      error
+	// Latest events, newest first
+	events     []healthEvent
+	notifyErrs []error
+	spinner    spinner.Model
+}
+
+func initHealthWatchModel(m healthWatchModel) healthWatchModel {
+	s := spinner.New()
+	s.Spinner = spinner.Dot
+	s.Style = lipgloss.NewStyle().Foreground(styles.D8XPurple)
+	m.spinner = s
+	return m
+}
+
+func (m healthWatchModel) Init() tea.Cmd {
+	return m.spinner.Tick
+}
+
+func (m healthWatchModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "q", "esc", "ctrl+c":
+			return m, tea.Quit
+		}
+		return m, nil
+	case healthWatchReportMsg:
+		m.report = &msg.report
+		m.lastPoll = msg.time
+		m.err = msg.err
+		m.notifyErrs = msg.notifyErrs
+		for _, e := range msg.events {
+			m.events = append([]healthEvent{e}, m.events...)
+		}
+		if len(m.events) > healthWatchDisplayedEvents {
+			m.events = m.events[:healthWatchDisplayedEvents]
+		}
+		return m, nil
+	default:
+		var cmd tea.Cmd
+		m.spinner, cmd = m.spinner.Update(msg)
+		return m, cmd
+	}
+}
+
+func (m healthWatchModel) View() string {
+	if m.report == nil {
+		return m.spinner.View() + " Performing health checks\n"
+	}
+
+	out := strings.Builder{}
+	out.WriteString(fmt.Sprintf(
+		"Watching health every %s, last check %s, %d notifier(s). Press q to exit.\n\nHTTP Endpoints:\n",
+		m.interval, m.lastPoll.Format(time.TimeOnly), m.notifiers,
+	))
+
+	for _, svc := range m.report.Services {
+		status := "unreachable"
+		icon := notok
+		if svc.Reachable {
+			status = "HTTP Status (" + strconv.Itoa(svc.HTTPStatus) + ")"
+			icon = ok
+			if svc.HTTPStatus >= 500 {
+				icon = warning
+			}
+		}
+		out.WriteString(fmt.Sprintf("%s %-20s %-45s %s\n", icon, svc.Service, svc.Hostname, status))
+	}
+
+	if len(m.report.SwarmServices) > 0 {
+		out.WriteString("\nDocker swarm services:\n")
+		for _, svc := range m.report.SwarmServices {
+			failed := 0
+			for _, t := range svc.Tasks {
+				if swarmTaskFailed(t) {
+					failed++
+				}
+			}
+			line := fmt.Sprintf("%-35s replicas %d/%d failed tasks %d", svc.Name, svc.RunningReplicas, svc.DesiredReplicas, failed)
+			if svc.RunningReplicas < svc.DesiredReplicas {
+				line = styles.ErrorText.Render(line)
+			}
+			out.WriteString(line + "\n")
+		}
+	}
+
+	if m.err != nil {
+		out.WriteString("\n" + styles.ErrorText.Render(m.err.Error()) + "\n")
+	}
+	for _, err := range m.notifyErrs {
+		out.WriteString(styles.ErrorText.Render(err.Error()) + "\n")
+	}
+
+	if len(m.events) > 0 {
+		out.WriteString("\nEvents:\n")
+		for _, e := range m.events {
+			out.WriteString(e.String() + "\n")
+		}
+	}
+
+	return out.String()
+}
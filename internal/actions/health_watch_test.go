@@ -0,0 +1,163 @@
+package actions
+
+import (
+	"encoding/json"
+	"flag"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/urfave/cli/v2"
+)
+
+func TestHealthWatcherObserve(t *testing.T) {
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	up := ServiceHealth{Service: "api", Hostname: "https://api.d8x.xyz", HTTPStatus: 200, Reachable: true}
+	down := ServiceHealth{Service: "api", Hostname: "https://api.d8x.xyz"}
+	failingTask := func(id string) SwarmTaskStatus {
+		return SwarmTaskStatus{Id: id, Name: "stack_api.1", Node: "worker-01", State: "Failed 1 second ago", Error: "task: non-zero exit (1)"}
+	}
+
+	// Each step is observed one minute after the previous one
+	steps := []struct {
+		name       string
+		report     HealthReport
+		wantEvents []healthEventKind
+	}{
+		{
+			name: "initial healthy state and old failures are not reported",
+			report: HealthReport{
+				Services: []ServiceHealth{up},
+				SwarmServices: []SwarmServiceStatus{
+					{Name: "stack_api", RunningReplicas: 1, DesiredReplicas: 1, Tasks: []SwarmTaskStatus{failingTask("old1"), failingTask("old2"), failingTask("old3")}},
+				},
+			},
+			wantEvents: []healthEventKind{},
+		},
+		{
+			name: "service down and replica shortfall",
+			report: HealthReport{
+				Services: []ServiceHealth{down},
+				SwarmServices: []SwarmServiceStatus{
+					{Name: "stack_api", RunningReplicas: 0, DesiredReplicas: 1, Tasks: []SwarmTaskStatus{failingTask("t1")}},
+				},
+			},
+			wantEvents: []healthEventKind{healthEventServiceDown, healthEventReplicaShortfall},
+		},
+		{
+			name: "no changes",
+			report: HealthReport{
+				Services: []ServiceHealth{down},
+				SwarmServices: []SwarmServiceStatus{
+					{Name: "stack_api", RunningReplicas: 0, DesiredReplicas: 1, Tasks: []SwarmTaskStatus{failingTask("t1"), failingTask("t2")}},
+				},
+			},
+			wantEvents: []healthEventKind{},
+		},
+		{
+			name: "restart loop",
+			report: HealthReport{
+				Services: []ServiceHealth{down},
+				SwarmServices: []SwarmServiceStatus{
+					{Name: "stack_api", RunningReplicas: 0, DesiredReplicas: 1, Tasks: []SwarmTaskStatus{failingTask("t1"), failingTask("t2"), failingTask("t3")}},
+				},
+			},
+			wantEvents: []healthEventKind{healthEventRestartLoop},
+		},
+		{
+			name: "recovery",
+			report: HealthReport{
+				Services: []ServiceHealth{up},
+				SwarmServices: []SwarmServiceStatus{
+					{Name: "stack_api", RunningReplicas: 1, DesiredReplicas: 1},
+				},
+			},
+			wantEvents: []healthEventKind{healthEventServiceUp, healthEventReplicasRecovered},
+		},
+	}
+
+	w := newHealthWatcher()
+	for i, step := range steps {
+		events := w.observe(step.report, start.Add(time.Minute*time.Duration(i)))
+		kinds := []healthEventKind{}
+		for _, e := range events {
+			kinds = append(kinds, e.Kind)
+		}
+		assert.Equal(t, step.wantEvents, kinds, step.name)
+	}
+
+	// Failures drop out of the restart loop window
+	events := w.observe(steps[4].report, start.Add(restartLoopWindow+time.Minute*5))
+	require.Len(t, events, 1)
+	assert.Equal(t, healthEventRestartLoopEnded, events[0].Kind)
+}
+
+func TestNotifyHealthEvents(t *testing.T) {
+	received := map[string]map[string]any{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		payload := map[string]any{}
+		_ = json.Unmarshal(body, &payload)
+		received[r.URL.Path] = payload
+	}))
+	defer server.Close()
+
+	set := flag.NewFlagSet("health", flag.ContinueOnError)
+	set.String("webhook-url", server.URL+"/generic", "")
+	set.String("slack-webhook-url", server.URL+"/slack", "")
+	set.String("telegram-bot-token", "", "")
+	set.String("telegram-chat-id", "", "")
+	ctx := cli.NewContext(nil, set, nil)
+
+	notifiers, err := healthNotifiersFromFlags(ctx)
+	require.NoError(t, err)
+	require.Len(t, notifiers, 2)
+
+	events := []healthEvent{
+		{Time: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC), Kind: healthEventServiceDown, Target: "api", Message: "service is unreachable at https://api.d8x.xyz"},
+	}
+	c := &Container{HttpClient: server.Client()}
+	assert.Empty(t, c.notifyHealthEvents(notifiers, events))
+
+	assert.Equal(t,
+		[]any{map[string]any{
+			"time":    "2024-01-01T12:00:00Z",
+			"kind":    "service_down",
+			"target":  "api",
+			"message": "service is unreachable at https://api.d8x.xyz",
+		}},
+		received["/generic"]["events"],
+	)
+	assert.Equal(t,
+		"D8X health:\n❌ [2024-01-01T12:00:00Z] api: service is unreachable at https://api.d8x.xyz",
+		received["/slack"]["text"],
+	)
+
+	// Telegram requires both token and chat id
+	require.NoError(t, set.Set("telegram-bot-token", "token"))
+	_, err = healthNotifiersFromFlags(ctx)
+	assert.EqualError(t, err, "both --telegram-bot-token and --telegram-chat-id must be provided")
+}
+
+func TestNotifyHealthEventsRedactsUrl(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	client := server.Client()
+	// Requests fail since server is closed
+	server.Close()
+
+	notifiers := []healthNotifier{{
+		name:    "telegram",
+		url:     server.URL + "/bot123:secret-token/sendMessage",
+		payload: func(events []healthEvent) any { return events },
+	}}
+	c := &Container{HttpClient: client}
+	errs := c.notifyHealthEvents(notifiers, []healthEvent{})
+	require.Len(t, errs, 1)
+	assert.Contains(t, errs[0].Error(), "telegram notification: ")
+	assert.NotContains(t, errs[0].Error(), "secret-token")
+}
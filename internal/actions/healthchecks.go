@@ -4,9 +4,11 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"os"
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/D8-X/d8x-cli/internal/configs"
@@ -21,13 +23,29 @@ import (
 const MaxRequestWaitTime = time.Second * 60
 
 func (c *Container) HealthCheck(ctx *cli.Context) error {
-	styles.PrintCommandTitle("Performing health checks...")
+	format, err := outputFormat(ctx)
+	if err != nil {
+		return err
+	}
+	structured := format != OutputText
+	if structured && ctx.Bool("watch") {
+		return fmt.Errorf("--watch can not be used with %s output", format)
+	}
+
+	if !structured {
+		styles.PrintCommandTitle("Performing health checks...")
+	}
 
 	cfg, err := c.ConfigRWriter.Read()
 	if err != nil {
 		return err
 	}
 
+	if ctx.Bool("watch") {
+		return c.healthWatch(ctx, cfg)
+	}
+
+	wg := sync.WaitGroup{}
 	svcsForModel := []*serviceHostnameStatus{}
 	for _, svc := range cfg.Services {
 		prefix := "http://"
@@ -51,7 +69,9 @@ func (c *Container) HealthCheck(ctx *cli.Context) error {
 		)
 
 		// Listen for updates from health checker
+		wg.Add(1)
 		go func(ch chan healthCheckMsg, s *serviceHostnameStatus) {
+			defer wg.Done()
 			for info := range ch {
 
 				if info.done {
@@ -67,12 +87,21 @@ func (c *Container) HealthCheck(ctx *cli.Context) error {
 		}(ch, shs)
 	}
 
-	_, err = tea.NewProgram(initHealthCheckModel(healthCheckModel{
-		services: svcsForModel,
-	})).Run()
+	if structured {
+		wg.Wait()
+	} else {
+		_, err = tea.NewProgram(initHealthCheckModel(healthCheckModel{
+			services: svcsForModel,
+		})).Run()
 
-	if err != nil {
-		return err
+		if err != nil {
+			return err
+		}
+	}
+
+	report := HealthReport{Services: make([]ServiceHealth, len(svcsForModel))}
+	for i, svc := range svcsForModel {
+		report.Services[i] = svc.health()
 	}
 
 	if cfg.SwarmDeployed {
@@ -81,17 +110,25 @@ func (c *Container) HealthCheck(ctx *cli.Context) error {
 		if err != nil {
 			return err
 		}
-		managerConn, err := conn.NewSSHConnection(ip, c.DefaultClusterUserName, c.SshKeyPath)
+		managerConn, err := c.CreateSSHConn(ip, c.DefaultClusterUserName, c.SshKeyPath)
 		if err != nil {
 			return fmt.Errorf("establishing ssh connection to manager node: %w", err)
 		}
 		// Once http endpoint checks are done - run docker services check
-		dockerSwarmInfoString, err := healthChecksSwarmServices(managerConn)
+		swarmServices, err := healthChecksSwarmServices(managerConn)
 		if err != nil {
 			return fmt.Errorf("retrieving docker swarm info: %w", err)
 		}
-		// Print the docker services info outside the bubbletea program
-		fmt.Printf("\nDocker swarm services status:%s\n", dockerSwarmInfoString)
+		report.SwarmServices = swarmServices
+
+		if !structured {
+			// Print the docker services info outside the bubbletea program
+			fmt.Printf("\nDocker swarm services status:%s\n", formatSwarmServicesStatus(swarmServices))
+		}
+	}
+
+	if structured {
+		return printStructured(os.Stdout, format, report)
 	}
 
 	return nil
@@ -139,122 +176,106 @@ func (c *Container) healthCheckWithBackoff(ch chan healthCheckMsg, svc configs.D
 }
 
 // healthChecksSwarmServices parses services statuses from manager node
-func healthChecksSwarmServices(managerConn conn.SSHConnection) (string, error) {
+func healthChecksSwarmServices(managerConn conn.SSHConnection) ([]SwarmServiceStatus, error) {
 
-	cmd := `docker service ls | awk 'NR > 1' | awk  '{print $2}' | xargs docker service ps --format 'table {{.Node}}[##]{{.Name}}[##]{{.CurrentState}}[##]{{.Error}}[##]' --no-trunc`
+	cmd := `docker service ls | awk 'NR > 1' | awk  '{print $2}' | xargs docker service ps ` + swarmServicePsArgs
 
 	psOutput, err := managerConn.ExecCommand(cmd)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	lsOutput, err := managerConn.ExecCommand("docker service ls")
 	if err != nil {
-		return "", err
-	}
-
-	psLines := strings.Split(string(psOutput), "\n")[1:]
-	lsLines := strings.Split(string(lsOutput), "\n")[1:]
-
-	// docker ps output info
-	type svcPsInfo struct {
-		node         string
-		currentState string
-		err          string
-		// svc task name (appened with .<int>)
-		name string
+		return nil, err
 	}
 
-	type svcInfo struct {
-		name string
-		// Number of running replicas
-		running int
-		// Number of total replicas defined to run
-		total          int
-		replicasString string
-		psInfo         []svcPsInfo
-	}
+	return parseSwarmServicesStatus(
+		strings.Split(string(lsOutput), "\n")[1:],
+		strings.Split(string(psOutput), "\n")[1:],
+	), nil
+}
 
+// parseSwarmServicesStatus parses `docker service ls` and `docker service ps`
+// output lines (without headers) into services statuses sorted by name
+func parseSwarmServicesStatus(lsLines, psLines []string) []SwarmServiceStatus {
 	// Parse `docker ls` info
 	//Fields: ID,NAME,MODE,REPLICAS,IMAGE,PORTS
-	svcNames := []string{}
-	svcs := map[string]svcInfo{}
+	svcs := []SwarmServiceStatus{}
+	svcIndex := map[string]int{}
 	for _, line := range lsLines {
 		fields := strings.Fields(line)
 
-		if len(fields) >= 3 {
+		if len(fields) >= 4 {
 			replicas := strings.Split(fields[3], "/")
 			running, _ := strconv.Atoi(replicas[0])
-			total, _ := strconv.Atoi(replicas[1])
-			info := svcInfo{
-				name:           fields[1],
-				running:        running,
-				total:          total,
-				psInfo:         []svcPsInfo{},
-				replicasString: fields[3],
+			total := 0
+			if len(replicas) > 1 {
+				total, _ = strconv.Atoi(replicas[1])
 			}
-			svcs[fields[1]] = info
-			svcNames = append(svcNames, fields[1])
+			svcs = append(svcs, SwarmServiceStatus{
+				Name:            fields[1],
+				RunningReplicas: running,
+				DesiredReplicas: total,
+				Tasks:           []SwarmTaskStatus{},
+			})
 		}
 	}
-	sort.StringSlice(svcNames).Sort()
+	sort.Slice(svcs, func(i, j int) bool {
+		return svcs[i].Name < svcs[j].Name
+	})
+	for i, svc := range svcs {
+		svcIndex[svc.Name] = i
+	}
 
 	// Parse `docker ps` info
-	// NODE, NAME, CURRENT STATE, ERROR
-	for _, line := range psLines {
-		// See the cmd for separator
-		fields := strings.Split(line, "[##]")
-		for i, f := range fields {
-			fields[i] = strings.TrimSpace(f)
-		}
-
-		if len(fields) >= 3 {
-			name := strings.Split(fields[1], ".")[0]
-			err := fields[3]
-
-			if v, ok := svcs[name]; ok {
-				v.psInfo = append(v.psInfo, svcPsInfo{
-					node:         fields[0],
-					currentState: fields[2],
-					err:          err,
-					name:         fields[1],
-				})
-				svcs[name] = v
-			}
+	for _, psInfo := range parseSwarmServicePs(psLines) {
+		name := strings.Split(psInfo.name, ".")[0]
+		if i, ok := svcIndex[name]; ok {
+			svcs[i].Tasks = append(svcs[i].Tasks, SwarmTaskStatus{
+				Id:    psInfo.id,
+				Name:  psInfo.name,
+				Node:  psInfo.node,
+				State: psInfo.currentState,
+				Error: psInfo.err,
+			})
 		}
 	}
 
-	// Build the output
+	return svcs
+}
+
+// formatSwarmServicesStatus renders services statuses as styled text
+func formatSwarmServicesStatus(svcs []SwarmServiceStatus) string {
 	fullOutput := strings.Builder{}
-	for _, svcName := range svcNames {
+	for _, v := range svcs {
 		out := strings.Builder{}
-		v := svcs[svcName]
 		out.WriteByte('\n')
 
 		// Name and instances
 		nameAndInstances := fmt.Sprintf(
-			"%s\n  instances: %s",
-			svcName, v.replicasString,
+			"%s\n  instances: %d/%d",
+			v.Name, v.RunningReplicas, v.DesiredReplicas,
 		)
 		out.WriteString(nameAndInstances)
 
 		// Instances info
 
-		for _, psInfo := range v.psInfo {
+		for _, task := range v.Tasks {
 			out.WriteString("\n  \\_ ")
-			out.WriteString(psInfo.name)
+			out.WriteString(task.Name)
 			out.WriteString(" on ")
-			out.WriteString(psInfo.node)
+			out.WriteString(task.Node)
 			out.WriteString(" status ")
-			out.WriteString(psInfo.currentState)
+			out.WriteString(task.State)
 
-			if psInfo.err != "" {
+			if task.Error != "" {
 				out.WriteString(" ")
-				out.WriteString(psInfo.err)
+				out.WriteString(task.Error)
 			}
 		}
 
-		if v.running < v.total {
+		if v.RunningReplicas < v.DesiredReplicas {
 			fullOutput.WriteString(styles.ErrorText.Render(
 				out.String(),
 			))
@@ -264,7 +285,50 @@ func healthChecksSwarmServices(managerConn conn.SSHConnection) (string, error) {
 
 	}
 
-	return fullOutput.String(), nil
+	return fullOutput.String()
+}
+
+// swarmServicePsArgs are the docker service ps arguments used to retrieve
+// services tasks info parsed by parseSwarmServicePs
+const swarmServicePsArgs = `--format 'table {{.Node}}[##]{{.Name}}[##]{{.CurrentState}}[##]{{.Error}}[##]{{.ID}}[##]' --no-trunc`
+
+// docker ps output info
+type svcPsInfo struct {
+	node         string
+	currentState string
+	err          string
+	// svc task name (appened with .<int>)
+	name string
+	// task id
+	id string
+}
+
+// parseSwarmServicePs parses `docker service ps` output lines (without the
+// header) retrieved with swarmServicePsArgs
+func parseSwarmServicePs(psLines []string) []svcPsInfo {
+	tasks := []svcPsInfo{}
+	// NODE, NAME, CURRENT STATE, ERROR, ID
+	for _, line := range psLines {
+		// See the cmd for separator
+		fields := strings.Split(line, "[##]")
+		for i, f := range fields {
+			fields[i] = strings.TrimSpace(f)
+		}
+
+		if len(fields) >= 4 {
+			task := svcPsInfo{
+				node:         fields[0],
+				currentState: fields[2],
+				err:          fields[3],
+				name:         fields[1],
+			}
+			if len(fields) >= 5 {
+				task.id = fields[4]
+			}
+			tasks = append(tasks, task)
+		}
+	}
+	return tasks
 }
 
 const (
@@ -283,6 +347,21 @@ type serviceHostnameStatus struct {
 	responseStatus     int
 }
 
+// health returns the final health check result of svc
+func (svc *serviceHostnameStatus) health() ServiceHealth {
+	h := ServiceHealth{
+		Service:    svc.service,
+		Hostname:   svc.hostname,
+		HTTPStatus: svc.responseStatus,
+		Reachable:  svc.success,
+	}
+	// First request is not a retry
+	if svc.currentRetry > 0 {
+		h.Retries = svc.currentRetry - 1
+	}
+	return h
+}
+
 var _ (tea.Model) = (*healthCheckModel)(nil)
 
 type healthCheckModel struct {
@@ -0,0 +1,106 @@
+package actions
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSwarmServicesStatus(t *testing.T) {
+	lsLines := []string{
+		"ykq0n9k1oh9h   stack_history    replicated   1/1        ghcr.io/d8-x/d8x-trader-history:main   ",
+		"4dkw9bsw6m1p   stack_api        replicated   1/2        ghcr.io/d8-x/d8x-trader-main:main      ",
+		"",
+	}
+	psLines := []string{
+		"worker-01[##]stack_api.1[##]Running 2 hours ago[##][##]t1[##]",
+		"worker-02[##]stack_api.2[##]Rejected 1 minute ago[##]No such image[##]t2[##]",
+		"worker-02[##]stack_history.1[##]Running 2 hours ago[##][##]t3[##]",
+		"",
+	}
+
+	want := []SwarmServiceStatus{
+		{
+			Name:            "stack_api",
+			RunningReplicas: 1,
+			DesiredReplicas: 2,
+			Tasks: []SwarmTaskStatus{
+				{Id: "t1", Name: "stack_api.1", Node: "worker-01", State: "Running 2 hours ago"},
+				{Id: "t2", Name: "stack_api.2", Node: "worker-02", State: "Rejected 1 minute ago", Error: "No such image"},
+			},
+		},
+		{
+			Name:            "stack_history",
+			RunningReplicas: 1,
+			DesiredReplicas: 1,
+			Tasks: []SwarmTaskStatus{
+				{Id: "t3", Name: "stack_history.1", Node: "worker-02", State: "Running 2 hours ago"},
+			},
+		},
+	}
+
+	assert.Equal(t, want, parseSwarmServicesStatus(lsLines, psLines))
+}
+
+func TestPrintStructured(t *testing.T) {
+	report := HealthReport{
+		Services: []ServiceHealth{
+			{Service: "api", Hostname: "https://api.d8x.xyz", HTTPStatus: 200, Retries: 1, Reachable: true},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		format  string
+		wantOut string
+		wantErr string
+	}{
+		{
+			name:   "json",
+			format: OutputJSON,
+			wantOut: `{
+  "services": [
+    {
+      "service": "api",
+      "hostname": "https://api.d8x.xyz",
+      "http_status": 200,
+      "retries": 1,
+      "reachable": true
+    }
+  ]
+}
+`,
+		},
+		{
+			name:   "yaml",
+			format: OutputYAML,
+			wantOut: `services:
+- service: api
+  hostname: https://api.d8x.xyz
+  http_status: 200
+  retries: 1
+  reachable: true
+`,
+		},
+		{
+			name:    "text",
+			format:  OutputText,
+			wantErr: "unsupported structured output format text",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			err := printStructured(buf, tt.format, report)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantOut, buf.String())
+		})
+	}
+}
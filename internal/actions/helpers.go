@@ -33,11 +33,21 @@ func (c *Container) DisplayPasswordAlert() {
 
 // Get password gets the password with the following precedence:
 // 1. --password flag
-// 2. ./password.txt file in cwd
-func defaultPasswordGetter(ctx *cli.Context) (string, error) {
+// 2. secrets vault, when it is used
+// 3. ./password.txt file in cwd
+func (c *Container) defaultPasswordGetter(ctx *cli.Context) (string, error) {
 	if pwd := ctx.String(flags.Password); pwd != "" {
 		return pwd, nil
 	}
+	if c.Secrets != nil {
+		secrets, err := c.Secrets.Load()
+		if err != nil {
+			return "", fmt.Errorf("could not retrieve the password: %w", err)
+		}
+		if pwd := secrets[configs.SecretUserPassword]; pwd != "" {
+			return pwd, nil
+		}
+	}
 	if pwd, err := os.ReadFile(configs.DEFAULT_PASSWORD_FILE); err != nil {
 		return "", fmt.Errorf("could not retrieve the password: %w", err)
 	} else {
@@ -68,9 +78,10 @@ func ValidateHttp(url string) bool {
 // entered key's address is correct. If any of the validation or
 // confirmation steps fail, it will restart the collection process. Returned
 // values are private key without 0x prefix and its address.
-func (c *Container) CollectAndValidatePrivateKey(title string) (string, string, error) {
+func (c *Container) CollectAndValidatePrivateKey(id, title string) (string, string, error) {
 	fmt.Println(title)
 	pk, err := c.TUI.NewInput(
+		components.TextInputOptId(id),
 		components.TextInputOptPlaceholder("<YOUR PRIVATE KEY>"),
 		components.TextInputOptMasked(),
 		components.TextInputOptDenyEmpty(),
@@ -81,20 +92,26 @@ func (c *Container) CollectAndValidatePrivateKey(title string) (string, string,
 	pk = strings.TrimPrefix(pk, "0x")
 	addr, err := PrivateKeyToAddress(pk)
 	if err != nil {
+		if err := components.RejectAnswer(c.TUI, id, "invalid private key: "+err.Error()); err != nil {
+			return "", "", err
+		}
 		info := fmt.Sprintf("Invalid private key, please try again...\n - %s\n", err.Error())
 		fmt.Println(styles.ErrorText.Render(info))
-		return c.CollectAndValidatePrivateKey(title)
+		return c.CollectAndValidatePrivateKey(id, title)
 	}
 
 	fmt.Printf("Wallet address of entered private key: %s\n", addr.Hex())
 
-	ok, err := c.TUI.NewPrompt("Is this the correct address?", true)
+	ok, err := c.TUI.NewPrompt("Is this the correct address?", true, components.PromptOptId(id+".confirm"))
 	if err != nil {
 		return "", "", err
 	}
 
 	if !ok {
-		return c.CollectAndValidatePrivateKey(title)
+		if err := components.RejectAnswer(c.TUI, id+".confirm", "address was not confirmed"); err != nil {
+			return "", "", err
+		}
+		return c.CollectAndValidatePrivateKey(id, title)
 	}
 
 	return pk, addr.Hex(), nil
@@ -0,0 +1,320 @@
+package actions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"sync"
+
+	"github.com/D8-X/d8x-cli/internal/styles"
+	"github.com/containers/image/types"
+	"github.com/distribution/reference"
+	"github.com/urfave/cli/v2"
+	"gopkg.in/yaml.v2"
+)
+
+// Statuses of ImageStatus
+const (
+	// Running image is the image which reference resolves to
+	ImageStatusCurrent = "current"
+	// Tag was moved to a different image since service was deployed
+	ImageStatusOutdated = "outdated"
+	// Image is pinned by digest, but a different image is running
+	ImageStatusDrift      = "drift"
+	ImageStatusNotRunning = "not-running"
+	// Running image or image reference digest is not known
+	ImageStatusUnknown = "unknown"
+)
+
+// Matches image lines of stack and compose files: indentation and optional
+// quotes, image reference, closing quote
+var stackImageLine = regexp.MustCompile(`(?m)^([ \t]*image:[ \t]*["']?)([^\s"'#]+)(["']?)`)
+
+// localStackFile returns local path of stack (compose) file of target
+func (t releaseTarget) localStackFile() string {
+	for _, f := range t.files {
+		if f.remote == t.stackFile {
+			return f.local
+		}
+	}
+	return ""
+}
+
+// readStackImages returns image references of services in stack (compose)
+// file
+func readStackImages(path string) (map[string]string, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	stack := struct {
+		Services map[string]struct {
+			Image string `yaml:"image"`
+		} `yaml:"services"`
+	}{}
+	if err := yaml.Unmarshal(contents, &stack); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	images := map[string]string{}
+	for svc, s := range stack.Services {
+		if s.Image != "" {
+			images[svc] = s.Image
+		}
+	}
+	return images, nil
+}
+
+// imageDigest returns digest of image reference, empty when reference is
+// not pinned by digest
+func imageDigest(img string) string {
+	named, err := reference.ParseNormalizedNamed(img)
+	if err != nil {
+		return ""
+	}
+	if canonical, ok := named.(reference.Canonical); ok {
+		return canonical.Digest().String()
+	}
+	return ""
+}
+
+// resolveImageDigests resolves digests of image references concurrently
+func resolveImageDigests(ctx context.Context, sys *types.SystemContext, images []string) (map[string]string, map[string]error) {
+	digests := map[string]string{}
+	errs := map[string]error{}
+	mu := sync.Mutex{}
+	wg := sync.WaitGroup{}
+	for _, img := range images {
+		wg.Add(1)
+		go func(img string) {
+			defer wg.Done()
+			dgst, err := resolveImageDigest(ctx, sys, img)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs[img] = err
+				return
+			}
+			digests[img] = dgst
+		}(img)
+	}
+	wg.Wait()
+	return digests, errs
+}
+
+// imageStatus compares digest of running image reference with digest which
+// image reference of stack file resolved to. Returns status and error of
+// unknown status.
+func imageStatus(img, runningRef, resolved string, resolveErr error) (string, string) {
+	pinned := imageDigest(img)
+	running := imageDigest(runningRef)
+	switch {
+	case runningRef == "":
+		return ImageStatusNotRunning, ""
+	case running == "":
+		return ImageStatusUnknown, "digest of running image " + runningRef + " is not known"
+	case pinned != "" && running != pinned:
+		return ImageStatusDrift, ""
+	case resolveErr != nil:
+		return ImageStatusUnknown, resolveErr.Error()
+	case running == resolved:
+		return ImageStatusCurrent, ""
+	}
+	return ImageStatusOutdated, ""
+}
+
+// targetImagesStatus compares images running on target server with digests
+// which images of local stack (compose) file resolve to now
+func (c *Container) targetImagesStatus(ctx context.Context, sys *types.SystemContext, t releaseTarget, ip string) ([]ImageStatus, error) {
+	images, err := readStackImages(t.localStackFile())
+	if err != nil {
+		return nil, fmt.Errorf("reading %s images: %w", t.name, err)
+	}
+	sshConn, err := c.CreateSSHConn(ip, c.DefaultClusterUserName, c.SshKeyPath)
+	if err != nil {
+		return nil, fmt.Errorf("establishing ssh connection to %s: %w", t.name, err)
+	}
+	out, err := sshConn.ExecCommand(t.imagesCmd)
+	if err != nil {
+		return nil, fmt.Errorf("retrieving %s running images: %w", t.name, err)
+	}
+	running := parseServiceImages(out, t.svcPrefix)
+
+	refs := []string{}
+	for _, svc := range sortedKeys(images) {
+		refs = append(refs, images[svc])
+	}
+	digests, errs := resolveImageDigests(ctx, sys, refs)
+
+	result := []ImageStatus{}
+	for _, svc := range sortedKeys(images) {
+		s := ImageStatus{
+			Target:   t.name,
+			Service:  svc,
+			Image:    images[svc],
+			Running:  imageDigest(running[svc]),
+			Resolved: digests[images[svc]],
+		}
+		s.Status, s.Error = imageStatus(s.Image, running[svc], s.Resolved, errs[s.Image])
+		result = append(result, s)
+	}
+	return result, nil
+}
+
+// ImagesStatus prints digests of images running on swarm and broker server
+// and whether they match the digests which image tags resolve to now
+func (c *Container) ImagesStatus(ctx *cli.Context) error {
+	format, err := outputFormat(ctx)
+	if err != nil {
+		return err
+	}
+	cfg, err := c.ConfigRWriter.Read()
+	if err != nil {
+		return err
+	}
+	sys := registrySystemContext(ctx)
+
+	statuses := []ImageStatus{}
+	var errs []error
+	if managerIp, err := c.HostsCfg.GetMangerPublicIp(); err != nil {
+		errs = append(errs, fmt.Errorf("finding manager ip address: %w", err))
+	} else {
+		s, err := c.targetImagesStatus(ctx.Context, sys, swarmReleaseTarget(), managerIp)
+		errs = append(errs, err)
+		statuses = append(statuses, s...)
+	}
+	if cfg.BrokerDeployed {
+		if brokerIp, err := c.HostsCfg.GetBrokerPublicIp(); err != nil {
+			errs = append(errs, fmt.Errorf("finding broker ip address: %w", err))
+		} else {
+			s, err := c.targetImagesStatus(ctx.Context, sys, brokerReleaseTarget(), brokerIp)
+			errs = append(errs, err)
+			statuses = append(statuses, s...)
+		}
+	}
+
+	if format != OutputText {
+		if err := printStructured(os.Stdout, format, statuses); err != nil {
+			return err
+		}
+		return errors.Join(errs...)
+	}
+
+	fmt.Printf("%-7s %-20s %-12s %s\n", "TARGET", "SERVICE", "STATUS", "IMAGE")
+	for _, s := range statuses {
+		status := s.Status
+		switch s.Status {
+		case ImageStatusCurrent:
+			status = styles.SuccessText.Render(fmt.Sprintf("%-12s", status))
+		case ImageStatusOutdated, ImageStatusUnknown:
+			status = styles.ItalicText.Render(fmt.Sprintf("%-12s", status))
+		default:
+			status = styles.ErrorText.Render(fmt.Sprintf("%-12s", status))
+		}
+		fmt.Printf("%-7s %-20s %s %s\n", s.Target, s.Service, status, s.Image)
+		if s.Running != "" {
+			fmt.Println(styles.GrayText.Render("  running:  " + s.Running))
+		}
+		if s.Resolved != "" && s.Resolved != s.Running {
+			fmt.Println(styles.GrayText.Render("  resolved: " + s.Resolved))
+		}
+		if s.Error != "" {
+			fmt.Println(styles.GrayText.Render("  error:    " + s.Error))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// pinImageRefs rewrites image references of stack (compose) file contents
+// to pinned references
+func pinImageRefs(contents []byte, pinned map[string]string) []byte {
+	return stackImageLine.ReplaceAllFunc(contents, func(line []byte) []byte {
+		m := stackImageLine.FindSubmatch(line)
+		if p, ok := pinned[string(m[2])]; ok {
+			return []byte(string(m[1]) + p + string(m[3]))
+		}
+		return line
+	})
+}
+
+// pinStackImages pins images of stack (compose) file at path by digest.
+// Images which are already pinned are left as they are. Returns the pinned
+// references.
+func (c *Container) pinStackImages(ctx context.Context, sys *types.SystemContext, path string) (map[string]string, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	refs := []string{}
+	for _, m := range stackImageLine.FindAllSubmatch(contents, -1) {
+		if ref := string(m[2]); imageDigest(ref) == "" {
+			refs = append(refs, ref)
+		}
+	}
+	digests, errs := resolveImageDigests(ctx, sys, refs)
+	for _, ref := range sortedKeys(errs) {
+		return nil, fmt.Errorf("resolving digest of %s: %w", ref, errs[ref])
+	}
+
+	pinned := map[string]string{}
+	for ref, dgst := range digests {
+		named, err := reference.ParseNormalizedNamed(ref)
+		if err != nil {
+			return nil, err
+		}
+		// Keep the reference as written and append the digest, so the tag
+		// stays visible in the file
+		if reference.IsNameOnly(named) {
+			pinned[ref] = ref + ":latest@" + dgst
+		} else {
+			pinned[ref] = ref + "@" + dgst
+		}
+	}
+	if len(pinned) == 0 {
+		return pinned, nil
+	}
+	if err := c.FS.WriteFile(path, pinImageRefs(contents, pinned)); err != nil {
+		return nil, fmt.Errorf("writing %s: %w", path, err)
+	}
+	return pinned, nil
+}
+
+// pinImages pins images of stack (compose) file at path and prints the
+// pinned references
+func (c *Container) pinImages(ctx *cli.Context, path string) error {
+	fmt.Printf("Pinning images of %s by digest...\n", path)
+	pinned, err := c.pinStackImages(ctx.Context, registrySystemContext(ctx), path)
+	if err != nil {
+		return fmt.Errorf("pinning images of %s: %w", path, err)
+	}
+	for _, ref := range sortedKeys(pinned) {
+		fmt.Println(styles.GrayText.Render("  " + pinned[ref]))
+	}
+	if len(pinned) == 0 {
+		fmt.Println(styles.ItalicText.Render("All images are already pinned"))
+	}
+	return nil
+}
+
+// ImagesPin pins images of local swarm stack file and broker compose file by
+// the digests their tags resolve to now
+func (c *Container) ImagesPin(ctx *cli.Context) error {
+	cfg, err := c.ConfigRWriter.Read()
+	if err != nil {
+		return err
+	}
+	if err := c.CopySwarmDeployConfigs(); err != nil {
+		return err
+	}
+	if err := c.pinImages(ctx, swarmReleaseTarget().localStackFile()); err != nil {
+		return err
+	}
+	if !cfg.BrokerDeployed {
+		return nil
+	}
+	if err := c.CopyBrokerDeployConfigs(); err != nil {
+		return err
+	}
+	return c.pinImages(ctx, brokerReleaseTarget().localStackFile())
+}
@@ -0,0 +1,121 @@
+package actions
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/D8-X/d8x-cli/internal/files"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testDigest = "sha256:1ee1d28936c39df0b2566c745ab590a54c05c69309de38de29bafc13456fdc51"
+const testOtherDigest = "sha256:2ce51e825a559029f47e73a73531d8a0b10191c6bc16950649036edf20ea8c35"
+
+func TestImageStatus(t *testing.T) {
+	tests := []struct {
+		name       string
+		img        string
+		runningRef string
+		resolved   string
+		resolveErr error
+		want       string
+	}{
+		{"current", "ghcr.io/d8-x/app:main", "ghcr.io/d8-x/app:main@" + testDigest, testDigest, nil, ImageStatusCurrent},
+		{"outdated", "ghcr.io/d8-x/app:main", "ghcr.io/d8-x/app:main@" + testDigest, testOtherDigest, nil, ImageStatusOutdated},
+		{"pinned", "ghcr.io/d8-x/app:main@" + testDigest, "ghcr.io/d8-x/app@" + testDigest, testDigest, nil, ImageStatusCurrent},
+		{"drift", "ghcr.io/d8-x/app:main@" + testOtherDigest, "ghcr.io/d8-x/app:main@" + testDigest, testOtherDigest, nil, ImageStatusDrift},
+		{"not running", "ghcr.io/d8-x/app:main", "", testDigest, nil, ImageStatusNotRunning},
+		{"unresolved", "ghcr.io/d8-x/app:main", "ghcr.io/d8-x/app:main@" + testDigest, "", errors.New("unauthorized"), ImageStatusUnknown},
+		{"running without digest", "ghcr.io/d8-x/app:main", "sha256:abc", testDigest, nil, ImageStatusUnknown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, _ := imageStatus(tt.img, tt.runningRef, tt.resolved, tt.resolveErr)
+			assert.Equal(t, tt.want, status)
+		})
+	}
+}
+
+func TestReadStackImages(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "docker-compose.yml")
+	require.NoError(t, os.WriteFile(path, []byte(`version: "3.8"
+services:
+  broker:
+    image: ghcr.io/d8-x/d8x-broker-server:main
+  redis:
+    image: "redis"
+  build-only:
+    build: .
+`), 0644))
+
+	images, err := readStackImages(path)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"broker": "ghcr.io/d8-x/d8x-broker-server:main",
+		"redis":  "redis",
+	}, images)
+}
+
+func TestPinImageRefs(t *testing.T) {
+	contents := `services:
+  api:
+    image: ghcr.io/d8-x/d8x-trader-main:main # api image
+  history:
+    image: "ghcr.io/d8-x/d8x-trader-history:main"
+  redis:
+    image: redis
+`
+	got := pinImageRefs([]byte(contents), map[string]string{
+		"ghcr.io/d8-x/d8x-trader-main:main":    "ghcr.io/d8-x/d8x-trader-main:main@" + testDigest,
+		"ghcr.io/d8-x/d8x-trader-history:main": "ghcr.io/d8-x/d8x-trader-history:main@" + testOtherDigest,
+	})
+	assert.Equal(t, `services:
+  api:
+    image: ghcr.io/d8-x/d8x-trader-main:main@`+testDigest+` # api image
+  history:
+    image: "ghcr.io/d8-x/d8x-trader-history:main@`+testOtherDigest+`"
+  redis:
+    image: redis
+`, string(got))
+}
+
+func TestPinStackImages(t *testing.T) {
+	registry := newFakeRegistry("d8-x/app")
+	mainDigest := registry.addImage("main", "2024-04-01T10:00:00Z", nil)
+	server := httptest.NewTLSServer(registry)
+	defer server.Close()
+	repo := strings.TrimPrefix(server.URL, "https://") + "/d8-x/app"
+
+	path := filepath.Join(t.TempDir(), "docker-swarm-stack.yml")
+	require.NoError(t, os.WriteFile(path, []byte(`services:
+  api:
+    image: `+repo+`:main
+  history:
+    image: `+repo+`:main@`+testOtherDigest+`
+`), 0644))
+
+	c := &Container{FS: files.NewFileSystemInteractor()}
+	pinned, err := c.pinStackImages(context.Background(), fakeRegistrySystemContext(t), path)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{repo + ":main": repo + ":main@" + mainDigest}, pinned)
+
+	contents, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, `services:
+  api:
+    image: `+repo+`:main@`+mainDigest+`
+  history:
+    image: `+repo+`:main@`+testOtherDigest+`
+`, string(contents))
+
+	// Pinned images are not resolved again
+	pinned, err = c.pinStackImages(context.Background(), fakeRegistrySystemContext(t), path)
+	require.NoError(t, err)
+	assert.Empty(t, pinned)
+}
@@ -6,7 +6,6 @@ import (
 	"sync"
 	"time"
 
-	"github.com/D8-X/d8x-cli/internal/conn"
 	"github.com/D8-X/d8x-cli/internal/styles"
 	"github.com/urfave/cli/v2"
 )
@@ -26,7 +25,7 @@ func (c *Container) IngressFix(ctx *cli.Context) error {
 	if err != nil {
 		return err
 	}
-	managerConn, err := conn.NewSSHConnection(ip, c.DefaultClusterUserName, c.SshKeyPath)
+	managerConn, err := c.CreateSSHConn(ip, c.DefaultClusterUserName, c.SshKeyPath)
 	if err != nil {
 		return err
 	}
@@ -74,7 +73,7 @@ func (c *Container) IngressFix(ctx *cli.Context) error {
 		go func(ip string) {
 			workerNum := n + 1
 			defer wg.Done()
-			workerConn, err := conn.NewSSHConnectionWithBastion(managerConn.GetClient(), ip, c.DefaultClusterUserName, c.SshKeyPath)
+			workerConn, err := c.CreateSSHConnWithBastion(managerConn, ip, c.DefaultClusterUserName, c.SshKeyPath)
 			if err != nil {
 				info := fmt.Sprintf("creating ssh connection to worker-%d %s: %s", workerNum, ip, err.Error())
 				fmt.Println(styles.ErrorText.Render(info))
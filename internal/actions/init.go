@@ -7,6 +7,7 @@ import (
 	"runtime"
 	"strings"
 
+	"github.com/D8-X/d8x-cli/internal/components"
 	"github.com/D8-X/d8x-cli/internal/styles"
 	"github.com/urfave/cli/v2"
 )
@@ -52,7 +53,7 @@ func (c *Container) Init(ctx *cli.Context) error {
 
 	if !tfFound || !ansibleFound {
 		fmt.Println(styles.SuccessText.Italic(true).MarginTop(1).Render("Select which dependencies you wish to install automatically:"))
-		selected, err := c.TUI.NewSelection(install)
+		selected, err := c.TUI.NewSelection(install, components.SelectionOptId("init.install_dependencies"))
 		if err != nil {
 			return err
 		}
@@ -2,36 +2,46 @@ package actions
 
 import (
 	"fmt"
+	"os"
 
 	"github.com/urfave/cli/v2"
 )
 
 func (c *Container) Ips(ctx *cli.Context) error {
+	format, err := outputFormat(ctx)
+	if err != nil {
+		return err
+	}
+
 	onlyIp := ctx.Bool("quiet")
-	switch ctx.Args().First() {
+	node := ctx.Args().First()
+	var ip string
+	switch node {
 	case "manager":
-		ip, err := c.HostsCfg.GetMangerPublicIp()
-		if err != nil {
-			return err
-		}
-		if onlyIp {
-			fmt.Println(ip)
-			return nil
-		}
-		fmt.Printf("Manager node public IP address: %s\n", ip)
+		ip, err = c.HostsCfg.GetMangerPublicIp()
 	case "broker":
-		ip, err := c.HostsCfg.GetBrokerPublicIp()
-		if err != nil {
-			return err
-		}
-		if onlyIp {
-			fmt.Println(ip)
-			return nil
-		}
-		fmt.Printf("Broker node public IP address: %s\n", ip)
+		ip, err = c.HostsCfg.GetBrokerPublicIp()
 	default:
 		return fmt.Errorf("Unknown argument: %s. Supported values: manager, broker", ctx.Args().First())
 	}
+	if err != nil {
+		return err
+	}
+
+	if format != OutputText {
+		return printStructured(os.Stdout, format, NodeIp{Node: node, PublicIp: ip})
+	}
+	if onlyIp {
+		fmt.Println(ip)
+		return nil
+	}
+
+	switch node {
+	case "manager":
+		fmt.Printf("Manager node public IP address: %s\n", ip)
+	case "broker":
+		fmt.Printf("Broker node public IP address: %s\n", ip)
+	}
 
 	return nil
 }
@@ -0,0 +1,185 @@
+package actions
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/D8-X/d8x-cli/internal/components"
+	"github.com/D8-X/d8x-cli/internal/configs"
+	"github.com/D8-X/d8x-cli/internal/conn"
+	"github.com/D8-X/d8x-cli/internal/styles"
+	"github.com/urfave/cli/v2"
+	"golang.org/x/crypto/ssh"
+)
+
+// Retries of host key scanning. Freshly provisioned servers might not accept
+// ssh connections right away.
+var (
+	hostKeyScanAttempts = 10
+	hostKeyScanInterval = time.Second * 6
+)
+
+// knownHostsPath returns the absolute path of d8x managed known_hosts file
+func (c *Container) knownHostsPath() string {
+	p := filepath.Join(c.ConfigDir, configs.DEFAULT_KNOWN_HOSTS_NAME)
+	if abs, err := filepath.Abs(p); err == nil {
+		return abs
+	}
+	return p
+}
+
+// InitKnownHosts enables host key verification of all ssh connections against
+// d8x managed known_hosts file. Must be called after the working directory is
+// changed, since config directory path can be relative.
+func (c *Container) InitKnownHosts() {
+	c.KnownHosts = conn.NewKnownHosts(c.knownHostsPath())
+	conn.UseKnownHosts(c.KnownHosts)
+}
+
+// scanHostKey retrieves the host key of server at ip, retrying until server
+// accepts connections. When bastion is not nil, server is reached via bastion.
+func scanHostKey(bastion conn.SSHConnection, ip string) (ssh.PublicKey, error) {
+	var client *ssh.Client
+	if bastion != nil {
+		client = bastion.GetClient()
+	}
+
+	var (
+		key ssh.PublicKey
+		err error
+	)
+	for i := 0; i < hostKeyScanAttempts; i++ {
+		if i > 0 {
+			time.Sleep(hostKeyScanInterval)
+		}
+		key, err = conn.ScanHostKey(client, conn.HostAddr(ip))
+		if err == nil {
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("retrieving host key of %s: %w", ip, err)
+}
+
+// pinHostKeys records host keys of freshly provisioned servers in known_hosts
+// file. Any previously recorded keys of the same ip addresses are replaced.
+// Failures are only reported, since keys are also recorded on first
+// connection.
+func (c *Container) pinHostKeys(bastion conn.SSHConnection, ips ...string) {
+	if c.DryRun != nil || c.KnownHosts == nil {
+		return
+	}
+
+	for _, ip := range ips {
+		key, err := scanHostKey(bastion, ip)
+		if err == nil {
+			err = c.KnownHosts.Pin(conn.HostAddr(ip), key)
+		}
+		if err != nil {
+			fmt.Println(styles.ErrorText.Render(
+				fmt.Sprintf("could not record host key of %s: %v", ip, err),
+			))
+			continue
+		}
+		fmt.Printf("Host key of %s was recorded: %s\n", ip, ssh.FingerprintSHA256(key))
+	}
+}
+
+// pinProvisionedHostKeys records host keys of all servers in hosts.cfg right
+// after provisioning. Private servers (aws workers) are scanned via manager.
+func (c *Container) pinProvisionedHostKeys(cfg *configs.D8XConfig) {
+	fmt.Println(styles.ItalicText.Render("Recording servers host keys..."))
+
+	publicIps := []string{}
+	if ip, err := c.HostsCfg.GetMangerPublicIp(); err == nil {
+		publicIps = append(publicIps, ip)
+	}
+	if ip, err := c.HostsCfg.GetBrokerPublicIp(); err == nil {
+		publicIps = append(publicIps, ip)
+	}
+	workerIps, _ := c.HostsCfg.GetWorkerIps()
+	if cfg.HasPublicServers() {
+		publicIps = append(publicIps, workerIps...)
+	}
+	c.pinHostKeys(nil, publicIps...)
+
+	if cfg.HasPublicServers() || len(workerIps) == 0 {
+		return
+	}
+	managerIp, err := c.HostsCfg.GetMangerPublicIp()
+	if err != nil {
+		return
+	}
+	managerConn, err := c.CreateSSHConn(managerIp, cfg.GetAnsibleUser(), c.SshKeyPath)
+	if err != nil {
+		fmt.Println(styles.ErrorText.Render(
+			fmt.Sprintf("could not record workers host keys: %v", err),
+		))
+		return
+	}
+	c.pinHostKeys(managerConn, workerIps...)
+}
+
+// HostsRekey replaces the recorded host key of a legitimately rebuilt server
+// with the key the server currently presents
+func (c *Container) HostsRekey(ctx *cli.Context) error {
+	styles.PrintCommandTitle("Updating server host key...")
+
+	node := ctx.Args().First()
+	ip, isWorker, err := c.resolveNodeIp(node)
+	if err != nil {
+		return err
+	}
+	cfg, err := c.ConfigRWriter.Read()
+	if err != nil {
+		return err
+	}
+
+	// Private workers are reached via manager
+	var bastion *ssh.Client
+	if isWorker && !cfg.HasPublicServers() {
+		managerIp, err := c.HostsCfg.GetMangerPublicIp()
+		if err != nil {
+			return err
+		}
+		managerConn, err := c.CreateSSHConn(managerIp, c.DefaultClusterUserName, c.SshKeyPath)
+		if err != nil {
+			return fmt.Errorf("connecting to manager: %w", err)
+		}
+		bastion = managerConn.GetClient()
+	}
+
+	key, err := conn.ScanHostKey(bastion, conn.HostAddr(ip))
+	if err != nil {
+		return fmt.Errorf("retrieving host key of %s: %w", ip, err)
+	}
+
+	recorded, err := c.KnownHosts.Has(conn.HostAddr(ip))
+	if err != nil {
+		return err
+	}
+	if !recorded {
+		fmt.Printf("No host key of %s (%s) is recorded in %s\n", node, ip, c.KnownHosts.Path())
+	}
+	fmt.Printf("%s (%s) presents host key %s\n", node, ip, ssh.FingerprintSHA256(key))
+
+	ok, err := c.TUI.NewPrompt(
+		"Only trust the new key if the server was rebuilt. Do you want to replace the recorded host key?",
+		false,
+		components.PromptOptId("hosts.rekey.confirm"),
+	)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		fmt.Println("Host key was not changed")
+		return nil
+	}
+
+	if err := c.KnownHosts.Pin(conn.HostAddr(ip), key); err != nil {
+		return err
+	}
+	fmt.Println(styles.SuccessText.Render(fmt.Sprintf("Host key of %s was updated", node)))
+
+	return nil
+}
@@ -0,0 +1,289 @@
+package actions
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"os"
+	"os/signal"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/D8-X/d8x-cli/internal/configs"
+	"github.com/D8-X/d8x-cli/internal/styles"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/urfave/cli/v2"
+)
+
+// Servers on which services run, used to qualify service names which exist on
+// both, i.e. broker/redis
+const (
+	logsServerSwarm  = "swarm"
+	logsServerBroker = "broker"
+)
+
+// logsService is the resolved service of logs command
+type logsService struct {
+	name   string
+	server string
+}
+
+// Logs streams logs of a swarm or broker-server service
+func (c *Container) Logs(ctx *cli.Context) error {
+	if ctx.Args().Len() != 1 {
+		return fmt.Errorf("service name is required, usage: d8x logs <service>")
+	}
+
+	svc, err := resolveLogsService(ctx.Args().First())
+	if err != nil {
+		return err
+	}
+
+	var grep *regexp.Regexp
+	if pattern := ctx.String("grep"); pattern != "" {
+		grep, err = regexp.Compile(pattern)
+		if err != nil {
+			return fmt.Errorf("invalid --grep pattern: %w", err)
+		}
+	}
+
+	var ip string
+	if svc.server == logsServerBroker {
+		ip, err = c.HostsCfg.GetBrokerPublicIp()
+	} else {
+		ip, err = c.HostsCfg.GetMangerPublicIp()
+	}
+	if err != nil {
+		return err
+	}
+	sshConn, err := c.CreateSSHConn(ip, c.DefaultClusterUserName, c.SshKeyPath)
+	if err != nil {
+		return err
+	}
+
+	// Lines are collected in a temporary file and archived when --save is
+	// set
+	var saved *os.File
+	savePath := ctx.String("save")
+	if savePath != "" {
+		saved, err = os.CreateTemp("", "d8x-logs-*.log")
+		if err != nil {
+			return fmt.Errorf("creating temporary logs file: %w", err)
+		}
+		defer os.Remove(saved.Name())
+		defer saved.Close()
+	}
+
+	// Stop the stream on interrupt, so that collected logs can still be
+	// saved
+	streamCtx, stop := signal.NotifyContext(ctx.Context, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var save io.Writer
+	if saved != nil {
+		save = saved
+	}
+	w := newLogsWriter(os.Stdout, save, grep)
+	cmd := logsCommand(svc, ctx.Bool("follow"), ctx.String("since"))
+	streamErr := sshConn.ExecCommandStream(streamCtx, cmd, w, w)
+	if err := w.Flush(); err != nil && streamErr == nil {
+		streamErr = err
+	}
+	if errors.Is(streamErr, context.Canceled) {
+		streamErr = nil
+	}
+
+	if saved != nil {
+		if err := writeLogsArchive(savePath, svc.name+".log", saved); err != nil {
+			return err
+		}
+		fmt.Println(styles.SuccessText.Render("Logs saved to " + savePath))
+	}
+
+	if streamErr != nil {
+		return fmt.Errorf("streaming logs of %s: %w", svc.name, streamErr)
+	}
+	return nil
+}
+
+// resolveLogsService finds the service in swarm stack or broker-server
+// compose file. Services which exist in both must be qualified with swarm/ or
+// broker/.
+func resolveLogsService(name string) (logsService, error) {
+	swarmServices, err := configs.GetSwarmDockerServices(false)
+	if err != nil {
+		return logsService{}, err
+	}
+	brokerServices, err := configs.GetBrokerServerComposeServices(false)
+	if err != nil {
+		return logsService{}, err
+	}
+
+	server := ""
+	if s, n, ok := strings.Cut(name, "/"); ok {
+		server, name = s, n
+	}
+	_, inSwarm := swarmServices[name]
+	_, inBroker := brokerServices[name]
+
+	switch {
+	case server == logsServerSwarm && inSwarm, server == "" && inSwarm && !inBroker:
+		return logsService{name: name, server: logsServerSwarm}, nil
+	case server == logsServerBroker && inBroker, server == "" && inBroker && !inSwarm:
+		return logsService{name: name, server: logsServerBroker}, nil
+	case server == "" && inSwarm && inBroker:
+		return logsService{}, fmt.Errorf("service %[1]s runs both in swarm and on broker server, use swarm/%[1]s or broker/%[1]s", name)
+	}
+
+	available := []string{}
+	for svc := range swarmServices {
+		available = append(available, logsServerSwarm+"/"+svc)
+	}
+	for svc := range brokerServices {
+		available = append(available, logsServerBroker+"/"+svc)
+	}
+	sort.Strings(available)
+	return logsService{}, fmt.Errorf("unknown service %s, available services: %s", name, strings.Join(available, ", "))
+}
+
+// logsCommand builds docker logs command of svc. Stderr is redirected so that
+// docker errors are shown in the stream.
+func logsCommand(svc logsService, follow bool, since string) string {
+	args := []string{}
+	if follow {
+		args = append(args, "--follow")
+	}
+	if since != "" {
+		args = append(args, fmt.Sprintf("--since '%s'", shellQuoteEscape(since)))
+	}
+	opts := strings.Join(append(args, ""), " ")
+
+	if svc.server == logsServerBroker {
+		// See broker.go for broker server setup directory
+		return fmt.Sprintf("cd ./broker && docker compose logs --no-color %s%s 2>&1", opts, svc.name)
+	}
+	return fmt.Sprintf("docker service logs %s%s_%s 2>&1", opts, dockerStackName, svc.name)
+}
+
+// logsWriter splits the stream into lines, filters them and colors line
+// prefixes (task or replica name) so that output of different replicas can be
+// told apart. Uncolored lines are copied to save.
+type logsWriter struct {
+	mu   sync.Mutex
+	out  io.Writer
+	save io.Writer
+	grep *regexp.Regexp
+	buf  []byte
+}
+
+func newLogsWriter(out io.Writer, save io.Writer, grep *regexp.Regexp) *logsWriter {
+	if save == nil {
+		save = io.Discard
+	}
+	return &logsWriter{out: out, save: save, grep: grep}
+}
+
+func (l *logsWriter) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.buf = append(l.buf, p...)
+	for {
+		i := bytes.IndexByte(l.buf, '\n')
+		if i < 0 {
+			break
+		}
+		line := string(l.buf[:i])
+		l.buf = l.buf[i+1:]
+		if err := l.writeLine(line); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// Flush writes out the last incomplete line
+func (l *logsWriter) Flush() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if len(l.buf) == 0 {
+		return nil
+	}
+	line := string(l.buf)
+	l.buf = nil
+	return l.writeLine(line)
+}
+
+func (l *logsWriter) writeLine(line string) error {
+	line = strings.TrimSuffix(line, "\r")
+	if l.grep != nil && !l.grep.MatchString(line) {
+		return nil
+	}
+	if _, err := fmt.Fprintln(l.save, line); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintln(l.out, colorLogLine(line))
+	return err
+}
+
+// colorLogLine colors the "<task or replica> |" prefix of docker logs line.
+// The same prefix always gets the same color.
+func colorLogLine(line string) string {
+	prefix, rest, ok := strings.Cut(line, "|")
+	if !ok {
+		return line
+	}
+	h := fnv.New32a()
+	h.Write([]byte(strings.TrimSpace(prefix)))
+	color := styles.LogPrefixColors[h.Sum32()%uint32(len(styles.LogPrefixColors))]
+	return lipgloss.NewStyle().Foreground(color).Render(prefix+"|") + rest
+}
+
+// writeLogsArchive writes contents of logs as fileName into gzipped tar
+// archive at archivePath
+func writeLogsArchive(archivePath, fileName string, logs *os.File) error {
+	info, err := logs.Stat()
+	if err != nil {
+		return fmt.Errorf("reading collected logs: %w", err)
+	}
+	if _, err := logs.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("reading collected logs: %w", err)
+	}
+
+	f, err := os.Create(archivePath)
+	if err != nil {
+		return fmt.Errorf("creating logs archive: %w", err)
+	}
+	defer f.Close()
+
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+	if err := tw.WriteHeader(&tar.Header{
+		Name:    fileName,
+		Mode:    0644,
+		Size:    info.Size(),
+		ModTime: time.Now(),
+	}); err != nil {
+		return fmt.Errorf("writing logs archive: %w", err)
+	}
+	if _, err := io.Copy(tw, logs); err != nil {
+		return fmt.Errorf("writing logs archive: %w", err)
+	}
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("writing logs archive: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return fmt.Errorf("writing logs archive: %w", err)
+	}
+	return f.Close()
+}
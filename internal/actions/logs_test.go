@@ -0,0 +1,150 @@
+package actions
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveLogsService(t *testing.T) {
+	tests := []struct {
+		name    string
+		arg     string
+		want    logsService
+		wantErr string
+	}{
+		{
+			name: "swarm service",
+			arg:  "api",
+			want: logsService{name: "api", server: logsServerSwarm},
+		},
+		{
+			name: "broker service",
+			arg:  "broker",
+			want: logsService{name: "broker", server: logsServerBroker},
+		},
+		{
+			name: "qualified swarm service",
+			arg:  "swarm/redis",
+			want: logsService{name: "redis", server: logsServerSwarm},
+		},
+		{
+			name: "qualified broker service",
+			arg:  "broker/redis",
+			want: logsService{name: "redis", server: logsServerBroker},
+		},
+		{
+			name:    "ambiguous service",
+			arg:     "redis",
+			wantErr: "service redis runs both in swarm and on broker server, use swarm/redis or broker/redis",
+		},
+		{
+			name:    "wrong server",
+			arg:     "broker/api",
+			wantErr: "unknown service api, available services:",
+		},
+		{
+			name:    "unknown service",
+			arg:     "db",
+			wantErr: "unknown service db, available services:",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveLogsService(tt.arg)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestLogsCommand(t *testing.T) {
+	assert.Equal(t,
+		"docker service logs stack_api 2>&1",
+		logsCommand(logsService{name: "api", server: logsServerSwarm}, false, ""),
+	)
+	assert.Equal(t,
+		"docker service logs --follow --since '1h' stack_history 2>&1",
+		logsCommand(logsService{name: "history", server: logsServerSwarm}, true, "1h"),
+	)
+	assert.Equal(t,
+		`cd ./broker && docker compose logs --no-color --since '1h'\''; rm -rf /' broker 2>&1`,
+		logsCommand(logsService{name: "broker", server: logsServerBroker}, false, "1h'; rm -rf /"),
+	)
+}
+
+func TestLogsWriter(t *testing.T) {
+	out := &bytes.Buffer{}
+	saved := &bytes.Buffer{}
+	w := newLogsWriter(out, saved, regexp.MustCompile("error"))
+
+	// Lines split across writes
+	_, err := w.Write([]byte("stack_api.1.abc@worker-1    | error: one\nstack_api.2.def@worker-2    | ok\nstack_api.2.def@worker-2    | err"))
+	require.NoError(t, err)
+	_, err = w.Write([]byte("or: two\r\nno prefix error"))
+	require.NoError(t, err)
+	require.NoError(t, w.Flush())
+
+	assert.Equal(t, `stack_api.1.abc@worker-1    | error: one
+stack_api.2.def@worker-2    | error: two
+no prefix error
+`, saved.String())
+
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	require.Len(t, lines, 3)
+	assert.Equal(t, colorLogLine("stack_api.1.abc@worker-1    | error: one"), lines[0])
+	assert.Equal(t, "no prefix error", lines[2])
+}
+
+func TestColorLogLine(t *testing.T) {
+	// Same prefix is always rendered the same way, message is left intact
+	a := colorLogLine("broker-1  | first")
+	b := colorLogLine("broker-1  | second")
+	assert.Equal(t, strings.TrimSuffix(a, " first"), strings.TrimSuffix(b, " second"))
+	assert.True(t, strings.HasSuffix(a, " first"))
+	assert.Equal(t, "no prefix", colorLogLine("no prefix"))
+}
+
+func TestWriteLogsArchive(t *testing.T) {
+	dir := t.TempDir()
+	logs, err := os.Create(filepath.Join(dir, "collected.log"))
+	require.NoError(t, err)
+	defer logs.Close()
+	_, err = logs.WriteString("line 1\nline 2\n")
+	require.NoError(t, err)
+
+	archivePath := filepath.Join(dir, "logs.tar.gz")
+	require.NoError(t, writeLogsArchive(archivePath, "api.log", logs))
+
+	f, err := os.Open(archivePath)
+	require.NoError(t, err)
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	require.NoError(t, err)
+	tr := tar.NewReader(gz)
+
+	hdr, err := tr.Next()
+	require.NoError(t, err)
+	assert.Equal(t, "api.log", hdr.Name)
+	contents, err := io.ReadAll(tr)
+	require.NoError(t, err)
+	assert.Equal(t, "line 1\nline 2\n", string(contents))
+
+	_, err = tr.Next()
+	assert.Equal(t, io.EOF, err)
+}
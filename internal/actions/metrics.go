@@ -67,16 +67,8 @@ func (c *Container) DeployMetrics(ctx *cli.Context) error {
 	if err != nil {
 		return err
 	}
-	prometheusYaml, err := os.ReadFile("./prometheus.yml")
-	if err != nil {
-		return err
-	}
-	if prometheusWithTargets, err := c.processPrometheusYaml(prometheusYaml, workerIPs); err != nil {
+	if err := c.writePrometheusTargets("./prometheus.yml", workerIPs); err != nil {
 		return err
-	} else {
-		if err := os.WriteFile("./prometheus.yml", prometheusWithTargets, 0666); err != nil {
-			return err
-		}
 	}
 
 	if err := manager.CopyFilesOverSftp(
@@ -111,8 +103,7 @@ func (c *Container) DeployMetrics(ctx *cli.Context) error {
 	}
 
 	// Block access of cadvisor port for public ip servers providers
-	switch cfg.ServerProvider {
-	case configs.D8XServerProviderLinode:
+	if cfg.HasPublicServers() {
 		workerIps, err := c.HostsCfg.GetWorkerIps()
 		if err != nil {
 			return err
@@ -123,40 +114,7 @@ func (c *Container) DeployMetrics(ctx *cli.Context) error {
 			return fmt.Errorf("getting sudo password: %w", err)
 		}
 
-		wg := sync.WaitGroup{}
-		for _, workerIp := range workerIps {
-			workerIp := workerIp
-			wg.Add(1)
-			go func(ip string) {
-				sh, err := conn.NewSSHConnection(ip, c.DefaultClusterUserName, c.SshKeyPath)
-				if err != nil {
-					fmt.Println(
-						styles.ErrorText.Render(
-							fmt.Sprintf("Connecting to worker %s: %s", ip, err.Error()),
-						),
-					)
-				}
-				check := "iptables -L -t raw | grep ':%d'"
-				check = fmt.Sprintf(check, CADVISOR_PORT)
-				// We only want to run additional iptables insert if cadvisor
-				// port was not found in grep. Here we'll add a drop rule to the
-				// raw table when destination port is our worker's public IP.
-				cmd := fmt.Sprintf(check+" || iptables -I PREROUTING 1 -t raw -p tcp -d %s --dport %d -j DROP && iptables-save > /etc/iptables/rules.v4", ip, CADVISOR_PORT)
-				out, err := sh.ExecCommand(
-					fmt.Sprintf(`echo '%s' | sudo -S bash -c '%s'`, pwd, cmd),
-				)
-				if err != nil {
-					fmt.Println(string(out))
-					fmt.Println(
-						styles.ErrorText.Render("[" + ip + "] Error blocking cadvisor port on worker: " + err.Error()),
-					)
-				}
-
-				wg.Done()
-			}(workerIp)
-		}
-		wg.Wait()
-
+		c.blockCadvisorPort(workerIps, pwd)
 	}
 
 	// Update cfg
@@ -165,6 +123,58 @@ func (c *Container) DeployMetrics(ctx *cli.Context) error {
 	return c.ConfigRWriter.Write(cfg)
 }
 
+// blockCadvisorPort drops incoming connections to cadvisor port on public ip
+// addresses of given workers
+func (c *Container) blockCadvisorPort(workerIps []string, pwd string) {
+	wg := sync.WaitGroup{}
+	for _, workerIp := range workerIps {
+		workerIp := workerIp
+		wg.Add(1)
+		go func(ip string) {
+			defer wg.Done()
+			sh, err := c.CreateSSHConn(ip, c.DefaultClusterUserName, c.SshKeyPath)
+			if err != nil {
+				fmt.Println(
+					styles.ErrorText.Render(
+						fmt.Sprintf("Connecting to worker %s: %s", ip, err.Error()),
+					),
+				)
+				return
+			}
+			check := "iptables -L -t raw | grep ':%d'"
+			check = fmt.Sprintf(check, CADVISOR_PORT)
+			// We only want to run additional iptables insert if cadvisor
+			// port was not found in grep. Here we'll add a drop rule to the
+			// raw table when destination port is our worker's public IP.
+			cmd := fmt.Sprintf(check+" || iptables -I PREROUTING 1 -t raw -p tcp -d %s --dport %d -j DROP && iptables-save > /etc/iptables/rules.v4", ip, CADVISOR_PORT)
+			out, err := sh.ExecCommand(
+				fmt.Sprintf(`echo '%s' | sudo -S bash -c '%s'`, pwd, cmd),
+			)
+			if err != nil {
+				fmt.Println(string(out))
+				fmt.Println(
+					styles.ErrorText.Render("[" + ip + "] Error blocking cadvisor port on worker: " + err.Error()),
+				)
+			}
+		}(workerIp)
+	}
+	wg.Wait()
+}
+
+// writePrometheusTargets sets workers as cadvisor targets in prometheus config
+// file
+func (c *Container) writePrometheusTargets(prometheusYamlPath string, workers []string) error {
+	prometheusYaml, err := os.ReadFile(prometheusYamlPath)
+	if err != nil {
+		return err
+	}
+	prometheusWithTargets, err := c.processPrometheusYaml(prometheusYaml, workers)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(prometheusYamlPath, prometheusWithTargets, 0666)
+}
+
 func (c *Container) processPrometheusYaml(promYamlContents []byte, workers []string) ([]byte, error) {
 
 	mp := map[any]any{}
@@ -204,7 +214,7 @@ func (c *Container) TunnelGrafana(ctx *cli.Context) error {
 	if err != nil {
 		return err
 	}
-	managerConn, err := conn.NewSSHConnection(managerIp, c.DefaultClusterUserName, c.SshKeyPath)
+	managerConn, err := c.CreateSSHConn(managerIp, c.DefaultClusterUserName, c.SshKeyPath)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,153 @@
+package actions
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/D8-X/d8x-cli/internal/configs"
+	"github.com/D8-X/d8x-cli/internal/flags"
+	"github.com/urfave/cli/v2"
+	"gopkg.in/yaml.v2"
+)
+
+// Supported --output formats
+const (
+	OutputText = "text"
+	OutputJSON = "json"
+	OutputYAML = "yaml"
+)
+
+// outputFormat returns validated --output flag value of the command
+func outputFormat(ctx *cli.Context) (string, error) {
+	format := ctx.String(flags.Output)
+	switch format {
+	case "", OutputText:
+		return OutputText, nil
+	case OutputJSON, OutputYAML:
+		return format, nil
+	}
+	return "", fmt.Errorf("unsupported output format %s, supported values: text, json, yaml", format)
+}
+
+// printStructured writes v to w in json or yaml format
+func printStructured(w io.Writer, format string, v any) error {
+	var (
+		out []byte
+		err error
+	)
+	switch format {
+	case OutputJSON:
+		out, err = json.MarshalIndent(v, "", "  ")
+		out = append(out, '\n')
+	case OutputYAML:
+		out, err = yaml.Marshal(v)
+	default:
+		return fmt.Errorf("unsupported structured output format %s", format)
+	}
+	if err != nil {
+		return fmt.Errorf("marshalling %s output: %w", format, err)
+	}
+	_, err = w.Write(out)
+	return err
+}
+
+// HealthReport is the structured output of health command
+type HealthReport struct {
+	Services []ServiceHealth `json:"services" yaml:"services"`
+	// Nil when swarm is not deployed
+	SwarmServices []SwarmServiceStatus `json:"swarm_services,omitempty" yaml:"swarm_services,omitempty"`
+}
+
+// ServiceHealth is the result of http health check of a single service
+type ServiceHealth struct {
+	Service  string `json:"service" yaml:"service"`
+	Hostname string `json:"hostname" yaml:"hostname"`
+	// 0 when service was not reached
+	HTTPStatus int  `json:"http_status" yaml:"http_status"`
+	Retries    uint `json:"retries" yaml:"retries"`
+	Reachable  bool `json:"reachable" yaml:"reachable"`
+}
+
+// SwarmServiceStatus describes replicas and tasks of a docker swarm service
+type SwarmServiceStatus struct {
+	Name            string            `json:"name" yaml:"name"`
+	RunningReplicas int               `json:"running_replicas" yaml:"running_replicas"`
+	DesiredReplicas int               `json:"desired_replicas" yaml:"desired_replicas"`
+	Tasks           []SwarmTaskStatus `json:"tasks" yaml:"tasks"`
+}
+
+// SwarmTaskStatus describes a single swarm service task
+type SwarmTaskStatus struct {
+	Id    string `json:"id" yaml:"id"`
+	Name  string `json:"name" yaml:"name"`
+	Node  string `json:"node" yaml:"node"`
+	State string `json:"state" yaml:"state"`
+	Error string `json:"error" yaml:"error"`
+}
+
+// NodeIp is the structured output of ip command
+type NodeIp struct {
+	Node     string `json:"node" yaml:"node"`
+	PublicIp string `json:"public_ip" yaml:"public_ip"`
+}
+
+// ExecResult is the output of exec command on a single node
+type ExecResult struct {
+	Node string `json:"node" yaml:"node"`
+	Host string `json:"host" yaml:"host"`
+	// -1 when command could not be run
+	ExitStatus int    `json:"exit_status" yaml:"exit_status"`
+	Output     string `json:"output" yaml:"output"`
+	Error      string `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// DbBackup is a scheduled database backup listed by backup-db list
+type DbBackup struct {
+	Name      string    `json:"name" yaml:"name"`
+	Size      int64     `json:"size" yaml:"size"`
+	CreatedAt time.Time `json:"created_at" yaml:"created_at"`
+	// Whether backup is stored on manager
+	Local bool `json:"local" yaml:"local"`
+	// Whether backup is stored in the bucket
+	Remote bool `json:"remote" yaml:"remote"`
+}
+
+// Profile is the structured output of profile list command
+type Profile struct {
+	Name   string `json:"name" yaml:"name"`
+	Active bool   `json:"active" yaml:"active"`
+	Path   string `json:"path" yaml:"path"`
+}
+
+// ConfigValidation is the structured output of config validate command
+type ConfigValidation struct {
+	Path          string                `json:"path" yaml:"path"`
+	SchemaVersion int                   `json:"schema_version" yaml:"schema_version"`
+	Valid         bool                  `json:"valid" yaml:"valid"`
+	Issues        []configs.ConfigIssue `json:"issues" yaml:"issues"`
+}
+
+// RpcServiceDistribution is the structured output of rpc distribution command
+type RpcServiceDistribution struct {
+	Service  string   `json:"service" yaml:"service"`
+	Strategy string   `json:"strategy" yaml:"strategy"`
+	Http     []string `json:"http" yaml:"http"`
+	Ws       []string `json:"ws" yaml:"ws"`
+}
+
+// ImageStatus is the structured output of images status command
+type ImageStatus struct {
+	Target  string `json:"target" yaml:"target"`
+	Service string `json:"service" yaml:"service"`
+	// Image reference of local stack (compose) file
+	Image string `json:"image" yaml:"image"`
+	// Digest of running image, empty when service is not running
+	Running string `json:"running" yaml:"running"`
+	// Digest which image reference resolves to now, empty when it could not
+	// be resolved
+	Resolved string `json:"resolved" yaml:"resolved"`
+	Status   string `json:"status" yaml:"status"`
+	Error    string `json:"error,omitempty" yaml:"error,omitempty"`
+}
@@ -0,0 +1,305 @@
+package actions
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/D8-X/d8x-cli/internal/components"
+	"github.com/D8-X/d8x-cli/internal/configs"
+	"github.com/D8-X/d8x-cli/internal/styles"
+	"github.com/urfave/cli/v2"
+)
+
+// profileWorkspaceFiles are the files and directories which d8x creates in
+// working directory. They are moved into a new profile with profile create
+// --import, together with the config directory.
+var profileWorkspaceFiles = []string{
+	configs.DEFAULT_HOSTS_FILE,
+	configs.DEFAULT_PASSWORD_FILE,
+	BROKER_SERVER_REDIS_PWD_FILE,
+	"id_ed25519",
+	"id_ed25519.pub",
+	TF_FILES_DIR,
+	"trader-backend",
+	"candles",
+	"broker-server",
+	"nginx",
+	"playbooks",
+	"grafana",
+	"prometheus.yml",
+	"docker-swarm-stack.yml",
+	"docker-swarm-metrics.yml",
+	"nginx.server.conf",
+	"nginx.configured.conf",
+	"nginx-broker.tpl.conf",
+	"nginx-broker.configured.conf",
+}
+
+// UseProfile switches the working directory to the directory of profile name.
+// When name is empty, active profile of workspace is used. Without active
+// profile the workspace directory itself is used. Must be called before any
+// relative paths (config directory, hosts.cfg, etc.) are accessed.
+func (c *Container) UseProfile(name string) error {
+	wd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	c.Workspace = configs.NewWorkspace(wd)
+
+	if name == "" {
+		name, err = c.Workspace.Active()
+		if err != nil {
+			return err
+		}
+		if name == "" {
+			return nil
+		}
+	}
+	if err := configs.ValidateProfileName(name); err != nil {
+		return err
+	}
+	if !c.Workspace.Exists(name) {
+		return fmt.Errorf("profile %s does not exist, create it with d8x profile create %s", name, name)
+	}
+	if err := os.Chdir(c.Workspace.ProfileDir(name)); err != nil {
+		return fmt.Errorf("changing to profile directory: %w", err)
+	}
+	c.Profile = name
+
+	return nil
+}
+
+// workspace returns the workspace of current working directory when
+// UseProfile was not called
+func (c *Container) workspace() (*configs.Workspace, error) {
+	if c.Workspace != nil {
+		return c.Workspace, nil
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	return configs.NewWorkspace(wd), nil
+}
+
+// profileArg returns validated profile name argument
+func profileArg(ctx *cli.Context) (string, error) {
+	name := ctx.Args().First()
+	if name == "" {
+		return "", fmt.Errorf("profile name must be provided")
+	}
+	return name, configs.ValidateProfileName(name)
+}
+
+// ProfileList prints all profiles of workspace
+func (c *Container) ProfileList(ctx *cli.Context) error {
+	format, err := outputFormat(ctx)
+	if err != nil {
+		return err
+	}
+	w, err := c.workspace()
+	if err != nil {
+		return err
+	}
+	names, err := w.Profiles()
+	if err != nil {
+		return err
+	}
+	active, err := w.Active()
+	if err != nil {
+		return err
+	}
+
+	profiles := make([]Profile, len(names))
+	for i, name := range names {
+		profiles[i] = Profile{
+			Name:   name,
+			Active: name == active,
+			Path:   w.ProfileDir(name),
+		}
+	}
+	if format != OutputText {
+		return printStructured(os.Stdout, format, profiles)
+	}
+
+	if len(profiles) == 0 {
+		fmt.Println("No profiles were created yet, workspace directory is used")
+		return nil
+	}
+	for _, p := range profiles {
+		if p.Active {
+			fmt.Println(styles.SuccessText.Render("* " + p.Name))
+		} else {
+			fmt.Println("  " + p.Name)
+		}
+	}
+	if active == "" {
+		fmt.Println(styles.ItalicText.Render("No profile is active, workspace directory is used"))
+	}
+
+	return nil
+}
+
+// ProfileCreate creates a new profile. With --import, existing d8x files of
+// workspace directory are moved into the profile.
+func (c *Container) ProfileCreate(ctx *cli.Context) error {
+	name, err := profileArg(ctx)
+	if err != nil {
+		return err
+	}
+	w, err := c.workspace()
+	if err != nil {
+		return err
+	}
+	if err := w.Create(name); err != nil {
+		return err
+	}
+	fmt.Println(styles.SuccessText.Render(fmt.Sprintf("Profile %s was created at %s", name, w.ProfileDir(name))))
+
+	if ctx.Bool("import") {
+		moved, err := importWorkspaceFiles(w.Dir(), w.ProfileDir(name), c.ConfigDir)
+		for _, f := range moved {
+			fmt.Printf("Moved %s\n", f)
+		}
+		if err != nil {
+			return fmt.Errorf("importing workspace files into profile %s: %w", name, err)
+		}
+	}
+
+	if ctx.Bool("use") {
+		if err := w.SetActive(name); err != nil {
+			return err
+		}
+		fmt.Println(styles.SuccessText.Render(fmt.Sprintf("Profile %s is now active", name)))
+	} else {
+		fmt.Printf("Run d8x profile use %s to make it active or pass --profile %s to commands\n", name, name)
+	}
+
+	return nil
+}
+
+// importWorkspaceFiles moves d8x files and configDir from workspace directory
+// into profile directory. Config directory outside of the workspace (absolute
+// path) is shared by profiles and is not moved. Names of moved files are
+// returned.
+func importWorkspaceFiles(workspaceDir, profileDir, configDir string) ([]string, error) {
+	files := profileWorkspaceFiles
+	if configDir != "" && !filepath.IsAbs(configDir) {
+		files = append([]string{configDir}, files...)
+	}
+
+	moved := []string{}
+	for _, f := range files {
+		src := filepath.Join(workspaceDir, f)
+		if _, err := os.Lstat(src); err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return moved, err
+		}
+		dst := filepath.Join(profileDir, f)
+		if err := os.MkdirAll(filepath.Dir(dst), 0775); err != nil {
+			return moved, err
+		}
+		if err := os.Rename(src, dst); err != nil {
+			return moved, err
+		}
+		moved = append(moved, filepath.Clean(f))
+	}
+	return moved, nil
+}
+
+// ProfileUse records the active profile of workspace
+func (c *Container) ProfileUse(ctx *cli.Context) error {
+	w, err := c.workspace()
+	if err != nil {
+		return err
+	}
+	if ctx.Bool("none") {
+		if err := w.SetActive(""); err != nil {
+			return err
+		}
+		fmt.Println(styles.SuccessText.Render("No profile is active, workspace directory will be used"))
+		return nil
+	}
+
+	name, err := profileArg(ctx)
+	if err != nil {
+		return err
+	}
+	if err := w.SetActive(name); err != nil {
+		return err
+	}
+	fmt.Println(styles.SuccessText.Render(fmt.Sprintf("Profile %s is now active", name)))
+
+	return nil
+}
+
+// ProfileDelete removes profile and all of its files. Profiles with
+// provisioned servers in terraform state are not deleted unless --force is
+// provided.
+func (c *Container) ProfileDelete(ctx *cli.Context) error {
+	name, err := profileArg(ctx)
+	if err != nil {
+		return err
+	}
+	w, err := c.workspace()
+	if err != nil {
+		return err
+	}
+	if !w.Exists(name) {
+		return fmt.Errorf("profile %s does not exist", name)
+	}
+
+	if !ctx.Bool("force") {
+		provisioned, err := tfStateHasResources(filepath.Join(w.ProfileDir(name), TF_FILES_DIR, "terraform.tfstate"))
+		if err != nil {
+			return err
+		}
+		if provisioned {
+			return fmt.Errorf("terraform state of profile %s contains provisioned resources, run d8x --profile %s tf-destroy first or use --force", name, name)
+		}
+	}
+
+	ok, err := c.TUI.NewPrompt(
+		fmt.Sprintf("Profile %s and all of its files (config, secrets, ssh keys) will be deleted. Do you want to continue?", name),
+		false,
+		components.PromptOptId("profile.delete.confirm"),
+	)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		fmt.Println("Profile was not deleted")
+		return nil
+	}
+
+	if err := w.Delete(name); err != nil {
+		return err
+	}
+	fmt.Println(styles.SuccessText.Render(fmt.Sprintf("Profile %s was deleted", name)))
+
+	return nil
+}
+
+// tfStateHasResources reports whether terraform state file at path contains
+// any resources
+func tfStateHasResources(path string) (bool, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return false, nil
+		}
+		return false, err
+	}
+	state := struct {
+		Resources []json.RawMessage `json:"resources"`
+	}{}
+	if err := json.Unmarshal(contents, &state); err != nil {
+		return false, fmt.Errorf("parsing terraform state %s: %w", path, err)
+	}
+	return len(state.Resources) > 0, nil
+}
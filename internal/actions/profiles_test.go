@@ -0,0 +1,52 @@
+package actions
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/D8-X/d8x-cli/internal/configs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImportWorkspaceFiles(t *testing.T) {
+	w := configs.NewWorkspace(t.TempDir())
+	require.NoError(t, os.WriteFile(filepath.Join(w.Dir(), "hosts.cfg"), []byte("[managers]"), 0644))
+	require.NoError(t, os.MkdirAll(filepath.Join(w.Dir(), "terraform"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(w.Dir(), "terraform", "terraform.tfstate"), []byte("{}"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(w.Dir(), "backup-d8x.dump"), nil, 0644))
+	require.NoError(t, os.MkdirAll(filepath.Join(w.Dir(), "configs", "arbitrum"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(w.Dir(), "configs", "arbitrum", "d8x.conf.json"), []byte("{}"), 0644))
+	require.NoError(t, w.Create("mainnet"))
+
+	moved, err := importWorkspaceFiles(w.Dir(), w.ProfileDir("mainnet"), "./configs/arbitrum")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"configs/arbitrum", "hosts.cfg", "terraform"}, moved)
+	assert.FileExists(t, filepath.Join(w.ProfileDir("mainnet"), "configs", "arbitrum", "d8x.conf.json"))
+	assert.FileExists(t, filepath.Join(w.ProfileDir("mainnet"), "hosts.cfg"))
+	assert.FileExists(t, filepath.Join(w.ProfileDir("mainnet"), "terraform", "terraform.tfstate"))
+	assert.NoFileExists(t, filepath.Join(w.Dir(), "hosts.cfg"))
+	// Files not created by d8x stay in workspace
+	assert.FileExists(t, filepath.Join(w.Dir(), "backup-d8x.dump"))
+}
+
+func TestTfStateHasResources(t *testing.T) {
+	dir := t.TempDir()
+
+	has, err := tfStateHasResources(filepath.Join(dir, "missing.tfstate"))
+	require.NoError(t, err)
+	assert.False(t, has)
+
+	destroyed := filepath.Join(dir, "destroyed.tfstate")
+	require.NoError(t, os.WriteFile(destroyed, []byte(`{"version":4,"resources":[]}`), 0644))
+	has, err = tfStateHasResources(destroyed)
+	require.NoError(t, err)
+	assert.False(t, has)
+
+	provisioned := filepath.Join(dir, "provisioned.tfstate")
+	require.NoError(t, os.WriteFile(provisioned, []byte(`{"version":4,"resources":[{"type":"linode_instance"}]}`), 0644))
+	has, err = tfStateHasResources(provisioned)
+	require.NoError(t, err)
+	assert.True(t, has)
+}
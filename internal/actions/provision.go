@@ -14,8 +14,10 @@ import (
 type SupportedServerProvider string
 
 const (
-	ServerProviderLinode SupportedServerProvider = "linode"
-	ServerProviderAws    SupportedServerProvider = "aws"
+	ServerProviderLinode  SupportedServerProvider = "linode"
+	ServerProviderAws     SupportedServerProvider = "aws"
+	ServerProviderHetzner SupportedServerProvider = "hetzner"
+	ServerProviderStatic  SupportedServerProvider = "static"
 )
 
 // Default terraform files directory without trailing slash
@@ -38,27 +40,10 @@ func (c *Container) Provision(ctx *cli.Context) error {
 		return err
 	}
 
-	// Terraform init must run after we copy all the terraform files via
-	// BuildTerraformCMD
-	tfInit := exec.Command("terraform", "init")
-	tfInit.Dir = c.ProvisioningTfDir
-	connectCMDToCurrentTerm(tfInit)
-	if err := tfInit.Run(); err != nil {
+	if err := c.runTerraformApply(tfCmd); err != nil {
 		return err
 	}
 
-	if tfCmd != nil {
-		// Set the tf dir
-		tfCmd.Dir = c.ProvisioningTfDir
-
-		connectCMDToCurrentTerm(tfCmd)
-		err := tfCmd.Run()
-		if err != nil {
-			fmt.Println(styles.ErrorText.Render("Terraform apply failed, please check the output above for more details.\nPossible issues:\n\tDuplicate server label\n\tIncorrect server provider credentials\n\tSelected region was used first time"))
-			return err
-		}
-	}
-
 	// Set the provisioning time
 	c.provisioningTime = time.Now()
 
@@ -72,6 +57,47 @@ func (c *Container) Provision(ctx *cli.Context) error {
 		return err
 	}
 
+	// Pin host keys of the new servers. Keys of static servers are recorded
+	// when their reachability is checked.
+	if tfCmd != nil {
+		cfg, err := c.ConfigRWriter.Read()
+		if err != nil {
+			return err
+		}
+		c.pinProvisionedHostKeys(cfg)
+	}
+
+	return nil
+}
+
+// runTerraformApply runs terraform init and terraform apply tfCmd built by
+// ServerProviderConfigurer. Nothing is done when tfCmd is nil.
+func (c *Container) runTerraformApply(tfCmd *exec.Cmd) error {
+	if tfCmd == nil {
+		return nil
+	}
+
+	// Terraform init must run after we copy all the terraform files via
+	// BuildTerraformCMD
+	tfInit := exec.Command("terraform", "init")
+	tfInit.Dir = c.ProvisioningTfDir
+	connectCMDToCurrentTerm(tfInit)
+	if err := c.RunCmd(tfInit); err != nil {
+		return err
+	}
+
+	// Set the tf dir
+	tfCmd.Dir = c.ProvisioningTfDir
+
+	connectCMDToCurrentTerm(tfCmd)
+	if err := c.RunCmd(tfCmd); err != nil {
+		fmt.Println(styles.ErrorText.Render("Terraform apply failed, please check the output above for more details.\nPossible issues:\n\tDuplicate server label\n\tIncorrect server provider credentials\n\tSelected region was used first time"))
+		return err
+	}
+
+	// Terraform regenerates hosts.cfg
+	c.HostsCfg.ClearCache()
+
 	return nil
 }
 
@@ -79,7 +105,7 @@ func (c *Container) Provision(ctx *cli.Context) error {
 type ServerProviderConfigurer interface {
 	//  BuildTerraformCMD generates neccessary files and configs to start
 	// terraform provisioning. Returned exec.Cmd can be used to execute
-	// terraform apply
+	// terraform apply. Nil exec.Cmd means terraform is not used.
 	BuildTerraformCMD(*Container) (*exec.Cmd, error)
 
 	// PostProvisioningAction is called once BuildTerraformCMD Cmd is executed
@@ -92,6 +118,7 @@ type ServerProviderConfigurer interface {
 func (c *InputCollector) CollectNumberOfWorkers(defaultNum string) (int, error) {
 	fmt.Println("Enter number of worker servers to create: ")
 	numWorkers, err := c.TUI.NewInput(
+		components.TextInputOptId("provision.num_workers"),
 		components.TextInputOptValue(defaultNum),
 		components.TextInputOptPlaceholder("4"),
 		components.TextInputOptValidation(func(s string) bool {
@@ -29,6 +29,11 @@ type awsConfigurer struct {
 	configs.D8XAWSConfig
 
 	authorizedKey string
+
+	// d8x managed known_hosts file used by manager jump host ssh config
+	knownHostsFile string
+	// ssh port of manager used by jump host ssh config
+	sshPort int
 }
 
 func (c *Container) CopyAWSTFFiles() error {
@@ -56,32 +61,25 @@ func (a *awsConfigurer) BuildTerraformCMD(c *Container) (*exec.Cmd, error) {
 	if err := c.CopyAWSTFFiles(); err != nil {
 		return nil, err
 	}
+	a.knownHostsFile = c.knownHostsPath()
+	// Manager is not known before the first apply
+	a.sshPort = c.defaultSSHPort()
+	if managerIp, err := c.HostsCfg.GetMangerPublicIp(); err == nil {
+		a.sshPort = c.sshPort(managerIp)
+	}
+	if a.sshPort == 0 {
+		a.sshPort = conn.DefaultSSHPort
+	}
 
 	return a.generateTerraformCommand(), nil
 }
 
+// PostProvisioningAction does nothing for aws. Manager host key which is used
+// by ansible jump host config is recorded together with other servers keys.
 func (a *awsConfigurer) PostProvisioningAction(c *Container) error {
-	// Attempt to update known_hosts with manager's host key
-	managerIp, _ := c.HostsCfg.GetMangerPublicIp()
-	if err := a.putManagerToKnownHosts(managerIp); err != nil {
-		fmt.Println(
-			styles.ErrorText.Render(
-				fmt.Sprintf("could not update ~/.ssh/known_hosts with manager ip address: %v", err),
-			),
-		)
-	}
-
 	return nil
 }
 
-// putManagerToKnownHosts attempts to put managerIpAddress to current user's
-// known_hosts file, so that user does not need to manually accept the host when
-// ansible configuration runs.
-func (a *awsConfigurer) putManagerToKnownHosts(managerIpAddress string) error {
-	cmd := exec.Command("bash", "-c", fmt.Sprintf("ssh-keyscan -H %s >> ~/.ssh/known_hosts", managerIpAddress))
-	return cmd.Run()
-}
-
 // generateTerraformCommand generates terraform apply command for aws provider
 func (a *awsConfigurer) generateTerraformCommand() *exec.Cmd {
 	cmd := exec.Command(
@@ -109,6 +107,8 @@ func (a *awsConfigurer) generateVariables() []string {
 		"-var", fmt.Sprintf(`rds_creds_filepath=%s`, RDS_CREDS_FILE),
 		"-var", fmt.Sprintf(`create_swarm=%t`, a.DeploySwarm),
 		"-var", fmt.Sprintf(`num_workers=%d`, a.NumWorker),
+		"-var", fmt.Sprintf(`known_hosts_file=%s`, a.knownHostsFile),
+		"-var", fmt.Sprintf(`ssh_port=%d`, a.sshPort),
 	}
 }
 
@@ -147,6 +147,7 @@ func (c *InputCollector) CollectAwProviderDetails(cfg *configs.D8XConfig) (awsCo
 
 	fmt.Println("Enter your AWS Access Key: ")
 	accessKey, err := c.TUI.NewInput(
+		components.TextInputOptId("aws.access_key"),
 		components.TextInputOptValue(awsKey),
 		components.TextInputOptPlaceholder("<AWS_ACCESS_KEY>"),
 	)
@@ -157,6 +158,7 @@ func (c *InputCollector) CollectAwProviderDetails(cfg *configs.D8XConfig) (awsCo
 
 	fmt.Println("Enter your AWS Secret Key: ")
 	secretKey, err := c.TUI.NewInput(
+		components.TextInputOptId("aws.secret_key"),
 		components.TextInputOptValue(awsSecret),
 		components.TextInputOptMasked(),
 		components.TextInputOptPlaceholder("<AWS_SECRET_KEY>"),
@@ -169,6 +171,7 @@ func (c *InputCollector) CollectAwProviderDetails(cfg *configs.D8XConfig) (awsCo
 	fmt.Println("Enter your AWS cluster region: ")
 
 	region, err := c.TUI.NewInput(
+		components.TextInputOptId("aws.region"),
 		components.TextInputOptValue(awsDefaultRegion),
 		components.TextInputOptPlaceholder("us-west-1"),
 	)
@@ -179,6 +182,7 @@ func (c *InputCollector) CollectAwProviderDetails(cfg *configs.D8XConfig) (awsCo
 
 	fmt.Println("Enter server tag prefix (must be unique between deployments): ")
 	labelPrefix, err := c.TUI.NewInput(
+		components.TextInputOptId("aws.label_prefix"),
 		components.TextInputOptValue(awsServerLabelPrefix),
 		components.TextInputOptPlaceholder("my-cluster"),
 	)
@@ -192,6 +196,7 @@ func (c *InputCollector) CollectAwProviderDetails(cfg *configs.D8XConfig) (awsCo
 	if c.setup.deploySwarm {
 		fmt.Println("Enter your AWS RDS DB instance class: ")
 		dbClass, err := c.TUI.NewInput(
+			components.TextInputOptId("aws.rds_instance_class"),
 			components.TextInputOptValue(awsRDSInstanceClass),
 			components.TextInputOptPlaceholder("db.t3.medium"),
 		)
@@ -246,7 +251,7 @@ func (a *awsConfigurer) createRDSDatabases(c *Container, historyDbName, referral
 		return err
 	}
 
-	sshConn, err := conn.NewSSHConnection(ip, AWS_DEFAULT_INITIAL_USER, c.SshKeyPath)
+	sshConn, err := c.CreateSSHConn(ip, AWS_DEFAULT_INITIAL_USER, c.SshKeyPath)
 	if err != nil {
 		return err
 	}
@@ -28,6 +28,7 @@ func TestAWSServerConfigurer(t *testing.T) {
 			expect: func(e *expecter) {
 				e.Components.EXPECT().
 					NewInput(
+						components.TextInputOptId("aws.access_key"),
 						components.TextInputOptValue(""),
 						components.TextInputOptPlaceholder("<AWS_ACCESS_KEY>"),
 					).Return("", assert.AnError)
@@ -39,11 +40,13 @@ func TestAWSServerConfigurer(t *testing.T) {
 			expect: func(e *expecter) {
 				e.Components.EXPECT().
 					NewInput(
+						components.TextInputOptId("aws.access_key"),
 						components.TextInputOptValue(""),
 						components.TextInputOptPlaceholder("<AWS_ACCESS_KEY>"),
 					).Return("aws-access-key", nil)
 				e.Components.EXPECT().
 					NewInput(
+						components.TextInputOptId("aws.secret_key"),
 						components.TextInputOptValue(""),
 						components.TextInputOptMasked(),
 						components.TextInputOptPlaceholder("<AWS_SECRET_KEY>"),
@@ -56,17 +59,20 @@ func TestAWSServerConfigurer(t *testing.T) {
 			expect: func(e *expecter) {
 				e.Components.EXPECT().
 					NewInput(
+						components.TextInputOptId("aws.access_key"),
 						components.TextInputOptValue(""),
 						components.TextInputOptPlaceholder("<AWS_ACCESS_KEY>"),
 					).Return("aws-access-key", nil)
 				e.Components.EXPECT().
 					NewInput(
+						components.TextInputOptId("aws.secret_key"),
 						components.TextInputOptValue(""),
 						components.TextInputOptMasked(),
 						components.TextInputOptPlaceholder("<AWS_SECRET_KEY>"),
 					).Return("aws-access-secret", nil)
 				e.Components.EXPECT().
 					NewInput(
+						components.TextInputOptId("aws.region"),
 						components.TextInputOptValue("eu-central-1"),
 						components.TextInputOptPlaceholder("us-west-1"),
 					).Return("", assert.AnError)
@@ -111,7 +117,9 @@ func TestAWSServerConfigurer(t *testing.T) {
 
 func TestAwsConfigurerGenerateVariables(t *testing.T) {
 	a := &awsConfigurer{
-		authorizedKey: "the_key",
+		authorizedKey:  "the_key",
+		knownHostsFile: "/d8x/known_hosts",
+		sshPort:        2222,
 		D8XAWSConfig: configs.D8XAWSConfig{
 			AccesKey:           "the_access_key",
 			SecretKey:          "secret",
@@ -134,6 +142,8 @@ func TestAwsConfigurerGenerateVariables(t *testing.T) {
 		"-var", "rds_creds_filepath=" + RDS_CREDS_FILE,
 		"-var", "create_swarm=true",
 		"-var", "num_workers=89",
+		"-var", "known_hosts_file=/d8x/known_hosts",
+		"-var", "ssh_port=2222",
 	}
 
 	assert.Equal(t, wantVars, a.generateVariables())
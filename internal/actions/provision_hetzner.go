@@ -0,0 +1,278 @@
+package actions
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/D8-X/d8x-cli/internal/components"
+	"github.com/D8-X/d8x-cli/internal/configs"
+	"github.com/D8-X/d8x-cli/internal/files"
+	"github.com/D8-X/d8x-cli/internal/styles"
+)
+
+// from hcloud location list
+var hetznerLocations = []components.ListItem{
+	{ItemTitle: "fsn1", ItemDesc: "Falkenstein, DE"},
+	{ItemTitle: "nbg1", ItemDesc: "Nuremberg, DE"},
+	{ItemTitle: "hel1", ItemDesc: "Helsinki, FI"},
+	{ItemTitle: "ash", ItemDesc: "Ashburn, VA"},
+	{ItemTitle: "hil", ItemDesc: "Hillsboro, OR"},
+	{ItemTitle: "sin", ItemDesc: "Singapore, SG"},
+}
+
+// Network zones of hetznerLocations. Private network subnet must be in the
+// same zone as servers.
+var hetznerNetworkZones = map[string]string{
+	"fsn1": "eu-central",
+	"nbg1": "eu-central",
+	"hel1": "eu-central",
+	"ash":  "us-east",
+	"hil":  "us-west",
+	"sin":  "ap-southeast",
+}
+
+// from hcloud server-type list
+var hetznerServerTypes = []components.ListItem{
+	{ItemTitle: "cpx21", ItemDesc: "3 shared AMD vCPU, 4GB RAM"},
+	{ItemTitle: "cpx31", ItemDesc: "4 shared AMD vCPU, 8GB RAM"},
+	{ItemTitle: "cpx41", ItemDesc: "8 shared AMD vCPU, 16GB RAM"},
+	{ItemTitle: "cx32", ItemDesc: "4 shared Intel vCPU, 8GB RAM"},
+	{ItemTitle: "cx42", ItemDesc: "8 shared Intel vCPU, 16GB RAM"},
+	{ItemTitle: "ccx13", ItemDesc: "2 dedicated AMD vCPU, 8GB RAM"},
+	{ItemTitle: "ccx23", ItemDesc: "4 dedicated AMD vCPU, 16GB RAM"},
+}
+
+var _ ServerProviderConfigurer = (*hetznerConfigurer)(nil)
+
+type hetznerConfigurer struct {
+	configs.D8XHetznerConfig
+
+	// public key that will be added to servers ssh keys
+	authorizedKey string
+}
+
+func (c *Container) CopyHetznerTFFiles() error {
+	return c.EmbedCopier.Copy(configs.EmbededConfigs,
+		files.EmbedCopierOp{
+			Src:       "embedded/trader-backend/tf-hetzner",
+			Dst:       c.ProvisioningTfDir,
+			Dir:       true,
+			Overwrite: true,
+		},
+	)
+}
+
+// BuildTerraformCMD builds terraform configuration for hetzner cluster
+// creation.
+func (h hetznerConfigurer) BuildTerraformCMD(c *Container) (*exec.Cmd, error) {
+	// Copy tf configs
+	if err := c.CopyHetznerTFFiles(); err != nil {
+		return nil, fmt.Errorf("generating hetzner terraform files: %w", err)
+	}
+
+	// Build the terraform apply command
+	args := h.generateArgs()
+	command := exec.Command("terraform", args...)
+	// for $HOME
+	command.Env = os.Environ()
+	command.Dir = c.ProvisioningTfDir
+	// Add hetzner token
+	command.Env = append(command.Env,
+		fmt.Sprintf("HCLOUD_TOKEN=%s", h.Token),
+	)
+
+	return command, nil
+}
+
+func (h hetznerConfigurer) generateArgs() []string {
+	args := []string{
+		"apply", "-auto-approve",
+		"-var", fmt.Sprintf(`authorized_key=%s`, strings.TrimSpace(h.authorizedKey)),
+		"-var", fmt.Sprintf(`location=%s`, h.Location),
+		"-var", fmt.Sprintf(`network_zone=%s`, hetznerNetworkZones[h.Location]),
+		"-var", fmt.Sprintf(`server_label_prefix=%s`, h.LabelPrefix),
+		"-var", fmt.Sprintf(`create_broker_server=%t`, h.CreateBrokerServer),
+		"-var", fmt.Sprintf(`create_swarm=%t`, h.DeploySwarm),
+		"-var", fmt.Sprintf(`num_workers=%d`, h.NumWorker),
+	}
+
+	if h.BrokerServerType != "" {
+		args = append(
+			args,
+			"-var", fmt.Sprintf(`broker_size=%s`, h.BrokerServerType),
+		)
+	}
+	if h.SwarmServerType != "" {
+		args = append(
+			args,
+			"-var", fmt.Sprintf(`worker_size=%s`, h.SwarmServerType),
+		)
+	}
+
+	return args
+}
+
+func getListItemByTitle(items []components.ListItem, title string) components.ListItem {
+	for _, item := range items {
+		if item.ItemTitle == title {
+			return item
+		}
+	}
+	return components.ListItem{}
+}
+
+// CollectHetznerProviderDetails collects hetzner provider details from user
+// input, creates a new hetznerConfigurer and fills in configuration details to
+// cfg.
+func (c *InputCollector) CollectHetznerProviderDetails(cfg *configs.D8XConfig) (hetznerConfigurer, error) {
+	if c.provisioning.collectedHetznerConfigurer != nil {
+		return *c.provisioning.collectedHetznerConfigurer, nil
+	}
+
+	h := hetznerConfigurer{}
+
+	// Attempt to load defaults from config
+	var (
+		defaultToken              = ""
+		defaultClusterLabelPrefix = "d8x-cluster"
+		defaultLocation           = ""
+		defaultSwarmServerType    = "cpx31"
+		defaultBrokerServerType   = "cpx31"
+		defaultNumberOfWokers     = "4"
+	)
+
+	if cfg.ServerProvider == configs.D8XServerProviderHetzner {
+		if cfg.HetznerConfig != nil {
+			defaultToken = cfg.HetznerConfig.Token
+			defaultLocation = cfg.HetznerConfig.Location
+			defaultClusterLabelPrefix = cfg.HetznerConfig.LabelPrefix
+			if cfg.HetznerConfig.SwarmServerType != "" {
+				defaultSwarmServerType = cfg.HetznerConfig.SwarmServerType
+			}
+			if cfg.HetznerConfig.BrokerServerType != "" {
+				defaultBrokerServerType = cfg.HetznerConfig.BrokerServerType
+			}
+			defaultNumberOfWokers = strconv.Itoa(cfg.HetznerConfig.NumWorker)
+			if cfg.HetznerConfig.NumWorker <= 0 {
+				defaultNumberOfWokers = "4"
+			}
+		}
+	}
+
+	// Token
+	fmt.Println("Enter your Hetzner Cloud API token")
+	token, err := c.TUI.NewInput(
+		components.TextInputOptId("hetzner.token"),
+		components.TextInputOptPlaceholder("<YOUR HETZNER API TOKEN>"),
+		components.TextInputOptValue(defaultToken),
+		components.TextInputOptMasked(),
+	)
+	if err != nil {
+		return h, err
+	}
+	h.Token = token
+
+	// Location
+	selected, err := c.TUI.NewList(
+		hetznerLocations,
+		"Choose the Hetzner cluster location",
+		components.ListOptId("hetzner.location"),
+		components.ListOptSelectedItem(getListItemByTitle(hetznerLocations, defaultLocation)),
+	)
+	if err != nil {
+		return h, err
+	}
+	h.Location = selected.ItemTitle
+	fmt.Printf(
+		"Selected location: %s\n\n",
+		styles.ItalicText.Render(
+			fmt.Sprintf("%s (%s)",
+				selected.ItemDesc,
+				selected.ItemTitle,
+			),
+		),
+	)
+
+	// Label prefix
+	fmt.Println("Enter your Hetzner servers name prefix")
+	label, err := c.TUI.NewInput(
+		components.TextInputOptId("hetzner.label_prefix"),
+		components.TextInputOptPlaceholder("my-d8x-cluster"),
+		components.TextInputOptValue(defaultClusterLabelPrefix),
+	)
+	if err != nil {
+		return h, err
+	}
+	h.LabelPrefix = label
+
+	// Broker-server
+	h.CreateBrokerServer = c.setup.deployBroker
+	if c.setup.deployBroker {
+		selected, err := c.TUI.NewList(
+			hetznerServerTypes,
+			"Choose the broker server type",
+			components.ListOptId("hetzner.broker_server_type"),
+			components.ListOptSelectedItem(getListItemByTitle(hetznerServerTypes, defaultBrokerServerType)),
+		)
+		if err != nil {
+			return h, err
+		}
+		h.BrokerServerType = selected.ItemTitle
+		fmt.Printf("Selected broker server type: %s\n\n", styles.ItalicText.Render(selected.ItemTitle))
+	}
+
+	// Swarm details
+	if c.setup.deploySwarm {
+		h.DeploySwarm = true
+
+		selected, err := c.TUI.NewList(
+			hetznerServerTypes,
+			"Choose the swarm servers type",
+			components.ListOptId("hetzner.swarm_server_type"),
+			components.ListOptSelectedItem(getListItemByTitle(hetznerServerTypes, defaultSwarmServerType)),
+		)
+		if err != nil {
+			return h, err
+		}
+		h.SwarmServerType = selected.ItemTitle
+		fmt.Printf("Selected swarm servers type: %s\n\n", styles.ItalicText.Render(selected.ItemTitle))
+
+		// Number of workers
+		numWorkers, err := c.CollectNumberOfWorkers(defaultNumberOfWokers)
+		if err != nil {
+			return h, fmt.Errorf("incorrect number of workers: %w", err)
+		}
+		h.NumWorker = numWorkers
+	}
+
+	c.provisioning.collectedHetznerConfigurer = &h
+
+	// Update the cfg
+	cfg.ServerProvider = configs.D8XServerProviderHetzner
+	cfg.HetznerConfig = &h.D8XHetznerConfig
+
+	return h, nil
+}
+
+func (h hetznerConfigurer) PostProvisioningAction(c *Container) error {
+	cfg, err := c.ConfigRWriter.Read()
+	if err != nil {
+		return err
+	}
+
+	// Hetzner does not provide managed postgres
+	c.externalDbNotice("Hetzner")
+
+	// Same as for linode - terraform always overwrites the hosts.cfg, so
+	// already configured servers must use cluster user for ssh login
+	if cfg.ConfigDetails.Done && len(cfg.ConfigDetails.ConfiguredServers) > 0 {
+		if err := c.LinodeInventorySetUserVar(cfg.ConfigDetails.ConfiguredServers, c.DefaultClusterUserName); err != nil {
+			return fmt.Errorf("updating hetzner inventory file: %w", err)
+		}
+	}
+
+	return nil
+}
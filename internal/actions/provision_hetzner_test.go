@@ -0,0 +1,75 @@
+package actions
+
+import (
+	"testing"
+
+	"github.com/D8-X/d8x-cli/internal/configs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHetznerGenerateArgs(t *testing.T) {
+
+	tests := []struct {
+		name    string
+		h       hetznerConfigurer
+		wantOut []string
+	}{
+		{
+			name: "ok",
+			h: hetznerConfigurer{
+				D8XHetznerConfig: configs.D8XHetznerConfig{
+					Token:              "token",
+					Location:           "hel1",
+					LabelPrefix:        "prefix",
+					CreateBrokerServer: false,
+					DeploySwarm:        true,
+					SwarmServerType:    "cpx41",
+					NumWorker:          5,
+				},
+				authorizedKey: "ssh-pub\n",
+			},
+			wantOut: []string{
+				"apply", "-auto-approve",
+				"-var", `authorized_key=ssh-pub`,
+				"-var", `location=hel1`,
+				"-var", `network_zone=eu-central`,
+				"-var", `server_label_prefix=prefix`,
+				"-var", `create_broker_server=false`,
+				"-var", `create_swarm=true`,
+				"-var", `num_workers=5`,
+				"-var", `worker_size=cpx41`,
+			},
+		},
+		{
+			name: "broker only",
+			h: hetznerConfigurer{
+				D8XHetznerConfig: configs.D8XHetznerConfig{
+					Token:              "token",
+					Location:           "ash",
+					LabelPrefix:        "prefix",
+					CreateBrokerServer: true,
+					BrokerServerType:   "cpx21",
+				},
+				authorizedKey: "ssh-pub",
+			},
+			wantOut: []string{
+				"apply", "-auto-approve",
+				"-var", `authorized_key=ssh-pub`,
+				"-var", `location=ash`,
+				"-var", `network_zone=us-east`,
+				"-var", `server_label_prefix=prefix`,
+				"-var", `create_broker_server=true`,
+				"-var", `create_swarm=false`,
+				"-var", `num_workers=0`,
+				"-var", `broker_size=cpx21`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := tt.h.generateArgs()
+			assert.Equal(t, tt.wantOut, args)
+		})
+	}
+}
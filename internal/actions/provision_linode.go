@@ -166,6 +166,7 @@ func (c *InputCollector) CollectLinodeProviderDetails(cfg *configs.D8XConfig) (l
 	// Token
 	fmt.Println("Enter your Linode API token")
 	token, err := c.TUI.NewInput(
+		components.TextInputOptId("linode.token"),
 		components.TextInputOptPlaceholder("<YOUR LINODE API TOKEN>"),
 		components.TextInputOptValue(defaultToken),
 		components.TextInputOptMasked(),
@@ -178,9 +179,10 @@ func (c *InputCollector) CollectLinodeProviderDetails(cfg *configs.D8XConfig) (l
 
 	// DB for swarm
 	if c.setup.deploySwarm {
-		if ok, err := c.TUI.NewPrompt("Do you want to use an external database? (choose no if you have a legacy Linode DB cluster)", true); err == nil && !ok {
+		if ok, err := c.TUI.NewPrompt("Do you want to use an external database? (choose no if you have a legacy Linode DB cluster)", true, components.PromptOptId("linode.external_db")); err == nil && !ok {
 			fmt.Println("Enter your Linode database cluster ID")
 			dbId, err := c.TUI.NewInput(
+				components.TextInputOptId("linode.db_id"),
 				components.TextInputOptPlaceholder("12345678"),
 				components.TextInputOptValue(defaultDbId),
 			)
@@ -197,6 +199,7 @@ func (c *InputCollector) CollectLinodeProviderDetails(cfg *configs.D8XConfig) (l
 	selected, err := c.TUI.NewList(
 		linodeRegions,
 		"Choose the Linode cluster region",
+		components.ListOptId("linode.region"),
 		components.ListOptSelectedItem(defaultRegionItem),
 	)
 	if err != nil {
@@ -216,6 +219,7 @@ func (c *InputCollector) CollectLinodeProviderDetails(cfg *configs.D8XConfig) (l
 	// Label prefix
 	fmt.Println("Enter your Linode nodes label prefix")
 	label, err := c.TUI.NewInput(
+		components.TextInputOptId("linode.label_prefix"),
 		components.TextInputOptPlaceholder("my-d8x-cluster"),
 		components.TextInputOptValue(defaultClusterLabelPrefix),
 	)
@@ -229,6 +233,7 @@ func (c *InputCollector) CollectLinodeProviderDetails(cfg *configs.D8XConfig) (l
 	if c.setup.deployBroker {
 		fmt.Println("Broker linode node size")
 		brokerNodeSize, err := c.TUI.NewInput(
+			components.TextInputOptId("linode.broker_node_size"),
 			components.TextInputOptPlaceholder("g6-dedicated-2"),
 			components.TextInputOptValue(defaultBrokerSize),
 		)
@@ -245,6 +250,7 @@ func (c *InputCollector) CollectLinodeProviderDetails(cfg *configs.D8XConfig) (l
 		// Servers sizes
 		fmt.Println("Swarm linode node size")
 		swarmNodeSize, err := c.TUI.NewInput(
+			components.TextInputOptId("linode.swarm_node_size"),
 			components.TextInputOptPlaceholder("g6-dedicated-2"),
 			components.TextInputOptValue(defaultSwarmNodeSize),
 		)
@@ -273,10 +279,18 @@ func (c *InputCollector) CollectLinodeProviderDetails(cfg *configs.D8XConfig) (l
 
 // noLinodeDbCheck displays some information to users when external db is used.
 func (i linodeConfigurer) noLinodeDbCheck(c *Container) {
-	if i.DbId == "" && !c.Input.BrokerOnly() {
+	if i.DbId == "" {
+		c.externalDbNotice("Linode")
+	}
+}
+
+// externalDbNotice displays some information to users of providers without
+// managed database.
+func (c *Container) externalDbNotice(providerName string) {
+	if !c.Input.BrokerOnly() {
 		fmt.Println(
 			styles.AlertImportant.Render(
-				"Make sure you configure external database to allow connections from Linode cluster!",
+				fmt.Sprintf("Make sure you configure external database to allow connections from %s cluster!", providerName),
 			),
 		)
 
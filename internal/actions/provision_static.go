@@ -0,0 +1,283 @@
+package actions
+
+import (
+	"fmt"
+	"net"
+	"os/exec"
+	"slices"
+	"strings"
+
+	"github.com/D8-X/d8x-cli/internal/components"
+	"github.com/D8-X/d8x-cli/internal/configs"
+	"github.com/D8-X/d8x-cli/internal/styles"
+)
+
+var _ ServerProviderConfigurer = (*staticConfigurer)(nil)
+
+// staticConfigurer is used for servers which were created outside of d8x-cli
+// (bare-metal or any other already running machines). No terraform is run,
+// only the hosts.cfg inventory is generated.
+type staticConfigurer struct {
+	configs.D8XStaticConfig
+}
+
+// privateIpOrPublic returns private ip if it is set, otherwise public ip is
+// used as private ip.
+func privateIpOrPublic(private, public string) string {
+	if private != "" {
+		return private
+	}
+	return public
+}
+
+// inventoryLines generates hosts.cfg lines in the same format as terraform
+// inventory.tpl templates do.
+func (s staticConfigurer) inventoryLines() []string {
+	lines := []string{}
+
+	if s.DeploySwarm {
+		lines = append(lines,
+			"[managers]",
+			fmt.Sprintf("%s manager_private_ip=%s hostname=manager-1",
+				s.ManagerIp,
+				privateIpOrPublic(s.ManagerPrivateIp, s.ManagerIp),
+			),
+			"",
+			"[workers]",
+		)
+		for i, ip := range s.WorkerIps {
+			privateIp := ""
+			if i < len(s.WorkerPrivateIps) {
+				privateIp = s.WorkerPrivateIps[i]
+			}
+			lines = append(lines,
+				fmt.Sprintf("%s worker_private_ip=%s hostname=worker-%02d",
+					ip,
+					privateIpOrPublic(privateIp, ip),
+					i+1,
+				),
+			)
+		}
+		lines = append(lines, "")
+	}
+
+	if s.CreateBrokerServer {
+		lines = append(lines,
+			"[broker]",
+			fmt.Sprintf("%s private_ip=%s", s.BrokerIp, privateIpOrPublic(s.BrokerPrivateIp, s.BrokerIp)),
+		)
+	}
+
+	return lines
+}
+
+// publicIps returns all public ip addresses of static servers
+func (s staticConfigurer) publicIps() []string {
+	ips := []string{}
+	if s.DeploySwarm {
+		ips = append(ips, s.ManagerIp)
+		ips = append(ips, s.WorkerIps...)
+	}
+	if s.CreateBrokerServer {
+		ips = append(ips, s.BrokerIp)
+	}
+	return ips
+}
+
+// BuildTerraformCMD does not build any terraform command for static servers.
+// It validates that all servers are reachable via ssh and generates the
+// hosts.cfg inventory file.
+func (s staticConfigurer) BuildTerraformCMD(c *Container) (*exec.Cmd, error) {
+	cfg, err := c.ConfigRWriter.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Println("Checking ssh connectivity of servers...")
+	unreachable := []string{}
+	for _, ip := range s.publicIps() {
+		// Already configured servers do not allow login with initial user
+		user := cfg.GetAnsibleUser()
+		if slices.Contains(cfg.ConfigDetails.ConfiguredServers, ip) {
+			user = c.DefaultClusterUserName
+		}
+
+		sshConn, err := c.CreateSSHConn(ip, user, c.SshKeyPath)
+		if err == nil {
+			_, err = sshConn.ExecCommand("echo ok")
+		}
+		if err != nil {
+			fmt.Println(styles.ErrorText.Render(fmt.Sprintf("Server %s@%s is not reachable: %v", user, ip, err)))
+			unreachable = append(unreachable, ip)
+			continue
+		}
+		fmt.Printf("Server %s@%s is reachable\n", user, ip)
+	}
+	if len(unreachable) > 0 {
+		return nil, fmt.Errorf("servers are not reachable via ssh: %s", strings.Join(unreachable, ", "))
+	}
+
+	if err := c.HostsCfg.WriteLines(s.inventoryLines()); err != nil {
+		return nil, fmt.Errorf("writing %s: %w", configs.DEFAULT_HOSTS_FILE, err)
+	}
+	fmt.Printf("Inventory file %s was generated\n", configs.DEFAULT_HOSTS_FILE)
+
+	return nil, nil
+}
+
+func (s staticConfigurer) PostProvisioningAction(c *Container) error {
+	cfg, err := c.ConfigRWriter.Read()
+	if err != nil {
+		return err
+	}
+
+	c.externalDbNotice("your")
+
+	// hosts.cfg is regenerated on each provisioning, so already configured
+	// servers must use cluster user for ssh login
+	if cfg.ConfigDetails.Done && len(cfg.ConfigDetails.ConfiguredServers) > 0 {
+		if err := c.LinodeInventorySetUserVar(cfg.ConfigDetails.ConfiguredServers, c.DefaultClusterUserName); err != nil {
+			return fmt.Errorf("updating static inventory file: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func validateIp(s string) bool {
+	return net.ParseIP(strings.TrimSpace(s)) != nil
+}
+
+// validateIpList validates comma separated list of ip addresses
+func validateIpList(s string) bool {
+	for _, ip := range strings.Split(s, ",") {
+		if !validateIp(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+func splitIpList(s string) []string {
+	ips := []string{}
+	for _, ip := range strings.Split(s, ",") {
+		if ip = strings.TrimSpace(ip); ip != "" {
+			ips = append(ips, ip)
+		}
+	}
+	return ips
+}
+
+// collectIp collects single ip address. Empty value is accepted only when
+// optional is true.
+func (c *InputCollector) collectIp(id, defaultValue string, optional bool) (string, error) {
+	ip, err := c.TUI.NewInput(
+		components.TextInputOptId(id),
+		components.TextInputOptValue(defaultValue),
+		components.TextInputOptPlaceholder("127.0.0.1"),
+		components.TextInputOptValidation(func(s string) bool {
+			return (optional && s == "") || validateIp(s)
+		}, "please provide a valid ip address"),
+	)
+	return strings.TrimSpace(ip), err
+}
+
+// collectIpList collects comma separated list of ip addresses
+func (c *InputCollector) collectIpList(id, defaultValue string, optional bool) ([]string, error) {
+	ips, err := c.TUI.NewInput(
+		components.TextInputOptId(id),
+		components.TextInputOptValue(defaultValue),
+		components.TextInputOptPlaceholder("127.0.0.1,127.0.0.2"),
+		components.TextInputOptValidation(func(s string) bool {
+			return (optional && s == "") || validateIpList(s)
+		}, "please provide a comma separated list of valid ip addresses"),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return splitIpList(ips), nil
+}
+
+// CollectStaticProviderDetails collects ip addresses of user provided servers
+// and fills in configuration details to cfg.
+func (c *InputCollector) CollectStaticProviderDetails(cfg *configs.D8XConfig) (staticConfigurer, error) {
+	if c.provisioning.collectedStaticConfigurer != nil {
+		return *c.provisioning.collectedStaticConfigurer, nil
+	}
+
+	s := staticConfigurer{}
+	defaults := configs.D8XStaticConfig{SSHUser: "root"}
+	if cfg.ServerProvider == configs.D8XServerProviderStatic && cfg.StaticConfig != nil {
+		defaults = *cfg.StaticConfig
+	}
+
+	fmt.Println("Enter the user used for initial ssh login to your servers")
+	user, err := c.TUI.NewInput(
+		components.TextInputOptId("static.ssh_user"),
+		components.TextInputOptValue(defaults.SSHUser),
+		components.TextInputOptPlaceholder("root"),
+	)
+	if err != nil {
+		return s, err
+	}
+	s.SSHUser = strings.TrimSpace(user)
+
+	if c.setup.deploySwarm {
+		s.DeploySwarm = true
+
+		fmt.Println("Enter the manager server public ip address")
+		if s.ManagerIp, err = c.collectIp("static.manager_ip", defaults.ManagerIp, false); err != nil {
+			return s, err
+		}
+		fmt.Println("Enter the manager server private ip address (leave empty to use public ip)")
+		if s.ManagerPrivateIp, err = c.collectIp("static.manager_private_ip", defaults.ManagerPrivateIp, true); err != nil {
+			return s, err
+		}
+
+		fmt.Println("Enter comma separated worker servers public ip addresses")
+		if s.WorkerIps, err = c.collectIpList("static.worker_ips", strings.Join(defaults.WorkerIps, ","), false); err != nil {
+			return s, err
+		}
+		fmt.Println("Enter comma separated worker servers private ip addresses in the same order (leave empty to use public ips)")
+		for {
+			if s.WorkerPrivateIps, err = c.collectIpList("static.worker_private_ips", strings.Join(defaults.WorkerPrivateIps, ","), true); err != nil {
+				return s, err
+			}
+			if len(s.WorkerPrivateIps) == 0 || len(s.WorkerPrivateIps) == len(s.WorkerIps) {
+				break
+			}
+			msg := fmt.Sprintf("Expected %d private ip addresses, got %d", len(s.WorkerIps), len(s.WorkerPrivateIps))
+			if err := components.RejectAnswer(c.TUI, "static.worker_private_ips", msg); err != nil {
+				return s, err
+			}
+			fmt.Println(styles.ErrorText.Render(msg))
+		}
+	}
+
+	if c.setup.deployBroker {
+		s.CreateBrokerServer = true
+
+		fmt.Println("Enter the broker server public ip address")
+		if s.BrokerIp, err = c.collectIp("static.broker_ip", defaults.BrokerIp, false); err != nil {
+			return s, err
+		}
+		fmt.Println("Enter the broker server private ip address (leave empty to use public ip)")
+		if s.BrokerPrivateIp, err = c.collectIp("static.broker_private_ip", defaults.BrokerPrivateIp, true); err != nil {
+			return s, err
+		}
+	}
+
+	fmt.Println(
+		styles.AlertImportant.Render(
+			fmt.Sprintf("Make sure the public key %s.pub is added to authorized_keys of %s user on all your servers!", c.SSHKeyPath, s.SSHUser),
+		),
+	)
+
+	c.provisioning.collectedStaticConfigurer = &s
+
+	// Update the cfg
+	cfg.ServerProvider = configs.D8XServerProviderStatic
+	cfg.StaticConfig = &s.D8XStaticConfig
+
+	return s, nil
+}
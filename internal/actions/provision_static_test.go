@@ -0,0 +1,167 @@
+package actions
+
+import (
+	"testing"
+
+	"github.com/D8-X/d8x-cli/internal/components"
+	"github.com/D8-X/d8x-cli/internal/configs"
+	"github.com/D8-X/d8x-cli/internal/conn"
+	"github.com/D8-X/d8x-cli/internal/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestStaticConfigurerInventoryLines(t *testing.T) {
+	tests := []struct {
+		name    string
+		s       staticConfigurer
+		wantOut []string
+	}{
+		{
+			name: "swarm and broker",
+			s: staticConfigurer{
+				D8XStaticConfig: configs.D8XStaticConfig{
+					ManagerIp:          "1.1.1.1",
+					ManagerPrivateIp:   "10.0.0.1",
+					WorkerIps:          []string{"1.1.1.2", "1.1.1.3"},
+					WorkerPrivateIps:   []string{"10.0.0.2", "10.0.0.3"},
+					BrokerIp:           "1.1.1.4",
+					BrokerPrivateIp:    "10.0.0.4",
+					CreateBrokerServer: true,
+					DeploySwarm:        true,
+				},
+			},
+			wantOut: []string{
+				"[managers]",
+				"1.1.1.1 manager_private_ip=10.0.0.1 hostname=manager-1",
+				"",
+				"[workers]",
+				"1.1.1.2 worker_private_ip=10.0.0.2 hostname=worker-01",
+				"1.1.1.3 worker_private_ip=10.0.0.3 hostname=worker-02",
+				"",
+				"[broker]",
+				"1.1.1.4 private_ip=10.0.0.4",
+			},
+		},
+		{
+			name: "no private ips",
+			s: staticConfigurer{
+				D8XStaticConfig: configs.D8XStaticConfig{
+					ManagerIp:   "1.1.1.1",
+					WorkerIps:   []string{"1.1.1.2"},
+					DeploySwarm: true,
+				},
+			},
+			wantOut: []string{
+				"[managers]",
+				"1.1.1.1 manager_private_ip=1.1.1.1 hostname=manager-1",
+				"",
+				"[workers]",
+				"1.1.1.2 worker_private_ip=1.1.1.2 hostname=worker-01",
+				"",
+			},
+		},
+		{
+			name: "broker only",
+			s: staticConfigurer{
+				D8XStaticConfig: configs.D8XStaticConfig{
+					BrokerIp:           "1.1.1.4",
+					CreateBrokerServer: true,
+				},
+			},
+			wantOut: []string{
+				"[broker]",
+				"1.1.1.4 private_ip=1.1.1.4",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.wantOut, tt.s.inventoryLines())
+		})
+	}
+}
+
+func TestStaticConfigurerBuildTerraformCMD(t *testing.T) {
+	s := staticConfigurer{
+		D8XStaticConfig: configs.D8XStaticConfig{
+			ManagerIp:   "1.1.1.1",
+			WorkerIps:   []string{"1.1.1.2"},
+			SSHUser:     "admin",
+			DeploySwarm: true,
+		},
+	}
+
+	tests := []struct {
+		name        string
+		unreachable string
+		wantErr     string
+	}{
+		{
+			name: "ok",
+		},
+		{
+			name:        "unreachable server",
+			unreachable: "1.1.1.2",
+			wantErr:     "servers are not reachable via ssh: 1.1.1.2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctl := gomock.NewController(t)
+			cfgRW := mocks.NewMockD8XConfigReadWriter(ctl)
+			cfgRW.EXPECT().Read().Return(&configs.D8XConfig{
+				ServerProvider: configs.D8XServerProviderStatic,
+				StaticConfig:   &s.D8XStaticConfig,
+			}, nil)
+			hostsCfg := mocks.NewMockHostsFileInteractor(ctl)
+			if tt.wantErr == "" {
+				hostsCfg.EXPECT().WriteLines(s.inventoryLines()).Return(nil)
+			}
+
+			c := &Container{
+				ConfigRWriter: cfgRW,
+				HostsCfg:      hostsCfg,
+				SshKeyPath:    "./id_ed25519",
+				CreateSSHConn: func(serverIp, user, idFilePath string) (conn.SSHConnection, error) {
+					assert.Equal(t, "admin", user)
+					sshConn := mocks.NewMockSSHConnection(ctl)
+					if serverIp == tt.unreachable {
+						sshConn.EXPECT().ExecCommand("echo ok").Return(nil, assert.AnError)
+					} else {
+						sshConn.EXPECT().ExecCommand("echo ok").Return([]byte("ok"), nil)
+					}
+					return sshConn, nil
+				},
+			}
+
+			cmd, err := s.BuildTerraformCMD(c)
+			assert.Nil(t, cmd)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestCollectStaticProviderDetailsRejectsPrivateIpsCount(t *testing.T) {
+	answers, err := components.NewAnswersRunner([]byte(`
+static.ssh_user: root
+static.manager_ip: 10.0.0.1
+static.manager_private_ip: ~
+static.worker_ips: 10.0.0.2,10.0.0.3
+static.worker_private_ips: 192.168.0.2
+`))
+	require.NoError(t, err)
+
+	c := &InputCollector{TUI: answers}
+	c.setup.deploySwarm = true
+
+	_, err = c.CollectStaticProviderDetails(configs.NewD8XConfig())
+	assert.ErrorContains(t, err, `answer for prompt "static.worker_private_ips" was rejected`)
+}
@@ -0,0 +1,387 @@
+package actions
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/containers/image/docker"
+	"github.com/containers/image/manifest"
+	"github.com/containers/image/pkg/blobinfocache/none"
+	"github.com/containers/image/types"
+	"github.com/distribution/reference"
+	"github.com/opencontainers/go-digest"
+	"github.com/urfave/cli/v2"
+	"golang.org/x/mod/semver"
+)
+
+// Media type of multi platform images pushed by docker buildx, which is not
+// known to containers/image
+const ociImageIndexMediaType = "application/vnd.oci.image.index.v1+json"
+
+// Number of image tags which are inspected concurrently
+const registryInspectConcurrency = 8
+
+// Number of newest image tags which are inspected, older tags are listed
+// without details
+const registryInspectMaxTags = 30
+
+// Labels of image config which link image to its source repository
+const (
+	ociSourceLabel   = "org.opencontainers.image.source"
+	ociRevisionLabel = "org.opencontainers.image.revision"
+	ociCreatedLabel  = "org.opencontainers.image.created"
+)
+
+func init() {
+	// Registries (ghcr.io) refuse to serve OCI indexes unless they are
+	// accepted
+	if !slices.Contains(manifest.DefaultRequestedManifestMIMETypes, ociImageIndexMediaType) {
+		manifest.DefaultRequestedManifestMIMETypes = append(manifest.DefaultRequestedManifestMIMETypes, ociImageIndexMediaType)
+	}
+}
+
+// registrySystemContext returns containers/image system context with
+// --registry-username and --registry-password credentials. Credentials of
+// docker login and podman login are used when flags are not provided.
+func registrySystemContext(ctx *cli.Context) *types.SystemContext {
+	sys := &types.SystemContext{}
+	if username := ctx.String("registry-username"); username != "" {
+		sys.DockerAuthConfig = &types.DockerAuthConfig{
+			Username: username,
+			Password: ctx.String("registry-password"),
+		}
+	}
+	return sys
+}
+
+// imageTag is a tag of image repository resolved via registry v2 api
+type imageTag struct {
+	Tag string
+	// Digest of tag manifest (or index of multi platform images)
+	Digest string
+	// Creation time of image, zero when it is not known
+	Created time.Time
+	// Link to release notes or commit of image source, empty when image has
+	// no source label
+	ReleaseNotes string
+}
+
+// Reference returns image reference of tag pinned to its digest
+func (t imageTag) Reference(img string) string {
+	ref := imageRepository(img) + ":" + t.Tag
+	if t.Digest != "" {
+		ref += "@" + t.Digest
+	}
+	return ref
+}
+
+// Label returns selection label of tag
+func (t imageTag) Label(img string) string {
+	details := []string{}
+	if !t.Created.IsZero() {
+		details = append(details, "created "+t.Created.Format(time.DateOnly))
+	}
+	if t.ReleaseNotes != "" {
+		details = append(details, t.ReleaseNotes)
+	}
+	label := t.Reference(img)
+	if len(details) > 0 {
+		label += " (" + strings.Join(details, ", ") + ")"
+	}
+	return label
+}
+
+// imageRepository strips tag and digest of img
+func imageRepository(img string) string {
+	ref, err := reference.ParseNormalizedNamed(img)
+	if err != nil {
+		return img
+	}
+	return reference.FamiliarName(ref)
+}
+
+// listImageTags lists tags of img repository with their digests, creation
+// dates and release notes, newest first. Only the registryInspectMaxTags newest
+// tags (see sortTagNames) are inspected. Older tags and tags which can not be
+// inspected are listed without details.
+func listImageTags(ctx context.Context, sys *types.SystemContext, img string) ([]imageTag, error) {
+	named, err := reference.ParseNormalizedNamed(img)
+	if err != nil {
+		return nil, err
+	}
+	named = reference.TrimNamed(named)
+	imgRef, err := docker.NewReference(reference.TagNameOnly(named))
+	if err != nil {
+		return nil, err
+	}
+	tags, err := docker.GetRepositoryTags(ctx, sys, imgRef)
+	if err != nil {
+		return nil, fmt.Errorf("listing tags: %w", err)
+	}
+	// Signatures and attestations are stored as sha256-<digest> tags
+	tags = slices.DeleteFunc(tags, func(tag string) bool {
+		return strings.HasPrefix(tag, "sha256-")
+	})
+	sortTagNames(tags)
+
+	result := make([]imageTag, len(tags))
+	for i, tag := range tags {
+		result[i] = imageTag{Tag: tag}
+	}
+	sem := make(chan struct{}, registryInspectConcurrency)
+	wg := sync.WaitGroup{}
+	for i, tag := range tags[:min(len(tags), registryInspectMaxTags)] {
+		wg.Add(1)
+		go func(i int, tag string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			tagged, err := reference.WithTag(named, tag)
+			if err != nil {
+				return
+			}
+			if t, err := inspectImageTag(ctx, sys, tagged); err == nil {
+				result[i] = t
+			}
+		}(i, tag)
+	}
+	wg.Wait()
+
+	sortImageTags(result)
+	return result, nil
+}
+
+// sortTagNames orders tag names before they are inspected: release tags from
+// the highest version, followed by other tags in reverse registry order, which
+// is usually the order they were pushed in.
+func sortTagNames(tags []string) {
+	slices.Reverse(tags)
+	sort.SliceStable(tags, func(i, j int) bool {
+		a, b := tagVersion(tags[i]), tagVersion(tags[j])
+		if (a == "") != (b == "") {
+			return a != ""
+		}
+		return semver.Compare(a, b) > 0
+	})
+}
+
+// tagVersion returns semantic version of release tag, empty when tag is not
+// a release tag
+func tagVersion(tag string) string {
+	v := tag
+	if !strings.HasPrefix(v, "v") {
+		v = "v" + v
+	}
+	if !releaseTagPattern.MatchString(tag) || !semver.IsValid(v) {
+		return ""
+	}
+	return v
+}
+
+// sortImageTags orders tags from the newest to the oldest, tags without
+// creation date come last in their current order
+func sortImageTags(tags []imageTag) {
+	sort.SliceStable(tags, func(i, j int) bool {
+		a, b := tags[i], tags[j]
+		if a.Created.IsZero() || b.Created.IsZero() {
+			return !a.Created.IsZero() && b.Created.IsZero()
+		}
+		if !a.Created.Equal(b.Created) {
+			return a.Created.After(b.Created)
+		}
+		return a.Tag > b.Tag
+	})
+}
+
+// registryManifest contains fields of image manifests, manifest lists and
+// OCI indexes which are needed to find image config
+type registryManifest struct {
+	MediaType string `json:"mediaType"`
+	Config    struct {
+		Digest digest.Digest `json:"digest"`
+		Size   int64         `json:"size"`
+	} `json:"config"`
+	Manifests []struct {
+		Digest   digest.Digest `json:"digest"`
+		Platform struct {
+			Architecture string `json:"architecture"`
+			OS           string `json:"os"`
+		} `json:"platform"`
+	} `json:"manifests"`
+	Annotations map[string]string `json:"annotations"`
+}
+
+// isIndex reports whether manifest of mimeType lists platform manifests
+func (m registryManifest) isIndex(mimeType string) bool {
+	for _, t := range []string{mimeType, m.MediaType} {
+		if t == ociImageIndexMediaType || t == manifest.DockerV2ListMediaType {
+			return true
+		}
+	}
+	return len(m.Manifests) > 0 && m.Config.Digest == ""
+}
+
+// platformManifest returns digest of linux/amd64 manifest of index, or of
+// the first manifest which is not an attestation
+func (m registryManifest) platformManifest() (digest.Digest, bool) {
+	for _, p := range m.Manifests {
+		if p.Platform.OS == "linux" && p.Platform.Architecture == "amd64" {
+			return p.Digest, true
+		}
+	}
+	for _, p := range m.Manifests {
+		if p.Platform.OS != "unknown" {
+			return p.Digest, true
+		}
+	}
+	return "", false
+}
+
+// registryImageConfig contains fields of image config blob
+type registryImageConfig struct {
+	Created time.Time `json:"created"`
+	Config  struct {
+		Labels map[string]string `json:"Labels"`
+	} `json:"config"`
+}
+
+// inspectImageTag resolves digest of tagged image and reads its creation
+// date and source labels from image config
+func inspectImageTag(ctx context.Context, sys *types.SystemContext, tagged reference.NamedTagged) (imageTag, error) {
+	result := imageTag{Tag: tagged.Tag()}
+	ref, err := docker.NewReference(tagged)
+	if err != nil {
+		return result, err
+	}
+	src, err := ref.NewImageSource(ctx, sys)
+	if err != nil {
+		return result, err
+	}
+	defer src.Close()
+
+	blob, mimeType, err := src.GetManifest(ctx, nil)
+	if err != nil {
+		return result, fmt.Errorf("fetching manifest: %w", err)
+	}
+	dgst, err := manifest.Digest(blob)
+	if err != nil {
+		return result, err
+	}
+	result.Digest = dgst.String()
+
+	m := registryManifest{}
+	if err := json.Unmarshal(blob, &m); err != nil {
+		return result, fmt.Errorf("parsing manifest: %w", err)
+	}
+	annotations := m.Annotations
+	if m.isIndex(mimeType) {
+		instance, ok := m.platformManifest()
+		if !ok {
+			return result, fmt.Errorf("index of %s has no platform manifests", tagged)
+		}
+		blob, _, err = src.GetManifest(ctx, &instance)
+		if err != nil {
+			return result, fmt.Errorf("fetching platform manifest: %w", err)
+		}
+		m = registryManifest{}
+		if err := json.Unmarshal(blob, &m); err != nil {
+			return result, fmt.Errorf("parsing platform manifest: %w", err)
+		}
+	}
+	if m.Config.Digest == "" {
+		return result, fmt.Errorf("manifest of %s has no config", tagged)
+	}
+
+	rc, _, err := src.GetBlob(ctx, types.BlobInfo{Digest: m.Config.Digest, Size: m.Config.Size}, none.NoCache)
+	if err != nil {
+		return result, fmt.Errorf("fetching image config: %w", err)
+	}
+	defer rc.Close()
+	configBlob, err := io.ReadAll(rc)
+	if err != nil {
+		return result, err
+	}
+	cfg := registryImageConfig{}
+	if err := json.Unmarshal(configBlob, &cfg); err != nil {
+		return result, fmt.Errorf("parsing image config: %w", err)
+	}
+
+	labels := cfg.Config.Labels
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	// Index annotations take precedence over labels of platform image
+	for k, v := range annotations {
+		labels[k] = v
+	}
+	result.Created = cfg.Created
+	if created, err := time.Parse(time.RFC3339, labels[ociCreatedLabel]); err == nil {
+		result.Created = created
+	}
+	result.ReleaseNotes = imageReleaseNotes(labels[ociSourceLabel], labels[ociRevisionLabel], result.Tag)
+	return result, nil
+}
+
+// Tags which are published as github releases, e.g. v1.2.3 or 0.4.1-rc.1
+var releaseTagPattern = regexp.MustCompile(`^v?[0-9]+\.[0-9]+(\.[0-9]+)?([-+.][0-9A-Za-z.-]+)?$`)
+
+// imageReleaseNotes returns link to release notes of tag on github, link to
+// commit of revision or source repository otherwise
+func imageReleaseNotes(source, revision, tag string) string {
+	source = strings.TrimSuffix(strings.TrimSuffix(source, "/"), ".git")
+	u, err := url.Parse(source)
+	if source == "" || err != nil || u.Host == "" {
+		return ""
+	}
+	if u.Host != "github.com" {
+		return source
+	}
+	if releaseTagPattern.MatchString(tag) {
+		return source + "/releases/tag/" + tag
+	}
+	if revision != "" {
+		return source + "/commit/" + revision
+	}
+	return source
+}
+
+// resolveImageDigest returns digest which image reference resolves to in its
+// registry. Digest of references pinned by digest is returned as is.
+func resolveImageDigest(ctx context.Context, sys *types.SystemContext, img string) (string, error) {
+	named, err := reference.ParseNormalizedNamed(img)
+	if err != nil {
+		return "", err
+	}
+	if canonical, ok := named.(reference.Canonical); ok {
+		return canonical.Digest().String(), nil
+	}
+	ref, err := docker.NewReference(reference.TagNameOnly(named))
+	if err != nil {
+		return "", err
+	}
+	src, err := ref.NewImageSource(ctx, sys)
+	if err != nil {
+		return "", err
+	}
+	defer src.Close()
+
+	blob, _, err := src.GetManifest(ctx, nil)
+	if err != nil {
+		return "", fmt.Errorf("fetching manifest: %w", err)
+	}
+	dgst, err := manifest.Digest(blob)
+	if err != nil {
+		return "", err
+	}
+	return dgst.String(), nil
+}
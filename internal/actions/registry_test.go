@@ -0,0 +1,263 @@
+package actions
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/containers/image/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeRegistry serves image manifests and blobs of a single repository with
+// bearer token auth like ghcr.io
+type fakeRegistry struct {
+	repo string
+	// Credentials required by token endpoint, anonymous when empty
+	username, password string
+	// Manifests by tag or digest and their media types
+	manifests map[string][]byte
+	mimeTypes map[string]string
+	blobs     map[string][]byte
+	tags      []string
+}
+
+func newFakeRegistry(repo string) *fakeRegistry {
+	return &fakeRegistry{
+		repo:      repo,
+		manifests: map[string][]byte{},
+		mimeTypes: map[string]string{},
+		blobs:     map[string][]byte{},
+	}
+}
+
+func fakeDigest(b []byte) string {
+	return fmt.Sprintf("sha256:%x", sha256.Sum256(b))
+}
+
+func (r *fakeRegistry) addBlob(v any) (string, int) {
+	b, _ := json.Marshal(v)
+	d := fakeDigest(b)
+	r.blobs[d] = b
+	return d, len(b)
+}
+
+func (r *fakeRegistry) addManifest(tag, mimeType string, v any) string {
+	b, _ := json.Marshal(v)
+	d := fakeDigest(b)
+	for _, ref := range []string{tag, d} {
+		if ref != "" {
+			r.manifests[ref] = b
+			r.mimeTypes[ref] = mimeType
+		}
+	}
+	if tag != "" {
+		r.tags = append(r.tags, tag)
+	}
+	return d
+}
+
+// addImage adds docker v2 image with config of created and labels
+func (r *fakeRegistry) addImage(tag string, created string, labels map[string]string) string {
+	configDigest, size := r.addBlob(map[string]any{
+		"created":      created,
+		"architecture": "amd64",
+		"os":           "linux",
+		"config":       map[string]any{"Labels": labels},
+	})
+	return r.addManifest(tag, "application/vnd.docker.distribution.manifest.v2+json", map[string]any{
+		"schemaVersion": 2,
+		"mediaType":     "application/vnd.docker.distribution.manifest.v2+json",
+		"config": map[string]any{
+			"mediaType": "application/vnd.docker.container.image.v1+json",
+			"digest":    configDigest,
+			"size":      size,
+		},
+		"layers": []any{},
+	})
+}
+
+func (r *fakeRegistry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.URL.Path == "/token" {
+		if r.username != "" {
+			u, p, ok := req.BasicAuth()
+			if !ok || u != r.username || p != r.password {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+		}
+		json.NewEncoder(w).Encode(map[string]string{"token": "fake-token"})
+		return
+	}
+	if req.Header.Get("Authorization") != "Bearer fake-token" {
+		w.Header().Set("WWW-Authenticate", fmt.Sprintf(
+			`Bearer realm="https://%s/token",service="fake",scope="repository:%s:pull"`, req.Host, r.repo,
+		))
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	prefix := "/v2/" + r.repo + "/"
+	switch {
+	case req.URL.Path == "/v2/":
+		w.WriteHeader(http.StatusOK)
+	case req.URL.Path == prefix+"tags/list":
+		json.NewEncoder(w).Encode(map[string]any{"name": r.repo, "tags": r.tags})
+	case strings.HasPrefix(req.URL.Path, prefix+"manifests/"):
+		ref := strings.TrimPrefix(req.URL.Path, prefix+"manifests/")
+		b, ok := r.manifests[ref]
+		if !ok || !strings.Contains(strings.Join(req.Header.Values("Accept"), ","), r.mimeTypes[ref]) {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", r.mimeTypes[ref])
+		w.Header().Set("Docker-Content-Digest", fakeDigest(b))
+		w.Write(b)
+	case strings.HasPrefix(req.URL.Path, prefix+"blobs/"):
+		b, ok := r.blobs[strings.TrimPrefix(req.URL.Path, prefix+"blobs/")]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write(b)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// fakeRegistrySystemContext does not use credentials of the host
+func fakeRegistrySystemContext(t *testing.T) *types.SystemContext {
+	t.Setenv("HOME", t.TempDir())
+	return &types.SystemContext{
+		AuthFilePath:                filepath.Join(t.TempDir(), "auth.json"),
+		DockerInsecureSkipTLSVerify: types.OptionalBoolTrue,
+	}
+}
+
+func TestListImageTags(t *testing.T) {
+	registry := newFakeRegistry("d8-x/app")
+	source := "https://github.com/D8-X/app"
+
+	// Multi platform release image
+	amd64 := registry.addImage("", "2024-03-01T10:00:00Z", map[string]string{ociSourceLabel: source})
+	arm64 := registry.addImage("", "2024-03-01T10:05:00Z", nil)
+	releaseDigest := registry.addManifest("v1.2.0", ociImageIndexMediaType, map[string]any{
+		"schemaVersion": 2,
+		"mediaType":     ociImageIndexMediaType,
+		"manifests": []any{
+			map[string]any{"digest": arm64, "platform": map[string]string{"architecture": "arm64", "os": "linux"}},
+			map[string]any{"digest": amd64, "platform": map[string]string{"architecture": "amd64", "os": "linux"}},
+		},
+	})
+	mainDigest := registry.addImage("main", "2024-04-01T10:00:00Z", map[string]string{
+		ociSourceLabel:   source,
+		ociRevisionLabel: "abc123",
+	})
+	registry.addImage("sha256-"+strings.TrimPrefix(mainDigest, "sha256:")+".sig", "2024-04-01T10:00:00Z", nil)
+	// Tag which can not be inspected
+	registry.tags = append(registry.tags, "broken")
+
+	server := httptest.NewTLSServer(registry)
+	defer server.Close()
+	img := strings.TrimPrefix(server.URL, "https://") + "/d8-x/app:main"
+
+	tags, err := listImageTags(context.Background(), fakeRegistrySystemContext(t), img)
+	require.NoError(t, err)
+
+	assert.Equal(t, []imageTag{
+		{
+			Tag:          "main",
+			Digest:       mainDigest,
+			Created:      time.Date(2024, 4, 1, 10, 0, 0, 0, time.UTC),
+			ReleaseNotes: source + "/commit/abc123",
+		},
+		{
+			Tag:          "v1.2.0",
+			Digest:       releaseDigest,
+			Created:      time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC),
+			ReleaseNotes: source + "/releases/tag/v1.2.0",
+		},
+		{Tag: "broken"},
+	}, tags)
+
+	repo := strings.TrimSuffix(img, ":main")
+	assert.Equal(t, repo+":v1.2.0@"+releaseDigest, tags[1].Reference(img))
+	assert.Equal(t,
+		repo+":v1.2.0@"+releaseDigest+" (created 2024-03-01, "+source+"/releases/tag/v1.2.0)",
+		tags[1].Label(img),
+	)
+	assert.Equal(t, repo+":broken", tags[2].Label(img))
+}
+
+func TestListImageTagsPrivateRegistry(t *testing.T) {
+	registry := newFakeRegistry("d8-x/private")
+	registry.username, registry.password = "user", "secret"
+	digest := registry.addImage("main", "2024-04-01T10:00:00Z", nil)
+
+	server := httptest.NewTLSServer(registry)
+	defer server.Close()
+	img := strings.TrimPrefix(server.URL, "https://") + "/d8-x/private"
+
+	sys := fakeRegistrySystemContext(t)
+	_, err := listImageTags(context.Background(), sys, img)
+	assert.Error(t, err)
+
+	sys.DockerAuthConfig = &types.DockerAuthConfig{Username: "user", Password: "secret"}
+	tags, err := listImageTags(context.Background(), sys, img)
+	require.NoError(t, err)
+	assert.Equal(t, []imageTag{
+		{Tag: "main", Digest: digest, Created: time.Date(2024, 4, 1, 10, 0, 0, 0, time.UTC)},
+	}, tags)
+}
+
+func TestImageReleaseNotes(t *testing.T) {
+	tests := []struct {
+		source, revision, tag string
+		want                  string
+	}{
+		{"https://github.com/D8-X/app", "abc", "v1.0.0", "https://github.com/D8-X/app/releases/tag/v1.0.0"},
+		{"https://github.com/D8-X/app.git", "abc", "0.4.1-rc.1", "https://github.com/D8-X/app/releases/tag/0.4.1-rc.1"},
+		{"https://github.com/D8-X/app", "abc", "main", "https://github.com/D8-X/app/commit/abc"},
+		{"https://github.com/D8-X/app", "", "dev", "https://github.com/D8-X/app"},
+		{"https://gitlab.com/d8x/app", "abc", "v1.0.0", "https://gitlab.com/d8x/app"},
+		{"", "abc", "v1.0.0", ""},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, imageReleaseNotes(tt.source, tt.revision, tt.tag))
+	}
+}
+
+func TestSortTagNames(t *testing.T) {
+	tags := []string{"v1.9.0", "main", "v1.10.0", "sha-abc", "0.9.1", "v1.10.0-rc.1", "dev"}
+	sortTagNames(tags)
+	assert.Equal(t, []string{"v1.10.0", "v1.10.0-rc.1", "v1.9.0", "0.9.1", "dev", "sha-abc", "main"}, tags)
+}
+
+func TestListImageTagsInspectsNewestTags(t *testing.T) {
+	registry := newFakeRegistry("d8-x/app")
+	for i := 0; i < registryInspectMaxTags+2; i++ {
+		registry.addImage(fmt.Sprintf("v1.0.%d", i), "2024-03-01T10:00:00Z", nil)
+	}
+
+	server := httptest.NewTLSServer(registry)
+	defer server.Close()
+	img := strings.TrimPrefix(server.URL, "https://") + "/d8-x/app"
+
+	tags, err := listImageTags(context.Background(), fakeRegistrySystemContext(t), img)
+	require.NoError(t, err)
+	require.Len(t, tags, registryInspectMaxTags+2)
+
+	// Oldest versions are listed without details, after the inspected tags
+	assert.Equal(t, []imageTag{{Tag: "v1.0.1"}, {Tag: "v1.0.0"}}, tags[registryInspectMaxTags:])
+	for _, tag := range tags[:registryInspectMaxTags] {
+		assert.NotEmpty(t, tag.Digest, tag.Tag)
+	}
+}
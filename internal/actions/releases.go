@@ -0,0 +1,509 @@
+package actions
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/D8-X/d8x-cli/internal/components"
+	"github.com/D8-X/d8x-cli/internal/configs"
+	"github.com/D8-X/d8x-cli/internal/conn"
+	"github.com/D8-X/d8x-cli/internal/styles"
+	"github.com/urfave/cli/v2"
+)
+
+// releaseFile is a file which is deployed to the server
+type releaseFile struct {
+	local  string
+	remote string
+	// Optional files are not required to exist locally
+	optional bool
+}
+
+// releaseTarget describes what is recorded in release ledger for a deployment
+// target
+type releaseTarget struct {
+	name  string
+	files []releaseFile
+	// Remote file path -> config name
+	configs map[string]string
+	// Remote paths of stack (compose) and .env files
+	stackFile string
+	envFile   string
+	// Command which outputs <service>[##]<image with digest> lines
+	imagesCmd string
+	// Prefix of service names in imagesCmd output
+	svcPrefix string
+}
+
+func swarmReleaseTarget() releaseTarget {
+	t := releaseTarget{
+		name: configs.ReleaseTargetSwarm,
+		files: []releaseFile{
+			{local: "./trader-backend/.env", remote: "./trader-backend/.env"},
+			// Note we are renaming to docker-stack.yml on remote!
+			{local: "./docker-swarm-stack.yml", remote: "./docker-stack.yml"},
+		},
+		configs:   map[string]string{},
+		stackFile: "./docker-stack.yml",
+		envFile:   "./trader-backend/.env",
+		imagesCmd: fmt.Sprintf(
+			`docker service ls -q --filter label=com.docker.stack.namespace=%s | xargs -r docker service inspect --format '{{.Spec.Name}}[##]{{.Spec.TaskTemplate.ContainerSpec.Image}}'`,
+			dockerStackName,
+		),
+		svcPrefix: dockerStackName + "_",
+	}
+	for _, dc := range swarmDockerConfigs {
+		t.files = append(t.files, releaseFile{local: dc.file, remote: dc.file})
+		t.configs[dc.file] = dc.name
+	}
+	return t
+}
+
+func brokerReleaseTarget() releaseTarget {
+	return releaseTarget{
+		name: configs.ReleaseTargetBroker,
+		files: []releaseFile{
+			{local: brokerDeployChainConfig, remote: "./broker/chainConfig.json"},
+			{local: brokerDeployRpcConfig, remote: "./broker/rpc.json"},
+			{local: brokerDeployDockerCompose, remote: "./broker/docker-compose.yml"},
+			{local: brokerEnvFile, remote: "./broker/.env", optional: true},
+		},
+		configs: map[string]string{
+			"./broker/chainConfig.json": "chainConfig.json",
+			"./broker/rpc.json":         "rpc.json",
+		},
+		stackFile: "./broker/docker-compose.yml",
+		envFile:   "./broker/.env",
+		// Images without repo digest (built or loaded locally) are recorded
+		// by the reference container was created from, local image id can
+		// not be pulled on redeploy
+		imagesCmd: `cd ./broker && docker compose ps -q | while read -r id; do ` +
+			`svc=$(docker inspect --format '{{index .Config.Labels "com.docker.compose.service"}}' "$id"); ` +
+			`img=$(docker inspect --format '{{.Image}}' "$id"); ` +
+			`ref=$(docker inspect --format '{{.Config.Image}}' "$id"); ` +
+			`digest=$(docker image inspect --format '{{if .RepoDigests}}{{index .RepoDigests 0}}{{end}}' "$img"); ` +
+			`echo "$svc[##]${digest:-$ref}"; done`,
+	}
+}
+
+// releaseLedger returns the release ledger stored in config directory
+func (c *Container) releaseLedger() *configs.ReleaseLedger {
+	return configs.NewReleaseLedger(filepath.Join(c.ConfigDir, configs.DEFAULT_RELEASES_DIR_NAME))
+}
+
+// parseServiceImages parses <service>[##]<image> lines and strips svcPrefix
+// from service names
+func parseServiceImages(out []byte, svcPrefix string) map[string]string {
+	images := map[string]string{}
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := strings.Split(strings.TrimSpace(line), "[##]")
+		if len(fields) != 2 || fields[0] == "" {
+			continue
+		}
+		images[strings.TrimPrefix(fields[0], svcPrefix)] = fields[1]
+	}
+	return images
+}
+
+// recordRelease stores what is currently live on target in release ledger.
+// Failure to record the release does not fail the deployment, error is only
+// printed.
+func (c *Container) recordRelease(sshConn conn.SSHConnection, t releaseTarget, source string, rollbackOf int) {
+	// Nothing was deployed in dry run
+	if c.DryRun != nil {
+		return
+	}
+
+	r, err := c.addRelease(sshConn, t, source, rollbackOf)
+	if err != nil {
+		fmt.Println(styles.ErrorText.Render(fmt.Sprintf("Could not record %s release: %v", t.name, err)))
+		return
+	}
+	fmt.Printf("Recorded %s release #%d\n", t.name, r.Id)
+}
+
+func (c *Container) addRelease(sshConn conn.SSHConnection, t releaseTarget, source string, rollbackOf int) (*configs.Release, error) {
+	out, err := sshConn.ExecCommand(t.imagesCmd)
+	if err != nil {
+		return nil, fmt.Errorf("retrieving deployed images: %w", err)
+	}
+
+	r := configs.Release{
+		Source:     source,
+		Target:     t.name,
+		RollbackOf: rollbackOf,
+		Images:     parseServiceImages(out, t.svcPrefix),
+		Configs:    map[string]string{},
+	}
+
+	cfg, err := c.ConfigRWriter.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	// Snapshot files as they were uploaded to the server, local files might
+	// have been edited after the deployment
+	files := map[string][]byte{}
+	for _, f := range t.files {
+		if f.optional {
+			if _, err := sshConn.ExecCommand("test -f " + f.remote); err != nil {
+				continue
+			}
+		}
+		contents, err := sshConn.ExecCommand("cat " + f.remote)
+		if err != nil {
+			return nil, fmt.Errorf("reading deployed %s: %w", f.remote, err)
+		}
+		// Secrets stay in the secrets vault, snapshot only references them
+		files[f.remote] = cfg.MaskSecrets(contents)
+
+		hash := configs.HashContents(contents)
+		if name, ok := t.configs[f.remote]; ok {
+			r.Configs[name] = hash
+		}
+		switch f.remote {
+		case t.stackFile:
+			r.StackFile = hash
+		case t.envFile:
+			r.Env = hash
+		}
+	}
+
+	return c.releaseLedger().Add(r, files)
+}
+
+// releaseIdArg parses the release id from n-th argument
+func releaseIdArg(ctx *cli.Context, n int) (int, error) {
+	arg := ctx.Args().Get(n)
+	if arg == "" {
+		return 0, fmt.Errorf("release id must be provided, see d8x releases list")
+	}
+	id, err := strconv.Atoi(strings.TrimPrefix(arg, "#"))
+	if err != nil {
+		return 0, fmt.Errorf("invalid release id %s", arg)
+	}
+	return id, nil
+}
+
+// ReleasesList prints all recorded releases
+func (c *Container) ReleasesList(ctx *cli.Context) error {
+	releases, err := c.releaseLedger().List()
+	if err != nil {
+		return err
+	}
+	if len(releases) == 0 {
+		fmt.Println("No releases were recorded yet")
+		return nil
+	}
+
+	fmt.Printf("%-5s %-20s %-7s %s\n", "ID", "CREATED", "TARGET", "SOURCE")
+	for _, r := range releases {
+		source := r.Source
+		if r.RollbackOf != 0 {
+			source = fmt.Sprintf("%s of #%d", source, r.RollbackOf)
+		}
+		fmt.Printf("%-5d %-20s %-7s %s\n", r.Id, r.CreatedAt.Local().Format("2006-01-02 15:04:05"), r.Target, source)
+	}
+
+	return nil
+}
+
+// ReleasesShow prints the details of a single release
+func (c *Container) ReleasesShow(ctx *cli.Context) error {
+	id, err := releaseIdArg(ctx, 0)
+	if err != nil {
+		return err
+	}
+	r, err := c.releaseLedger().Get(id)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Release #%d\n", r.Id)
+	fmt.Printf("Created:    %s\n", r.CreatedAt.Local().Format("2006-01-02 15:04:05"))
+	fmt.Printf("Target:     %s\n", r.Target)
+	fmt.Printf("Source:     %s\n", r.Source)
+	if r.RollbackOf != 0 {
+		fmt.Printf("Rollback of: #%d\n", r.RollbackOf)
+	}
+	fmt.Printf("Stack file: %s\n", r.StackFile)
+	if r.Env != "" {
+		fmt.Printf(".env:       %s\n", r.Env)
+	}
+	fmt.Println("Images:")
+	for _, k := range sortedKeys(r.Images) {
+		fmt.Printf("  %s: %s\n", k, r.Images[k])
+	}
+	fmt.Println("Configs:")
+	for _, k := range sortedKeys(r.Configs) {
+		fmt.Printf("  %s: %s\n", k, r.Configs[k])
+	}
+
+	return nil
+}
+
+// ReleasesDiff prints the differences between two releases
+func (c *Container) ReleasesDiff(ctx *cli.Context) error {
+	idA, err := releaseIdArg(ctx, 0)
+	if err != nil {
+		return err
+	}
+	idB, err := releaseIdArg(ctx, 1)
+	if err != nil {
+		return err
+	}
+	ledger := c.releaseLedger()
+	a, err := ledger.Get(idA)
+	if err != nil {
+		return err
+	}
+	b, err := ledger.Get(idB)
+	if err != nil {
+		return err
+	}
+
+	diff := diffReleases(a, b)
+	if len(diff) == 0 {
+		fmt.Printf("Releases #%d and #%d are identical\n", a.Id, b.Id)
+		return nil
+	}
+	fmt.Printf("Differences between release #%d and #%d:\n", a.Id, b.Id)
+	for _, line := range diff {
+		fmt.Println(line)
+	}
+
+	return nil
+}
+
+// diffReleases returns human readable differences between release a and b
+func diffReleases(a, b *configs.Release) []string {
+	diff := []string{}
+	if a.Target != b.Target {
+		diff = append(diff, fmt.Sprintf("target: %s -> %s", a.Target, b.Target))
+	}
+	diff = append(diff, diffMaps("image", a.Images, b.Images)...)
+	diff = append(diff, diffMaps("config", a.Configs, b.Configs)...)
+	if a.StackFile != b.StackFile {
+		diff = append(diff, "stack file: changed")
+	}
+	if a.Env != b.Env {
+		diff = append(diff, ".env: changed")
+	}
+	return diff
+}
+
+func diffMaps(kind string, a, b map[string]string) []string {
+	keys := map[string]string{}
+	for k := range a {
+		keys[k] = ""
+	}
+	for k := range b {
+		keys[k] = ""
+	}
+
+	diff := []string{}
+	for _, k := range sortedKeys(keys) {
+		va, okA := a[k]
+		vb, okB := b[k]
+		switch {
+		case !okA:
+			diff = append(diff, fmt.Sprintf("%s %s: added %s", kind, k, vb))
+		case !okB:
+			diff = append(diff, fmt.Sprintf("%s %s: removed", kind, k))
+		case va != vb:
+			diff = append(diff, fmt.Sprintf("%s %s: %s -> %s", kind, k, va, vb))
+		}
+	}
+	return diff
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Rollback redeploys files and image digests of a previously recorded release
+func (c *Container) Rollback(ctx *cli.Context) error {
+	styles.PrintCommandTitle("Rolling back to previous release...")
+
+	id, err := releaseIdArg(ctx, 0)
+	if err != nil {
+		return err
+	}
+	r, err := c.releaseLedger().Get(id)
+	if err != nil {
+		return err
+	}
+
+	ok, err := c.TUI.NewPrompt(
+		fmt.Sprintf("Do you want to redeploy %s release #%d created at %s?", r.Target, r.Id, r.CreatedAt.Local().Format("2006-01-02 15:04:05")),
+		true,
+		components.PromptOptId("rollback.confirm"),
+	)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return nil
+	}
+
+	switch r.Target {
+	case configs.ReleaseTargetSwarm:
+		return c.rollbackSwarm(ctx, r)
+	case configs.ReleaseTargetBroker:
+		return c.rollbackBroker(r)
+	}
+	return fmt.Errorf("unknown release target %s", r.Target)
+}
+
+// uploadReleaseFiles copies the snapshot of release files to the server. Stack
+// file is uploaded with images pinned to the release digests.
+func (c *Container) uploadReleaseFiles(sshConn conn.SSHConnection, t releaseTarget, r *configs.Release) error {
+	tmpDir, err := os.MkdirTemp("", "d8x-release-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	cfg, err := c.ConfigRWriter.Read()
+	if err != nil {
+		return err
+	}
+
+	ledger := c.releaseLedger()
+	copyList := []conn.SftpCopySrcDest{}
+	for i, file := range r.Files {
+		contents, err := ledger.ReadFile(r.Id, file)
+		if err != nil {
+			return fmt.Errorf("reading release snapshot of %s: %w", file, err)
+		}
+		contents, err = cfg.UnmaskSecrets(contents)
+		if err != nil {
+			return fmt.Errorf("restoring secrets of %s: %w", file, err)
+		}
+		if file == t.stackFile {
+			contents, err = configs.PinServiceImages(contents, r.Images)
+			if err != nil {
+				return fmt.Errorf("pinning images in %s: %w", file, err)
+			}
+		}
+
+		src := filepath.Join(tmpDir, strconv.Itoa(i))
+		if err := os.WriteFile(src, contents, 0600); err != nil {
+			return err
+		}
+		copyList = append(copyList, conn.SftpCopySrcDest{Src: src, Dst: file})
+	}
+
+	return sshConn.CopyFilesOverSftp(copyList...)
+}
+
+func (c *Container) rollbackSwarm(ctx *cli.Context, r *configs.Release) error {
+	t := swarmReleaseTarget()
+
+	managerIp, err := c.HostsCfg.GetMangerPublicIp()
+	if err != nil {
+		return err
+	}
+	pwd, err := c.GetPassword(ctx)
+	if err != nil {
+		return err
+	}
+	managerSSHConn, err := c.CreateSSHConn(managerIp, c.DefaultClusterUserName, c.SshKeyPath)
+	if err != nil {
+		return err
+	}
+
+	latest, err := c.releaseLedger().Latest(t.name)
+	if err != nil {
+		return err
+	}
+	configsChanged := latest == nil || len(diffMaps("config", latest.Configs, r.Configs)) > 0
+
+	fmt.Println(styles.ItalicText.Render("Copying release files to manager node " + managerIp))
+	if err := c.uploadReleaseFiles(managerSSHConn, t, r); err != nil {
+		return fmt.Errorf("copying release files to manager: %w", err)
+	}
+
+	// Docker configs can not be replaced while they are used by services
+	if configsChanged {
+		fmt.Println(styles.ItalicText.Render("Docker configs differ, removing existing stack..."))
+		out, err := managerSSHConn.ExecCommand(
+			fmt.Sprintf(
+				`docker stack rm %[1]s; until [ -z "$(docker service ls -q --filter label=com.docker.stack.namespace=%[1]s)" ]; do sleep 1; done`,
+				dockerStackName,
+			),
+		)
+		if err != nil {
+			fmt.Println(string(out))
+			return fmt.Errorf("removing existing stack: %w", err)
+		}
+
+		fmt.Println(styles.ItalicText.Render("Creating docker configs..."))
+		out, err = recreateSwarmDockerConfigs(managerSSHConn)
+		if err != nil {
+			fmt.Println(string(out))
+			return fmt.Errorf("creating docker configs: %w", err)
+		}
+	}
+
+	fmt.Println(styles.ItalicText.Render("Deploying docker swarm via manager node..."))
+	out, err := deploySwarmStack(managerSSHConn, pwd)
+	fmt.Println(string(out))
+	if err != nil {
+		return fmt.Errorf("swarm deployment failed: %w", err)
+	}
+	fmt.Println(styles.SuccessText.Render(fmt.Sprintf("Swarm was rolled back to release #%d", r.Id)))
+
+	c.recordRelease(managerSSHConn, t, "rollback", r.Id)
+
+	return nil
+}
+
+func (c *Container) rollbackBroker(r *configs.Release) error {
+	t := brokerReleaseTarget()
+
+	cfg, err := c.ConfigRWriter.Read()
+	if err != nil {
+		return err
+	}
+	brokerIp, err := c.HostsCfg.GetBrokerPublicIp()
+	if err != nil {
+		return err
+	}
+	sshConn, err := c.CreateSSHConn(brokerIp, c.DefaultClusterUserName, c.SshKeyPath)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(styles.ItalicText.Render("Copying release files to broker-server..."))
+	if err := c.uploadReleaseFiles(sshConn, t, r); err != nil {
+		return fmt.Errorf("copying release files to broker-server: %w", err)
+	}
+
+	fmt.Println(styles.ItalicText.Render("Starting docker compose on broker-server..."))
+	out, err := sshConn.ExecCommand(
+		fmt.Sprintf(
+			"cd ./broker && BROKER_FEE_TBPS=%s REDIS_PW=%s docker compose up -d",
+			cfg.BrokerServerConfig.FeeTBPS,
+			cfg.BrokerServerConfig.RedisPassword,
+		),
+	)
+	if err != nil {
+		fmt.Printf("%s\n\n%s", out, styles.ErrorText.Render("Something went wrong during broker-server rollback ^^^"))
+		return err
+	}
+	fmt.Println(styles.SuccessText.Render(fmt.Sprintf("Broker server was rolled back to release #%d", r.Id)))
+
+	c.recordRelease(sshConn, t, "rollback", r.Id)
+
+	return nil
+}
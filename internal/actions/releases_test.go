@@ -0,0 +1,49 @@
+package actions
+
+import (
+	"testing"
+
+	"github.com/D8-X/d8x-cli/internal/configs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseServiceImages(t *testing.T) {
+	out := []byte("stack_api[##]ghcr.io/d8-x/d8x-trader-main:main@sha256:aaa\n" +
+		"stack_history[##]ghcr.io/d8-x/d8x-trader-history:main@sha256:bbb\n\n")
+	assert.Equal(t,
+		map[string]string{
+			"api":     "ghcr.io/d8-x/d8x-trader-main:main@sha256:aaa",
+			"history": "ghcr.io/d8-x/d8x-trader-history:main@sha256:bbb",
+		},
+		parseServiceImages(out, "stack_"),
+	)
+}
+
+func TestDiffReleases(t *testing.T) {
+	a := &configs.Release{
+		Target:    configs.ReleaseTargetSwarm,
+		Images:    map[string]string{"api": "api@sha256:aaa", "history": "history@sha256:bbb"},
+		Configs:   map[string]string{"cfg_rpc": "sha256:111", "cfg_prices": "sha256:222"},
+		StackFile: "sha256:stack",
+		Env:       "sha256:env",
+	}
+	b := &configs.Release{
+		Target:    configs.ReleaseTargetSwarm,
+		Images:    map[string]string{"api": "api@sha256:ccc", "referral": "referral@sha256:ddd"},
+		Configs:   map[string]string{"cfg_rpc": "sha256:333", "cfg_prices": "sha256:222"},
+		StackFile: "sha256:stack",
+		Env:       "sha256:env2",
+	}
+
+	assert.Equal(t,
+		[]string{
+			"image api: api@sha256:aaa -> api@sha256:ccc",
+			"image history: removed",
+			"image referral: added referral@sha256:ddd",
+			"config cfg_rpc: sha256:111 -> sha256:333",
+			".env: changed",
+		},
+		diffReleases(a, b),
+	)
+	assert.Empty(t, diffReleases(a, a))
+}
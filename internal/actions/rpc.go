@@ -1,6 +1,7 @@
 package actions
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/url"
@@ -8,6 +9,7 @@ import (
 	"slices"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/D8-X/d8x-cli/internal/components"
 	"github.com/D8-X/d8x-cli/internal/configs"
@@ -69,6 +71,7 @@ func (c *InputCollector) RPCUrlCollector(protocol rpcTransport, chainId string,
 
 		fmt.Printf("Enter %s RPC url #%d for chain id %s\n", transportUpper, len(endpoints)+1, chainId)
 		endpoint, err := c.TUI.NewInput(
+			components.TextInputOptId("rpc."+string(protocol)+"."+chainId),
 			components.TextInputOptPlaceholder(protocol.SecureProtocolPrefix()+"your-rpc-provider.com"),
 			components.TextInputOptDenyEmpty(),
 			components.TextInputOptValue(val),
@@ -83,6 +86,9 @@ func (c *InputCollector) RPCUrlCollector(protocol rpcTransport, chainId string,
 		}
 		// Validate entered endpoint
 		if err := validate(endpoint); err != nil {
+			if err := components.RejectAnswer(c.TUI, "rpc."+string(protocol)+"."+chainId, err.Error()); err != nil {
+				return nil, err
+			}
 			fmt.Println(
 				styles.ErrorText.Render(
 					fmt.Sprintf("Invalid RPC url (%s), please try again...", err.Error()),
@@ -90,6 +96,14 @@ func (c *InputCollector) RPCUrlCollector(protocol rpcTransport, chainId string,
 			)
 			continue
 		}
+		if ok, err := c.verifyRpcUrl(protocol, chainId, endpoint); err != nil {
+			return nil, err
+		} else if !ok {
+			if err := components.RejectAnswer(c.TUI, "rpc."+string(protocol)+"."+chainId, "rpc url check failed"); err != nil {
+				return nil, err
+			}
+			continue
+		}
 		endpoints = append(endpoints, endpoint)
 		if len(endpoints) >= requireAtLeast {
 			recommendedText := "We recommend having at least " + strconv.Itoa(recommended) + " RPCs. "
@@ -99,6 +113,7 @@ func (c *InputCollector) RPCUrlCollector(protocol rpcTransport, chainId string,
 			ok, err := c.TUI.NewPrompt(
 				fmt.Sprintf("%sAdd another one?", recommendedText),
 				true,
+				components.PromptOptId("rpc."+string(protocol)+"."+chainId+".add_another"),
 			)
 			if err != nil {
 				return nil, err
@@ -111,6 +126,42 @@ func (c *InputCollector) RPCUrlCollector(protocol rpcTransport, chainId string,
 	return endpoints, nil
 }
 
+// Timeout of checking the rpc url entered in RPCUrlCollector
+const rpcCollectorProbeTimeout = 15 * time.Second
+
+// verifyRpcUrl probes the entered endpoint. Endpoints of another chain are
+// rejected, unreachable endpoints can be used when user confirms it.
+func (c *InputCollector) verifyRpcUrl(protocol rpcTransport, chainId, endpoint string) (bool, error) {
+	expectedChainId, err := strconv.ParseUint(chainId, 10, 64)
+	if err != nil {
+		return false, fmt.Errorf("invalid chain id %s: %w", chainId, err)
+	}
+
+	fmt.Printf("Checking %s...\n", endpoint)
+	probe := probeRpc(context.Background(), protocol, endpoint, rpcProbeOptions{
+		chainId: expectedChainId,
+		samples: 1,
+		timeout: rpcCollectorProbeTimeout,
+	})
+	if probe.Healthy() {
+		fmt.Println(styles.SuccessText.Render(
+			fmt.Sprintf("RPC is reachable (block %d, %.1fms)", probe.BlockNumber, probe.LatencyMs),
+		))
+		return true, nil
+	}
+	if probe.ChainId != 0 && probe.ChainId != expectedChainId {
+		fmt.Println(styles.ErrorText.Render(fmt.Sprintf("Invalid RPC url (%s), please try again...", probe.Error)))
+		return false, nil
+	}
+
+	fmt.Println(styles.ErrorText.Render(fmt.Sprintf("RPC check failed: %s", probe.Error)))
+	return c.TUI.NewPrompt(
+		"Do you want to use this RPC url anyway?",
+		false,
+		components.PromptOptId("rpc."+string(protocol)+"."+chainId+".use_unverified"),
+	)
+}
+
 // CollectHTTPRPCUrls collects http rpc urls and writes them into the config
 // file
 func (input *InputCollector) CollectHTTPRPCUrls(cfg *configs.D8XConfig, chainId string) error {
@@ -118,7 +169,7 @@ func (input *InputCollector) CollectHTTPRPCUrls(cfg *configs.D8XConfig, chainId
 	httpRpcs, exists := cfg.HttpRpcList[chainId]
 	if exists {
 		fmt.Printf("The following HTTP RPC urls were found: \n%s \n", strings.Join(httpRpcs, "\n"))
-		keep, err := input.TUI.NewPrompt("Do you want to keep these HTTP RPC urls?", true)
+		keep, err := input.TUI.NewPrompt("Do you want to keep these HTTP RPC urls?", true, components.PromptOptId("rpc.http."+chainId+".keep"))
 		if err != nil {
 			return err
 		}
@@ -144,7 +195,7 @@ func (c *InputCollector) CollectWebsocketRPCUrls(cfg *configs.D8XConfig, chainId
 	wspRpcs, exists := cfg.WsRpcList[chainId]
 	if exists {
 		fmt.Printf("The following Websocket RPC urls were found: \n%s \n", strings.Join(wspRpcs, "\n"))
-		keep, err := c.TUI.NewPrompt("Do you want to keep these Websocket RPC urls?", true)
+		keep, err := c.TUI.NewPrompt("Do you want to keep these Websocket RPC urls?", true, components.PromptOptId("rpc.ws."+chainId+".keep"))
 		if err != nil {
 			return err
 		}
@@ -262,6 +313,14 @@ type RPCConfigEntry struct {
 // omitted, however, when it is empty slice - it will be included as empty array
 // in json output.
 func (c *Container) editRpcConfigUrls(rpcConfigFilePath string, chainId uint, wsRpcs, httpRpcs []string) error {
+	return c.replaceRpcConfigUrls(rpcConfigFilePath, chainId, nil, wsRpcs, httpRpcs)
+}
+
+// replaceRpcConfigUrls works as editRpcConfigUrls, but urls in replaced are
+// removed from the chainId entry before wsRpcs and httpRpcs are added. This
+// lets previously distributed user rpcs be swapped while public rpc urls are
+// kept.
+func (c *Container) replaceRpcConfigUrls(rpcConfigFilePath string, chainId uint, replaced, wsRpcs, httpRpcs []string) error {
 	rpcCfg, err := os.ReadFile(rpcConfigFilePath)
 	if err != nil {
 		return err
@@ -271,6 +330,10 @@ func (c *Container) editRpcConfigUrls(rpcConfigFilePath string, chainId uint, ws
 		return err
 	}
 
+	isReplaced := func(s string) bool {
+		return slices.Contains(replaced, s)
+	}
+
 	// Find and replace our RPC config entry or create it if not found (for
 	// given chainId)
 	found := false
@@ -281,6 +344,11 @@ func (c *Container) editRpcConfigUrls(rpcConfigFilePath string, chainId uint, ws
 
 	for i, entry := range rpcConfig {
 		if entry.ChainId == chainId {
+			entry.HttpRpcs = slices.DeleteFunc(entry.HttpRpcs, isReplaced)
+			if entry.WsRpcs != nil {
+				*entry.WsRpcs = slices.DeleteFunc(*entry.WsRpcs, isReplaced)
+			}
+
 			// Append existing urls to our new entry
 			entry.HttpRpcs = slices.Compact(append(entry.HttpRpcs, newEntry.HttpRpcs...))
 
@@ -328,13 +396,14 @@ func (c *Container) editRpcConfigUrls(rpcConfigFilePath string, chainId uint, ws
 }
 
 // DistributeRpcs distribute rpc from cfg (user supplied rpcs) based on provided
-// serviceIndex. RPC distribution is done in a card dealing way (serviceIndex 0
-// gets 0, 0 + numServices, 0 + 2*numServices, etc.). We currently support 4
-// services which need rpcs: main, history, referral and broker-server
-// (optional) with serviceIndex values 0, 1,2 and 3 respectively. It is
-// suggested to have at least 4 Http rpcs added (3 without broker). Only
-// serviceIndex 0 and 1 gets websockets (main, history). Returned slices are
-// http and ws rpcs list.
+// serviceIndex. We currently support 4 services which need rpcs: main,
+// history, referral and broker-server (optional) with serviceIndex values 0, 1,
+// 2 and 3 respectively (see configs.RpcServices). Only serviceIndex 0 and 1
+// gets websockets (main, history). Rpcs are distributed according to the
+// strategy of each service in cfg.RpcDistribution, by default in a card
+// dealing way (serviceIndex 0 gets 0, 0 + numServices, 0 + 2*numServices,
+// etc.). It is suggested to have at least 4 Http rpcs added (3 without
+// broker). Returned slices are http and ws rpcs list.
 func DistributeRpcs(serviceIndex int, chainId string, cfg *configs.D8XConfig) ([]string, []string) {
 	// Maximum number of serviceIndex for http/ws lists
 	// main, history, referral
@@ -346,44 +415,8 @@ func DistributeRpcs(serviceIndex int, chainId string, cfg *configs.D8XConfig) ([
 		httpServices = 4
 	}
 
-	httpAvailable := len(cfg.HttpRpcList[chainId])
-	wsAvailable := len(cfg.WsRpcList[chainId])
-
-	distributionFunc := func(rpcsAvailable, rpcsServices int, rpcsList []string) []string {
-		returnList := []string{}
-
-		// Deny higher serviceIndex than supported via httpServices or
-		// wsServices.
-		if serviceIndex+1 > rpcsServices {
-			return returnList
-		}
-
-		// Special case - only single rpc available - always use it
-		if rpcsAvailable == 1 {
-			returnList = append(returnList, rpcsList[0])
-		} else if rpcsAvailable > 0 && rpcsAvailable < rpcsServices {
-			// Not enough http rpcs available - make sure serviceIndex 0 gets only
-			// 0th and others get everything else in sequence
-			rpcIndexToGet := serviceIndex
-			if serviceIndex > 0 {
-				// Start from 1st slice element and distribute in sequence one
-				// by one. Basically we cut the first element out of the
-				// equation.
-				rpcIndexToGet = 1 + (serviceIndex-1)%(rpcsAvailable-1)
-			}
-
-			returnList = []string{rpcsList[rpcIndexToGet]}
-		} else if rpcsAvailable >= rpcsServices {
-			for i := serviceIndex; i < rpcsAvailable; i += rpcsServices {
-				returnList = append(returnList, rpcsList[i])
-			}
-		}
-
-		return returnList
-	}
-
-	httpRpcs := distributionFunc(httpAvailable, httpServices, cfg.HttpRpcList[chainId])
-	wsRpcs := distributionFunc(wsAvailable, wsServices, cfg.WsRpcList[chainId])
+	httpRpcs := distributeRpcList(serviceIndex, httpServices, cfg.HttpRpcList[chainId], cfg.RpcDistribution)
+	wsRpcs := distributeRpcList(serviceIndex, wsServices, cfg.WsRpcList[chainId], cfg.RpcDistribution)
 
 	return httpRpcs, wsRpcs
 }
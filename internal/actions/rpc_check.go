@@ -0,0 +1,130 @@
+package actions
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+
+	"github.com/D8-X/d8x-cli/internal/styles"
+	"github.com/urfave/cli/v2"
+)
+
+// RpcCheck probes http and websocket rpc endpoints of d8x.conf.json and ranks
+// them by health, block lag and latency. With --apply the endpoints are
+// reordered by rank, so that DistributeRpcs hands out the best endpoints first.
+// Endpoints serving a different chain are removed.
+func (c *Container) RpcCheck(ctx *cli.Context) error {
+	format, err := outputFormat(ctx)
+	if err != nil {
+		return err
+	}
+	cfg, err := c.ConfigRWriter.Read()
+	if err != nil {
+		return err
+	}
+	if cfg.ChainId == 0 {
+		return fmt.Errorf("chain id is not set in %s, run d8x setup first", c.ConfigRWriter.GetPath())
+	}
+	chainId := strconv.Itoa(int(cfg.ChainId))
+	httpRpcs, wsRpcs := cfg.HttpRpcList[chainId], cfg.WsRpcList[chainId]
+	if len(httpRpcs)+len(wsRpcs) == 0 {
+		return fmt.Errorf("no rpc endpoints for chain %s were found in %s", chainId, c.ConfigRWriter.GetPath())
+	}
+
+	opts := rpcProbeOptions{
+		chainId: uint64(cfg.ChainId),
+		samples: ctx.Int("samples"),
+		timeout: ctx.Duration("timeout"),
+	}
+	probeCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	var results []RpcProbe
+	if format == OutputText {
+		stopSpinner := make(chan struct{})
+		go c.TUI.NewSpinner(stopSpinner, fmt.Sprintf("Probing %d rpc endpoints of chain %s", len(httpRpcs)+len(wsRpcs), chainId))
+		results = probeRpcs(probeCtx, httpRpcs, wsRpcs, opts)
+		stopSpinner <- struct{}{}
+		printRpcProbes(results)
+	} else {
+		results = probeRpcs(probeCtx, httpRpcs, wsRpcs, opts)
+		if err := printStructured(os.Stdout, format, results); err != nil {
+			return err
+		}
+	}
+
+	if ctx.Bool("apply") {
+		httpRanked, wsRanked, dropped, err := rankedRpcsToApply(results, opts.chainId, ctx.Bool("keep-unhealthy"))
+		if err != nil {
+			return err
+		}
+		cfg.HttpRpcList[chainId] = httpRanked
+		cfg.WsRpcList[chainId] = wsRanked
+		if err := c.ConfigRWriter.Write(cfg); err != nil {
+			return err
+		}
+		if format == OutputText {
+			for _, url := range dropped {
+				fmt.Println(styles.ItalicText.Render("Removed " + url + ", it serves a different chain"))
+			}
+			fmt.Println(styles.SuccessText.Render("RPC endpoints were reordered by rank, run swarm-deploy and broker-deploy to distribute them to services"))
+		}
+	}
+
+	failed := 0
+	for _, r := range results {
+		if !r.Healthy() {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d rpc endpoints failed the check", failed, len(results))
+	}
+	return nil
+}
+
+// rankedRpcsToApply returns ranked http and websocket urls which --apply
+// writes to d8x.conf.json and the dropped urls. Endpoints serving a different
+// chain are dropped. Other unhealthy endpoints are kept at the end of the lists
+// with keepUnhealthy, otherwise applying is refused.
+func rankedRpcsToApply(results []RpcProbe, chainId uint64, keepUnhealthy bool) ([]string, []string, []string, error) {
+	kept := []RpcProbe{}
+	dropped := []string{}
+	unhealthy := []string{}
+	for _, r := range results {
+		switch {
+		case r.Healthy():
+		case r.ChainId != 0 && r.ChainId != chainId:
+			dropped = append(dropped, r.Url)
+			continue
+		case !keepUnhealthy:
+			unhealthy = append(unhealthy, r.Url)
+		}
+		kept = append(kept, r)
+	}
+	if len(unhealthy) > 0 {
+		return nil, nil, nil, fmt.Errorf(
+			"rpc endpoints were not reordered, unhealthy endpoints %s must be removed with d8x rpc remove or kept at the end of the list with --keep-unhealthy",
+			strings.Join(unhealthy, ", "),
+		)
+	}
+	httpRpcs := rankedRpcUrls(kept, rpcTransportHTTP)
+	if len(httpRpcs) == 0 {
+		return nil, nil, nil, fmt.Errorf("rpc endpoints were not reordered, no http endpoint serves chain %d", chainId)
+	}
+	return httpRpcs, rankedRpcUrls(kept, rpcTransportWS), dropped, nil
+}
+
+func printRpcProbes(results []RpcProbe) {
+	fmt.Printf("%-4s %-4s %-10s %-12s %-5s %s\n", "RANK", "TYPE", "LATENCY", "BLOCK", "LAG", "URL")
+	for i, r := range results {
+		if !r.Healthy() {
+			fmt.Println(styles.ErrorText.Render(fmt.Sprintf("%-4d %-4s %s: %s", i+1, r.Transport, r.Url, r.Error)))
+			continue
+		}
+		fmt.Printf("%-4d %-4s %-10s %-12d %-5d %s\n", i+1, r.Transport, fmt.Sprintf("%.1fms", r.LatencyMs), r.BlockNumber, r.BlockLag, r.Url)
+	}
+}
@@ -0,0 +1,237 @@
+package actions
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/D8-X/d8x-cli/internal/configs"
+	"github.com/D8-X/d8x-cli/internal/styles"
+	"github.com/urfave/cli/v2"
+)
+
+// RpcDistribution prints rpcs which each service receives on swarm-deploy,
+// broker-deploy and rpc rotation
+func (c *Container) RpcDistribution(ctx *cli.Context) error {
+	format, err := outputFormat(ctx)
+	if err != nil {
+		return err
+	}
+	cfg, err := c.ConfigRWriter.Read()
+	if err != nil {
+		return err
+	}
+	if cfg.ChainId == 0 {
+		return fmt.Errorf("chain id is not set in %s, run d8x setup first", c.ConfigRWriter.GetPath())
+	}
+
+	distribution := rpcDistribution(cfg)
+	if format != OutputText {
+		return printStructured(os.Stdout, format, distribution)
+	}
+
+	for _, d := range distribution {
+		fmt.Println(styles.SuccessText.Render(d.Service) + styles.GrayText.Render(" ("+d.Strategy+")"))
+		fmt.Printf("  http: %s\n", strings.Join(d.Http, ", "))
+		if len(d.Ws) > 0 {
+			fmt.Printf("  ws:   %s\n", strings.Join(d.Ws, ", "))
+		}
+	}
+	if !cfg.BrokerDeployed {
+		fmt.Println(styles.ItalicText.Render("Broker server is not deployed, broker receives rpcs once it is deployed"))
+	}
+	return nil
+}
+
+// rpcDistribution returns rpcs of every service for chain of cfg
+func rpcDistribution(cfg *configs.D8XConfig) []RpcServiceDistribution {
+	chainId := strconv.Itoa(int(cfg.ChainId))
+	numServices := 3
+	if cfg.BrokerDeployed {
+		numServices = 4
+	}
+	result := []RpcServiceDistribution{}
+	for i, service := range configs.RpcServices[:numServices] {
+		httpRpcs, wsRpcs := DistributeRpcs(i, chainId, cfg)
+		result = append(result, RpcServiceDistribution{
+			Service:  service,
+			Strategy: cfg.RpcDistribution.Policy(service).Strategy,
+			Http:     httpRpcs,
+			Ws:       wsRpcs,
+		})
+	}
+	return result
+}
+
+// distributeRpcList returns rpcs of serviceIndex out of numServices services
+// which receive rpcs of the list. Urls pinned by any service are reserved for
+// pinned services, unless all rpcs are pinned. Every service receives at
+// least one rpc when rpcs is not empty.
+func distributeRpcList(serviceIndex, numServices int, rpcs []string, d *configs.D8XRpcDistribution) []string {
+	// Deny higher serviceIndex than supported via numServices
+	if serviceIndex+1 > numServices || len(rpcs) == 0 {
+		return []string{}
+	}
+
+	policies := make([]configs.D8XRpcServicePolicy, numServices)
+	reserved := []string{}
+	for i := range policies {
+		policies[i] = d.Policy(configs.RpcServices[i])
+		if !slices.Contains(configs.RpcStrategies, policies[i].Strategy) {
+			policies[i].Strategy = configs.RpcStrategyRoundRobin
+		}
+		if policies[i].Strategy == configs.RpcStrategyPinned {
+			reserved = append(reserved, policies[i].Urls...)
+		}
+	}
+	pool := slices.DeleteFunc(slices.Clone(rpcs), func(s string) bool {
+		return slices.Contains(reserved, s)
+	})
+	if len(pool) == 0 {
+		pool = rpcs
+	}
+
+	// Number of services using strategy and position of serviceIndex among
+	// them
+	participants := func(strategy string) (int, int) {
+		n, position := 0, 0
+		for i, p := range policies {
+			if p.Strategy == strategy {
+				if i == serviceIndex {
+					position = n
+				}
+				n++
+			}
+		}
+		return n, position
+	}
+
+	policy := policies[serviceIndex]
+	result := []string{}
+	switch policy.Strategy {
+	case configs.RpcStrategyPinned:
+		for _, url := range policy.Urls {
+			if slices.Contains(rpcs, url) && !slices.Contains(result, url) {
+				result = append(result, url)
+			}
+		}
+	case configs.RpcStrategyWeighted:
+		n, position := participants(configs.RpcStrategyWeighted)
+		result = dealRpcs(weightedRpcDeck(pool, d), n, position)
+	case configs.RpcStrategyTiered:
+		result = tieredRpcs(pool, d, policy.Tier, policy.MinCount)
+	default:
+		n, position := participants(configs.RpcStrategyRoundRobin)
+		result = dealRpcs(pool, n, position)
+	}
+
+	// Top up with not pinned rpcs first
+	minCount := max(policy.MinCount, 1)
+	for _, list := range [][]string{pool, rpcs} {
+		for _, url := range list {
+			if len(result) >= minCount {
+				return result
+			}
+			if !slices.Contains(result, url) {
+				result = append(result, url)
+			}
+		}
+	}
+	return result
+}
+
+// dealRpcs deals rpcs like cards between numServices services and returns the
+// rpcs of service at position. When there are not enough rpcs, service at
+// position 0 gets only the first rpc and others share the rest. Duplicate
+// cards (see weightedRpcDeck) are returned once.
+func dealRpcs(rpcs []string, numServices, position int) []string {
+	returnList := []string{}
+	rpcsAvailable := len(rpcs)
+
+	// Special case - only single rpc available - always use it
+	if rpcsAvailable == 1 {
+		returnList = append(returnList, rpcs[0])
+	} else if rpcsAvailable > 0 && rpcsAvailable < numServices {
+		// Not enough rpcs available - make sure position 0 gets only 0th and
+		// others get everything else in sequence
+		rpcIndexToGet := position
+		if position > 0 {
+			// Start from 1st slice element and distribute in sequence one by
+			// one. Basically we cut the first element out of the equation.
+			rpcIndexToGet = 1 + (position-1)%(rpcsAvailable-1)
+		}
+		returnList = []string{rpcs[rpcIndexToGet]}
+	} else if rpcsAvailable >= numServices {
+		for i := position; i < rpcsAvailable; i += numServices {
+			if !slices.Contains(returnList, rpcs[i]) {
+				returnList = append(returnList, rpcs[i])
+			}
+		}
+	}
+
+	return returnList
+}
+
+// weightedRpcDeck repeats every rpc by its weight. Rpcs are interleaved with
+// smooth weighted round robin, so that the heaviest rpc is not dealt to
+// consecutive services only.
+func weightedRpcDeck(rpcs []string, d *configs.D8XRpcDistribution) []string {
+	weights := make([]int, len(rpcs))
+	total := 0
+	for i, url := range rpcs {
+		weights[i] = d.Endpoint(url).Weight
+		total += weights[i]
+	}
+
+	current := make([]int, len(rpcs))
+	deck := make([]string, 0, total)
+	for len(deck) < total {
+		best := 0
+		for i := range rpcs {
+			current[i] += weights[i]
+			if current[i] > current[best] {
+				best = i
+			}
+		}
+		current[best] -= total
+		deck = append(deck, rpcs[best])
+	}
+	return deck
+}
+
+// tieredRpcs returns all rpcs of tier. When there are less than minCount
+// rpcs, rpcs of lower priority tiers (higher numbers) are added, then rpcs of
+// higher priority tiers.
+func tieredRpcs(rpcs []string, d *configs.D8XRpcDistribution, tier, minCount int) []string {
+	byTier := map[int][]string{}
+	tiers := []int{}
+	for _, url := range rpcs {
+		t := d.Endpoint(url).Tier
+		if _, ok := byTier[t]; !ok {
+			tiers = append(tiers, t)
+		}
+		byTier[t] = append(byTier[t], url)
+	}
+	sort.Slice(tiers, func(i, j int) bool {
+		a, b := tiers[i], tiers[j]
+		if (a < tier) != (b < tier) {
+			return a >= tier
+		}
+		if a >= tier {
+			return a < b
+		}
+		return a > b
+	})
+
+	result := []string{}
+	for _, t := range tiers {
+		if len(result) >= max(minCount, 1) {
+			break
+		}
+		result = append(result, byTier[t]...)
+	}
+	return result
+}
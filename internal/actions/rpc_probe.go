@@ -0,0 +1,193 @@
+package actions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// Block lag which is not considered when ranking rpc endpoints, since block
+// numbers of endpoints are not retrieved at the same time
+const rpcBlockLagTolerance = 2
+
+// RpcProbe is the result of probing a single rpc endpoint
+type RpcProbe struct {
+	Url       string `json:"url" yaml:"url"`
+	Transport string `json:"transport" yaml:"transport"`
+	// Chain id reported by endpoint, 0 when it was not retrieved
+	ChainId uint64 `json:"chain_id" yaml:"chain_id"`
+	// Latest block number reported by endpoint (eth_blockNumber for http,
+	// first newHeads notification for websockets)
+	BlockNumber uint64 `json:"block_number" yaml:"block_number"`
+	// Number of blocks behind the highest block of all probed endpoints
+	BlockLag uint64 `json:"block_lag" yaml:"block_lag"`
+	// Median round trip time of eth_blockNumber requests in milliseconds
+	LatencyMs float64 `json:"latency_ms" yaml:"latency_ms"`
+	// Empty when endpoint is healthy
+	Error string `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+func (p RpcProbe) Healthy() bool {
+	return p.Error == ""
+}
+
+// rpcProbeOptions control how rpc endpoints are probed
+type rpcProbeOptions struct {
+	// Expected chain id of endpoints
+	chainId uint64
+	// Number of eth_blockNumber requests used to measure latency
+	samples int
+	// Timeout of probing a single endpoint, including waiting for the new
+	// head of websocket endpoints
+	timeout time.Duration
+}
+
+// probeRpc checks that endpoint url serves chain opts.chainId and measures its
+// latency. Websocket endpoints must additionally deliver a newHeads
+// subscription notification.
+func probeRpc(ctx context.Context, transport rpcTransport, url string, opts rpcProbeOptions) RpcProbe {
+	result := RpcProbe{Url: url, Transport: string(transport)}
+	if err := doProbeRpc(ctx, transport, url, opts, &result); err != nil {
+		result.Error = err.Error()
+	}
+	return result
+}
+
+func doProbeRpc(ctx context.Context, transport rpcTransport, url string, opts rpcProbeOptions, result *RpcProbe) error {
+	ctx, cancel := context.WithTimeout(ctx, opts.timeout)
+	defer cancel()
+
+	client, err := ethclient.DialContext(ctx, url)
+	if err != nil {
+		return fmt.Errorf("connecting: %w", err)
+	}
+	defer client.Close()
+
+	chainId, err := client.ChainID(ctx)
+	if err != nil {
+		return fmt.Errorf("eth_chainId: %w", err)
+	}
+	result.ChainId = chainId.Uint64()
+	if result.ChainId != opts.chainId {
+		return fmt.Errorf("endpoint serves chain %d instead of %d", result.ChainId, opts.chainId)
+	}
+
+	latencies := []time.Duration{}
+	for i := 0; i < max(opts.samples, 1); i++ {
+		start := time.Now()
+		block, err := client.BlockNumber(ctx)
+		if err != nil {
+			return fmt.Errorf("eth_blockNumber: %w", err)
+		}
+		latencies = append(latencies, time.Since(start))
+		result.BlockNumber = max(result.BlockNumber, block)
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	result.LatencyMs = float64(latencies[len(latencies)/2].Microseconds()) / 1000
+
+	if transport != rpcTransportWS {
+		return nil
+	}
+
+	heads := make(chan *types.Header, 1)
+	sub, err := client.SubscribeNewHead(ctx, heads)
+	if err != nil {
+		return fmt.Errorf("subscribing to newHeads: %w", err)
+	}
+	defer sub.Unsubscribe()
+	select {
+	case head := <-heads:
+		result.BlockNumber = max(result.BlockNumber, head.Number.Uint64())
+	case err := <-sub.Err():
+		return fmt.Errorf("newHeads subscription: %w", err)
+	case <-ctx.Done():
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return fmt.Errorf("no newHeads notification was received within %s", opts.timeout)
+		}
+		return ctx.Err()
+	}
+
+	return nil
+}
+
+// probeRpcs probes http and websocket endpoints concurrently. Block lag of
+// each endpoint is computed against the highest block of all endpoints and
+// results are ranked with rankRpcProbes.
+func probeRpcs(ctx context.Context, httpRpcs, wsRpcs []string, opts rpcProbeOptions) []RpcProbe {
+	type endpoint struct {
+		transport rpcTransport
+		url       string
+	}
+	endpoints := []endpoint{}
+	for _, url := range httpRpcs {
+		endpoints = append(endpoints, endpoint{rpcTransportHTTP, url})
+	}
+	for _, url := range wsRpcs {
+		endpoints = append(endpoints, endpoint{rpcTransportWS, url})
+	}
+
+	results := make([]RpcProbe, len(endpoints))
+	wg := sync.WaitGroup{}
+	for i, e := range endpoints {
+		wg.Add(1)
+		go func(i int, e endpoint) {
+			defer wg.Done()
+			results[i] = probeRpc(ctx, e.transport, e.url, opts)
+		}(i, e)
+	}
+	wg.Wait()
+
+	var highest uint64
+	for _, r := range results {
+		if r.Healthy() {
+			highest = max(highest, r.BlockNumber)
+		}
+	}
+	for i, r := range results {
+		if r.Healthy() {
+			results[i].BlockLag = highest - r.BlockNumber
+		}
+	}
+
+	rankRpcProbes(results)
+	return results
+}
+
+// rankRpcProbes sorts results from the best to the worst endpoint. Healthy
+// endpoints come first, then endpoints are ordered by block lag (above
+// rpcBlockLagTolerance) and latency.
+func rankRpcProbes(results []RpcProbe) {
+	lag := func(r RpcProbe) uint64 {
+		if r.BlockLag <= rpcBlockLagTolerance {
+			return 0
+		}
+		return r.BlockLag
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		if a.Healthy() != b.Healthy() {
+			return a.Healthy()
+		}
+		if lag(a) != lag(b) {
+			return lag(a) < lag(b)
+		}
+		return a.LatencyMs < b.LatencyMs
+	})
+}
+
+// rankedRpcUrls returns urls of transport in the order of ranked results
+func rankedRpcUrls(results []RpcProbe, transport rpcTransport) []string {
+	urls := []string{}
+	for _, r := range results {
+		if r.Transport == string(transport) {
+			urls = append(urls, r.Url)
+		}
+	}
+	return urls
+}
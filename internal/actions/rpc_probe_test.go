@@ -0,0 +1,174 @@
+package actions
+
+import (
+	"context"
+	"math/big"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testEthService implements eth_chainId, eth_blockNumber and newHeads
+// subscription
+type testEthService struct {
+	chainId uint64
+	block   uint64
+	// Whether newHeads notifications are sent
+	heads bool
+}
+
+func (s *testEthService) ChainId() hexutil.Uint64 {
+	return hexutil.Uint64(s.chainId)
+}
+
+func (s *testEthService) BlockNumber() hexutil.Uint64 {
+	return hexutil.Uint64(s.block)
+}
+
+func (s *testEthService) NewHeads(ctx context.Context) (*rpc.Subscription, error) {
+	notifier, ok := rpc.NotifierFromContext(ctx)
+	if !ok {
+		return nil, rpc.ErrNotificationsUnsupported
+	}
+	sub := notifier.CreateSubscription()
+	if s.heads {
+		go notifier.Notify(sub.ID, &types.Header{Number: new(big.Int).SetUint64(s.block + 1), Difficulty: big.NewInt(0)})
+	}
+	return sub, nil
+}
+
+// newTestRpcServer starts json rpc server and returns its http and websocket
+// urls
+func newTestRpcServer(t *testing.T, svc *testEthService) (string, string) {
+	srv := rpc.NewServer()
+	require.NoError(t, srv.RegisterName("eth", svc))
+	httpSrv := httptest.NewServer(srv)
+	wsSrv := httptest.NewServer(srv.WebsocketHandler([]string{"*"}))
+	t.Cleanup(func() {
+		httpSrv.Close()
+		wsSrv.Close()
+		srv.Stop()
+	})
+	return httpSrv.URL, "ws" + strings.TrimPrefix(wsSrv.URL, "http")
+}
+
+func TestProbeRpcs(t *testing.T) {
+	synced, syncedWs := newTestRpcServer(t, &testEthService{chainId: 1101, block: 100, heads: true})
+	behind, _ := newTestRpcServer(t, &testEthService{chainId: 1101, block: 90})
+	wrongChain, _ := newTestRpcServer(t, &testEthService{chainId: 1, block: 100})
+	_, noHeadsWs := newTestRpcServer(t, &testEthService{chainId: 1101, block: 100})
+
+	results := probeRpcs(
+		context.Background(),
+		[]string{wrongChain, behind, synced},
+		[]string{noHeadsWs, syncedWs},
+		rpcProbeOptions{chainId: 1101, samples: 2, timeout: time.Second},
+	)
+	require.Len(t, results, 5)
+
+	// Healthy endpoints come first, lagging endpoint is ranked after synced
+	// ones
+	assert.ElementsMatch(t, []string{synced, syncedWs}, []string{results[0].Url, results[1].Url})
+	for _, r := range results[:2] {
+		assert.True(t, r.Healthy(), r.Error)
+		assert.Equal(t, uint64(1101), r.ChainId)
+	}
+	assert.Equal(t, behind, results[2].Url)
+	// Websocket endpoint received block 101 via newHeads
+	assert.Equal(t, uint64(11), results[2].BlockLag)
+
+	failed := map[string]string{results[3].Url: results[3].Error, results[4].Url: results[4].Error}
+	assert.Equal(t, "endpoint serves chain 1 instead of 1101", failed[wrongChain])
+	assert.Equal(t, "no newHeads notification was received within 1s", failed[noHeadsWs])
+
+	assert.Equal(t, []string{synced, behind, wrongChain}, rankedRpcUrls(results, rpcTransportHTTP))
+	assert.Equal(t, []string{syncedWs, noHeadsWs}, rankedRpcUrls(results, rpcTransportWS))
+}
+
+func TestRankRpcProbes(t *testing.T) {
+	results := []RpcProbe{
+		{Url: "failed", Error: "connecting: refused"},
+		{Url: "slow", LatencyMs: 300},
+		{Url: "lagging", LatencyMs: 10, BlockLag: 20},
+		{Url: "fast-small-lag", LatencyMs: 50, BlockLag: rpcBlockLagTolerance},
+	}
+	rankRpcProbes(results)
+
+	urls := []string{}
+	for _, r := range results {
+		urls = append(urls, r.Url)
+	}
+	assert.Equal(t, []string{"fast-small-lag", "slow", "lagging", "failed"}, urls)
+}
+
+func TestRankedRpcsToApply(t *testing.T) {
+	healthy := []RpcProbe{
+		{Url: "https://a", Transport: string(rpcTransportHTTP), ChainId: 1},
+		{Url: "wss://a", Transport: string(rpcTransportWS), ChainId: 1},
+	}
+	wrongChain := RpcProbe{Url: "https://other", Transport: string(rpcTransportHTTP), ChainId: 2, Error: "chain id 2 does not match 1"}
+	unreachable := RpcProbe{Url: "https://down", Transport: string(rpcTransportHTTP), Error: "connecting: refused"}
+
+	tests := []struct {
+		name          string
+		results       []RpcProbe
+		keepUnhealthy bool
+		wantHttp      []string
+		wantWs        []string
+		wantDropped   []string
+		wantErr       string
+	}{
+		{
+			name:        "healthy",
+			results:     healthy,
+			wantHttp:    []string{"https://a"},
+			wantWs:      []string{"wss://a"},
+			wantDropped: []string{},
+		},
+		{
+			name:        "wrong chain is dropped",
+			results:     append(append([]RpcProbe{}, healthy...), wrongChain),
+			wantHttp:    []string{"https://a"},
+			wantWs:      []string{"wss://a"},
+			wantDropped: []string{"https://other"},
+		},
+		{
+			name:    "unhealthy is refused",
+			results: append(append([]RpcProbe{}, healthy...), unreachable),
+			wantErr: "https://down",
+		},
+		{
+			name:          "unhealthy is kept",
+			results:       append(append([]RpcProbe{}, healthy...), unreachable, wrongChain),
+			keepUnhealthy: true,
+			wantHttp:      []string{"https://a", "https://down"},
+			wantWs:        []string{"wss://a"},
+			wantDropped:   []string{"https://other"},
+		},
+		{
+			name:    "no http endpoint left",
+			results: []RpcProbe{wrongChain, healthy[1]},
+			wantErr: "no http endpoint serves chain 1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			httpRpcs, wsRpcs, dropped, err := rankedRpcsToApply(tt.results, 1, tt.keepUnhealthy)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantHttp, httpRpcs)
+			assert.Equal(t, tt.wantWs, wsRpcs)
+			assert.Equal(t, tt.wantDropped, dropped)
+		})
+	}
+}
@@ -0,0 +1,470 @@
+package actions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/D8-X/d8x-cli/internal/configs"
+	"github.com/D8-X/d8x-cli/internal/conn"
+	"github.com/D8-X/d8x-cli/internal/styles"
+	"github.com/urfave/cli/v2"
+)
+
+// Broker-server compose service which reads ./broker/rpc.json
+const brokerRpcConfigService = "broker"
+
+// swarmServiceConfig is a docker config mounted into a swarm service
+type swarmServiceConfig struct {
+	// Service name without stack prefix
+	service string
+	config  string
+	// Mount path of the config in service containers
+	target string
+}
+
+// Command which outputs <service>[##]<config>[##]<target> line for every
+// docker config of every stack service
+var swarmServiceConfigsCmd = fmt.Sprintf(
+	`docker service ls -q --filter label=com.docker.stack.namespace=%s | xargs -r docker service inspect --format '{{$svc := .Spec.Name}}{{range .Spec.TaskTemplate.ContainerSpec.Configs}}{{$svc}}[##]{{.ConfigName}}[##]{{.File.Name}}{{"\n"}}{{end}}'`,
+	dockerStackName,
+)
+
+// parseSwarmServiceConfigs parses swarmServiceConfigsCmd output and strips
+// svcPrefix from service names
+func parseSwarmServiceConfigs(out []byte, svcPrefix string) []swarmServiceConfig {
+	result := []swarmServiceConfig{}
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := strings.Split(strings.TrimSpace(line), "[##]")
+		if len(fields) != 3 || fields[0] == "" || fields[1] == "" {
+			continue
+		}
+		result = append(result, swarmServiceConfig{
+			service: strings.TrimPrefix(fields[0], svcPrefix),
+			config:  fields[1],
+			target:  fields[2],
+		})
+	}
+	return result
+}
+
+// swarmServiceConfigNames returns names of docker configs referenced by the
+// current spec of service svcStackName
+func swarmServiceConfigNames(sshConn conn.SSHConnection, svcStackName string) ([]string, error) {
+	out, err := sshConn.ExecCommand(
+		fmt.Sprintf(`docker service inspect --format '{{range .Spec.TaskTemplate.ContainerSpec.Configs}}{{.ConfigName}}{{"\n"}}{{end}}' %s`, svcStackName),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("inspecting service %s configs: %w", svcStackName, err)
+	}
+	return strings.Fields(string(out)), nil
+}
+
+var versionedConfigNameRe = regexp.MustCompile(`^(.+)_v([0-9]+)$`)
+
+// parseVersionedConfigName splits docker config name into its base name and
+// version. Configs created by swarm-deploy (base names) are version 1.
+func parseVersionedConfigName(name string) (string, int) {
+	m := versionedConfigNameRe.FindStringSubmatch(name)
+	if m == nil {
+		return name, 1
+	}
+	version, err := strconv.Atoi(m[2])
+	if err != nil {
+		return name, 1
+	}
+	return m[1], version
+}
+
+// nextSwarmConfigVersion returns the name of the next version of docker config
+// base, based on existing config names on manager. Docker configs are
+// immutable, therefore every change gets a new name (cfg_rpc_v2, cfg_rpc_v3,
+// etc.).
+func nextSwarmConfigVersion(base string, existing []string) string {
+	latest := 1
+	for _, name := range existing {
+		if b, v := parseVersionedConfigName(name); b == base {
+			latest = max(latest, v)
+		}
+	}
+	return fmt.Sprintf("%s_v%d", base, latest+1)
+}
+
+// rpcUrlArgs splits url arguments into http and websocket urls
+func rpcUrlArgs(args []string) ([]string, []string, error) {
+	httpRpcs, wsRpcs := []string{}, []string{}
+	for _, url := range args {
+		switch {
+		case strings.HasPrefix(url, "http://") || strings.HasPrefix(url, "https://"):
+			httpRpcs = append(httpRpcs, url)
+		case strings.HasPrefix(url, "ws://") || strings.HasPrefix(url, "wss://"):
+			wsRpcs = append(wsRpcs, url)
+		default:
+			return nil, nil, fmt.Errorf("invalid rpc url %s, url must start with http(s):// or ws(s)://", url)
+		}
+	}
+	if len(httpRpcs)+len(wsRpcs) == 0 {
+		return nil, nil, fmt.Errorf("at least one rpc url must be provided")
+	}
+	return httpRpcs, wsRpcs, nil
+}
+
+// rpcListEdit returns new list of rpc urls from current list and urls given
+// as arguments (of the same transport)
+type rpcListEdit func(current, urls []string) ([]string, error)
+
+// rpcListSet replaces the list, lists of transports without urls are kept
+func rpcListSet(current, urls []string) ([]string, error) {
+	if len(urls) == 0 {
+		return current, nil
+	}
+	return rpcListAdd(nil, urls)
+}
+
+func rpcListAdd(current, urls []string) ([]string, error) {
+	result := slices.Clone(current)
+	for _, url := range urls {
+		if !slices.Contains(result, url) {
+			result = append(result, url)
+		}
+	}
+	return result, nil
+}
+
+func rpcListRemove(current, urls []string) ([]string, error) {
+	for _, url := range urls {
+		if !slices.Contains(current, url) {
+			return nil, fmt.Errorf("rpc url %s is not configured", url)
+		}
+	}
+	return slices.DeleteFunc(slices.Clone(current), func(s string) bool {
+		return slices.Contains(urls, s)
+	}), nil
+}
+
+// RpcSet replaces http and/or websocket rpc endpoints and rotates them on
+// deployed services
+func (c *Container) RpcSet(ctx *cli.Context) error {
+	return c.rotateRpcs(ctx, rpcListSet)
+}
+
+// RpcAdd adds rpc endpoints and rotates them on deployed services
+func (c *Container) RpcAdd(ctx *cli.Context) error {
+	return c.rotateRpcs(ctx, rpcListAdd)
+}
+
+// RpcRemove removes rpc endpoints and rotates them on deployed services
+func (c *Container) RpcRemove(ctx *cli.Context) error {
+	return c.rotateRpcs(ctx, rpcListRemove)
+}
+
+// rotateRpcs edits HttpRpcList and WsRpcList of configured chain with edit,
+// regenerates rpc config files and updates only the services which consume
+// them. New urls are verified with probeRpc unless --no-verify is provided.
+func (c *Container) rotateRpcs(ctx *cli.Context, edit rpcListEdit) error {
+	httpArgs, wsArgs, err := rpcUrlArgs(ctx.Args().Slice())
+	if err != nil {
+		return err
+	}
+	cfg, err := c.ConfigRWriter.Read()
+	if err != nil {
+		return err
+	}
+	if cfg.ChainId == 0 {
+		return fmt.Errorf("chain id is not set in %s, run d8x setup first", c.ConfigRWriter.GetPath())
+	}
+	chainId := strconv.Itoa(int(cfg.ChainId))
+
+	prevHttpRpcs, prevWsRpcs := cfg.HttpRpcList[chainId], cfg.WsRpcList[chainId]
+	httpRpcs, err := edit(prevHttpRpcs, httpArgs)
+	if err != nil {
+		return err
+	}
+	wsRpcs, err := edit(prevWsRpcs, wsArgs)
+	if err != nil {
+		return err
+	}
+	if len(httpRpcs) == 0 {
+		return fmt.Errorf("at least one http rpc endpoint is required")
+	}
+	if slices.Equal(httpRpcs, prevHttpRpcs) && slices.Equal(wsRpcs, prevWsRpcs) {
+		fmt.Println("RPC endpoints are unchanged")
+		return nil
+	}
+
+	newHttpRpcs := slices.DeleteFunc(slices.Clone(httpRpcs), func(s string) bool { return slices.Contains(prevHttpRpcs, s) })
+	newWsRpcs := slices.DeleteFunc(slices.Clone(wsRpcs), func(s string) bool { return slices.Contains(prevWsRpcs, s) })
+	if !ctx.Bool("no-verify") && len(newHttpRpcs)+len(newWsRpcs) > 0 {
+		if err := c.verifyNewRpcs(cfg.ChainId, newHttpRpcs, newWsRpcs, ctx.Duration("timeout")); err != nil {
+			return err
+		}
+	}
+
+	cfg.HttpRpcList[chainId] = httpRpcs
+	cfg.WsRpcList[chainId] = wsRpcs
+	if err := c.ConfigRWriter.Write(cfg); err != nil {
+		return err
+	}
+	fmt.Println(styles.SuccessText.Render(fmt.Sprintf("RPC endpoints of chain %s were updated in %s", chainId, c.ConfigRWriter.GetPath())))
+
+	if ctx.Bool("no-rollout") {
+		fmt.Println("Endpoints will be distributed to services on the next swarm-deploy and broker-deploy")
+		return nil
+	}
+	if !cfg.SwarmDeployed && !cfg.BrokerDeployed {
+		fmt.Println("Swarm and broker server are not deployed, endpoints will be distributed to services on deployment")
+		return nil
+	}
+
+	// All previously configured urls are replaced in rpc config files, so
+	// that removed urls are not kept as pre-existing ones
+	replaced := append(slices.Clone(prevHttpRpcs), prevWsRpcs...)
+	rolloutErrs := []error{}
+	if cfg.SwarmDeployed {
+		if err := c.rotateSwarmRpcConfigs(cfg, replaced); err != nil {
+			rolloutErrs = append(rolloutErrs, fmt.Errorf("swarm: %w", err))
+		}
+	}
+	if cfg.BrokerDeployed {
+		if err := c.rotateBrokerRpcConfig(cfg, replaced); err != nil {
+			rolloutErrs = append(rolloutErrs, fmt.Errorf("broker-server: %w", err))
+		}
+	}
+	if len(rolloutErrs) > 0 {
+		return fmt.Errorf("rotating rpc endpoints: %w", errors.Join(rolloutErrs...))
+	}
+
+	return nil
+}
+
+// verifyNewRpcs probes http and websocket urls and fails when any of them is
+// not healthy
+func (c *Container) verifyNewRpcs(chainId uint, httpRpcs, wsRpcs []string, timeout time.Duration) error {
+	probeCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	stopSpinner := make(chan struct{})
+	go c.TUI.NewSpinner(stopSpinner, fmt.Sprintf("Verifying %d rpc endpoints", len(httpRpcs)+len(wsRpcs)))
+	results := probeRpcs(probeCtx, httpRpcs, wsRpcs, rpcProbeOptions{
+		chainId: uint64(chainId),
+		samples: 1,
+		timeout: timeout,
+	})
+	stopSpinner <- struct{}{}
+
+	failed := 0
+	for _, r := range results {
+		if !r.Healthy() {
+			failed++
+			fmt.Println(styles.ErrorText.Render(fmt.Sprintf("%s: %s", r.Url, r.Error)))
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d rpc endpoint(s) failed verification, use --no-verify to skip it", failed)
+	}
+	return nil
+}
+
+// rotateSwarmRpcConfigs regenerates trader-backend rpc config files, creates
+// new versions of their docker configs and performs a rolling update of the
+// services which consume them. Other services are not touched.
+func (c *Container) rotateSwarmRpcConfigs(cfg *configs.D8XConfig, replaced []string) error {
+	managerIp, err := c.HostsCfg.GetMangerPublicIp()
+	if err != nil {
+		return fmt.Errorf("finding manager ip address: %w", err)
+	}
+	if err := c.CopySwarmDeployConfigs(); err != nil {
+		return err
+	}
+
+	chainId := strconv.Itoa(int(cfg.ChainId))
+	copyList := []conn.SftpCopySrcDest{}
+	for i, rpcConfigFilePath := range swarmRpcConfigFiles {
+		httpRpcs, wsRpcs := DistributeRpcs(i, chainId, cfg)
+		// No wsRPC for referral
+		if i == 2 {
+			wsRpcs = nil
+		}
+		fmt.Printf("Updating %s config...\n", rpcConfigFilePath)
+		if err := c.replaceRpcConfigUrls(rpcConfigFilePath, cfg.ChainId, replaced, wsRpcs, httpRpcs); err != nil {
+			return fmt.Errorf("updating %s: %w", rpcConfigFilePath, err)
+		}
+		copyList = append(copyList, conn.SftpCopySrcDest{Src: rpcConfigFilePath, Dst: rpcConfigFilePath})
+	}
+
+	managerSSHConn, err := c.CreateSSHConn(managerIp, c.DefaultClusterUserName, c.SshKeyPath)
+	if err != nil {
+		return err
+	}
+	fmt.Println(styles.ItalicText.Render("Copying rpc configs to manager node " + managerIp))
+	if err := managerSSHConn.CopyFilesOverSftp(copyList...); err != nil {
+		return fmt.Errorf("copying rpc configs to manager: %w", err)
+	}
+
+	out, err := managerSSHConn.ExecCommand(swarmServiceConfigsCmd)
+	if err != nil {
+		return fmt.Errorf("listing configs of stack services: %w", err)
+	}
+	serviceConfigs := parseSwarmServiceConfigs(out, dockerStackName+"_")
+	out, err = managerSSHConn.ExecCommand(`docker config ls --format '{{.Name}}'`)
+	if err != nil {
+		return fmt.Errorf("listing docker configs: %w", err)
+	}
+	existing := strings.Fields(string(out))
+
+	// Service -> final result of its update
+	results := map[string]string{}
+	updated := []string{}
+	for _, dc := range swarmDockerConfigs {
+		if !slices.Contains(swarmRpcConfigFiles, dc.file) {
+			continue
+		}
+		consumers := []swarmServiceConfig{}
+		for _, sc := range serviceConfigs {
+			if base, _ := parseVersionedConfigName(sc.config); base == dc.name {
+				consumers = append(consumers, sc)
+			}
+		}
+		if len(consumers) == 0 {
+			fmt.Println(styles.ItalicText.Render(fmt.Sprintf("No %s service uses docker config %s, skipping", dockerStackName, dc.name)))
+			continue
+		}
+
+		newConfig := nextSwarmConfigVersion(dc.name, existing)
+		fmt.Printf("Creating docker config %s from %s\n", newConfig, dc.file)
+		if out, err := managerSSHConn.ExecCommand(fmt.Sprintf("docker config create %s %s", newConfig, dc.file)); err != nil {
+			fmt.Println(string(out))
+			return fmt.Errorf("creating docker config %s: %w", newConfig, err)
+		}
+		existing = append(existing, newConfig)
+
+		promoted := false
+		for _, sc := range consumers {
+			fmt.Printf("Updating service %s to docker config %s\n", sc.service, newConfig)
+			svcStackName := dockerStackName + "_" + sc.service
+			result, err := c.rollingUpdateSwarmService(
+				managerSSHConn,
+				sc.service,
+				svcStackName,
+				rollingConfigUpdateCmd(sc.config, newConfig, sc.target, svcStackName),
+				swarmServicePublicServices(cfg, sc.service),
+			)
+			if err != nil {
+				fmt.Println(styles.ErrorText.Render(fmt.Sprintf("Could not update service %s: %s", sc.service, err.Error())))
+				result = rollingUpdateFailed
+			}
+			// Service is reported as rolled back only when it references the
+			// previous config again
+			if result == rollingUpdateRolledBack && c.DryRun == nil {
+				names, err := swarmServiceConfigNames(managerSSHConn, svcStackName)
+				if err != nil || !slices.Contains(names, sc.config) || slices.Contains(names, newConfig) {
+					fmt.Println(styles.ErrorText.Render(
+						fmt.Sprintf("Service %s was not reverted to docker config %s", sc.service, sc.config),
+					))
+					result = rollingUpdateFailed
+				}
+			}
+			results[sc.service] = result
+			updated = append(updated, sc.service)
+			if result != rollingUpdatePromoted {
+				continue
+			}
+			promoted = true
+
+			// Previous version can only be removed once no service uses it,
+			// failure to remove it is not an error
+			if _, err := managerSSHConn.ExecCommand(fmt.Sprintf("docker config rm %s", sc.config)); err == nil {
+				fmt.Printf("Removed docker config %s\n", sc.config)
+			}
+		}
+		// Unused new version would be left behind otherwise
+		if !promoted {
+			managerSSHConn.ExecCommand(fmt.Sprintf("docker config rm %s", newConfig))
+		}
+	}
+
+	fmt.Println("\nSwarm services update results:")
+	rolledBack, failed := 0, 0
+	for _, svc := range updated {
+		line := fmt.Sprintf("  %s: %s", svc, results[svc])
+		switch results[svc] {
+		case rollingUpdatePromoted:
+			fmt.Println(styles.SuccessText.Render(line))
+			continue
+		case rollingUpdateRolledBack:
+			rolledBack++
+		default:
+			failed++
+		}
+		fmt.Println(styles.ErrorText.Render(line))
+	}
+
+	c.recordRelease(managerSSHConn, swarmReleaseTarget(), "rpc-rotate", 0)
+
+	if failed > 0 {
+		return fmt.Errorf("%d service(s) failed to update and might use either previous or new rpc endpoints", failed)
+	}
+	if rolledBack > 0 {
+		return fmt.Errorf("%d service(s) still use previous rpc endpoints", rolledBack)
+	}
+	return nil
+}
+
+// rotateBrokerRpcConfig regenerates broker-server rpc.json and recreates the
+// broker service container which reads it
+func (c *Container) rotateBrokerRpcConfig(cfg *configs.D8XConfig, replaced []string) error {
+	brokerIp, err := c.HostsCfg.GetBrokerPublicIp()
+	if err != nil {
+		return fmt.Errorf("finding broker ip address: %w", err)
+	}
+	if err := c.CopyBrokerDeployConfigs(); err != nil {
+		return err
+	}
+
+	httpRpcs, _ := DistributeRpcs(3, strconv.Itoa(int(cfg.ChainId)), cfg)
+	fmt.Printf("Updating %s config...\n", brokerDeployRpcConfig)
+	if err := c.replaceRpcConfigUrls(brokerDeployRpcConfig, cfg.ChainId, replaced, nil, httpRpcs); err != nil {
+		return fmt.Errorf("updating %s: %w", brokerDeployRpcConfig, err)
+	}
+
+	sshConn, err := c.CreateSSHConn(brokerIp, c.DefaultClusterUserName, c.SshKeyPath)
+	if err != nil {
+		return err
+	}
+	fmt.Println(styles.ItalicText.Render("Copying rpc config to broker-server..."))
+	if err := sshConn.CopyFilesOverSftp(
+		conn.SftpCopySrcDest{Src: brokerDeployRpcConfig, Dst: "./broker/rpc.json"},
+	); err != nil {
+		return fmt.Errorf("copying rpc config to broker-server: %w", err)
+	}
+
+	fmt.Println(styles.ItalicText.Render("Restarting broker service..."))
+	out, err := sshConn.ExecCommand(brokerRpcRestartCmd(cfg.BrokerServerConfig))
+	if err != nil {
+		fmt.Printf("%s\n\n%s", out, styles.ErrorText.Render("Something went wrong during broker service restart ^^^"))
+		return err
+	}
+	fmt.Println(styles.SuccessText.Render("Broker service was restarted with new rpc endpoints"))
+
+	c.recordRelease(sshConn, brokerReleaseTarget(), "rpc-rotate", 0)
+
+	return nil
+}
+
+// brokerRpcRestartCmd recreates only the broker service container, redis and
+// other services keep running
+func brokerRpcRestartCmd(b configs.D8XBrokerServerConfig) string {
+	return fmt.Sprintf(
+		"cd ./broker && BROKER_FEE_TBPS=%s REDIS_PW=%s docker compose up -d --force-recreate --no-deps %s",
+		b.FeeTBPS,
+		b.RedisPassword,
+		brokerRpcConfigService,
+	)
+}
@@ -0,0 +1,119 @@
+package actions
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/D8-X/d8x-cli/internal/configs"
+	"github.com/D8-X/d8x-cli/internal/files"
+	"github.com/D8-X/d8x-cli/internal/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestParseSwarmServiceConfigs(t *testing.T) {
+	out := []byte("stack_api[##]cfg_rpc_v2[##]/cfg_rpc\n" +
+		"stack_api[##]cfg_prices[##]/cfg_prices\n\n" +
+		"stack_referral[##]cfg_rpc_referral[##]/cfg_rpc_referral\n")
+	assert.Equal(t,
+		[]swarmServiceConfig{
+			{service: "api", config: "cfg_rpc_v2", target: "/cfg_rpc"},
+			{service: "api", config: "cfg_prices", target: "/cfg_prices"},
+			{service: "referral", config: "cfg_rpc_referral", target: "/cfg_rpc_referral"},
+		},
+		parseSwarmServiceConfigs(out, "stack_"),
+	)
+}
+
+func TestNextSwarmConfigVersion(t *testing.T) {
+	tests := []struct {
+		base     string
+		existing []string
+		want     string
+	}{
+		{"cfg_rpc", []string{"cfg_rpc", "cfg_rpc_history"}, "cfg_rpc_v2"},
+		{"cfg_rpc", []string{}, "cfg_rpc_v2"},
+		{"cfg_rpc", []string{"cfg_rpc_v3", "cfg_rpc_v2", "cfg_rpc_history_v7"}, "cfg_rpc_v4"},
+		{"cfg_rpc_history", []string{"cfg_rpc_v3", "cfg_rpc_history_v7"}, "cfg_rpc_history_v8"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, nextSwarmConfigVersion(tt.base, tt.existing))
+	}
+}
+
+func TestRpcUrlArgs(t *testing.T) {
+	httpRpcs, wsRpcs, err := rpcUrlArgs([]string{"https://a", "wss://b", "http://c"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"https://a", "http://c"}, httpRpcs)
+	assert.Equal(t, []string{"wss://b"}, wsRpcs)
+
+	_, _, err = rpcUrlArgs([]string{"ftp://a"})
+	assert.ErrorContains(t, err, "invalid rpc url ftp://a")
+	_, _, err = rpcUrlArgs(nil)
+	assert.Error(t, err)
+}
+
+func TestRpcListEdits(t *testing.T) {
+	current := []string{"https://a", "https://b"}
+
+	got, err := rpcListSet(current, []string{"https://c", "https://c"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"https://c"}, got)
+	got, err = rpcListSet(current, nil)
+	require.NoError(t, err)
+	assert.Equal(t, current, got)
+
+	got, err = rpcListAdd(current, []string{"https://b", "https://c"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"https://a", "https://b", "https://c"}, got)
+
+	got, err = rpcListRemove(current, []string{"https://a"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"https://b"}, got)
+	_, err = rpcListRemove(current, []string{"https://c"})
+	assert.ErrorContains(t, err, "https://c is not configured")
+
+	// Current list is not modified
+	assert.Equal(t, []string{"https://a", "https://b"}, current)
+}
+
+func TestReplaceRpcConfigUrls(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rpc.json")
+	require.NoError(t, os.WriteFile(path, []byte(`[
+		{"chainId": 1101, "HTTP": ["https://public", "https://old"], "WS": ["wss://public", "wss://old"]},
+		{"chainId": 42161, "HTTP": ["https://old"]}
+	]`), 0644))
+
+	c := &Container{FS: files.NewFileSystemInteractor()}
+	require.NoError(t,
+		c.replaceRpcConfigUrls(path, 1101, []string{"https://old", "wss://old"}, []string{"wss://new"}, []string{"https://new"}),
+	)
+
+	contents, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.JSONEq(t, `[
+		{"chainId": 1101, "HTTP": ["https://public", "https://new"], "WS": ["wss://public", "wss://new"]},
+		{"chainId": 42161, "HTTP": ["https://old"]}
+	]`, string(contents))
+}
+
+func TestBrokerRpcRestartCmd(t *testing.T) {
+	assert.Equal(t,
+		"cd ./broker && BROKER_FEE_TBPS=60 REDIS_PW=pw docker compose up -d --force-recreate --no-deps broker",
+		brokerRpcRestartCmd(configs.D8XBrokerServerConfig{FeeTBPS: "60", RedisPassword: "pw"}),
+	)
+}
+
+func TestSwarmServiceConfigNames(t *testing.T) {
+	ctl := gomock.NewController(t)
+	sshConn := mocks.NewMockSSHConnection(ctl)
+	sshConn.EXPECT().ExecCommand(
+		`docker service inspect --format '{{range .Spec.TaskTemplate.ContainerSpec.Configs}}{{.ConfigName}}{{"\n"}}{{end}}' stack_referral`,
+	).Return([]byte("cfg_referral_rpc_v2\ncfg_referral_settings\n"), nil)
+
+	names, err := swarmServiceConfigNames(sshConn, "stack_referral")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"cfg_referral_rpc_v2", "cfg_referral_settings"}, names)
+}
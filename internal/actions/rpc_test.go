@@ -261,6 +261,212 @@ func TestDistributeRpcs(t *testing.T) {
 			wantWss:      []string{"ws-rpc-1"},
 			serviceIndex: 1,
 		},
+		{
+			name: "pinned main receives only pinned rpc",
+			cfg: &configs.D8XConfig{
+				HttpRpcList: map[string][]string{
+					"1442": {"premium", "h1", "h2", "h3"},
+				},
+				WsRpcList: map[string][]string{
+					"1442": {"w1", "w2"},
+				},
+				BrokerDeployed:  true,
+				RpcDistribution: pinnedPremiumDistribution(0),
+			},
+			chainId:      "1442",
+			wantHttp:     []string{"premium"},
+			wantWss:      []string{"w1"},
+			serviceIndex: 0,
+		},
+		{
+			name: "pinned broker receives only pinned rpc",
+			cfg: &configs.D8XConfig{
+				HttpRpcList: map[string][]string{
+					"1442": {"premium", "h1", "h2", "h3"},
+				},
+				BrokerDeployed:  true,
+				RpcDistribution: pinnedPremiumDistribution(0),
+			},
+			chainId:      "1442",
+			wantHttp:     []string{"premium"},
+			wantWss:      []string{},
+			serviceIndex: 3,
+		},
+		{
+			name: "round robin history does not receive pinned rpc",
+			cfg: &configs.D8XConfig{
+				HttpRpcList: map[string][]string{
+					"1442": {"premium", "h1", "h2", "h3"},
+				},
+				WsRpcList: map[string][]string{
+					"1442": {"w1", "w2"},
+				},
+				BrokerDeployed:  true,
+				RpcDistribution: pinnedPremiumDistribution(0),
+			},
+			chainId:      "1442",
+			wantHttp:     []string{"h1", "h3"},
+			wantWss:      []string{"w1", "w2"},
+			serviceIndex: 1,
+		},
+		{
+			name: "round robin referral does not receive pinned rpc",
+			cfg: &configs.D8XConfig{
+				HttpRpcList: map[string][]string{
+					"1442": {"premium", "h1", "h2", "h3"},
+				},
+				BrokerDeployed:  true,
+				RpcDistribution: pinnedPremiumDistribution(0),
+			},
+			chainId:      "1442",
+			wantHttp:     []string{"h2"},
+			wantWss:      []string{},
+			serviceIndex: 2,
+		},
+		{
+			name: "pinned main min count adds not pinned rpc",
+			cfg: &configs.D8XConfig{
+				HttpRpcList: map[string][]string{
+					"1442": {"premium", "h1", "h2", "h3"},
+				},
+				BrokerDeployed:  true,
+				RpcDistribution: pinnedPremiumDistribution(2),
+			},
+			chainId:      "1442",
+			wantHttp:     []string{"premium", "h1"},
+			wantWss:      []string{},
+			serviceIndex: 0,
+		},
+		{
+			name: "all rpcs pinned are shared with round robin services",
+			cfg: &configs.D8XConfig{
+				HttpRpcList: map[string][]string{
+					"1442": {"premium"},
+				},
+				RpcDistribution: pinnedPremiumDistribution(0),
+			},
+			chainId:      "1442",
+			wantHttp:     []string{"premium"},
+			wantWss:      []string{},
+			serviceIndex: 1,
+		},
+		{
+			name: "weighted main receives heavy rpc",
+			cfg: &configs.D8XConfig{
+				HttpRpcList: map[string][]string{
+					"1442": {"premium", "h1", "h2"},
+				},
+				WsRpcList: map[string][]string{
+					"1442": {"w1"},
+				},
+				BrokerDeployed:  true,
+				RpcDistribution: weightedDistribution(),
+			},
+			chainId:      "1442",
+			wantHttp:     []string{"premium"},
+			wantWss:      []string{"w1"},
+			serviceIndex: 0,
+		},
+		{
+			name: "weighted history receives light rpc",
+			cfg: &configs.D8XConfig{
+				HttpRpcList: map[string][]string{
+					"1442": {"premium", "h1", "h2"},
+				},
+				BrokerDeployed:  true,
+				RpcDistribution: weightedDistribution(),
+			},
+			chainId:      "1442",
+			wantHttp:     []string{"h1"},
+			wantWss:      []string{},
+			serviceIndex: 1,
+		},
+		{
+			name: "weighted broker receives heavy rpc",
+			cfg: &configs.D8XConfig{
+				HttpRpcList: map[string][]string{
+					"1442": {"premium", "h1", "h2"},
+				},
+				BrokerDeployed:  true,
+				RpcDistribution: weightedDistribution(),
+			},
+			chainId:      "1442",
+			wantHttp:     []string{"premium"},
+			wantWss:      []string{},
+			serviceIndex: 3,
+		},
+		{
+			name: "tiered main receives tier 0",
+			cfg: &configs.D8XConfig{
+				HttpRpcList: map[string][]string{
+					"1442": {"h1", "premium", "h2"},
+				},
+				WsRpcList: map[string][]string{
+					"1442": {"w1"},
+				},
+				RpcDistribution: tieredDistribution(0),
+			},
+			chainId:      "1442",
+			wantHttp:     []string{"premium"},
+			wantWss:      []string{"w1"},
+			serviceIndex: 0,
+		},
+		{
+			name: "tiered history receives tier 1",
+			cfg: &configs.D8XConfig{
+				HttpRpcList: map[string][]string{
+					"1442": {"h1", "premium", "h2"},
+				},
+				RpcDistribution: tieredDistribution(0),
+			},
+			chainId:      "1442",
+			wantHttp:     []string{"h1", "h2"},
+			wantWss:      []string{},
+			serviceIndex: 1,
+		},
+		{
+			name: "tiered history min count falls back to higher priority tier",
+			cfg: &configs.D8XConfig{
+				HttpRpcList: map[string][]string{
+					"1442": {"h1", "premium", "h2"},
+				},
+				RpcDistribution: tieredDistribution(3),
+			},
+			chainId:      "1442",
+			wantHttp:     []string{"h1", "h2", "premium"},
+			wantWss:      []string{},
+			serviceIndex: 1,
+		},
+		{
+			name: "tiered referral without rpcs of its tier falls back to lower tier",
+			cfg: &configs.D8XConfig{
+				HttpRpcList: map[string][]string{
+					"1442": {"h1", "premium", "h2"},
+				},
+				RpcDistribution: tieredDistribution(0),
+			},
+			chainId:      "1442",
+			wantHttp:     []string{"h1", "h2"},
+			wantWss:      []string{},
+			serviceIndex: 2,
+		},
+		{
+			name: "round robin history min count",
+			cfg: &configs.D8XConfig{
+				HttpRpcList: map[string][]string{
+					"1442": {"h1", "h2", "h3"},
+				},
+				RpcDistribution: &configs.D8XRpcDistribution{
+					Services: map[string]configs.D8XRpcServicePolicy{
+						"history": {Strategy: configs.RpcStrategyRoundRobin, MinCount: 2},
+					},
+				},
+			},
+			chainId:      "1442",
+			wantHttp:     []string{"h2", "h1"},
+			wantWss:      []string{},
+			serviceIndex: 1,
+		},
 	}
 
 	for _, tt := range tests {
@@ -271,3 +477,44 @@ func TestDistributeRpcs(t *testing.T) {
 		})
 	}
 }
+
+// pinnedPremiumDistribution pins premium rpc to main and broker, history and
+// referral use round robin
+func pinnedPremiumDistribution(mainMinCount int) *configs.D8XRpcDistribution {
+	return &configs.D8XRpcDistribution{
+		Services: map[string]configs.D8XRpcServicePolicy{
+			"main":   {Strategy: configs.RpcStrategyPinned, Urls: []string{"premium"}, MinCount: mainMinCount},
+			"broker": {Strategy: configs.RpcStrategyPinned, Urls: []string{"premium"}},
+		},
+	}
+}
+
+func weightedDistribution() *configs.D8XRpcDistribution {
+	services := map[string]configs.D8XRpcServicePolicy{}
+	for _, svc := range configs.RpcServices {
+		services[svc] = configs.D8XRpcServicePolicy{Strategy: configs.RpcStrategyWeighted}
+	}
+	return &configs.D8XRpcDistribution{
+		Services: services,
+		Endpoints: map[string]configs.D8XRpcEndpoint{
+			"premium": {Weight: 2},
+		},
+	}
+}
+
+// tieredDistribution puts premium rpc into tier 0 and other rpcs into tier 1.
+// Main uses tier 0, history tier 1 and referral tier 2.
+func tieredDistribution(historyMinCount int) *configs.D8XRpcDistribution {
+	return &configs.D8XRpcDistribution{
+		Services: map[string]configs.D8XRpcServicePolicy{
+			"main":     {Strategy: configs.RpcStrategyTiered, Tier: 0},
+			"history":  {Strategy: configs.RpcStrategyTiered, Tier: 1, MinCount: historyMinCount},
+			"referral": {Strategy: configs.RpcStrategyTiered, Tier: 2},
+		},
+		Endpoints: map[string]configs.D8XRpcEndpoint{
+			"h1": {Tier: 1},
+			"h2": {Tier: 1},
+			"w1": {Tier: 1},
+		},
+	}
+}
@@ -0,0 +1,476 @@
+package actions
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/D8-X/d8x-cli/internal/components"
+	"github.com/D8-X/d8x-cli/internal/configs"
+	"github.com/D8-X/d8x-cli/internal/conn"
+	"github.com/D8-X/d8x-cli/internal/styles"
+	"github.com/urfave/cli/v2"
+)
+
+var (
+	// How long to wait for tasks of drained worker to be rescheduled
+	scaleDrainTimeout = time.Minute * 5
+	// Interval between drained worker task checks
+	scaleDrainPollInterval = time.Second * 5
+)
+
+// workerHostname returns hostname of worker with given index in hosts.cfg, the
+// same as assigned by terraform inventory templates
+func workerHostname(index int) string {
+	return fmt.Sprintf("worker-%02d", index+1)
+}
+
+// ScaleWorkers changes the number of swarm worker servers. New workers are
+// provisioned, configured and joined to the swarm, removed workers are drained
+// and removed from the swarm before they are destroyed.
+func (c *Container) ScaleWorkers(ctx *cli.Context) error {
+	styles.PrintCommandTitle("Scaling swarm workers...")
+
+	numWorkers, err := strconv.Atoi(ctx.Args().First())
+	if err != nil || numWorkers < 1 {
+		return fmt.Errorf("number of workers must be a positive number")
+	}
+
+	cfg, err := c.ConfigRWriter.Read()
+	if err != nil {
+		return err
+	}
+	if !cfg.ConfigDetails.Done {
+		return fmt.Errorf("servers are not configured yet, please run setup first")
+	}
+
+	managerIp, err := c.HostsCfg.GetMangerPublicIp()
+	if err != nil {
+		return fmt.Errorf("finding manager ip address: %w", err)
+	}
+	workerIps, err := c.HostsCfg.GetWorkerIps()
+	if err != nil {
+		return fmt.Errorf("finding worker ip addresses: %w", err)
+	}
+
+	if numWorkers == len(workerIps) {
+		fmt.Printf("Swarm already has %d workers, nothing to do\n", numWorkers)
+		return nil
+	}
+
+	configurer, err := c.scaledServerProviderConfigurer(cfg, numWorkers)
+	if err != nil {
+		return err
+	}
+
+	pwd, err := c.GetPassword(ctx)
+	if err != nil {
+		return err
+	}
+	c.UserPassword = pwd
+
+	managerSSHConn, err := c.CreateSSHConn(managerIp, c.DefaultClusterUserName, c.SshKeyPath)
+	if err != nil {
+		return err
+	}
+
+	// Scale down - drain and remove workers from swarm before destroying them
+	removedIps := []string{}
+	if numWorkers < len(workerIps) {
+		removedIps, err = c.removedWorkerIps(cfg, workerIps, numWorkers)
+		if err != nil {
+			return err
+		}
+		// Static servers are not managed by d8x, they are only left out of
+		// the swarm
+		removal := "removed from the swarm and destroyed"
+		if cfg.ServerProvider == configs.D8XServerProviderStatic {
+			removal = "removed from the swarm (servers are left running)"
+		}
+		ok, err := c.TUI.NewPrompt(
+			fmt.Sprintf("Workers %s will be %s. Do you want to continue?", strings.Join(removedIps, ", "), removal),
+			false,
+			components.PromptOptId("scale.confirm"),
+		)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			fmt.Println("Not scaling...")
+			return nil
+		}
+
+		for i, ip := range removedIps {
+			if err := c.drainAndRemoveWorker(managerSSHConn, cfg, ip, workerHostname(numWorkers+i)); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Provision servers with the new workers count
+	tfCmd, err := configurer.BuildTerraformCMD(c)
+	if err != nil {
+		return err
+	}
+	if err := c.runTerraformApply(tfCmd); err != nil {
+		return err
+	}
+
+	cfg.ConfigDetails.ConfiguredServers = slices.DeleteFunc(cfg.ConfigDetails.ConfiguredServers, func(ip string) bool {
+		return slices.Contains(removedIps, ip)
+	})
+	// Terraform overwrites hosts.cfg, use cluster user for configured servers
+	if cfg.HasPublicServers() {
+		if err := c.LinodeInventorySetUserVar(cfg.ConfigDetails.ConfiguredServers, c.DefaultClusterUserName); err != nil {
+			return fmt.Errorf("updating inventory file: %w", err)
+		}
+	}
+	if err := c.ConfigRWriter.Write(cfg); err != nil {
+		return err
+	}
+
+	newWorkerIps, err := c.HostsCfg.GetWorkerIps()
+	if err != nil {
+		return fmt.Errorf("finding worker ip addresses: %w", err)
+	}
+	addedIps := slices.DeleteFunc(slices.Clone(newWorkerIps), func(ip string) bool {
+		return slices.Contains(workerIps, ip)
+	})
+
+	// Keep known_hosts in sync with the current workers. Keys of static
+	// servers are recorded when their reachability is checked.
+	if c.KnownHosts != nil {
+		for _, ip := range removedIps {
+			if _, err := c.KnownHosts.Remove(conn.HostAddr(ip)); err != nil {
+				return err
+			}
+		}
+	}
+	if tfCmd != nil {
+		var bastion conn.SSHConnection
+		if !cfg.HasPublicServers() {
+			bastion = managerSSHConn
+		}
+		c.pinHostKeys(bastion, addedIps...)
+	}
+
+	// Scale up - configure new workers and join them to the swarm
+	if len(addedIps) > 0 {
+		if err := c.joinNewWorkers(managerSSHConn, cfg, addedIps); err != nil {
+			return err
+		}
+	}
+
+	if cfg.SwarmDeployed {
+		if err := c.updateWorkersNfs(managerSSHConn, cfg, pwd, addedIps); err != nil {
+			return err
+		}
+	}
+
+	if cfg.MetricsDeployed {
+		if err := c.updateWorkersMetrics(managerSSHConn, cfg, pwd, addedIps); err != nil {
+			return err
+		}
+	}
+
+	fmt.Println(styles.SuccessText.Render(fmt.Sprintf("Swarm was scaled to %d workers", numWorkers)))
+
+	return nil
+}
+
+// tfWorkerResource is the terraform resource of swarm workers created with
+// count, see embedded terraform files of providers
+type tfWorkerResource struct {
+	module string
+	typ    string
+	// Attribute which holds the worker ip address used in hosts.cfg
+	ipAttr string
+}
+
+var tfWorkerResources = map[configs.D8XServerProvider]tfWorkerResource{
+	configs.D8XServerProviderLinode:  {typ: "linode_instance", ipAttr: "ip_address"},
+	configs.D8XServerProviderHetzner: {typ: "hcloud_server", ipAttr: "ipv4_address"},
+	configs.D8XServerProviderAWS:     {module: "module.swarm_servers[0]", typ: "aws_instance", ipAttr: "private_ip"},
+}
+
+// tfWorkerIps returns ip addresses of worker resources in terraform state file
+// at path, ordered by their count index
+func tfWorkerIps(path string, res tfWorkerResource) ([]string, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading terraform state: %w", err)
+	}
+	state := struct {
+		Resources []struct {
+			Module    string `json:"module"`
+			Mode      string `json:"mode"`
+			Type      string `json:"type"`
+			Name      string `json:"name"`
+			Instances []struct {
+				IndexKey   int            `json:"index_key"`
+				Attributes map[string]any `json:"attributes"`
+			} `json:"instances"`
+		} `json:"resources"`
+	}{}
+	if err := json.Unmarshal(contents, &state); err != nil {
+		return nil, fmt.Errorf("parsing terraform state %s: %w", path, err)
+	}
+
+	for _, r := range state.Resources {
+		if r.Module != res.module || r.Mode != "managed" || r.Type != res.typ || r.Name != "nodes" {
+			continue
+		}
+		ips := make([]string, len(r.Instances))
+		for _, inst := range r.Instances {
+			ip, _ := inst.Attributes[res.ipAttr].(string)
+			if inst.IndexKey < 0 || inst.IndexKey >= len(ips) || ip == "" {
+				return nil, fmt.Errorf("unexpected worker %s.nodes[%d] in terraform state", res.typ, inst.IndexKey)
+			}
+			ips[inst.IndexKey] = ip
+		}
+		return ips, nil
+	}
+	return []string{}, nil
+}
+
+// removedWorkerIps returns ip addresses of workers which are removed when
+// scaling down to numWorkers. Terraform destroys workers with the highest
+// count indexes, therefore they are looked up in terraform state instead of
+// relying on the order of hosts.cfg.
+func (c *Container) removedWorkerIps(cfg *configs.D8XConfig, workerIps []string, numWorkers int) ([]string, error) {
+	res, ok := tfWorkerResources[cfg.ServerProvider]
+	if !ok {
+		// Static workers are removed from the end of static_config.worker_ips
+		return workerIps[numWorkers:], nil
+	}
+
+	tfIps, err := tfWorkerIps(filepath.Join(c.ProvisioningTfDir, "terraform.tfstate"), res)
+	if err != nil {
+		return nil, err
+	}
+	if len(tfIps) != len(workerIps) {
+		return nil, fmt.Errorf("terraform state contains %d workers, but hosts.cfg contains %d, run d8x setup provision first", len(tfIps), len(workerIps))
+	}
+	for _, ip := range tfIps {
+		if !slices.Contains(workerIps, ip) {
+			return nil, fmt.Errorf("worker %s from terraform state is not in hosts.cfg, run d8x setup provision first", ip)
+		}
+	}
+	return tfIps[numWorkers:], nil
+}
+
+// scaledServerProviderConfigurer updates the number of workers in cfg provider
+// details and returns the ServerProviderConfigurer for it
+func (c *Container) scaledServerProviderConfigurer(cfg *configs.D8XConfig, numWorkers int) (ServerProviderConfigurer, error) {
+	if cfg.ServerProvider == configs.D8XServerProviderStatic {
+		s := cfg.StaticConfig
+		if s == nil {
+			return nil, fmt.Errorf("static config is not defined")
+		}
+		if len(s.WorkerIps) < numWorkers {
+			return nil, fmt.Errorf(
+				"static_config contains only %d worker ip addresses, please add ip addresses of new workers to static_config.worker_ips in %s",
+				len(s.WorkerIps),
+				configs.DEFAULT_D8X_CONFIG_NAME,
+			)
+		}
+		s.WorkerIps = s.WorkerIps[:numWorkers]
+		if len(s.WorkerPrivateIps) > numWorkers {
+			s.WorkerPrivateIps = s.WorkerPrivateIps[:numWorkers]
+		}
+		return staticConfigurer{D8XStaticConfig: *s}, nil
+	}
+
+	authorizedKey, err := getPublicKey(c.SshKeyPath)
+	if err != nil {
+		return nil, err
+	}
+
+	switch cfg.ServerProvider {
+	case configs.D8XServerProviderLinode:
+		if cfg.LinodeConfig == nil {
+			return nil, fmt.Errorf("linode config is not defined")
+		}
+		cfg.LinodeConfig.NumWorker = numWorkers
+		return linodeConfigurer{D8XLinodeConfig: *cfg.LinodeConfig, authorizedKey: authorizedKey}, nil
+	case configs.D8XServerProviderAWS:
+		if cfg.AWSConfig == nil {
+			return nil, fmt.Errorf("aws config is not defined")
+		}
+		cfg.AWSConfig.NumWorker = numWorkers
+		return &awsConfigurer{D8XAWSConfig: *cfg.AWSConfig, authorizedKey: authorizedKey}, nil
+	case configs.D8XServerProviderHetzner:
+		if cfg.HetznerConfig == nil {
+			return nil, fmt.Errorf("hetzner config is not defined")
+		}
+		cfg.HetznerConfig.NumWorker = numWorkers
+		return hetznerConfigurer{D8XHetznerConfig: *cfg.HetznerConfig, authorizedKey: authorizedKey}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported server provider: %s", cfg.ServerProvider)
+}
+
+// drainAndRemoveWorker drains worker node, waits until its tasks are
+// rescheduled on other nodes and removes it from the swarm
+func (c *Container) drainAndRemoveWorker(managerSSHConn conn.SSHConnection, cfg *configs.D8XConfig, workerIp, hostname string) error {
+	fmt.Println(styles.ItalicText.Render(fmt.Sprintf("Draining worker %s (%s)...", hostname, workerIp)))
+	if out, err := managerSSHConn.ExecCommand(
+		fmt.Sprintf("docker node update --availability drain %s", hostname),
+	); err != nil {
+		fmt.Println(string(out))
+		return fmt.Errorf("draining worker %s: %w", hostname, err)
+	}
+
+	if c.DryRun == nil {
+		deadline := time.Now().Add(scaleDrainTimeout)
+		for {
+			out, err := managerSSHConn.ExecCommand(
+				fmt.Sprintf("docker node ps %s --filter desired-state=running --format '{{.ID}}'", hostname),
+			)
+			if err != nil {
+				return fmt.Errorf("listing tasks of worker %s: %w", hostname, err)
+			}
+			if strings.TrimSpace(string(out)) == "" {
+				break
+			}
+			if time.Now().After(deadline) {
+				return fmt.Errorf("tasks of worker %s were not rescheduled within %s", hostname, scaleDrainTimeout)
+			}
+			fmt.Printf("Waiting for tasks of %s to be rescheduled...\n", hostname)
+			time.Sleep(scaleDrainPollInterval)
+		}
+	}
+	fmt.Printf("Tasks of %s were rescheduled\n", hostname)
+
+	// Leaving the swarm is not critical, node is removed forcefully from
+	// manager anyway
+	if workerConn, err := c.GetWorkerConnection(workerIp, cfg); err == nil {
+		if out, err := workerConn.ExecCommand("docker swarm leave"); err != nil {
+			fmt.Println(string(out))
+		}
+	}
+
+	if out, err := managerSSHConn.ExecCommand(
+		fmt.Sprintf("docker node rm --force %s", hostname),
+	); err != nil {
+		fmt.Println(string(out))
+		return fmt.Errorf("removing worker %s from swarm: %w", hostname, err)
+	}
+	fmt.Println(styles.SuccessText.Render(fmt.Sprintf("Worker %s was removed from swarm", hostname)))
+
+	return nil
+}
+
+// joinNewWorkers runs setup playbook only on new workers and joins them to the
+// swarm
+func (c *Container) joinNewWorkers(managerSSHConn conn.SSHConnection, cfg *configs.D8XConfig, newIps []string) error {
+	out, err := managerSSHConn.ExecCommand("docker swarm join-token -q worker")
+	if err != nil {
+		fmt.Println(string(out))
+		return fmt.Errorf("retrieving swarm join token: %w", err)
+	}
+	joinToken := strings.TrimSpace(string(out))
+
+	fmt.Println(styles.ItalicText.Render(fmt.Sprintf("Configuring new workers %s...", strings.Join(newIps, ", "))))
+	if err := c.runSetupPlaybook(cfg,
+		"--limit", strings.Join(newIps, ","),
+		"--extra-vars", fmt.Sprintf("worker_join_token=%s", joinToken),
+	); err != nil {
+		return err
+	}
+
+	cfg.ConfigDetails.ConfiguredServers = append(cfg.ConfigDetails.ConfiguredServers, newIps...)
+	if cfg.HasPublicServers() {
+		if err := c.LinodeInventorySetUserVar(cfg.ConfigDetails.ConfiguredServers, c.DefaultClusterUserName); err != nil {
+			return fmt.Errorf("updating inventory file: %w", err)
+		}
+	}
+
+	return c.ConfigRWriter.Write(cfg)
+}
+
+// updateWorkersNfs updates NFS exports on manager to match current workers and
+// mounts NFS volume on new workers
+func (c *Container) updateWorkersNfs(managerSSHConn conn.SSHConnection, cfg *configs.D8XConfig, pwd string, addedIps []string) error {
+	ipMgrPriv, err := c.HostsCfg.GetMangerPrivateIp()
+	if err != nil {
+		return err
+	}
+	ipWorkersPriv, err := c.HostsCfg.GetWorkerPrivateIps()
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(styles.ItalicText.Render("Updating NFS exports..."))
+	if err := c.FS.WriteFile("./trader-backend/exports", []byte(nfsExportsConfig(ipWorkersPriv))); err != nil {
+		return fmt.Errorf("temp storage of /etc/exports file failed: %w", err)
+	}
+	if err := managerSSHConn.CopyFilesOverSftp(
+		conn.SftpCopySrcDest{Src: "./trader-backend/exports", Dst: "./trader-backend/exports"},
+	); err != nil {
+		return fmt.Errorf("copying exports file to manager: %w", err)
+	}
+	cmd := ""
+	for _, ip := range ipWorkersPriv {
+		cmd = cmd + nfsUfwAllowCmd(pwd, ip) + "&& "
+	}
+	cmd = cmd + fmt.Sprintf(`echo '%s' | sudo -S bash -c "cp ./trader-backend/exports /etc/exports && exportfs -ra"`, pwd)
+	if out, err := managerSSHConn.ExecCommand(cmd); err != nil {
+		fmt.Println(string(out))
+		return fmt.Errorf("updating NFS exports on manager: %w", err)
+	}
+
+	for _, ip := range addedIps {
+		fmt.Println(styles.ItalicText.Render("Mounting NFS directory on worker "), ip)
+		workerConn, err := c.GetWorkerConnection(ip, cfg)
+		if err != nil {
+			return err
+		}
+		if out, err := workerConn.ExecCommand(nfsMountCmd(pwd, ipMgrPriv)); err != nil {
+			fmt.Println(string(out))
+			return fmt.Errorf("failed to mount nfs dir on worker: %w", err)
+		}
+		if out, err := workerConn.ExecCommand(nfsVolumeCreateCmd(ipMgrPriv)); err != nil {
+			fmt.Println(string(out))
+			return fmt.Errorf("creating volume on worker failed: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// updateWorkersMetrics updates prometheus cadvisor targets on manager and
+// blocks cadvisor port on new workers
+func (c *Container) updateWorkersMetrics(managerSSHConn conn.SSHConnection, cfg *configs.D8XConfig, pwd string, addedIps []string) error {
+	workerIPs, err := c.HostsCfg.GetWorkerPrivateIps()
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(styles.ItalicText.Render("Updating prometheus targets..."))
+	if err := c.writePrometheusTargets("./prometheus.yml", workerIPs); err != nil {
+		return fmt.Errorf("updating prometheus targets: %w", err)
+	}
+	if err := managerSSHConn.CopyFilesOverSftp(
+		conn.SftpCopySrcDest{Src: "./prometheus.yml", Dst: "./prometheus.yml"},
+	); err != nil {
+		return fmt.Errorf("copying prometheus config to manager: %w", err)
+	}
+	if out, err := managerSSHConn.ExecCommand(
+		"docker compose -f docker-swarm-metrics.yml up -d --force-recreate prometheus",
+	); err != nil {
+		fmt.Println(string(out))
+		return fmt.Errorf("restarting prometheus: %w", err)
+	}
+
+	if cfg.HasPublicServers() && len(addedIps) > 0 {
+		c.blockCadvisorPort(addedIps, pwd)
+	}
+
+	return nil
+}
@@ -0,0 +1,132 @@
+package actions
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/D8-X/d8x-cli/internal/configs"
+	"github.com/D8-X/d8x-cli/internal/conn"
+	"github.com/D8-X/d8x-cli/internal/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestDrainAndRemoveWorker(t *testing.T) {
+	oldTimeout, oldInterval := scaleDrainTimeout, scaleDrainPollInterval
+	scaleDrainTimeout, scaleDrainPollInterval = time.Millisecond*50, time.Millisecond
+	defer func() {
+		scaleDrainTimeout, scaleDrainPollInterval = oldTimeout, oldInterval
+	}()
+
+	psCmd := "docker node ps worker-03 --filter desired-state=running --format '{{.ID}}'"
+
+	tests := []struct {
+		name    string
+		expect  func(manager, worker *mocks.MockSSHConnection)
+		wantErr string
+	}{
+		{
+			name: "tasks rescheduled",
+			expect: func(manager, worker *mocks.MockSSHConnection) {
+				gomock.InOrder(
+					manager.EXPECT().ExecCommand("docker node update --availability drain worker-03").Return(nil, nil),
+					manager.EXPECT().ExecCommand(psCmd).Return([]byte("abc\n"), nil),
+					manager.EXPECT().ExecCommand(psCmd).Return([]byte(""), nil),
+					worker.EXPECT().ExecCommand("docker swarm leave").Return(nil, nil),
+					manager.EXPECT().ExecCommand("docker node rm --force worker-03").Return(nil, nil),
+				)
+			},
+		},
+		{
+			name: "tasks not rescheduled",
+			expect: func(manager, worker *mocks.MockSSHConnection) {
+				manager.EXPECT().ExecCommand("docker node update --availability drain worker-03").Return(nil, nil)
+				manager.EXPECT().ExecCommand(psCmd).Return([]byte("abc\n"), nil).MinTimes(1)
+			},
+			wantErr: "tasks of worker worker-03 were not rescheduled within 50ms",
+		},
+		{
+			name: "drain failed",
+			expect: func(manager, worker *mocks.MockSSHConnection) {
+				manager.EXPECT().ExecCommand("docker node update --availability drain worker-03").Return(nil, assert.AnError)
+			},
+			wantErr: "draining worker worker-03: " + assert.AnError.Error(),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctl := gomock.NewController(t)
+			manager := mocks.NewMockSSHConnection(ctl)
+			worker := mocks.NewMockSSHConnection(ctl)
+			tt.expect(manager, worker)
+
+			c := &Container{
+				CreateSSHConn: func(serverIp, user, idFilePath string) (conn.SSHConnection, error) {
+					return worker, nil
+				},
+			}
+			cfg := &configs.D8XConfig{ServerProvider: configs.D8XServerProviderLinode}
+
+			err := c.drainAndRemoveWorker(manager, cfg, "1.1.1.3", "worker-03")
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestScaledServerProviderConfigurerStatic(t *testing.T) {
+	cfg := &configs.D8XConfig{
+		ServerProvider: configs.D8XServerProviderStatic,
+		StaticConfig: &configs.D8XStaticConfig{
+			ManagerIp:        "1.1.1.1",
+			WorkerIps:        []string{"1.1.1.2", "1.1.1.3", "1.1.1.4"},
+			WorkerPrivateIps: []string{"10.0.0.2", "10.0.0.3", "10.0.0.4"},
+			DeploySwarm:      true,
+		},
+	}
+	c := &Container{}
+
+	_, err := c.scaledServerProviderConfigurer(cfg, 4)
+	assert.EqualError(t, err, "static_config contains only 3 worker ip addresses, please add ip addresses of new workers to static_config.worker_ips in d8x.conf.json")
+
+	configurer, err := c.scaledServerProviderConfigurer(cfg, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1.1.1.2", "1.1.1.3"}, configurer.(staticConfigurer).WorkerIps)
+	assert.Equal(t, []string{"10.0.0.2", "10.0.0.3"}, configurer.(staticConfigurer).WorkerPrivateIps)
+	assert.Equal(t, []string{"1.1.1.2", "1.1.1.3"}, cfg.StaticConfig.WorkerIps)
+}
+
+func TestRemovedWorkerIps(t *testing.T) {
+	tfDir := t.TempDir()
+	state := `{"resources": [
+	{"mode": "managed", "type": "linode_instance", "name": "manager", "instances": [{"attributes": {"ip_address": "10.0.0.1"}}]},
+	{"mode": "managed", "type": "linode_instance", "name": "nodes", "instances": [
+		{"index_key": 2, "attributes": {"ip_address": "10.0.0.4"}},
+		{"index_key": 0, "attributes": {"ip_address": "10.0.0.2"}},
+		{"index_key": 1, "attributes": {"ip_address": "10.0.0.3"}}
+	]}
+]}`
+	require.NoError(t, os.WriteFile(filepath.Join(tfDir, "terraform.tfstate"), []byte(state), 0600))
+	c := &Container{ProvisioningTfDir: tfDir}
+	cfg := &configs.D8XConfig{ServerProvider: configs.D8XServerProviderLinode}
+
+	// hosts.cfg order differs from terraform count index order
+	removed, err := c.removedWorkerIps(cfg, []string{"10.0.0.4", "10.0.0.2", "10.0.0.3"}, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.3", "10.0.0.4"}, removed)
+
+	_, err = c.removedWorkerIps(cfg, []string{"10.0.0.2", "10.0.0.3"}, 1)
+	assert.ErrorContains(t, err, "terraform state contains 3 workers, but hosts.cfg contains 2")
+
+	cfg.ServerProvider = configs.D8XServerProviderStatic
+	removed, err = c.removedWorkerIps(cfg, []string{"10.0.0.4", "10.0.0.2"}, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.2"}, removed)
+}
@@ -0,0 +1,234 @@
+package actions
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/D8-X/d8x-cli/internal/components"
+	"github.com/D8-X/d8x-cli/internal/configs"
+	"github.com/D8-X/d8x-cli/internal/styles"
+	"github.com/urfave/cli/v2"
+)
+
+// secretsVaultPath returns the path of encrypted secrets vault in config
+// directory
+func (c *Container) secretsVaultPath() string {
+	return filepath.Join(c.ConfigDir, configs.DEFAULT_SECRETS_VAULT_NAME)
+}
+
+// InitSecretsStore switches the container to encrypted secrets vault whenever
+// it exists in the config directory. Otherwise legacy plain text secrets are
+// used.
+func (c *Container) InitSecretsStore() {
+	store := configs.NewVaultSecretsStore(c.secretsVaultPath(), c.VaultPassphrase)
+	if !store.Exists() {
+		return
+	}
+	c.Secrets = store
+	c.ConfigRWriter = configs.NewSecretsD8XConfigRW(c.ConfigRWriter, store)
+}
+
+// keyringAccount returns the os keyring account name for current vault
+func (c *Container) keyringAccount() string {
+	abs, err := filepath.Abs(c.secretsVaultPath())
+	if err != nil {
+		return c.secretsVaultPath()
+	}
+	return abs
+}
+
+// VaultPassphrase retrieves the secrets vault passphrase with the following
+// precedence:
+// 1. D8X_VAULT_PASSPHRASE environment variable
+// 2. OS keyring
+// 3. User input
+func (c *Container) VaultPassphrase() (string, error) {
+	if pass := os.Getenv(configs.VAULT_PASSPHRASE_ENV); pass != "" {
+		return pass, nil
+	}
+	if pass, err := configs.KeyringGet(c.keyringAccount()); err == nil {
+		return pass, nil
+	}
+
+	fmt.Println("Enter secrets vault passphrase:")
+	return c.TUI.NewInput(
+		components.TextInputOptId("secrets.vault_passphrase"),
+		components.TextInputOptPlaceholder("<VAULT PASSPHRASE>"),
+		components.TextInputOptMasked(),
+		components.TextInputOptDenyEmpty(),
+	)
+}
+
+// newVaultPassphrase collects a new passphrase for the secrets vault
+func (c *Container) newVaultPassphrase() (string, error) {
+	if pass := os.Getenv(configs.VAULT_PASSPHRASE_ENV); pass != "" {
+		return pass, nil
+	}
+
+	for {
+		fmt.Println("Enter new secrets vault passphrase:")
+		pass, err := c.TUI.NewInput(
+			components.TextInputOptId("secrets.new_vault_passphrase"),
+			components.TextInputOptPlaceholder("<VAULT PASSPHRASE>"),
+			components.TextInputOptMasked(),
+			components.TextInputOptDenyEmpty(),
+		)
+		if err != nil {
+			return "", err
+		}
+		fmt.Println("Repeat the passphrase:")
+		repeated, err := c.TUI.NewInput(
+			components.TextInputOptId("secrets.new_vault_passphrase.confirm"),
+			components.TextInputOptPlaceholder("<VAULT PASSPHRASE>"),
+			components.TextInputOptMasked(),
+			components.TextInputOptDenyEmpty(),
+		)
+		if err != nil {
+			return "", err
+		}
+		if pass == repeated {
+			return pass, nil
+		}
+		if err := components.RejectAnswer(c.TUI, "secrets.new_vault_passphrase.confirm", "passphrases do not match"); err != nil {
+			return "", err
+		}
+		fmt.Println(styles.ErrorText.Render("Passphrases do not match, please try again..."))
+	}
+}
+
+// SecretsMigrate moves all secrets from plain text d8x.conf.json,
+// password.txt and redis_broker_password.txt to encrypted secrets vault
+func (c *Container) SecretsMigrate(ctx *cli.Context) error {
+	styles.PrintCommandTitle("Migrating secrets to encrypted vault...")
+
+	cfg, err := c.ConfigRWriter.Read()
+	if err != nil {
+		return err
+	}
+
+	vaultPath := c.secretsVaultPath()
+	vaultExists := c.Secrets != nil && c.Secrets.Exists()
+
+	// Determine the passphrase of the vault
+	var passphrase string
+	switch {
+	case vaultExists:
+		passphrase, err = c.VaultPassphrase()
+	case ctx.Bool("keyring"):
+		passphrase, err = generatePassword(32)
+	default:
+		passphrase, err = c.newVaultPassphrase()
+	}
+	if err != nil {
+		return err
+	}
+	if ctx.Bool("keyring") {
+		if err := configs.KeyringSet(c.keyringAccount(), passphrase); err != nil {
+			return err
+		}
+		fmt.Println("Vault passphrase was stored in os keyring")
+	}
+
+	store := configs.NewVaultSecretsStore(vaultPath, func() (string, error) {
+		return passphrase, nil
+	})
+	secrets, err := store.Load()
+	if err != nil {
+		return err
+	}
+
+	// Legacy plain text files
+	migratedFiles := []string{}
+	if pwd, err := os.ReadFile(configs.DEFAULT_PASSWORD_FILE); err == nil {
+		secrets[configs.SecretUserPassword] = string(pwd)
+		migratedFiles = append(migratedFiles, configs.DEFAULT_PASSWORD_FILE)
+	} else if c.UserPassword != "" {
+		secrets[configs.SecretUserPassword] = c.UserPassword
+	}
+	if pwd, err := os.ReadFile(BROKER_SERVER_REDIS_PWD_FILE); err == nil {
+		if cfg.BrokerServerConfig.RedisPassword == "" {
+			cfg.BrokerServerConfig.RedisPassword = string(pwd)
+		}
+		migratedFiles = append(migratedFiles, BROKER_SERVER_REDIS_PWD_FILE)
+	}
+	if err := store.Save(secrets); err != nil {
+		return err
+	}
+
+	// Rewriting the config moves all config secrets to the vault
+	rw := configs.NewSecretsD8XConfigRW(
+		configs.NewFileBasedD8XConfigRW(c.ConfigRWriter.GetPath()),
+		store,
+	)
+	if err := rw.Write(cfg); err != nil {
+		return fmt.Errorf("writing config without secrets: %w", err)
+	}
+	c.Secrets = store
+	c.ConfigRWriter = rw
+
+	if !ctx.Bool("keep-files") {
+		for _, f := range migratedFiles {
+			if err := os.Remove(f); err != nil {
+				return fmt.Errorf("removing %s: %w", f, err)
+			}
+			fmt.Printf("Removed %s\n", f)
+		}
+	}
+
+	fmt.Println(styles.SuccessText.Render("Secrets were migrated to " + vaultPath))
+	if !ctx.Bool("keyring") {
+		fmt.Println(styles.AlertImportant.Render("Make sure to remember your vault passphrase, secrets can not be recovered without it!"))
+	}
+
+	return nil
+}
+
+// SecretsList lists the names of secrets stored in the vault
+func (c *Container) SecretsList(ctx *cli.Context) error {
+	if c.Secrets == nil {
+		return errSecretsVaultNotFound
+	}
+	secrets, err := c.Secrets.Load()
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(secrets))
+	for name := range secrets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Println(name)
+	}
+
+	return nil
+}
+
+// SecretsGet prints the value of a single secret from the vault
+func (c *Container) SecretsGet(ctx *cli.Context) error {
+	if c.Secrets == nil {
+		return errSecretsVaultNotFound
+	}
+	name := ctx.Args().First()
+	if name == "" {
+		return fmt.Errorf("secret name must be provided, see d8x secrets list")
+	}
+
+	secrets, err := c.Secrets.Load()
+	if err != nil {
+		return err
+	}
+	v, ok := secrets[name]
+	if !ok {
+		return fmt.Errorf("secret %s was not found", name)
+	}
+	fmt.Println(v)
+
+	return nil
+}
+
+var errSecretsVaultNotFound = errors.New("secrets vault was not found, run d8x secrets migrate first")
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/D8-X/d8x-cli/internal/components"
 	"github.com/D8-X/d8x-cli/internal/configs"
 	"github.com/D8-X/d8x-cli/internal/styles"
 	"github.com/urfave/cli/v2"
@@ -27,6 +28,7 @@ func (c *Container) Setup(ctx *cli.Context) error {
 		keepConfig, err := c.TUI.NewPrompt(
 			fmt.Sprintf("Existing configuration (%s) was found. Do you want to use it?", cfg.ServerProvider),
 			true,
+			components.PromptOptId("setup.keep_config"),
 		)
 
 		if err != nil {
@@ -38,7 +40,7 @@ func (c *Container) Setup(ctx *cli.Context) error {
 			fmt.Println(
 				styles.AlertImportant.Render("Warning! Existing configuration will be completely removed!"),
 			)
-			if yes, err := c.TUI.NewPrompt("Are you sure you want to continue?", false); err != nil {
+			if yes, err := c.TUI.NewPrompt("Are you sure you want to continue?", false, components.PromptOptId("setup.remove_config_confirm")); err != nil {
 				return err
 			} else if yes {
 				// Make a backup of the existing config just in case
@@ -75,13 +77,14 @@ func (c *Container) Setup(ctx *cli.Context) error {
 	// If configuration fails we might still want to proceed with other actions
 	// in case this is a retry
 	if err := c.Configure(ctx); err != nil {
-		// On linode: when subsequent setup runs are performed, old servers will
-		// not be accessible because of permit root login is set to false and we
-		// can't provide dynamic user list to ansible.
-		if cfg.ServerProvider == configs.D8XServerProviderLinode && (cfg.SwarmDeployed || cfg.BrokerDeployed) {
+		// On linode, hetzner and static servers: when subsequent setup runs
+		// are performed, old servers will not be accessible because of permit
+		// root login is set to false and we can't provide dynamic user list to
+		// ansible.
+		if cfg.HasPublicServers() && (cfg.SwarmDeployed || cfg.BrokerDeployed) {
 			fmt.Println("Some configuration steps failed, but we will continue with other actions...")
 		} else {
-			if ok, _ := c.TUI.NewPrompt("Configuration failed, do you want to continue?", false); !ok {
+			if ok, _ := c.TUI.NewPrompt("Configuration failed, do you want to continue?", false, components.PromptOptId("setup.continue_on_configure_failure")); !ok {
 				return err
 			}
 		}
@@ -118,7 +121,7 @@ func (c *Container) Setup(ctx *cli.Context) error {
 		}
 	}
 
-	if ok, _ := c.TUI.NewPrompt("Do you want to perform services healthchecks?", true); ok {
+	if ok, _ := c.TUI.NewPrompt("Do you want to perform services healthchecks?", true, components.PromptOptId("setup.healthchecks")); ok {
 		if err := c.HealthCheck(ctx); err != nil {
 			return err
 		}
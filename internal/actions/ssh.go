@@ -23,36 +23,7 @@ func (c *Container) SSH(ctx *cli.Context) error {
 	}
 
 	serverName := ctx.Args().First()
-	ip := ""
-	isWorker := false
-	switch serverName {
-	case "manager":
-		ip, err = c.HostsCfg.GetMangerPublicIp()
-	case "broker":
-		ip, err = c.HostsCfg.GetBrokerPublicIp()
-	default:
-		// Parse workers
-		if strings.HasPrefix(serverName, "worker-") {
-			ips, err := c.HostsCfg.GetWorkerIps()
-			if err != nil {
-				return err
-			}
-			parsedWorkerNum, err := strconv.Atoi(strings.Split(serverName, "worker-")[1])
-			if err != nil {
-				return fmt.Errorf("Incorrect worker name was passed. Accepted values are worker-1, worker-2, worker-3, worker-*...")
-			}
-
-			isWorker = true
-
-			if parsedWorkerNum <= len(ips) {
-				ip = ips[parsedWorkerNum-1]
-				break
-			}
-		}
-
-		return fmt.Errorf("Incorrect server name was passed. Accepted values are manager, broker, worker-* (where * is a digit)")
-	}
-
+	ip, isWorker, err := c.resolveNodeIp(serverName)
 	if err != nil {
 		return err
 	}
@@ -69,7 +40,7 @@ func (c *Container) SSH(ctx *cli.Context) error {
 	if isWorker {
 		cn, connErr = c.GetWorkerConnection(ip, cfg)
 	} else {
-		cn, connErr = conn.NewSSHConnection(ip, c.DefaultClusterUserName, c.SshKeyPath)
+		cn, connErr = c.CreateSSHConn(ip, c.DefaultClusterUserName, c.SshKeyPath)
 	}
 	if connErr != nil {
 		return connErr
@@ -118,6 +89,36 @@ func (c *Container) SSH(ctx *cli.Context) error {
 	return nil
 }
 
+// resolveNodeIp finds the ip address of manager, broker or worker-* server in
+// hosts.cfg
+func (c *Container) resolveNodeIp(serverName string) (ip string, isWorker bool, err error) {
+	switch serverName {
+	case "manager":
+		ip, err = c.HostsCfg.GetMangerPublicIp()
+		return ip, false, err
+	case "broker":
+		ip, err = c.HostsCfg.GetBrokerPublicIp()
+		return ip, false, err
+	}
+
+	// Parse workers
+	if strings.HasPrefix(serverName, "worker-") {
+		ips, err := c.HostsCfg.GetWorkerIps()
+		if err != nil {
+			return "", false, err
+		}
+		parsedWorkerNum, err := strconv.Atoi(strings.Split(serverName, "worker-")[1])
+		if err != nil {
+			return "", false, fmt.Errorf("Incorrect worker name was passed. Accepted values are worker-1, worker-2, worker-3, worker-*...")
+		}
+		if parsedWorkerNum >= 1 && parsedWorkerNum <= len(ips) {
+			return ips[parsedWorkerNum-1], true, nil
+		}
+	}
+
+	return "", false, fmt.Errorf("Incorrect server name was passed. Accepted values are manager, broker, worker-* (where * is a digit)")
+}
+
 // GetWorkerConnection establishes a connection to given worker. If Server
 // provider is AWS, we use manager as a bastion server
 func (c *Container) GetWorkerConnection(workerIp string, cfg *configs.D8XConfig) (conn.SSHConnection, error) {
@@ -126,8 +127,8 @@ func (c *Container) GetWorkerConnection(workerIp string, cfg *configs.D8XConfig)
 		connErr error
 	)
 
-	if cfg.ServerProvider == configs.D8XServerProviderLinode {
-		cn, connErr = conn.NewSSHConnection(workerIp, c.DefaultClusterUserName, c.SshKeyPath)
+	if cfg.HasPublicServers() {
+		cn, connErr = c.CreateSSHConn(workerIp, c.DefaultClusterUserName, c.SshKeyPath)
 	} else {
 		managerIp, err := c.HostsCfg.GetMangerPublicIp()
 		if err != nil {
@@ -135,11 +136,11 @@ func (c *Container) GetWorkerConnection(workerIp string, cfg *configs.D8XConfig)
 		}
 
 		// Workers are accessible through manager for AWS
-		managerConn, errMngr := conn.NewSSHConnection(managerIp, c.DefaultClusterUserName, c.SshKeyPath)
+		managerConn, errMngr := c.CreateSSHConn(managerIp, c.DefaultClusterUserName, c.SshKeyPath)
 		if errMngr != nil {
 			return nil, errMngr
 		}
-		cn, connErr = conn.NewSSHConnectionWithBastion(managerConn.GetClient(), workerIp, c.DefaultClusterUserName, c.SshKeyPath)
+		cn, connErr = c.CreateSSHConnWithBastion(managerConn, workerIp, c.DefaultClusterUserName, c.SshKeyPath)
 	}
 
 	return cn, connErr
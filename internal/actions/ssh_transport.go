@@ -0,0 +1,138 @@
+package actions
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"time"
+
+	"github.com/D8-X/d8x-cli/internal/components"
+	"github.com/D8-X/d8x-cli/internal/configs"
+	"github.com/D8-X/d8x-cli/internal/conn"
+	"golang.org/x/crypto/ssh"
+)
+
+// InitSSHTransport configures ports, ssh-agent authentication, key passphrase
+// retrieval and keepalives of all ssh connections
+func (c *Container) InitSSHTransport() {
+	t := conn.DefaultTransport()
+	t.Port = c.sshPort
+	t.Passphrase = c.sshKeyPassphrase
+	c.sshTransport = t
+	conn.UseTransport(t)
+}
+
+// sshPort returns ssh port of host. Port set in hosts.cfg takes precedence
+// over ssh_port of d8x.conf.json. 0 means default port.
+func (c *Container) sshPort(host string) int {
+	if port := c.HostsCfg.GetSSHPort(host); port != 0 {
+		return port
+	}
+	return c.defaultSSHPort()
+}
+
+// defaultSSHPort returns ssh_port of d8x.conf.json
+func (c *Container) defaultSSHPort() int {
+	c.sshPortOnce.Do(func() {
+		if c.ConfigRWriter == nil {
+			return
+		}
+		if cfg, err := c.ConfigRWriter.Read(); err == nil {
+			c.sshPortDefault = cfg.SSHPort
+		}
+	})
+	return c.sshPortDefault
+}
+
+// sshKeyPassphrase retrieves the passphrase of encrypted ssh key from
+// D8X_SSH_KEY_PASSPHRASE or prompts for it. Passphrase is retrieved once for
+// concurrent dials and cached only when it decrypts the key.
+func (c *Container) sshKeyPassphrase(keyPath string) ([]byte, error) {
+	c.sshKeyPassMu.Lock()
+	defer c.sshKeyPassMu.Unlock()
+	if c.sshKeyPass != nil {
+		return c.sshKeyPass, nil
+	}
+
+	pass := os.Getenv(configs.SSH_KEY_PASSPHRASE_ENV)
+	if pass == "" {
+		fmt.Printf("Enter passphrase of ssh key %s:\n", keyPath)
+		var err error
+		pass, err = c.TUI.NewInput(
+			components.TextInputOptId("ssh.key_passphrase"),
+			components.TextInputOptMasked(),
+			components.TextInputOptDenyEmpty(),
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	pk, err := os.ReadFile(keyPath)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := ssh.ParsePrivateKeyWithPassphrase(pk, []byte(pass)); err != nil {
+		return nil, fmt.Errorf("decrypting private key %s: %w", keyPath, err)
+	}
+	c.sshKeyPass = []byte(pass)
+	return c.sshKeyPass, nil
+}
+
+// sshKeyEncrypted reports whether ssh key at keyPath is passphrase protected
+func sshKeyEncrypted(keyPath string) bool {
+	pk, err := os.ReadFile(keyPath)
+	if err != nil {
+		return false
+	}
+	_, err = ssh.ParseRawPrivateKey(pk)
+	var passErr *ssh.PassphraseMissingError
+	return errors.As(err, &passErr)
+}
+
+// ansibleSSHArgs returns ansible-playbook arguments which make ansible verify
+// host keys against d8x managed known_hosts file and send keepalives the same
+// way as d8x ssh connections do
+func (c *Container) ansibleSSHArgs() []string {
+	sshArgs := fmt.Sprintf("-o UserKnownHostsFile=%s -o StrictHostKeyChecking=yes", c.knownHostsPath())
+	if c.sshTransport.KeepAliveInterval > 0 {
+		sshArgs += fmt.Sprintf(
+			" -o ServerAliveInterval=%d -o ServerAliveCountMax=%d",
+			int(c.sshTransport.KeepAliveInterval/time.Second),
+			c.sshTransport.KeepAliveMaxMissed,
+		)
+	}
+	return []string{
+		"--extra-vars", fmt.Sprintf(`ansible_ssh_extra_args='%s'`, sshArgs),
+	}
+}
+
+// ansibleSSHEnv returns environment of ansible-playbook. Default ssh port of
+// d8x.conf.json is passed via ANSIBLE_REMOTE_PORT, so that ansible_port of
+// individual hosts still takes precedence. ssh-agent is used via inherited
+// SSH_AUTH_SOCK.
+func (c *Container) ansibleSSHEnv() ([]string, error) {
+	if sshKeyEncrypted(c.SshKeyPath) && c.sshTransport.AgentSocket == "" {
+		return nil, fmt.Errorf("ssh key %s is encrypted, ansible can only use encrypted keys via ssh-agent. Start ssh-agent and add the key with ssh-add %s", c.SshKeyPath, c.SshKeyPath)
+	}
+
+	env := os.Environ()
+	if port := c.defaultSSHPort(); port != 0 {
+		env = append(env, "ANSIBLE_REMOTE_PORT="+strconv.Itoa(port))
+	}
+	return env, nil
+}
+
+// ansiblePlaybookCmd creates ansible-playbook command with ssh transport
+// arguments and environment
+func (c *Container) ansiblePlaybookCmd(args ...string) (*exec.Cmd, error) {
+	env, err := c.ansibleSSHEnv()
+	if err != nil {
+		return nil, err
+	}
+	cmd := exec.Command("ansible-playbook", append(args, c.ansibleSSHArgs()...)...)
+	cmd.Env = env
+	return cmd, nil
+}
@@ -0,0 +1,102 @@
+package actions
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/D8-X/d8x-cli/internal/configs"
+	"github.com/D8-X/d8x-cli/internal/conn"
+	"github.com/D8-X/d8x-cli/internal/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestSSHPort(t *testing.T) {
+	ctl := gomock.NewController(t)
+	hosts := mocks.NewMockHostsFileInteractor(ctl)
+	rw := mocks.NewMockD8XConfigReadWriter(ctl)
+
+	hosts.EXPECT().GetSSHPort("10.0.0.1").Return(2200)
+	hosts.EXPECT().GetSSHPort("10.0.0.2").Return(0).Times(2)
+	// Config is read only once
+	rw.EXPECT().Read().Return(&configs.D8XConfig{SSHPort: 2222}, nil).Times(1)
+
+	c := &Container{HostsCfg: hosts, ConfigRWriter: rw}
+	assert.Equal(t, 2200, c.sshPort("10.0.0.1"))
+	assert.Equal(t, 2222, c.sshPort("10.0.0.2"))
+	assert.Equal(t, 2222, c.sshPort("10.0.0.2"))
+}
+
+// writeEncryptedSSHKey writes ed25519 private key encrypted with passphrase
+func writeEncryptedSSHKey(t *testing.T, passphrase string) string {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	block, err := ssh.MarshalPrivateKeyWithPassphrase(key, "", []byte(passphrase))
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "id_ed25519")
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(block), 0600))
+	return path
+}
+
+func TestSSHKeyPassphrase(t *testing.T) {
+	keyPath := writeEncryptedSSHKey(t, "secret")
+	ctl := gomock.NewController(t)
+	tui := mocks.NewMockComponentsRunner(ctl)
+	c := &Container{TUI: tui}
+
+	// Wrong passphrase is not cached
+	t.Setenv(configs.SSH_KEY_PASSPHRASE_ENV, "wrong")
+	_, err := c.sshKeyPassphrase(keyPath)
+	assert.ErrorContains(t, err, "decrypting private key")
+	assert.Nil(t, c.sshKeyPass)
+
+	// Concurrent dials prompt only once
+	t.Setenv(configs.SSH_KEY_PASSPHRASE_ENV, "")
+	tui.EXPECT().NewInput(gomock.Any()).Return("secret", nil).Times(1)
+	wg := sync.WaitGroup{}
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			pass, err := c.sshKeyPassphrase(keyPath)
+			assert.NoError(t, err)
+			assert.Equal(t, []byte("secret"), pass)
+		}()
+	}
+	wg.Wait()
+}
+
+func TestAnsiblePlaybookCmd(t *testing.T) {
+	ctl := gomock.NewController(t)
+	rw := mocks.NewMockD8XConfigReadWriter(ctl)
+	rw.EXPECT().Read().Return(&configs.D8XConfig{SSHPort: 2222}, nil)
+
+	configDir := t.TempDir()
+	c := &Container{
+		ConfigDir:     configDir,
+		ConfigRWriter: rw,
+		SshKeyPath:    filepath.Join(configDir, "id_ed25519"),
+		sshTransport: conn.Transport{
+			KeepAliveInterval:  time.Second * 30,
+			KeepAliveMaxMissed: 3,
+		},
+	}
+
+	cmd, err := c.ansiblePlaybookCmd("-i", "./hosts.cfg", "./playbooks/setup.ansible.yaml")
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{
+		"ansible-playbook",
+		"-i", "./hosts.cfg", "./playbooks/setup.ansible.yaml",
+		"--extra-vars", "ansible_ssh_extra_args='-o UserKnownHostsFile=" + filepath.Join(configDir, "known_hosts") + " -o StrictHostKeyChecking=yes -o ServerAliveInterval=30 -o ServerAliveCountMax=3'",
+	}, cmd.Args)
+	assert.Contains(t, cmd.Env, "ANSIBLE_REMOTE_PORT=2222")
+}
@@ -0,0 +1,249 @@
+package actions
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/D8-X/d8x-cli/internal/configs"
+	"github.com/D8-X/d8x-cli/internal/conn"
+	"github.com/D8-X/d8x-cli/internal/styles"
+)
+
+// Rolling update settings for swarm services. Tasks are replaced one by one,
+// swarm rolls the service back automatically whenever a new task fails within
+// the monitor period.
+const (
+	rollingUpdateParallelism = 1
+	rollingUpdateDelay       = "10s"
+	rollingUpdateMonitor     = "30s"
+)
+
+var (
+	// How long to wait for updated tasks to converge before rolling back
+	rollingUpdateTimeout = time.Minute * 3
+	// How long to keep probing public hostnames of updated service
+	rollingUpdateProbeTimeout = time.Minute
+	// Interval between task state checks and hostname probes
+	rollingUpdatePollInterval = time.Second * 5
+)
+
+// Final result of a rolling update
+const (
+	rollingUpdatePromoted   = "promoted"
+	rollingUpdateRolledBack = "rolled back"
+	// Update could not be performed or rolled back
+	rollingUpdateFailed = "failed"
+)
+
+// swarmServicesPublicServices maps swarm services to D8XServices which are
+// exposed via nginx and can be probed after the update.
+var swarmServicesPublicServices = map[string][]configs.D8XServiceName{
+	"api":               {configs.D8XServiceMainHTTP, configs.D8XServiceMainWS},
+	"history":           {configs.D8XServiceHistory},
+	"referral":          {configs.D8XServiceReferral},
+	"candles-ws-server": {configs.D8XServiceCandlesWs},
+}
+
+// swarmServicePublicServices returns configured services of swarm service
+// svcName which have a hostname
+func swarmServicePublicServices(cfg *configs.D8XConfig, svcName string) []configs.D8XService {
+	publicServices := []configs.D8XService{}
+	for _, name := range swarmServicesPublicServices[svcName] {
+		if svc, ok := cfg.Services[name]; ok && svc.HostName != "" {
+			publicServices = append(publicServices, svc)
+		}
+	}
+	return publicServices
+}
+
+// rollingUpdateCmd returns the docker service update command which starts the
+// rolling update of svcStackName to img without waiting for it to finish
+func rollingUpdateCmd(img, svcStackName string) string {
+	return rollingServiceUpdateCmd("--image "+img, svcStackName)
+}
+
+// rollingConfigUpdateCmd returns the docker service update command which
+// starts the rolling update of svcStackName, replacing docker config oldConfig
+// with newConfig mounted at target
+func rollingConfigUpdateCmd(oldConfig, newConfig, target, svcStackName string) string {
+	return rollingServiceUpdateCmd(
+		fmt.Sprintf("--config-rm %s --config-add source=%s,target=%s", oldConfig, newConfig, target),
+		svcStackName,
+	)
+}
+
+// rollingReferralUpdateCmd returns the rolling update command for the referral
+// service, which is scaled down before the update (see updateSwarmServices).
+// Replicas are set in the same update so that a rollback reverts the image
+// and not only the scale.
+func rollingReferralUpdateCmd(img, svcStackName string) string {
+	return rollingServiceUpdateCmd("--image "+img+" --replicas 1", svcStackName)
+}
+
+func rollingServiceUpdateCmd(updateArgs, svcStackName string) string {
+	return fmt.Sprintf(
+		"docker service update --detach %s --update-parallelism %d --update-delay %s --update-monitor %s --update-failure-action rollback %s",
+		updateArgs,
+		rollingUpdateParallelism,
+		rollingUpdateDelay,
+		rollingUpdateMonitor,
+		svcStackName,
+	)
+}
+
+// swarmTasksConverged reports whether all given tasks which should be running
+// are running
+func swarmTasksConverged(tasks []svcPsInfo) bool {
+	if len(tasks) == 0 {
+		return false
+	}
+	for _, t := range tasks {
+		if !strings.HasPrefix(t.currentState, "Running") {
+			return false
+		}
+	}
+	return true
+}
+
+// swarmServiceUpdateState returns the UpdateStatus.State of the service, which
+// is empty when service was never updated
+func swarmServiceUpdateState(sshConn conn.SSHConnection, svcStackName string) (string, error) {
+	out, err := sshConn.ExecCommand(
+		fmt.Sprintf(`docker service inspect --format '{{if .UpdateStatus}}{{.UpdateStatus.State}}{{end}}' %s`, svcStackName),
+	)
+	if err != nil {
+		return "", fmt.Errorf("inspecting service %s: %w", svcStackName, err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// swarmServiceRunningTasks returns the tasks of the service which should be
+// running
+func swarmServiceRunningTasks(sshConn conn.SSHConnection, svcStackName string) ([]svcPsInfo, error) {
+	out, err := sshConn.ExecCommand(
+		fmt.Sprintf("docker service ps %s --filter desired-state=running %s", svcStackName, swarmServicePsArgs),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("listing service %s tasks: %w", svcStackName, err)
+	}
+	lines := strings.Split(string(out), "\n")
+	if len(lines) > 0 {
+		lines = lines[1:]
+	}
+	return parseSwarmServicePs(lines), nil
+}
+
+// rollingUpdateSwarmService performs a rolling update of swarm service
+// svcStackName with updateCmd (see rollingUpdateCmd and
+// rollingConfigUpdateCmd). Task states of the service are watched until the
+// update converges, then given public services are probed. Whenever the new
+// version fails, the service is rolled back. Returns rollingUpdatePromoted or
+// rollingUpdateRolledBack.
+func (c *Container) rollingUpdateSwarmService(sshConn conn.SSHConnection, svcName, svcStackName, updateCmd string, publicServices []configs.D8XService) (string, error) {
+	if err := sshConn.ExecCommandPiped(updateCmd); err != nil {
+		return "", fmt.Errorf("starting rolling update of %s: %w", svcName, err)
+	}
+
+	// Nothing to watch when commands are only recorded
+	if c.DryRun != nil {
+		return rollingUpdatePromoted, nil
+	}
+
+	fmt.Printf("Waiting for %s tasks to converge...\n", svcName)
+	deadline := time.Now().Add(rollingUpdateTimeout)
+	converged := false
+	var tasks []svcPsInfo
+	for time.Now().Before(deadline) {
+		// Give swarm time to pick up the update before inspecting it
+		time.Sleep(rollingUpdatePollInterval)
+
+		state, err := swarmServiceUpdateState(sshConn, svcStackName)
+		if err != nil {
+			return "", err
+		}
+		// Swarm detected failed tasks and rolled back on its own
+		if strings.HasPrefix(state, "rollback") {
+			fmt.Println(styles.ErrorText.Render(
+				fmt.Sprintf("New version of %s failed, swarm rolled the service back (%s)", svcName, state),
+			))
+			return rollingUpdateRolledBack, nil
+		}
+
+		tasks, err = swarmServiceRunningTasks(sshConn, svcStackName)
+		if err != nil {
+			return "", err
+		}
+		if (state == "" || state == "completed") && swarmTasksConverged(tasks) {
+			converged = true
+			break
+		}
+	}
+
+	if !converged {
+		fmt.Println(styles.ErrorText.Render(fmt.Sprintf("Service %s update timed out", svcName)))
+		for _, t := range tasks {
+			fmt.Printf("  \\_ %s on %s status %s %s\n", t.name, t.node, t.currentState, t.err)
+		}
+		return rollbackSwarmService(sshConn, svcName, svcStackName)
+	}
+
+	for _, svc := range publicServices {
+		fmt.Printf("Probing %s (%s)\n", svc.Name, svc.HostName)
+		if err := c.probeServiceHostname(svc, rollingUpdateProbeTimeout); err != nil {
+			fmt.Println(styles.ErrorText.Render(
+				fmt.Sprintf("Service %s is not reachable at %s after the update: %v", svcName, svc.HostName, err),
+			))
+			return rollbackSwarmService(sshConn, svcName, svcStackName)
+		}
+	}
+
+	return rollingUpdatePromoted, nil
+}
+
+// rollbackSwarmService reverts the service to its previous specification
+func rollbackSwarmService(sshConn conn.SSHConnection, svcName, svcStackName string) (string, error) {
+	fmt.Printf("Rolling back service %s\n", svcName)
+	if err := sshConn.ExecCommandPiped(fmt.Sprintf("docker service rollback %s", svcStackName)); err != nil {
+		return "", fmt.Errorf("rolling back service %s: %w", svcName, err)
+	}
+	return rollingUpdateRolledBack, nil
+}
+
+// probeServiceHostname sends requests to the service hostname until it
+// responds with non 5xx status or timeout is reached
+func (c *Container) probeServiceHostname(svc configs.D8XService, timeout time.Duration) error {
+	prefix := "http://"
+	if svc.UsesHTTPS {
+		prefix = "https://"
+	}
+
+	var lastErr error
+	deadline := time.Now().Add(timeout)
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), rollingUpdatePollInterval)
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, prefix+svc.HostName, nil)
+		if err != nil {
+			cancel()
+			return err
+		}
+		resp, err := c.HttpClient.Do(req)
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode < 500 {
+				cancel()
+				return nil
+			}
+			err = fmt.Errorf("HTTP status %d", resp.StatusCode)
+		}
+		cancel()
+		lastErr = err
+
+		if time.Now().Add(rollingUpdatePollInterval).After(deadline) {
+			return lastErr
+		}
+		time.Sleep(rollingUpdatePollInterval)
+	}
+}
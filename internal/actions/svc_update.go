@@ -1,23 +1,16 @@
 package actions
 
 import (
-	"context"
 	"fmt"
-	"net/http"
 	"sort"
 	"strings"
 	"sync"
-	"time"
 
 	"github.com/D8-X/d8x-cli/internal/components"
 	"github.com/D8-X/d8x-cli/internal/configs"
 	"github.com/D8-X/d8x-cli/internal/conn"
 	"github.com/D8-X/d8x-cli/internal/styles"
-	"github.com/containers/image/docker"
-	"github.com/containers/image/types"
-	"github.com/distribution/reference"
 	"github.com/urfave/cli/v2"
-	"golang.org/x/net/html"
 )
 
 type DokcerStackFileServices struct {
@@ -55,13 +48,13 @@ func (c *Container) ServiceUpdate(ctx *cli.Context) error {
 	})
 
 	fmt.Println("Select swarm services to update")
-	selectedSwarmServicesToUpdate, err := c.TUI.NewSelection(swarmSelection)
+	selectedSwarmServicesToUpdate, err := c.TUI.NewSelection(swarmSelection, components.SelectionOptId("update.swarm_services"))
 	if err != nil {
 		return err
 	}
 
 	fmt.Println("Select broker-server services to update")
-	selectedBrokerServicesToUpdate, err := c.TUI.NewSelection(brokerServerSelection)
+	selectedBrokerServicesToUpdate, err := c.TUI.NewSelection(brokerServerSelection, components.SelectionOptId("update.broker_services"))
 	if err != nil {
 		return err
 	}
@@ -77,7 +70,7 @@ func (c *Container) ServiceUpdate(ctx *cli.Context) error {
 		}
 
 		// Ask for private key
-		pk, _, err := c.CollectAndValidatePrivateKey("Enter your broker private key:")
+		pk, _, err := c.CollectAndValidatePrivateKey("broker.private_key", "Enter your broker private key:")
 		if err != nil {
 			return err
 		}
@@ -87,7 +80,9 @@ func (c *Container) ServiceUpdate(ctx *cli.Context) error {
 			fmt.Println(styles.ErrorText.Render("Broker server configuration not found, make sure you have deployed the broker server first (d8x setup broker-deploy), otherwise the update might fail."))
 			fmt.Println("Enter your broker redis password:")
 			pwd, err := c.TUI.NewInput(
+				components.TextInputOptId("broker.redis_password"),
 				components.TextInputOptPlaceholder("<YOUR REDIS PASSWORD>"),
+				components.TextInputOptMasked(),
 			)
 			if err != nil {
 				return err
@@ -130,23 +125,27 @@ func (c *Container) updateSwarmServices(ctx *cli.Context, selectedSwarmServicesT
 		return nil
 	}
 
-	// Collect the sha hashes for selected services
-	svcTagsWithShaHashes := map[string][]string{}
+	// Resolve tags with digests of selected services via registry api
+	sys := registrySystemContext(ctx)
+	svcTags := map[string][]imageTag{}
+	mu := sync.Mutex{}
 	wg := sync.WaitGroup{}
 	for _, svcToUpdate := range selectedSwarmServicesToUpdate {
 		wg.Add(1)
 		go func(svcToUpdate string) {
-			fmt.Println("Fetching image tags with sha hashes for service " + svcToUpdate)
+			defer wg.Done()
 			img := services[svcToUpdate].Image
-			tags, err := getTagsWithHashes(svcToUpdate, img)
-			// Just print the error if tags cannot be fetched/parsed
+			fmt.Println("Fetching image tags for service " + svcToUpdate)
+			tags, err := listImageTags(ctx.Context, sys, img)
+			// Just print the error if tags cannot be fetched
 			if err != nil {
 				fmt.Println(styles.ErrorText.Render(fmt.Sprintf("Could not get tags for %s: %s", img, err.Error())))
-			} else {
-				fmt.Println(styles.SuccessText.Render(fmt.Sprintf("Image tags fetched for service %s", img)))
-				svcTagsWithShaHashes[svcToUpdate] = tags
+				return
 			}
-			wg.Done()
+			fmt.Println(styles.SuccessText.Render(fmt.Sprintf("Image tags fetched for service %s", img)))
+			mu.Lock()
+			svcTags[svcToUpdate] = tags
+			mu.Unlock()
 		}(svcToUpdate)
 	}
 	wg.Wait()
@@ -156,22 +155,26 @@ func (c *Container) updateSwarmServices(ctx *cli.Context, selectedSwarmServicesT
 	referralExecutorKey := ""
 
 	// Prompt user to select the tags to use for updating services. Use image
-	// tags with hashes fetched from github but also allow to enter the image
-	// reference manually
+	// tags with digests fetched from the registry but also allow to enter the
+	// image reference manually
 	enterManuallyOption := "Enter image reference manually"
 	selectedImageReferenceForUpdate := map[string]string{}
 	for _, svcToUpdate := range selectedSwarmServicesToUpdate {
 		img := services[svcToUpdate].Image
-		imagesSelection := svcTagsWithShaHashes[svcToUpdate]
-		// Append the image url
-		for i, tagHash := range imagesSelection {
-			imagesSelection[i] = img + ":" + tagHash
+		// Selection labels mapped to image references
+		labelRefs := map[string]string{}
+		imagesSelection := []string{}
+		for _, tag := range svcTags[svcToUpdate] {
+			label := tag.Label(img)
+			labelRefs[label] = tag.Reference(img)
+			imagesSelection = append(imagesSelection, label)
 		}
 		imagesSelection = append(imagesSelection, enterManuallyOption)
 
 		fmt.Printf("\nChoose which image reference to update %s service to\n", svcToUpdate)
 		selectedImageToUpdate, err := c.TUI.NewSelection(
 			imagesSelection,
+			components.SelectionOptId("update.image."+svcToUpdate),
 			components.SelectionOptAllowOnlySingleItem(),
 			components.SelectionOptRequireSelection(),
 		)
@@ -181,6 +184,7 @@ func (c *Container) updateSwarmServices(ctx *cli.Context, selectedSwarmServicesT
 
 		imgToUse := selectedImageToUpdate[0]
 		if imgToUse != enterManuallyOption {
+			imgToUse = labelRefs[imgToUse]
 			fmt.Printf("Service %s will be updated to %s\n", svcToUpdate, imgToUse)
 		} else {
 			fmt.Printf("Provide a full path to image with tag (or optionally sha256 hash) to update to\n")
@@ -191,6 +195,7 @@ func (c *Container) updateSwarmServices(ctx *cli.Context, selectedSwarmServicesT
 
 			fmt.Println("Enter image to update to:")
 			enteredImage, err := c.TUI.NewInput(
+				components.TextInputOptId("update.image_manual."+svcToUpdate),
 				components.TextInputOptValue(img),
 				components.TextInputOptPlaceholder("ghcr.io/d8-x/image@sha256:hash"),
 			)
@@ -204,7 +209,7 @@ func (c *Container) updateSwarmServices(ctx *cli.Context, selectedSwarmServicesT
 
 		// Collect private key for referral service
 		if svcToUpdate == "referral" {
-			executorkey, _, err := c.Input.CollectAndValidatePrivateKey("Enter your referral payment executor private key:")
+			executorkey, _, err := c.Input.CollectAndValidatePrivateKey("referral.executor_private_key", "Enter your referral payment executor private key:")
 			if err != nil {
 				return err
 			}
@@ -231,11 +236,18 @@ func (c *Container) updateSwarmServices(ctx *cli.Context, selectedSwarmServicesT
 	if err != nil {
 		return err
 	}
-	sshConn, err := conn.NewSSHConnection(managerIp, c.DefaultClusterUserName, c.SshKeyPath)
+	sshConn, err := c.CreateSSHConn(managerIp, c.DefaultClusterUserName, c.SshKeyPath)
 	if err != nil {
 		return err
 	}
 
+	cfg, err := c.ConfigRWriter.Read()
+	if err != nil {
+		return err
+	}
+
+	// Final result of each service update
+	results := map[string]string{}
 	for _, svcToUpdate := range selectedSwarmServicesToUpdate {
 		imgToUse := selectedImageReferenceForUpdate[svcToUpdate]
 		fmt.Printf("Updating %s to %s\n", svcToUpdate, imgToUse)
@@ -243,80 +255,131 @@ func (c *Container) updateSwarmServices(ctx *cli.Context, selectedSwarmServicesT
 		// For referral system - we need to update the referral executor private
 		// key, since the new version will have different encryption key and
 		// keyfile.txt will be reencrypted
-		// var oldKeyfile string = ""
+		oldKeyfile := ""
+		// Append stack name for service
+		svcStackName := dockerStackName + "_" + svcToUpdate
 		if svcToUpdate == "referral" {
+			// Store old key to restore it when the update is not promoted
+			out, err := sshConn.ExecCommand(fmt.Sprintf(`echo '%s' | sudo -S -p '' cat /var/nfs/general/keyfile.txt`, password))
+			if err != nil {
+				fmt.Println(styles.ErrorText.Render(
+					fmt.Sprintf("reading referral executor private key file: %v\n", err),
+				))
+				results[svcToUpdate] = rollingUpdateFailed
+				continue
+			}
+			oldKeyfile = string(out)
+
 			// Remove existing referral service
 			fmt.Println("Scaling down referral service")
 			if err := sshConn.ExecCommandPiped(
-				fmt.Sprintf("docker service scale %s_%s=0", dockerStackName, svcToUpdate),
+				fmt.Sprintf("docker service scale %s=0", svcStackName),
 			); err != nil {
 				fmt.Println(styles.ErrorText.Render(
 					fmt.Sprintf("removing referral service: %v\n", err),
 				))
+				if err := restoreReferralKeyfile(sshConn, password, oldKeyfile, svcStackName); err != nil {
+					fmt.Println(styles.ErrorText.Render(
+						fmt.Sprintf("Could not restore referral service: %v\n", err),
+					))
+				}
+				results[svcToUpdate] = rollingUpdateFailed
 				continue
 			}
 
-			// Store old key just in case
-			_, err := sshConn.ExecCommand(fmt.Sprintf(`echo '%s' | sudo -S cat /var/nfs/general/keyfile.txt`, password))
-			if err != nil {
-				return err
-			}
 			// Write new keyfile
-			out, err := sshConn.ExecCommand(fmt.Sprintf(`echo '%s' | sudo -S bash -c "echo -n '%s' > /var/nfs/general/keyfile.txt"`, password, referralExecutorKey))
+			out, err = sshConn.ExecCommand(fmt.Sprintf(`echo '%s' | sudo -S bash -c "echo -n '%s' > /var/nfs/general/keyfile.txt"`, password, referralExecutorKey))
 			if err != nil {
 				fmt.Println(string(out))
-				return fmt.Errorf("updating executor private key file: %w", err)
-			}
-		}
-
-		// Append stack name for service
-		svcStackName := dockerStackName + "_" + svcToUpdate
-
-		t := time.NewTimer(time.Minute * 2)
-		done := make(chan struct{})
-		go func() {
-			err := sshConn.ExecCommandPiped(
-				fmt.Sprintf(`docker service update --image %s %s`, imgToUse, svcStackName),
-			)
-			if err != nil {
-				fmt.Println(
-					styles.ErrorText.Render(
-						fmt.Sprintf("Could not update service %s: %s\n", svcToUpdate, err.Error()),
-					),
-				)
-			} else {
-				fmt.Println(
-					styles.SuccessText.Render(
-						fmt.Sprintf("Service %s updated successfully\n", svcToUpdate),
-					),
-				)
-			}
-
-			// Scale back the referral service
-			if svcToUpdate == "referral" {
-				if err := sshConn.ExecCommandPiped(
-					fmt.Sprintf("docker service scale %s_%s=1", dockerStackName, svcToUpdate),
-				); err != nil {
+				fmt.Println(styles.ErrorText.Render(
+					fmt.Sprintf("updating executor private key file: %v\n", err),
+				))
+				if err := restoreReferralKeyfile(sshConn, password, oldKeyfile, svcStackName); err != nil {
 					fmt.Println(styles.ErrorText.Render(
-						fmt.Sprintf("scaling referral service: %v\n", err),
+						fmt.Sprintf("Could not restore referral service: %v\n", err),
 					))
 				}
+				results[svcToUpdate] = rollingUpdateFailed
+				continue
 			}
+		}
 
-			done <- struct{}{}
-		}()
+		publicServices := swarmServicePublicServices(cfg, svcToUpdate)
+		updateCmd := rollingUpdateCmd(imgToUse, svcStackName)
+		if svcToUpdate == "referral" {
+			updateCmd = rollingReferralUpdateCmd(imgToUse, svcStackName)
+		}
+		result, err := c.rollingUpdateSwarmService(sshConn, svcToUpdate, svcStackName, updateCmd, publicServices)
+		if err != nil {
+			fmt.Println(
+				styles.ErrorText.Render(
+					fmt.Sprintf("Could not update service %s: %s\n", svcToUpdate, err.Error()),
+				),
+			)
+			result = rollingUpdateFailed
+		}
 
-		select {
-		case <-t.C:
-			fmt.Println(styles.ErrorText.Render(fmt.Sprintf("Service %s update timed out\n", svcToUpdate)))
-		case <-done:
+		if svcToUpdate == "referral" && result != rollingUpdatePromoted {
+			result = restoreReferralService(sshConn, password, oldKeyfile, svcStackName, result)
 		}
+		results[svcToUpdate] = result
+	}
 
+	fmt.Println("\nSwarm services update results:")
+	promoted := false
+	for _, svcToUpdate := range selectedSwarmServicesToUpdate {
+		line := fmt.Sprintf("  %s: %s", svcToUpdate, results[svcToUpdate])
+		if results[svcToUpdate] == rollingUpdatePromoted {
+			promoted = true
+			fmt.Println(styles.SuccessText.Render(line))
+		} else {
+			fmt.Println(styles.ErrorText.Render(line))
+		}
+	}
+
+	// Nothing new is live when no service was promoted
+	if promoted {
+		c.recordRelease(sshConn, swarmReleaseTarget(), "update", 0)
 	}
 
 	return nil
 }
 
+// restoreReferralService brings referral service back to its previous
+// version after the rolling update was not promoted. Failed updates are rolled
+// back first, since service might be left with the new image. Returns result of
+// the update, which is rollingUpdateFailed when the service could not be
+// restored.
+func restoreReferralService(sshConn conn.SSHConnection, password, oldKeyfile, svcStackName, result string) string {
+	if result == rollingUpdateFailed {
+		if _, err := rollbackSwarmService(sshConn, "referral", svcStackName); err != nil {
+			fmt.Println(styles.ErrorText.Render(err.Error()))
+		}
+	}
+	if err := restoreReferralKeyfile(sshConn, password, oldKeyfile, svcStackName); err != nil {
+		fmt.Println(styles.ErrorText.Render(
+			fmt.Sprintf("Could not restore referral service: %v\n", err),
+		))
+		return rollingUpdateFailed
+	}
+	return result
+}
+
+// restoreReferralKeyfile writes back the keyfile of the previous referral
+// service version and scales the rolled back referral service up again
+func restoreReferralKeyfile(sshConn conn.SSHConnection, password, oldKeyfile, svcStackName string) error {
+	fmt.Println("Restoring previous referral executor private key file")
+	out, err := sshConn.ExecCommand(fmt.Sprintf(`echo '%s' | sudo -S bash -c "echo -n '%s' > /var/nfs/general/keyfile.txt"`, password, oldKeyfile))
+	if err != nil {
+		fmt.Println(string(out))
+		return fmt.Errorf("restoring executor private key file: %w", err)
+	}
+	if err := sshConn.ExecCommandPiped(fmt.Sprintf("docker service scale --detach %s=1", svcStackName)); err != nil {
+		return fmt.Errorf("scaling referral service: %w", err)
+	}
+	return nil
+}
+
 // updateBrokerServerServices performs broker-server services update on broker
 // server. Broker-server update involves  uploading the key to a new volume.
 func (c *Container) updateBrokerServerServices(selectedSwarmServicesToUpdate []string, pk, redisPassword, feeTBPS string) error {
@@ -331,7 +394,7 @@ func (c *Container) updateBrokerServerServices(selectedSwarmServicesToUpdate []s
 	if err != nil {
 		return err
 	}
-	sshConn, err := conn.NewSSHConnection(ipBroker, c.DefaultClusterUserName, c.SshKeyPath)
+	sshConn, err := c.CreateSSHConn(ipBroker, c.DefaultClusterUserName, c.SshKeyPath)
 	if err != nil {
 		return err
 	}
@@ -384,154 +447,9 @@ func (c *Container) updateBrokerServerServices(selectedSwarmServicesToUpdate []s
 		}
 	}
 
-	return nil
-}
-
-// See docker-swarm-stack.yml and github packages for urls
-var githubPackageVersionsPage = map[string]string{
-	"api":                 "https://github.com/D8-X/d8x-trader-backend/pkgs/container/d8x-trader-main/versions",
-	"history":             "https://github.com/D8-X/d8x-trader-backend/pkgs/container/d8x-trader-history/versions",
-	"referral":            "https://github.com/D8-X/referral-system/pkgs/container/d8x-referral-system/versions",
-	"candles-pyth-client": "https://github.com/D8-X/d8x-candles/pkgs/container/d8x-candles-pyth-client/versions",
-	"candles-ws-server":   "https://github.com/D8-X/d8x-candles/pkgs/container/d8x-candles-ws-server/versions",
-	"candles-poly-client": "https://github.com/D8-X/d8x-candles/pkgs/container/d8x-candles-poly-client/versions",
-}
-
-// getTagsWithHashes is a hacky way for us to gather the sha digest hashes of
-// the given image from github packages version page. If version page url is
-// found - we try to parse the html and find ul li items with tag button <a>.
-// The common parent node (<li>) of <a> with tag name contains another child
-// with sha256 hash. Returned fullTags slice will contain the sha256 hash
-// appended to the tag name.
-func getTagsWithHashes(svcName, imgUrl string) (fullTags []string, err error) {
-	tags, err := getTags(imgUrl)
-	if err != nil {
-		return nil, err
-	}
-
-	packageUrl, ok := githubPackageVersionsPage[svcName]
-	if !ok {
-		fmt.Println(
-			styles.ErrorText.Render("Could not find package url for service " + svcName + ". Image "),
-		)
-		return tags, nil
-	}
-
-	resp, err := http.DefaultClient.Get(packageUrl)
-	if err != nil {
-		return tags, nil
-	}
-	defer resp.Body.Close()
-
-	htmlTree, err := html.Parse(resp.Body)
-	if err != nil {
-		return tags, nil
-	}
-
-	// Inspect the github version page html to see the structure. First we'll
-	// find <li> with Box-row class. intialTree := htmlTree
-	liItems := []*html.Node{}
-	findHtmlNodes(htmlTree, ghTagLiFinder, &liItems)
-
-	// Modify tags slice in place and append sha hashes found from the github
-	// packages version pages
-	for _, li := range liItems {
-		for i, tag := range tags {
-			foundHash := ghLiTagShaHashFinder(li, tag)
-			if foundHash != "" {
-				tags[i] = tag + "@" + foundHash
-			}
-		}
-	}
-
-	return tags, nil
-}
-
-// htmlFinderFunc is a function that finds matching  node and a bool indicating
-// that node was found.
-type htmlFinderFunc func(*html.Node) (*html.Node, bool)
-
-// ghTagLiFinder finds li.Box-row elements which contain the sha hashes of image
-// versions.
-func ghTagLiFinder(n *html.Node) (*html.Node, bool) {
-	if n.Type == html.ElementNode && n.Data == "li" {
-		for _, attr := range n.Attr {
-			if attr.Key == "class" && strings.Contains(attr.Val, "Box-row") {
-				return n, true
-			}
-		}
-	}
-	return nil, false
-}
-
-// ghLiTagShaHashFinder finds html element which contains link and also text of
-// given gitTag
-func ghLiTagShaHashFinder(li *html.Node, gitTags string) string {
-	// Find the <a> tag with href containing the gitTag
-	res := []*html.Node{}
-	findHtmlNodes(li, func(n *html.Node) (*html.Node, bool) {
-		if n.Type == html.ElementNode && n.Data == "a" {
-			for _, attr := range n.Attr {
-				if attr.Key == "href" && strings.Contains(strings.ToLower(attr.Val), "?tag="+gitTags) {
-					return n, true
-				}
-			}
-		}
-		return nil, false
-	}, &res)
-
-	// Find any element inside our li node which hash the sha256 hash text
-	if len(res) > 0 {
-		hashNode := []*html.Node{}
-		lastSavedHahs := ""
-		findHtmlNodes(li, func(n *html.Node) (*html.Node, bool) {
-			if n.Type == html.TextNode {
-				innerText := strings.TrimSpace(n.Data)
-				if strings.HasPrefix(innerText, "sha256:") {
-					lastSavedHahs = innerText
-					return n, true
-				}
-			}
-			return nil, false
-		}, &hashNode)
-
-		if len(hashNode) > 0 {
-			return lastSavedHahs
-		}
-	}
-
-	return ""
-}
+	c.recordRelease(sshConn, brokerReleaseTarget(), "update", 0)
 
-func findHtmlNodes(parent *html.Node, f htmlFinderFunc, result *[]*html.Node) {
-	foundNode, found := f(parent)
-
-	if found {
-		*result = append(*result, foundNode)
-	}
-
-	for c := parent.FirstChild; c != nil; c = c.NextSibling {
-		findHtmlNodes(c, f, result)
-	}
-}
-
-// getTags retrieves available tags for given image
-func getTags(imgUrl string) ([]string, error) {
-	ref, err := reference.ParseNormalizedNamed(imgUrl)
-	if err != nil {
-		return nil, err
-	}
-
-	imgRef, err := docker.NewReference(reference.TagNameOnly(ref))
-	if err != nil {
-		return nil, err
-	}
-
-	return docker.GetRepositoryTags(
-		context.Background(),
-		&types.SystemContext{},
-		imgRef,
-	)
+	return nil
 }
 
 // PurgeWorkers removes all all docker artifacts on each worker
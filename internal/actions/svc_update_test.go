@@ -1,76 +1,162 @@
 package actions
 
 import (
+	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/D8-X/d8x-cli/internal/configs"
+	"github.com/D8-X/d8x-cli/internal/mocks"
 	"github.com/stretchr/testify/assert"
-	"golang.org/x/net/html"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
 )
 
-func TestFindGithubShaHashes(t *testing.T) {
-	testHtml := `
-		<html>
-			<head>
-			</head>
-			<body>
-				<div></div>
-				<div>
-					<ul>
-						<li>1</li>
-						<li class="Box-row">
-							<div></div>
-							<p>Something</p>
-							<p>Something</p>
-							<p>
-								Something
-							</p>
-							<div>
+func TestRollingUpdateSwarmService(t *testing.T) {
+	defer func(poll, probe time.Duration) {
+		rollingUpdatePollInterval = poll
+		rollingUpdateProbeTimeout = probe
+	}(rollingUpdatePollInterval, rollingUpdateProbeTimeout)
+	rollingUpdatePollInterval = time.Millisecond
+	rollingUpdateProbeTimeout = time.Millisecond * 10
 
-							</div>
-						</li>
-					</ul>
-				</div>
-				<div>
-					<ul>
-						<li>1</li>
-					</ul>
-				</div>
-				<div></div>
-			</body>
-		</html>
-	`
+	const (
+		svcStackName = "stack_api"
+		img          = "ghcr.io/d8-x/d8x-trader-main:v1.0.1"
+		inspectCmd   = `docker service inspect --format '{{if .UpdateStatus}}{{.UpdateStatus.State}}{{end}}' stack_api`
+		psCmd        = "docker service ps stack_api --filter desired-state=running " + swarmServicePsArgs
+		rollbackCmd  = "docker service rollback stack_api"
+	)
+	psRunning := []byte("NODE[##]NAME[##]CURRENT STATE[##]ERROR[##]\n" +
+		"worker-1[##]stack_api.1[##]Running 20 seconds ago[##][##]\n")
+	psStarting := []byte("NODE[##]NAME[##]CURRENT STATE[##]ERROR[##]\n" +
+		"worker-1[##]stack_api.1[##]Starting 1 second ago[##][##]\n")
 
-	// Test if ghList tag is correctly found
-	correctLiTag := `<li class="Box-row"></li><li class="Box-row"></li><li class="Box-row"></li>
-		<div>
-			<li class="Box-row">
-				<li class="Box-row"></li>
-			</li>
-		</div>
-	`
-	lis, err := html.Parse(strings.NewReader(correctLiTag))
-	assert.NoError(t, err)
-	res := []*html.Node{}
-	findHtmlNodes(lis, ghTagLiFinder, &res)
-	assert.Len(t, res, 5)
+	tests := []struct {
+		name        string
+		probeStatus int
+		expect      func(*mocks.MockSSHConnection)
+		wantResult  string
+		wantErr     string
+	}{
+		{
+			name:        "promoted",
+			probeStatus: http.StatusOK,
+			expect: func(m *mocks.MockSSHConnection) {
+				gomock.InOrder(
+					m.EXPECT().ExecCommandPiped(rollingUpdateCmd(img, svcStackName)).Return(nil),
+					m.EXPECT().ExecCommand(inspectCmd).Return([]byte("updating\n"), nil),
+					m.EXPECT().ExecCommand(psCmd).Return(psStarting, nil),
+					m.EXPECT().ExecCommand(inspectCmd).Return([]byte("completed\n"), nil),
+					m.EXPECT().ExecCommand(psCmd).Return(psRunning, nil),
+				)
+			},
+			wantResult: rollingUpdatePromoted,
+		},
+		{
+			name:        "rolled back by swarm",
+			probeStatus: http.StatusOK,
+			expect: func(m *mocks.MockSSHConnection) {
+				gomock.InOrder(
+					m.EXPECT().ExecCommandPiped(rollingUpdateCmd(img, svcStackName)).Return(nil),
+					m.EXPECT().ExecCommand(inspectCmd).Return([]byte("rollback_completed\n"), nil),
+				)
+			},
+			wantResult: rollingUpdateRolledBack,
+		},
+		{
+			name:        "probe fails - rolled back",
+			probeStatus: http.StatusBadGateway,
+			expect: func(m *mocks.MockSSHConnection) {
+				gomock.InOrder(
+					m.EXPECT().ExecCommandPiped(rollingUpdateCmd(img, svcStackName)).Return(nil),
+					m.EXPECT().ExecCommand(inspectCmd).Return([]byte("completed\n"), nil),
+					m.EXPECT().ExecCommand(psCmd).Return(psRunning, nil),
+					m.EXPECT().ExecCommandPiped(rollbackCmd).Return(nil),
+				)
+			},
+			wantResult: rollingUpdateRolledBack,
+		},
+		{
+			name:        "update error",
+			probeStatus: http.StatusOK,
+			expect: func(m *mocks.MockSSHConnection) {
+				m.EXPECT().ExecCommandPiped(rollingUpdateCmd(img, svcStackName)).Return(assert.AnError)
+			},
+			wantErr: assert.AnError.Error(),
+		},
+	}
 
-	// Test if incorrect tag is correctly not found
-	correctLiTag = `<div class="Box-row">
-		<p></p>
-	</div>`
-	lis, err = html.Parse(strings.NewReader(correctLiTag))
-	assert.NoError(t, err)
-	res = []*html.Node{}
-	findHtmlNodes(lis, ghTagLiFinder, &res)
-	assert.Len(t, res, 0)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.probeStatus)
+			}))
+			defer srv.Close()
 
-	tree, err := html.Parse(strings.NewReader(testHtml))
-	assert.NoError(t, err)
-	assert.NotNil(t, tree)
+			ctl := gomock.NewController(t)
+			sshConn := mocks.NewMockSSHConnection(ctl)
+			tt.expect(sshConn)
 
-	res2 := []*html.Node{}
-	findHtmlNodes(tree, ghTagLiFinder, &res2)
-	assert.Len(t, res2, 1)
+			c := &Container{HttpClient: srv.Client()}
+			publicServices := []configs.D8XService{
+				{
+					Name:     configs.D8XServiceMainHTTP,
+					HostName: strings.TrimPrefix(srv.URL, "http://"),
+				},
+			}
 
+			result, err := c.rollingUpdateSwarmService(sshConn, "api", svcStackName, rollingUpdateCmd(img, svcStackName), publicServices)
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantResult, result)
+		})
+	}
+}
+
+func TestRestoreReferralKeyfile(t *testing.T) {
+	ctl := gomock.NewController(t)
+	sshConn := mocks.NewMockSSHConnection(ctl)
+	gomock.InOrder(
+		sshConn.EXPECT().ExecCommand(`echo 'pwd' | sudo -S bash -c "echo -n 'oldkey' > /var/nfs/general/keyfile.txt"`).Return(nil, nil),
+		sshConn.EXPECT().ExecCommandPiped("docker service scale --detach stack_referral=1").Return(nil),
+	)
+
+	require.NoError(t, restoreReferralKeyfile(sshConn, "pwd", "oldkey", "stack_referral"))
+	assert.Contains(t, rollingReferralUpdateCmd("img", "stack_referral"), "--image img --replicas 1")
+}
+
+func TestRestoreReferralService(t *testing.T) {
+	restoreKeyCmd := `echo 'pwd' | sudo -S bash -c "echo -n 'oldkey' > /var/nfs/general/keyfile.txt"`
+	scaleCmd := "docker service scale --detach stack_referral=1"
+
+	t.Run("failed update is rolled back", func(t *testing.T) {
+		sshConn := mocks.NewMockSSHConnection(gomock.NewController(t))
+		gomock.InOrder(
+			sshConn.EXPECT().ExecCommandPiped("docker service rollback stack_referral").Return(nil),
+			sshConn.EXPECT().ExecCommand(restoreKeyCmd).Return(nil, nil),
+			sshConn.EXPECT().ExecCommandPiped(scaleCmd).Return(nil),
+		)
+		assert.Equal(t, rollingUpdateFailed, restoreReferralService(sshConn, "pwd", "oldkey", "stack_referral", rollingUpdateFailed))
+	})
+
+	t.Run("rolled back update", func(t *testing.T) {
+		sshConn := mocks.NewMockSSHConnection(gomock.NewController(t))
+		gomock.InOrder(
+			sshConn.EXPECT().ExecCommand(restoreKeyCmd).Return(nil, nil),
+			sshConn.EXPECT().ExecCommandPiped(scaleCmd).Return(nil),
+		)
+		assert.Equal(t, rollingUpdateRolledBack, restoreReferralService(sshConn, "pwd", "oldkey", "stack_referral", rollingUpdateRolledBack))
+	})
+
+	t.Run("restore fails", func(t *testing.T) {
+		sshConn := mocks.NewMockSSHConnection(gomock.NewController(t))
+		sshConn.EXPECT().ExecCommand(restoreKeyCmd).Return(nil, assert.AnError)
+		assert.Equal(t, rollingUpdateFailed, restoreReferralService(sshConn, "pwd", "oldkey", "stack_referral", rollingUpdateRolledBack))
+	})
 }
@@ -7,12 +7,12 @@ import (
 	"fmt"
 	"io"
 	"os"
-	"os/exec"
 	"slices"
 	"strconv"
 	"strings"
 
 	"github.com/D8-X/d8x-cli/internal/actions/contracts"
+	"github.com/D8-X/d8x-cli/internal/components"
 	"github.com/D8-X/d8x-cli/internal/configs"
 	"github.com/D8-X/d8x-cli/internal/conn"
 	"github.com/D8-X/d8x-cli/internal/files"
@@ -27,6 +27,31 @@ import (
 // TODO - store this in config and make this configurable via flags
 var dockerStackName = "stack"
 
+// swarmDockerConfig is a docker config created from file on manager node
+type swarmDockerConfig struct {
+	name string
+	// Path of the source file (same locally and on manager)
+	file string
+}
+
+// Docker configs managed by the cli
+var swarmDockerConfigs = []swarmDockerConfig{
+	{name: "cfg_rpc", file: "./trader-backend/rpc.main.json"},
+	{name: "cfg_rpc_referral", file: "./trader-backend/rpc.referral.json"},
+	{name: "cfg_rpc_history", file: "./trader-backend/rpc.history.json"},
+	{name: "cfg_referral", file: "./trader-backend/live.referralSettings.json"},
+	{name: "cfg_prices", file: "./candles/prices.config.json"},
+	// {name: "prometheus_config", file: "./prometheus.yml"},
+}
+
+// Rpc config files of trader-backend services. Index of the file is the
+// serviceIndex of DistributeRpcs.
+var swarmRpcConfigFiles = []string{
+	"./trader-backend/rpc.main.json",
+	"./trader-backend/rpc.history.json",
+	"./trader-backend/rpc.referral.json",
+}
+
 // NginxConfigSection defines a comment section that can be uncommented via
 // processNginxConfigComments. Section starts with {NginxConfigSection} and ends
 // with {/NginxConfigSection}. All lines starting with # will be trimmed and
@@ -38,6 +63,32 @@ const (
 	EnableRateLimiting NginxConfigSection = "enable_rate_limiting"
 )
 
+// nfsExportsConfig generates /etc/exports contents of manager which allow nfs
+// access for given workers private ips
+func nfsExportsConfig(workerPrivateIps []string) string {
+	configEtcExports := "#"
+	for _, ip := range workerPrivateIps {
+		configEtcExports = configEtcExports + "\n" + fmt.Sprintf(`/var/nfs/general %s(rw,sync,no_subtree_check)`, ip)
+	}
+	return configEtcExports
+}
+
+// nfsUfwAllowCmd allows nfs port for worker ip on manager
+func nfsUfwAllowCmd(pwd, workerPrivateIp string) string {
+	return fmt.Sprintf(`echo '%s' | sudo -S bash -c "ufw allow from %s to any port nfs" `, pwd, workerPrivateIp)
+}
+
+// nfsMountCmd mounts manager nfs directory on worker
+func nfsMountCmd(pwd, managerPrivateIp string) string {
+	return fmt.Sprintf(`echo '%s' | sudo -S bash -c "mkdir -p /nfs/general && mount %s:/var/nfs/general /nfs/general"`, pwd, managerPrivateIp)
+}
+
+// nfsVolumeCreateCmd creates nfsvol docker volume backed by manager nfs
+// directory
+func nfsVolumeCreateCmd(managerPrivateIp string) string {
+	return fmt.Sprintf(`docker volume create --driver local --opt type=nfs4 --opt o=addr=%s,rw --opt device=:/var/nfs/general nfsvol`, managerPrivateIp)
+}
+
 // EditSwarmEnv edits the .env file for swarm deployment with user provided and
 // provisioning values.
 func (c *Container) EditSwarmEnv(envPath string, cfg *configs.D8XConfig) error {
@@ -203,6 +254,11 @@ func (c *Container) swarmDeploy(ctx *cli.Context, showConfigConfirmation bool) e
 	if err := c.CopySwarmDeployConfigs(); err != nil {
 		return err
 	}
+	if ctx.Bool("pin-images") {
+		if err := c.pinImages(ctx, "./docker-swarm-stack.yml"); err != nil {
+			return err
+		}
+	}
 
 	if c.Input.swarmDeployInput.guideConfig {
 		// Update .env file
@@ -211,11 +267,7 @@ func (c *Container) swarmDeploy(ctx *cli.Context, showConfigConfirmation bool) e
 		}
 
 		// Update rpcconfigs
-		for i, rpconfigFilePath := range []string{
-			"./trader-backend/rpc.main.json",
-			"./trader-backend/rpc.history.json",
-			"./trader-backend/rpc.referral.json",
-		} {
+		for i, rpconfigFilePath := range swarmRpcConfigFiles {
 			httpRpcs, wsRpcs := DistributeRpcs(
 				i,
 				strconv.Itoa(int(cfg.ChainId)),
@@ -338,7 +390,7 @@ func (c *Container) swarmDeploy(ctx *cli.Context, showConfigConfirmation bool) e
 	if _, err := managerSSHConn.ExecCommand(
 		"echo '" + pwd + "'| sudo -S docker stack ls | grep " + dockerStackName + " >/dev/null 2>&1",
 	); err == nil {
-		ok, err := c.TUI.NewPrompt("\nThere seems to be an existing stack deployed. Do you want to remove it before redeploying?", true)
+		ok, err := c.TUI.NewPrompt("\nThere seems to be an existing stack deployed. Do you want to remove it before redeploying?", true, components.PromptOptId("swarm_deploy.remove_existing_stack"))
 		if err != nil {
 			return err
 		}
@@ -368,12 +420,10 @@ func (c *Container) swarmDeploy(ctx *cli.Context, showConfigConfirmation bool) e
 	}
 	fmt.Println(styles.ItalicText.Render("Creating NFS Config..."))
 	cmd := fmt.Sprintf(`echo '%s' | sudo -S bash -c "mkdir /var/nfs/general -p && chown nobody:nogroup /var/nfs/general" `, pwd)
-	configEtcExports := "#"
 	for _, ip := range ipWorkersPriv {
-		cmdUfw := fmt.Sprintf(`&& echo '%s' | sudo -S bash -c "ufw allow from %s to any port nfs" `, pwd, ip)
-		cmd = cmd + cmdUfw
-		configEtcExports = configEtcExports + "\n" + fmt.Sprintf(`/var/nfs/general %s(rw,sync,no_subtree_check)`, ip)
+		cmd = cmd + "&& " + nfsUfwAllowCmd(pwd, ip)
 	}
+	configEtcExports := nfsExportsConfig(ipWorkersPriv)
 	_, err = managerSSHConn.ExecCommand(
 		cmd,
 	)
@@ -384,25 +434,6 @@ func (c *Container) swarmDeploy(ctx *cli.Context, showConfigConfirmation bool) e
 		return fmt.Errorf("temp storage of /etc/exports file failed: %w", err)
 	}
 
-	managedConfigNames := []string{
-		"cfg_rpc",
-		"cfg_rpc_referral",
-		"cfg_rpc_history",
-		"cfg_referral",
-		"cfg_prices",
-	}
-	// Lines of docker config commands which we will concat into single
-	// bash -c ssh call
-	dockerConfigsCMD := []string{
-		`docker config create cfg_rpc ./trader-backend/rpc.main.json >/dev/null 2>&1`,
-		`docker config create cfg_rpc_referral ./trader-backend/rpc.referral.json >/dev/null 2>&1`,
-		`docker config create cfg_rpc_history ./trader-backend/rpc.history.json >/dev/null 2>&1`,
-		`docker config create cfg_referral ./trader-backend/live.referralSettings.json >/dev/null 2>&1`,
-		`docker config create cfg_prices ./candles/prices.config.json >/dev/null 2>&1`,
-
-		// `docker config create prometheus_config ./prometheus.yml >/dev/null 2>&1`,
-	}
-
 	// List of files to transfer to manager
 	copyList := []conn.SftpCopySrcDest{
 		{Src: "./trader-backend/.env", Dst: "./trader-backend/.env"},
@@ -442,7 +473,7 @@ func (c *Container) swarmDeploy(ctx *cli.Context, showConfigConfirmation bool) e
 	}
 
 	fmt.Println(styles.ItalicText.Render("Mounting NFS directories on workers..."))
-	cmd = fmt.Sprintf(`echo '%s' | sudo -S bash -c "mkdir -p /nfs/general && mount %s:/var/nfs/general /nfs/general" `, pwd, ipMgrPriv)
+	cmd = nfsMountCmd(pwd, ipMgrPriv)
 	for k, ip := range ipWorkersPriv {
 		fmt.Println(styles.ItalicText.Render("worker "), ip)
 		var (
@@ -450,8 +481,8 @@ func (c *Container) swarmDeploy(ctx *cli.Context, showConfigConfirmation bool) e
 			err           error
 		)
 		if cfg.ServerProvider == configs.D8XServerProviderAWS {
-			sshConnWorker, err = conn.NewSSHConnectionWithBastion(
-				managerSSHConn.GetClient(),
+			sshConnWorker, err = c.CreateSSHConnWithBastion(
+				managerSSHConn,
 				ipWorkers[k],
 				c.DefaultClusterUserName,
 				c.SshKeyPath,
@@ -476,9 +507,7 @@ func (c *Container) swarmDeploy(ctx *cli.Context, showConfigConfirmation bool) e
 
 	// Recreate configs
 	fmt.Println(styles.ItalicText.Render("Creating docker configs..."))
-	out, err := managerSSHConn.ExecCommand(
-		"echo -e '" + strings.Join(managedConfigNames, "\n") + `' | while read -r configname; do docker config rm "$configname"; done;` + strings.Join(dockerConfigsCMD, ";"),
-	)
+	out, err := recreateSwarmDockerConfigs(managerSSHConn)
 	fmt.Println(string(out))
 	if err != nil {
 		return fmt.Errorf("creating docker configs: %w", err)
@@ -489,7 +518,7 @@ func (c *Container) swarmDeploy(ctx *cli.Context, showConfigConfirmation bool) e
 	fmt.Println(styles.ItalicText.Render("Preparing Docker volumes..."))
 
 	fmt.Printf("\nPrivate ip : %s\n", ipMgrPriv)
-	cmd = fmt.Sprintf(`docker volume create --driver local --opt type=nfs4 --opt o=addr=%s,rw --opt device=:/var/nfs/general nfsvol`, ipMgrPriv)
+	cmd = nfsVolumeCreateCmd(ipMgrPriv)
 	out, err = managerSSHConn.ExecCommand(
 		cmd,
 	)
@@ -499,23 +528,15 @@ func (c *Container) swarmDeploy(ctx *cli.Context, showConfigConfirmation bool) e
 	}
 	// create volume on worker nodes
 
-	cmd = fmt.Sprintf(
-		`docker volume create --driver local --opt type=nfs4 --opt o=addr=%s,rw --opt device=:/var/nfs/general nfsvol`,
-		ipMgrPriv,
-	)
-	cmdDir := fmt.Sprintf(
-		`echo '%s' | sudo -S bash -c "mkdir -p /nfs/general && mount %s:/var/nfs/general /nfs/general"`,
-		pwd,
-		ipMgrPriv,
-	)
+	cmdDir := nfsMountCmd(pwd, ipMgrPriv)
 	for _, ip := range ipWorkers {
 		var (
 			sshConnWorker conn.SSHConnection
 			err           error
 		)
 		if cfg.ServerProvider == configs.D8XServerProviderAWS {
-			sshConnWorker, err = conn.NewSSHConnectionWithBastion(
-				managerSSHConn.GetClient(),
+			sshConnWorker, err = c.CreateSSHConnWithBastion(
+				managerSSHConn,
 				ip,
 				c.DefaultClusterUserName,
 				c.SshKeyPath,
@@ -548,23 +569,52 @@ func (c *Container) swarmDeploy(ctx *cli.Context, showConfigConfirmation bool) e
 
 	// Deploy swarm stack
 	fmt.Println(styles.ItalicText.Render("Deploying docker swarm via manager node..."))
-	swarmDeployCMD := fmt.Sprintf(
-		`echo '%s' | sudo -S bash -c "docker compose --env-file ./trader-backend/.env -f ./docker-stack.yml config | sed -E 's/published: \"([0-9]+)\"/published: \1/g' | sed -E 's/^name: .*$/ /'|  docker stack deploy -c - %s"`,
-		pwd,
-		dockerStackName,
-	)
-	out, err = managerSSHConn.ExecCommand(swarmDeployCMD)
+	out, err = deploySwarmStack(managerSSHConn, pwd)
 	fmt.Println(string(out))
 	if err != nil {
 		return fmt.Errorf("swarm deployment failed: %w", err)
 	}
 	fmt.Println(styles.SuccessText.Render("D8X-trader-backend swarm was deployed"))
 
+	c.recordRelease(managerSSHConn, swarmReleaseTarget(), "swarm-deploy", 0)
+
 	// Update config
 	cfg.SwarmDeployed = true
 	return c.ConfigRWriter.Write(cfg)
 }
 
+// recreateSwarmDockerConfigs removes and recreates swarmDockerConfigs from
+// files on manager node. Versioned configs created by rpc rotation (see
+// nextSwarmConfigVersion) are removed as well.
+func recreateSwarmDockerConfigs(managerSSHConn conn.SSHConnection) ([]byte, error) {
+	managedConfigNames := []string{}
+	// Lines of docker config commands which we will concat into single
+	// bash -c ssh call
+	dockerConfigsCMD := []string{}
+	for _, dc := range swarmDockerConfigs {
+		managedConfigNames = append(managedConfigNames, dc.name)
+		dockerConfigsCMD = append(dockerConfigsCMD, fmt.Sprintf(`docker config create %s %s >/dev/null 2>&1`, dc.name, dc.file))
+	}
+
+	return managerSSHConn.ExecCommand(
+		fmt.Sprintf(
+			`docker config ls --format '{{.Name}}' | grep -E '^(%s)(_v[0-9]+)?$' | while read -r configname; do docker config rm "$configname"; done;`,
+			strings.Join(managedConfigNames, "|"),
+		) + strings.Join(dockerConfigsCMD, ";"),
+	)
+}
+
+// deploySwarmStack deploys ./docker-stack.yml with ./trader-backend/.env on
+// manager node
+func deploySwarmStack(managerSSHConn conn.SSHConnection, pwd string) ([]byte, error) {
+	swarmDeployCMD := fmt.Sprintf(
+		`echo '%s' | sudo -S bash -c "docker compose --env-file ./trader-backend/.env -f ./docker-stack.yml config | sed -E 's/published: \"([0-9]+)\"/published: \1/g' | sed -E 's/^name: .*$/ /'|  docker stack deploy -c - %s"`,
+		pwd,
+		dockerStackName,
+	)
+	return managerSSHConn.ExecCommand(swarmDeployCMD)
+}
+
 func (c *Container) SwarmNginx(ctx *cli.Context) error {
 	styles.PrintCommandTitle("Starting swarm nginx and certbot setup...")
 
@@ -661,13 +711,15 @@ func (c *Container) SwarmNginx(ctx *cli.Context) error {
 	// Run ansible-playbook for nginx setup on broker server
 	args := []string{
 		"--extra-vars", fmt.Sprintf(`ansible_ssh_private_key_file='%s'`, c.SshKeyPath),
-		"--extra-vars", "ansible_host_key_checking=false",
 		"--extra-vars", fmt.Sprintf(`ansible_become_pass='%s'`, password),
 		"-i", configs.DEFAULT_HOSTS_FILE,
 		"-u", c.DefaultClusterUserName,
 		"./playbooks/nginx.ansible.yaml",
 	}
-	cmd := exec.Command("ansible-playbook", args...)
+	cmd, err := c.ansiblePlaybookCmd(args...)
+	if err != nil {
+		return err
+	}
 	connectCMDToCurrentTerm(cmd)
 	if err := c.RunCmd(cmd); err != nil {
 		return err
@@ -694,7 +746,7 @@ func (c *Container) SwarmNginx(ctx *cli.Context) error {
 		fmt.Println(string(out))
 
 		if err != nil {
-			restart, err2 := c.TUI.NewPrompt("Certbot setup failed, do you want to restart the swarm-nginx setup?", true)
+			restart, err2 := c.TUI.NewPrompt("Certbot setup failed, do you want to restart the swarm-nginx setup?", true, components.PromptOptId("swarm_nginx.restart_on_certbot_failure"))
 			if err2 != nil {
 				return err2
 			}
@@ -776,7 +828,7 @@ func (c *Container) CheckSwarmIngressIsCorrect(ctx *cli.Context) error {
 	if err != nil {
 		return err
 	}
-	managerConn, err := conn.NewSSHConnection(managerIp, c.DefaultClusterUserName, c.SshKeyPath)
+	managerConn, err := c.CreateSSHConn(managerIp, c.DefaultClusterUserName, c.SshKeyPath)
 	if err != nil {
 		return err
 	}
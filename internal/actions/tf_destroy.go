@@ -5,7 +5,9 @@ import (
 	"os"
 	"os/exec"
 
+	"github.com/D8-X/d8x-cli/internal/components"
 	"github.com/D8-X/d8x-cli/internal/configs"
+	"github.com/D8-X/d8x-cli/internal/conn"
 	"github.com/D8-X/d8x-cli/internal/styles"
 	"github.com/urfave/cli/v2"
 )
@@ -13,7 +15,7 @@ import (
 func (c *Container) TerraformDestroy(ctx *cli.Context) error {
 	styles.PrintCommandTitle("Running terraform destroy...")
 
-	ok, err := c.TUI.NewPrompt("Are you sure you want to run terraform destroy? This will destroy all the resources created by d8x-cli. This action is irreversible!", false)
+	ok, err := c.TUI.NewPrompt("Are you sure you want to run terraform destroy? This will destroy all the resources created by d8x-cli. This action is irreversible!", false, components.PromptOptId("tf_destroy.confirm"))
 	if err != nil {
 		return err
 	}
@@ -27,6 +29,11 @@ func (c *Container) TerraformDestroy(ctx *cli.Context) error {
 		return err
 	}
 
+	if cfg.ServerProvider == configs.D8XServerProviderStatic {
+		fmt.Println("Servers were not provisioned with terraform, nothing to destroy")
+		return nil
+	}
+
 	fmt.Printf("Using provider from config: %s\n", cfg.ServerProvider)
 
 	var args []string = []string{
@@ -46,14 +53,25 @@ func (c *Container) TerraformDestroy(ctx *cli.Context) error {
 		if err != nil {
 			return err
 		}
-		awsConfigurer := &awsConfigurer{D8XAWSConfig: *a, authorizedKey: authorizedKey}
+		awsConfigurer := &awsConfigurer{D8XAWSConfig: *a, authorizedKey: authorizedKey, knownHostsFile: c.knownHostsPath()}
 		args = append(args, awsConfigurer.generateVariables()...)
 
 	case configs.D8XServerProviderLinode:
 		args = append(args, "-var", `authorized_keys=[""]`)
 		env = append(env, fmt.Sprintf("LINODE_TOKEN=%s", cfg.LinodeConfig.Token))
+
+	case configs.D8XServerProviderHetzner:
+		h := cfg.HetznerConfig
+		if h == nil {
+			return fmt.Errorf("hetzner config is not defined")
+		}
+		args = append(args, "-var", `authorized_key=""`)
+		env = append(env, fmt.Sprintf("HCLOUD_TOKEN=%s", h.Token))
 	}
 
+	// Terraform removes hosts.cfg, collect the addresses before destroying
+	destroyedIps := c.HostsCfg.GetAllPublicIps()
+
 	cmd := exec.Command("terraform", args...)
 	cmd.Env = append(os.Environ(), env...)
 	cmd.Dir = c.ProvisioningTfDir
@@ -63,6 +81,16 @@ func (c *Container) TerraformDestroy(ctx *cli.Context) error {
 		return err
 	}
 
+	// Host keys of destroyed servers must not be trusted for new servers
+	// which might reuse the same ip addresses
+	if c.KnownHosts != nil && c.DryRun == nil {
+		for _, ip := range destroyedIps {
+			if _, err := c.KnownHosts.Remove(conn.HostAddr(ip)); err != nil {
+				return err
+			}
+		}
+	}
+
 	// Update d8x config values and set deployment statuses to false
 	cfg.ResetDeploymentStatus()
 
@@ -56,6 +56,7 @@ Files created by setup and it's subcommands:
 	- password.txt - default user password on all servers
 	- aws_rds_postgres.txt - aws postgres instance credentials (only for AWS provider)
 	- manager_ssh_jump.conf - ssh config file for manager server to be used as jump host (only for AWS provider)
+	- <config directory>/known_hosts - recorded ssh host keys of your servers
 `
 
 const ProvisionDescription = `Command provision performs resource provisioning with terraform.
@@ -91,3 +92,264 @@ const SwarmNginxDescription = `Command swarm-nginx performs nginx + certbot setu
 const ConfigureDescription = `Command configure performs configuration of provisioned resources with ansible.`
 
 const DeployMetricsDescription = `Command metrics-deploy configures and deploys prometheus and grafana instances on manager node. `
+
+const SecretsDescription = `Command secrets manages the encrypted secrets vault.
+
+By default d8x.conf.json stores api tokens, database dsn and redis passwords in
+plain text and the user password is stored in password.txt. Once you run
+d8x secrets migrate, all secrets are moved to passphrase encrypted
+secrets.vault file in the config directory and are decrypted in memory only.
+
+Vault passphrase is read from D8X_VAULT_PASSPHRASE environment variable, the
+os keyring (when migrated with --keyring) or entered interactively.
+`
+
+const ReleasesDescription = `Command releases inspects the release ledger.
+
+A release is recorded whenever swarm-deploy, broker-deploy, update or rollback
+finishes. Each release holds image references resolved to sha256 digests,
+hashes of the docker configs, stack file and .env, and a snapshot of the
+deployed files, which is stored in the releases directory of the config directory.
+
+Use d8x rollback <release-id> to redeploy a recorded release.
+`
+
+const ImagesDescription = `Command images compares deployed images with their image references.
+
+d8x images status compares, for each service of docker-swarm-stack.yml and of
+the broker-server docker-compose.yml, the digest of the image running on the
+swarm (docker service inspect) and on the broker server with the digest which
+the image reference resolves to now in its registry:
+
+  current      running image is the image the tag resolves to
+  outdated     tag was moved to a newer image since the service was deployed
+  drift        image is pinned by digest, but a different image is running
+  not-running  service is not running
+  unknown      digest could not be retrieved
+
+d8x images pin rewrites image references of the local stack and compose files
+to <image>:<tag>@<digest>, so that exactly these images are deployed. Images
+which are already pinned are left as they are, remove the digest to pin the
+current image of the tag. Use --pin-images of swarm-deploy and broker-deploy to
+pin images right before deploying.
+`
+
+const ScaleDescription = `Command scale changes the number of swarm servers without full reprovisioning.
+
+d8x scale workers <n> applies terraform with the new number of workers. New
+workers are configured with setup playbook and joined to the swarm. When
+scaling down, removed workers are drained, their tasks are rescheduled on
+remaining workers and they are removed from the swarm before they are
+destroyed. hosts.cfg, NFS exports on manager and prometheus targets are updated
+to match the new workers.
+
+For static provider, ip addresses of new workers must be added to
+static_config.worker_ips in d8x.conf.json before scaling up.
+`
+
+const HealthDescription = `Command health performs health checks of deployed services endpoints and
+docker swarm services.
+
+With --watch, services are polled every --interval and the live status is
+displayed until you press q. State changes (service down and up again, replica
+shortfall and recovery, task restart loops) are sent to the configured
+webhooks:
+
+  --webhook-url         generic webhook, receives {"events": [...]} json
+  --slack-webhook-url   Slack compatible incoming webhook, receives {"text": "..."}
+  --telegram-bot-token  Telegram bot token, requires --telegram-chat-id
+`
+
+const HostsDescription = `Command hosts manages ssh host keys of your servers.
+
+Host keys are recorded in known_hosts file in the configuration directory right
+after provisioning. Keys of servers which are not recorded yet are trusted on
+first connection. All later ssh connections and ansible runs verify servers
+against the recorded keys and fail when a server presents a different key.
+
+When a server is legitimately rebuilt, use d8x hosts rekey <node> (manager,
+broker or worker-*) to replace its recorded key.
+`
+
+const ExecDescription = `Command exec runs the same command on multiple cluster nodes in parallel.
+
+--target selects the nodes: manager, workers, broker or all (default). Broker is
+included in all only when broker server exists. Workers of AWS setup are
+reached via manager. Command is run as the cluster user, use --sudo to run it
+with sudo.
+
+Output of each node is printed prefixed with the node name, followed by the
+exit status. Use --output json or --output yaml to get structured output.
+
+Examples:
+	d8x exec --target workers -- docker ps
+	d8x exec --target all --sudo -- apt-get update
+	d8x exec --target manager --output json -- df -h
+`
+
+const LogsDescription = `Command logs streams logs of a docker swarm service from the manager or of a
+broker-server service from the broker server.
+
+Service names are the names from docker stack and broker-server docker compose
+files, for example api, history or broker. Services which run both in swarm and
+on broker server (redis) must be prefixed with swarm/ or broker/.
+
+Lines are prefixed with the task or replica name, each prefix is shown in its
+own color. Use --save to additionally write the (filtered) logs to a .tar.gz
+archive, which you can attach to bug reports. When --follow is used, the
+archive is written after you stop the stream with ctrl+c.
+
+Examples:
+	d8x logs api --since 1h
+	d8x logs history --follow --grep error
+	d8x logs broker/redis --save redis-logs.tar.gz
+`
+
+const RestoreDbDescription = `Command restore-db restores a backup created by backup-db.
+
+The backup file is uploaded to the manager server and loaded with psql (plain
+SQL backups, .dump.sql[.gz|.zst]) or pg_restore (.dump and .dir.tar archives)
+from the postgresql-client package, which is installed on the manager if
+needed. Database credentials are read from d8x.conf.json file.
+
+By default the configured database is restored. Use --database to restore into
+a different database, which is created when it does not exist. Existing
+database is dropped and recreated, you are asked for confirmation before that
+happens.
+
+Use --stop-services to scale history and referral services to zero while the
+restore runs. Services are scaled back to their previous replicas afterwards.
+
+Examples:
+	d8x restore-db --stop-services backup-d8x-2024-01-02-13-23-37.dump.sql
+	d8x restore-db --database d8x_restored backup-d8x-2024-01-02-13-23-37.dump.sql
+`
+
+const BackupDbScheduleDescription = `Command backup-db schedule installs a systemd timer on the manager server which
+periodically backs up the database.
+
+Each backup is created with pg_dump in custom compressed format, encrypted with
+age to the provided public keys (--public-key, a file with age1... or ssh
+public keys, one per line) and uploaded to a S3 compatible bucket (AWS S3,
+MinIO, etc) with rclone. --keep-local latest backups are kept on the manager,
+backups older than --retention-days are removed from the bucket.
+
+Settings are stored in d8x.conf.json (bucket credentials in secrets store), so
+running the command again only requires the flags you want to change.
+
+Use d8x backup-db list to see stored backups and d8x backup-db fetch <name> to
+download one of them. Backups are decrypted and restored with:
+
+	age --decrypt -i <identity file> -o backup.dump <backup name>
+	pg_restore -h <host> -U <user> -d <database> backup.dump
+
+Examples:
+	d8x backup-db schedule --public-key ./backup-recipients.txt --s3-endpoint https://s3.eu-central-1.amazonaws.com --s3-region eu-central-1 --s3-bucket my-backups
+	d8x backup-db schedule --on-calendar '*-*-* 03:00:00' --retention-days 14 --now
+`
+
+const BackupDbDescription = `Command backup-db backs up the database to local machine. Database credentials
+are read from d8x.conf.json file.
+
+By default a plain SQL backup is created on the manager server and downloaded.
+Use --format custom or --format directory to create pg_dump archives (directory
+backups are downloaded as tar archive) and --compress to choose the compression
+(none, gzip, zstd, optionally with level, e.g. zstd:10). --table and
+--exclude-table select the tables to back up and can be repeated.
+
+With --stream, pg_dump output is piped over the ssh connection straight into
+the local file, so no backup file is stored on the manager. Checksum of every
+downloaded or streamed backup is verified.
+
+Examples:
+	d8x backup-db --format custom --compress zstd --stream
+	d8x backup-db --exclude-table 'trades_*' --output-dir db-backups
+`
+
+const ProfileDescription = `Command profile manages named profiles of the working directory.
+
+Profiles let you manage multiple clusters (for example testnet and mainnet) from
+a single workspace. Each profile is stored in profiles/<name> directory and
+holds its own config directory, hosts.cfg, password.txt, terraform state, ssh
+key and generated deployment files.
+
+All commands use the active profile, which is recorded in .d8x-profile file of
+the workspace. Use --profile (or D8X_PROFILE environment variable) to run a
+single command against a different profile. Without an active profile, the
+workspace directory itself is used, as in earlier versions.
+
+Use d8x profile create --import <name> to move an existing setup of the
+workspace directory into a new profile.
+
+Examples:
+	d8x profile create --use testnet
+	d8x profile create --import mainnet
+	d8x --profile mainnet health
+	d8x profile use --none
+`
+
+const ConfigDescription = `Command config validates and migrates d8x.conf.json.
+
+d8x.conf.json contains schema_version field. Configs written by older versions
+of d8x are upgraded to the current schema version whenever they are read. A
+backup of the previous config is saved next to it (d8x.conf.json.backup-<time>)
+before the upgraded config is written. Use d8x config migrate to upgrade the
+config right away.
+
+d8x config validate reports missing and inconsistent fields, for example
+deployed swarm without manager ip in hosts.cfg, https services without
+certbot or chain id which is not supported (not found in chain.json). Command
+exits with non zero status when errors are found.
+`
+
+const RpcDescription = `Command rpc manages the http and websocket rpc endpoints of d8x.conf.json.
+
+d8x rpc check calls eth_chainId and eth_blockNumber on every http endpoint and
+additionally subscribes to newHeads on every websocket endpoint. Endpoints which
+serve a different chain than the configured chain id, are unreachable or do not
+deliver new blocks fail the check. Healthy endpoints are ranked by block lag
+(number of blocks behind the most recent endpoint) and latency. Command exits
+with non zero status when any endpoint fails.
+
+With --apply, endpoints are reordered by rank in d8x.conf.json. Endpoints are
+distributed to services in this order on swarm-deploy and broker-deploy, so the
+main service receives the best endpoint. Endpoints serving a different chain
+are removed. While other endpoints fail the check, the list is not changed,
+unless --keep-unhealthy is given to keep them at the end of the list.
+
+d8x rpc set|add|remove edit the endpoints of the configured chain and rotate
+them on deployed services without redeploying the swarm. Urls are passed as
+arguments, http(s):// urls edit the http list and ws(s):// urls edit the
+websocket list. set replaces only the lists of transports which were given.
+New endpoints are verified like in d8x rpc check (skip with --no-verify).
+
+Rpc config files (rpc.main.json, rpc.history.json, rpc.referral.json and
+broker-server rpc.json) are regenerated and uploaded. Docker configs can not be
+changed, so a new version of every rpc config is created (cfg_rpc_v2,
+cfg_rpc_v3, etc.) and only the services which use it are updated with a rolling
+update. The previous version is removed once no service uses it. On broker
+server, only the broker container is recreated. The next swarm-deploy replaces
+versioned configs with the regular ones.
+
+Example:
+
+	d8x rpc add https://rpc-1.example.com wss://ws-1.example.com
+	d8x rpc remove https://rpc-2.example.com
+
+Endpoints are distributed to services (main, history, referral and broker,
+websockets only to main and history) according to rpc_distribution of
+d8x.conf.json. Strategy of each service is one of:
+
+	round_robin  endpoints are dealt like cards between round_robin services
+	             (default)
+	pinned       service receives only its urls, pinned urls are not given to
+	             other services
+	weighted     endpoints are dealt between weighted services, endpoint with
+	             weight n is dealt n times
+	tiered       service receives all endpoints of its tier, lower priority
+	             tiers (higher numbers) are used when there are not enough
+
+min_count sets the minimum number of endpoints of a service, missing endpoints
+are added from endpoints which are not pinned. d8x rpc distribution shows the
+resulting endpoints of every service.
+`
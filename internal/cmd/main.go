@@ -6,8 +6,11 @@ import (
 	"os"
 	"path/filepath"
 	"slices"
+	"strings"
+	"time"
 
 	"github.com/D8-X/d8x-cli/internal/actions"
+	"github.com/D8-X/d8x-cli/internal/components"
 	"github.com/D8-X/d8x-cli/internal/configs"
 	"github.com/D8-X/d8x-cli/internal/flags"
 	"github.com/D8-X/d8x-cli/internal/styles"
@@ -35,6 +38,10 @@ func RunD8XCli() {
 		log.Fatal(err)
 	}
 
+	// Set when interactive session is being recorded
+	var recorder *components.RecordingRunner
+	recordAnswersPath := ""
+
 	// Shared flags below
 
 	// Default terraform files and state directory (./terraform)
@@ -75,7 +82,11 @@ func RunD8XCli() {
 						pwd, err := container.GetPassword(ctx)
 						if err == nil && len(pwd) > 0 {
 							container.UserPassword = pwd
-							fmt.Printf("User password retrieved from %s\n", configs.DEFAULT_PASSWORD_FILE)
+							if container.Secrets != nil {
+								fmt.Println("User password retrieved from secrets vault")
+							} else {
+								fmt.Printf("User password retrieved from %s\n", configs.DEFAULT_PASSWORD_FILE)
+							}
 						}
 					}
 
@@ -119,6 +130,7 @@ func RunD8XCli() {
 						Name:   "broker-deploy",
 						Usage:  "Deploy and configure broker-server deployment",
 						Action: container.BrokerDeploy,
+						Flags:  pinImagesFlags,
 					},
 					{
 						Name:   "broker-nginx",
@@ -130,6 +142,7 @@ func RunD8XCli() {
 						Usage:       "Deploy and configure d8x-trader-backend swarm cluster",
 						Action:      container.SwarmDeploy,
 						Description: SwarmDeployDescription,
+						Flags:       pinImagesFlags,
 					},
 					{
 						Name:        "swarm-nginx",
@@ -149,17 +162,52 @@ func RunD8XCli() {
 				Name:   "update",
 				Usage:  "Update service with new image version",
 				Action: container.ServiceUpdate,
+				Flags:  registryFlags,
 			},
 			{
 				Name:   "health",
 				Usage:  "Perform health checks of deployed services",
 				Action: container.HealthCheck,
+				Flags: []cli.Flag{
+					outputFlag,
+					&cli.BoolFlag{
+						Name:  "watch",
+						Usage: "Keep polling services health and show live status",
+					},
+					&cli.DurationFlag{
+						Name:  "interval",
+						Value: time.Second * 30,
+						Usage: "Polling interval of --watch mode",
+					},
+					&cli.StringFlag{
+						Name:    "webhook-url",
+						EnvVars: []string{"D8X_HEALTH_WEBHOOK_URL"},
+						Usage:   "Generic webhook which receives json health state change events in --watch mode",
+					},
+					&cli.StringFlag{
+						Name:    "slack-webhook-url",
+						EnvVars: []string{"D8X_HEALTH_SLACK_WEBHOOK_URL"},
+						Usage:   "Slack compatible incoming webhook which receives health state change messages in --watch mode",
+					},
+					&cli.StringFlag{
+						Name:    "telegram-bot-token",
+						EnvVars: []string{"D8X_HEALTH_TELEGRAM_BOT_TOKEN"},
+						Usage:   "Telegram bot token used to send health state change messages in --watch mode",
+					},
+					&cli.StringFlag{
+						Name:    "telegram-chat-id",
+						EnvVars: []string{"D8X_HEALTH_TELEGRAM_CHAT_ID"},
+						Usage:   "Telegram chat id which receives health state change messages in --watch mode",
+					},
+				},
+				Description: HealthDescription,
 			},
 			{
 				Name:      "ip",
 				Usage:     "Retrieve node ip addresses",
 				ArgsUsage: "manager|broker",
 				Action:    container.Ips,
+				Flags:     []cli.Flag{outputFlag},
 			},
 			{
 				Name:   "tf-destroy",
@@ -171,6 +219,51 @@ func RunD8XCli() {
 				Usage:  "Attach ssh session to one of your servers",
 				Action: container.SSH,
 			},
+			{
+				Name:        "exec",
+				Usage:       "Run a command on cluster nodes",
+				ArgsUsage:   "-- <cmd>",
+				Description: ExecDescription,
+				Action:      container.Exec,
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "target",
+						Value: actions.ExecTargetAll,
+						Usage: "Nodes to run the command on: manager, workers, broker or all",
+					},
+					&cli.BoolFlag{
+						Name:  "sudo",
+						Usage: "Run the command with sudo",
+					},
+					outputFlag,
+				},
+			},
+			{
+				Name:        "logs",
+				Usage:       "Stream logs of a swarm or broker-server service",
+				ArgsUsage:   "<service>",
+				Description: LogsDescription,
+				Action:      container.Logs,
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:    "follow",
+						Aliases: []string{"f"},
+						Usage:   "Follow log output",
+					},
+					&cli.StringFlag{
+						Name:  "since",
+						Usage: "Show logs since timestamp (2024-01-02T13:23:37Z) or relative duration (1h, 30m)",
+					},
+					&cli.StringFlag{
+						Name:  "grep",
+						Usage: "Show only lines matching the regular expression",
+					},
+					&cli.StringFlag{
+						Name:  "save",
+						Usage: "Save the logs to a .tar.gz archive at given path",
+					},
+				},
+			},
 			{
 				Name:      "grafana-tunnel",
 				Usage:     "Create ssh tunnel to grafana service on manager",
@@ -179,10 +272,10 @@ func RunD8XCli() {
 			},
 			{
 				Name:        "cp-configs",
-				ArgsUsage:   "swarm|broker|tf-aws|tf-linode",
+				ArgsUsage:   "swarm|broker|tf-aws|tf-linode|tf-hetzner",
 				Action:      container.CopyConfigs,
 				Usage:       "Copy configuration files to current working directory",
-				Description: "Copy specified configuration files to current working directory. Available configs are swarm, broker, tf-aws, tf-linode.",
+				Description: "Copy specified configuration files to current working directory. Available configs are swarm, broker, tf-aws, tf-linode, tf-hetzner.",
 			},
 			{
 				Name:   "backup-db",
@@ -192,8 +285,120 @@ func RunD8XCli() {
 						Name:  "output-dir",
 						Usage: "Backup directory path. Backup files will be saved in this directory.",
 					},
+					&cli.StringFlag{
+						Name:  "format",
+						Value: "plain",
+						Usage: "pg_dump output format: plain, custom or directory",
+					},
+					&cli.StringFlag{
+						Name:  "compress",
+						Usage: "Compression method: none, gzip or zstd, with optional level (zstd:10). Defaults to none for plain format and gzip otherwise.",
+					},
+					&cli.StringSliceFlag{
+						Name:  "table",
+						Usage: "Backup only tables matching the pattern, can be repeated",
+					},
+					&cli.StringSliceFlag{
+						Name:  "exclude-table",
+						Usage: "Do not backup tables matching the pattern, can be repeated",
+					},
+					&cli.BoolFlag{
+						Name:  "stream",
+						Usage: "Stream pg_dump output directly to local file without storing it on manager",
+					},
+				},
+				Description: BackupDbDescription,
+				Subcommands: []*cli.Command{
+					{
+						Name:        "schedule",
+						Usage:       "Schedule encrypted database backups to S3 compatible bucket",
+						Description: BackupDbScheduleDescription,
+						Action:      container.BackupDbSchedule,
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:  "on-calendar",
+								Usage: "systemd OnCalendar expression of backup schedule, for example daily or *-*-* 03:00:00",
+							},
+							&cli.StringFlag{
+								Name:  "public-key",
+								Usage: "Path to age recipients file (age or ssh public keys) which backups are encrypted to",
+							},
+							&cli.StringFlag{
+								Name:  "s3-endpoint",
+								Usage: "S3 compatible endpoint url, for example https://s3.us-east-1.amazonaws.com or http://minio:9000",
+							},
+							&cli.StringFlag{
+								Name:  "s3-region",
+								Usage: "Bucket region",
+							},
+							&cli.StringFlag{
+								Name:  "s3-bucket",
+								Usage: "Bucket name",
+							},
+							&cli.StringFlag{
+								Name:  "s3-prefix",
+								Usage: "Key prefix of backups in the bucket, defaults to <servers label>/",
+							},
+							&cli.StringFlag{
+								Name:    "s3-access-key",
+								Usage:   "Bucket access key",
+								EnvVars: []string{"D8X_BACKUP_S3_ACCESS_KEY"},
+							},
+							&cli.StringFlag{
+								Name:    "s3-secret-key",
+								Usage:   "Bucket secret key",
+								EnvVars: []string{"D8X_BACKUP_S3_SECRET_KEY"},
+							},
+							&cli.IntFlag{
+								Name:  "keep-local",
+								Usage: "Number of latest backups kept on manager (default 3)",
+							},
+							&cli.IntFlag{
+								Name:  "retention-days",
+								Usage: "Backups older than this are removed from the bucket (default 30)",
+							},
+							&cli.BoolFlag{
+								Name:  "now",
+								Usage: "Run the first backup right away",
+							},
+						},
+					},
+					{
+						Name:   "list",
+						Usage:  "List scheduled backups stored on manager and in the bucket",
+						Action: container.BackupDbList,
+						Flags:  []cli.Flag{outputFlag},
+					},
+					{
+						Name:      "fetch",
+						Usage:     "Download scheduled backup to local machine",
+						ArgsUsage: "<backup name>",
+						Action:    container.BackupDbFetch,
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:  "output-dir",
+								Usage: "Directory where backup file is saved",
+							},
+						},
+					},
+				},
+			},
+			{
+				Name:        "restore-db",
+				Action:      container.RestoreDb,
+				ArgsUsage:   "<backup file>",
+				Usage:       "Restore database from a backup-db backup file",
+				Description: RestoreDbDescription,
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "database",
+						Usage: "Restore into this database instead of the configured one. Database is created when it does not exist.",
+					},
+					&cli.BoolFlag{
+						Name:  "stop-services",
+						Usage: "Scale history and referral services to zero while the restore runs",
+					},
 				},
-				Description: "Backup database to local machine. Database credentials are read from d8x.conf.json file.",
 			},
 			{
 				Name:        "db-tunnel",
@@ -206,6 +411,245 @@ func RunD8XCli() {
 				Usage:  "Fix faulty ingress network",
 				Action: container.IngressFix,
 			},
+			{
+				Name:        "scale",
+				Usage:       "Scale swarm cluster servers",
+				Description: ScaleDescription,
+				Subcommands: []*cli.Command{
+					{
+						Name:      "workers",
+						Usage:     "Change the number of swarm worker servers",
+						ArgsUsage: "<number of workers>",
+						Action:    container.ScaleWorkers,
+					},
+				},
+			},
+			{
+				Name:        "hosts",
+				Usage:       "Manage recorded ssh host keys of your servers",
+				Description: HostsDescription,
+				Subcommands: []*cli.Command{
+					{
+						Name:      "rekey",
+						Usage:     "Replace the recorded host key of a rebuilt server",
+						ArgsUsage: "manager|broker|worker-*",
+						Action:    container.HostsRekey,
+					},
+				},
+			},
+			{
+				Name:        "releases",
+				Usage:       "Inspect recorded releases of swarm and broker-server deployments",
+				Description: ReleasesDescription,
+				Subcommands: []*cli.Command{
+					{
+						Name:   "list",
+						Usage:  "List recorded releases",
+						Action: container.ReleasesList,
+					},
+					{
+						Name:      "show",
+						Usage:     "Show image digests and config hashes of a release",
+						ArgsUsage: "<release-id>",
+						Action:    container.ReleasesShow,
+					},
+					{
+						Name:      "diff",
+						Usage:     "Show differences between two releases",
+						ArgsUsage: "<release-id> <release-id>",
+						Action:    container.ReleasesDiff,
+					},
+				},
+			},
+			{
+				Name:      "rollback",
+				Usage:     "Redeploy a previously recorded release",
+				ArgsUsage: "<release-id>",
+				Action:    container.Rollback,
+			},
+			{
+				Name:        "images",
+				Usage:       "Compare running images with image tags and pin images by digest",
+				Description: ImagesDescription,
+				Subcommands: []*cli.Command{
+					{
+						Name:   "status",
+						Usage:  "Show whether running images match the digests their tags resolve to",
+						Flags:  append([]cli.Flag{outputFlag}, registryFlags...),
+						Action: container.ImagesStatus,
+					},
+					{
+						Name:   "pin",
+						Usage:  "Pin images of local stack and compose files by digest",
+						Flags:  registryFlags,
+						Action: container.ImagesPin,
+					},
+				},
+			},
+			{
+				Name:        "rpc",
+				Usage:       "Manage rpc endpoints of d8x.conf.json",
+				Description: RpcDescription,
+				Subcommands: []*cli.Command{
+					{
+						Name:  "check",
+						Usage: "Check chain id, block lag and latency of rpc endpoints and rank them",
+						Flags: []cli.Flag{
+							outputFlag,
+							&cli.BoolFlag{
+								Name:  "apply",
+								Usage: "Reorder rpc endpoints in d8x.conf.json by rank, best endpoints are distributed to services first. Endpoints serving a different chain are removed",
+							},
+							&cli.BoolFlag{
+								Name:  "keep-unhealthy",
+								Usage: "With --apply, keep unreachable endpoints at the end of the list instead of refusing to apply",
+							},
+							&cli.IntFlag{
+								Name:  "samples",
+								Value: 3,
+								Usage: "Number of eth_blockNumber requests used to measure latency of each endpoint",
+							},
+							&cli.DurationFlag{
+								Name:  "timeout",
+								Value: 15 * time.Second,
+								Usage: "Timeout of checking a single endpoint, including waiting for a new block on websocket endpoints",
+							},
+						},
+						Action: container.RpcCheck,
+					},
+					{
+						Name:   "distribution",
+						Usage:  "Show which rpc endpoints each service receives",
+						Flags:  []cli.Flag{outputFlag},
+						Action: container.RpcDistribution,
+					},
+					{
+						Name:      "set",
+						Usage:     "Replace http and/or websocket rpc endpoints and rotate them on deployed services",
+						ArgsUsage: "<url>...",
+						Flags:     rpcRotateFlags,
+						Action:    container.RpcSet,
+					},
+					{
+						Name:      "add",
+						Usage:     "Add rpc endpoints and rotate them on deployed services",
+						ArgsUsage: "<url>...",
+						Flags:     rpcRotateFlags,
+						Action:    container.RpcAdd,
+					},
+					{
+						Name:      "remove",
+						Usage:     "Remove rpc endpoints and rotate them on deployed services",
+						ArgsUsage: "<url>...",
+						Flags:     rpcRotateFlags,
+						Action:    container.RpcRemove,
+					},
+				},
+			},
+			{
+				Name:        "config",
+				Usage:       "Validate and migrate d8x.conf.json",
+				Description: ConfigDescription,
+				Subcommands: []*cli.Command{
+					{
+						Name:   "validate",
+						Usage:  "Report missing and inconsistent fields of d8x.conf.json",
+						Flags:  []cli.Flag{outputFlag},
+						Action: container.ConfigValidate,
+					},
+					{
+						Name:   "migrate",
+						Usage:  "Upgrade d8x.conf.json to the current schema version",
+						Action: container.ConfigMigrate,
+					},
+				},
+			},
+			{
+				Name:        "profile",
+				Usage:       "Manage named profiles (clusters) of the working directory",
+				Description: ProfileDescription,
+				Subcommands: []*cli.Command{
+					{
+						Name:   "list",
+						Usage:  "List profiles of the working directory",
+						Flags:  []cli.Flag{outputFlag},
+						Action: container.ProfileList,
+					},
+					{
+						Name:      "create",
+						Usage:     "Create a new profile",
+						ArgsUsage: "<profile name>",
+						Flags: []cli.Flag{
+							&cli.BoolFlag{
+								Name:  "import",
+								Usage: "Move existing d8x files (config, hosts.cfg, terraform state, ssh key, etc.) of the working directory into the profile",
+							},
+							&cli.BoolFlag{
+								Name:  "use",
+								Usage: "Make the created profile active",
+							},
+						},
+						Action: container.ProfileCreate,
+					},
+					{
+						Name:      "use",
+						Usage:     "Set the active profile",
+						ArgsUsage: "<profile name>",
+						Flags: []cli.Flag{
+							&cli.BoolFlag{
+								Name:  "none",
+								Usage: "Deactivate profiles and use the working directory itself",
+							},
+						},
+						Action: container.ProfileUse,
+					},
+					{
+						Name:      "delete",
+						Usage:     "Delete a profile and all of its files",
+						ArgsUsage: "<profile name>",
+						Flags: []cli.Flag{
+							&cli.BoolFlag{
+								Name:  "force",
+								Usage: "Delete the profile even if its terraform state contains provisioned resources",
+							},
+						},
+						Action: container.ProfileDelete,
+					},
+				},
+			},
+			{
+				Name:        "secrets",
+				Usage:       "Manage encrypted secrets vault",
+				Description: SecretsDescription,
+				Subcommands: []*cli.Command{
+					{
+						Name:  "migrate",
+						Usage: "Move plain text secrets from d8x.conf.json, password.txt and redis_broker_password.txt to encrypted vault",
+						Flags: []cli.Flag{
+							&cli.BoolFlag{
+								Name:  "keyring",
+								Usage: "Store the vault passphrase in os keyring instead of entering it manually",
+							},
+							&cli.BoolFlag{
+								Name:  "keep-files",
+								Usage: "Do not remove migrated password.txt and redis_broker_password.txt files",
+							},
+						},
+						Action: container.SecretsMigrate,
+					},
+					{
+						Name:   "list",
+						Usage:  "List names of stored secrets",
+						Action: container.SecretsList,
+					},
+					{
+						Name:      "get",
+						Usage:     "Print the value of stored secret",
+						ArgsUsage: "<secret name>",
+						Action:    container.SecretsGet,
+					},
+				},
+			},
 		},
 		// Global flags accessible to all subcommands
 		Flags: []cli.Flag{
@@ -238,11 +682,28 @@ func RunD8XCli() {
 				Name:  "chdir",
 				Usage: "Change directory to provided one before executing anything",
 			},
+			&cli.StringFlag{
+				Name:    flags.Profile,
+				EnvVars: []string{configs.PROFILE_ENV},
+				Usage:   "Profile to use instead of the active profile of working directory, see d8x profile --help",
+			},
 			&cli.BoolFlag{
 				Name:    "quiet",
 				Aliases: []string{"q"},
 				Usage:   "Suppress verbose output",
 			},
+			&cli.StringFlag{
+				Name:  flags.Answers,
+				Usage: "Answers file (yaml) used to answer all prompts non-interactively. Missing answers result in an error.",
+			},
+			&cli.BoolFlag{
+				Name:  flags.DryRun,
+				Usage: "Do not execute any remote commands, file copies or local commands, print the plan of actions instead",
+			},
+			&cli.StringFlag{
+				Name:  flags.RecordAnswers,
+				Usage: "Record answers of interactive session into provided answers file which can be later used with --" + flags.Answers,
+			},
 		},
 		Action: func(ctx *cli.Context) error {
 			// Disallow running d8x with incorrect subcommands
@@ -265,12 +726,22 @@ func RunD8XCli() {
 				filepath.Join(container.ConfigDir, configs.DEFAULT_D8X_CONFIG_NAME),
 			)
 
-			// Initialize the input collector
-			container.Input = &actions.InputCollector{
-				ConfigRWriter: container.ConfigRWriter,
-				TUI:           container.TUI,
-				ChainJson:     chainJsonData,
-				SSHKeyPath:    container.SshKeyPath,
+			// Answer prompts from answers file or record the interactive
+			// session when requested
+			if answersFile := ctx.String(flags.Answers); answersFile != "" {
+				runner, err := components.NewAnswersRunnerFromFile(answersFile)
+				if err != nil {
+					return err
+				}
+				container.TUI = runner
+			} else if recordFile := ctx.String(flags.RecordAnswers); recordFile != "" {
+				// Resolve the path before --chdir is applied
+				recordAnswersPath, err = filepath.Abs(recordFile)
+				if err != nil {
+					return err
+				}
+				recorder = components.NewRecordingRunner(container.TUI)
+				container.TUI = recorder
 			}
 
 			// Chdir functionality
@@ -281,8 +752,37 @@ func RunD8XCli() {
 				}
 			}
 
+			// Switch to the profile directory. Profile command manages the
+			// profiles of workspace itself.
+			profileCmd := ctx.Args().First() == "profile"
+			if !profileCmd {
+				if err := container.UseProfile(ctx.String(flags.Profile)); err != nil {
+					return err
+				}
+			}
+
+			// Use encrypted secrets vault when it exists
+			container.InitSecretsStore()
+
+			if ctx.Bool(flags.DryRun) {
+				container.EnableDryRun()
+			}
+			container.EnableSSHPool()
+
+			// Initialize the input collector
+			container.Input = &actions.InputCollector{
+				ConfigRWriter: container.ConfigRWriter,
+				TUI:           container.TUI,
+				ChainJson:     chainJsonData,
+				SSHKeyPath:    container.SshKeyPath,
+			}
+
+			// Verify ssh host keys against d8x managed known_hosts
+			container.InitKnownHosts()
+			container.InitSSHTransport()
+
 			// Welcome msg
-			if !ctx.Bool("quiet") {
+			if !ctx.Bool("quiet") && !structuredOutputRequested(ctx.Args().Slice()) {
 				fmt.Println(
 					styles.PurpleBgText.
 						Copy().
@@ -290,18 +790,109 @@ func RunD8XCli() {
 						Border(lipgloss.NormalBorder()).
 						Render(D8XASCII),
 				)
+				if container.Profile != "" {
+					fmt.Println(styles.ItalicText.Render("Using profile " + container.Profile))
+				}
 			}
 
 			// Create config directory if it does not exist already
+			if profileCmd {
+				return nil
+			}
 			if err := container.MakeConfigDir(); err != nil {
 				return fmt.Errorf("could not create config directory: %w", err)
 			}
 
 			return nil
 		},
+		After: func(ctx *cli.Context) error {
+			container.CloseSSHConnections()
+			container.PrintDryRunPlan()
+
+			if recorder == nil {
+				return nil
+			}
+			if err := recorder.Save(recordAnswersPath); err != nil {
+				return fmt.Errorf("saving recorded answers: %w", err)
+			}
+			fmt.Printf("Answers were recorded in %s\n", recordAnswersPath)
+			return nil
+		},
 	}
 
 	if err := app.Run(os.Args); err != nil {
 		log.Fatal(err)
 	}
 }
+
+// outputFlag is the --output flag of commands supporting structured output
+var outputFlag = &cli.StringFlag{
+	Name:    flags.Output,
+	Aliases: []string{"o"},
+	Value:   actions.OutputText,
+	Usage:   "Output format: text, json or yaml",
+}
+
+// rpcRotateFlags are the flags of rpc set, add and remove commands
+var rpcRotateFlags = []cli.Flag{
+	&cli.BoolFlag{
+		Name:  "no-verify",
+		Usage: "Do not check chain id and block retrieval of new endpoints",
+	},
+	&cli.BoolFlag{
+		Name:  "no-rollout",
+		Usage: "Only update d8x.conf.json, endpoints are distributed on the next swarm-deploy and broker-deploy",
+	},
+	&cli.DurationFlag{
+		Name:  "timeout",
+		Value: 15 * time.Second,
+		Usage: "Timeout of verifying a single endpoint",
+	},
+}
+
+// registryFlags are credentials of private image registries
+var registryFlags = []cli.Flag{
+	&cli.StringFlag{
+		Name:    "registry-username",
+		Usage:   "Username of private image registry",
+		EnvVars: []string{"D8X_REGISTRY_USERNAME"},
+	},
+	&cli.StringFlag{
+		Name:    "registry-password",
+		Usage:   "Password or token of private image registry",
+		EnvVars: []string{"D8X_REGISTRY_PASSWORD"},
+	},
+}
+
+// pinImagesFlags are the flags of swarm-deploy and broker-deploy commands
+var pinImagesFlags = append([]cli.Flag{
+	&cli.BoolFlag{
+		Name:  "pin-images",
+		Usage: "Pin images of stack (compose) file by the digests their tags resolve to before deploying",
+	},
+}, registryFlags...)
+
+// structuredOutputRequested reports whether json or yaml output was requested
+// for the subcommand in args. Subcommand flags are not parsed yet when app
+// Before runs, but welcome message must not be printed in structured output.
+func structuredOutputRequested(args []string) bool {
+	for i, arg := range args {
+		// Arguments of the command passed to exec
+		if arg == "--" {
+			break
+		}
+		value := ""
+		switch {
+		case arg == "--"+flags.Output || arg == "-o":
+			if i+1 < len(args) {
+				value = args[i+1]
+			}
+		case strings.HasPrefix(arg, "--"+flags.Output+"="):
+			value = strings.TrimPrefix(arg, "--"+flags.Output+"=")
+		}
+		if value == actions.OutputJSON || value == actions.OutputYAML {
+			return true
+		}
+	}
+	return false
+}
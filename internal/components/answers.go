@@ -0,0 +1,320 @@
+package components
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/D8-X/d8x-cli/internal/styles"
+	"gopkg.in/yaml.v2"
+)
+
+var _ (ComponentsRunner) = (*AnswersRunner)(nil)
+
+// AnswersRunner is a non-interactive ComponentsRunner which answers every
+// prompt from a predefined answers file instead of waiting for user input.
+// Answers are looked up by prompt id (see TextInputOptId, SelectionOptId,
+// ListOptId and PromptOptId). Prompts without explicit id fall back to an id
+// derived from their label. A missing or invalid answer results in an error.
+//
+// Answers file is a flat yaml map of prompt id to the answer. When the same
+// prompt is shown multiple times, a list of answers can be provided which will
+// be consumed in order. A null answer accepts the prompt's default value.
+// Environment variables (${VAR}) in string answers are expanded, which allows
+// to keep secrets out of the answers file.
+type AnswersRunner struct {
+	answers map[string]any
+	// Number of already consumed answers for each prompt id
+	consumed map[string]int
+
+	out io.Writer
+}
+
+// NewAnswersRunnerFromFile reads the answers file and creates a new
+// AnswersRunner
+func NewAnswersRunnerFromFile(path string) (*AnswersRunner, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading answers file: %w", err)
+	}
+	return NewAnswersRunner(contents)
+}
+
+// NewAnswersRunner creates a new AnswersRunner from yaml contents
+func NewAnswersRunner(contents []byte) (*AnswersRunner, error) {
+	answers := map[string]any{}
+	if err := yaml.Unmarshal(contents, &answers); err != nil {
+		return nil, fmt.Errorf("parsing answers file: %w", err)
+	}
+
+	return &AnswersRunner{
+		answers:  answers,
+		consumed: map[string]int{},
+		out:      os.Stdout,
+	}, nil
+}
+
+// next returns the next answer for given prompt id. Second return value is
+// false if answer for given id is not present. When isList is true, list
+// answer is treated as a single answer unless it is a list of lists.
+func (a *AnswersRunner) next(id string, isList bool) (any, bool, error) {
+	v, ok := a.answers[id]
+	if !ok {
+		return nil, false, fmt.Errorf("no answer provided for prompt %q", id)
+	}
+
+	seq, isSeq := v.([]any)
+	if isSeq && isList && (len(seq) == 0 || !isSlice(seq[0])) {
+		isSeq = false
+	}
+	if !isSeq {
+		return v, v != nil, nil
+	}
+
+	i := a.consumed[id]
+	if i >= len(seq) {
+		return nil, false, fmt.Errorf("all %d answers for prompt %q were already used", len(seq), id)
+	}
+	a.consumed[id]++
+
+	return seq[i], seq[i] != nil, nil
+}
+
+// RejectAnswer must be called when the answer of prompt id was rejected and
+// the prompt is about to be shown again. AnswersRunner would return the same
+// answer on every retry, therefore an error is returned whenever runner
+// answers from a file. For interactive runners nil is returned.
+func RejectAnswer(runner ComponentsRunner, id, reason string) error {
+	if r, ok := runner.(*RecordingRunner); ok {
+		runner = r.Runner
+	}
+	if _, ok := runner.(*AnswersRunner); ok {
+		return fmt.Errorf("answer for prompt %q was rejected: %s", id, reason)
+	}
+	return nil
+}
+
+// print outputs the prompt id and the answer which was used
+func (a *AnswersRunner) print(id, label, answer string) {
+	if label != "" {
+		fmt.Fprintln(a.out, label)
+	}
+	fmt.Fprintf(a.out, "%s %s\n", styles.GrayText.Render("["+id+"]"), answer)
+}
+
+func (a *AnswersRunner) NewConfirmation(text string) error {
+	fmt.Fprintln(a.out, text)
+	return nil
+}
+
+func (a *AnswersRunner) NewInput(opts ...TextInputOpt) (string, error) {
+	mdl := newInputModel(opts...)
+	id := inputId(mdl)
+
+	v, ok, err := a.next(id, false)
+	if err != nil {
+		return "", err
+	}
+	value := strings.TrimSpace(mdl.textInput.Value())
+	if ok {
+		value = strings.TrimSpace(os.ExpandEnv(answerString(v)))
+	}
+
+	if mdl.denyEmpty && value == "" {
+		return "", fmt.Errorf("answer for prompt %q must not be empty", id)
+	}
+	for _, validator := range mdl.validators {
+		if !validator.valid(value) {
+			return "", fmt.Errorf("answer for prompt %q is invalid: %s", id, validator.errorMsg)
+		}
+	}
+
+	shown := value
+	if mdl.masked {
+		shown = strings.Repeat("*", len(value))
+	}
+	a.print(id, "", shown)
+
+	return value, nil
+}
+
+func (a *AnswersRunner) NewList(listItems []ListItem, listTitle string, opts ...ListOpt) (ListItem, error) {
+	mdl := newListModel(listItems, listTitle, opts...)
+	id := listId(mdl)
+
+	v, ok, err := a.next(id, false)
+	if err != nil {
+		return ListItem{}, err
+	}
+	if !ok {
+		item, _ := mdl.list.SelectedItem().(ListItem)
+		a.print(id, listTitle, item.ItemTitle)
+		return item, nil
+	}
+
+	answer := os.ExpandEnv(answerString(v))
+	for _, item := range listItems {
+		if item.ItemTitle == answer {
+			a.print(id, listTitle, item.ItemTitle)
+			return item, nil
+		}
+	}
+
+	return ListItem{}, fmt.Errorf("answer %q for prompt %q is not one of the available items", answer, id)
+}
+
+func (a *AnswersRunner) NewPrompt(question string, confirmed bool, opts ...PromptOpt) (bool, error) {
+	mdl := newPromptModel(question, confirmed, opts...)
+	id := promptId(mdl)
+
+	v, ok, err := a.next(id, false)
+	if err != nil {
+		return false, err
+	}
+	if ok {
+		confirmed, err = answerBool(v)
+		if err != nil {
+			return false, fmt.Errorf("answer for prompt %q: %w", id, err)
+		}
+	}
+
+	a.print(id, question, yesNo(confirmed))
+	return confirmed, nil
+}
+
+func (a *AnswersRunner) NewSelection(selection []string, opts ...SelectionOpts) ([]string, error) {
+	mdl := newSelectionModel(selection, opts...)
+	id := selectionId(mdl)
+
+	v, ok, err := a.next(id, true)
+	if err != nil {
+		return nil, err
+	}
+
+	selected := []string{}
+	if !ok {
+		for i := range mdl.selected {
+			if mdl.selected[i] {
+				selected = append(selected, selection[i])
+			}
+		}
+	} else {
+		items := []any{v}
+		if l, isList := v.([]any); isList {
+			items = l
+		}
+		for _, item := range items {
+			answer := os.ExpandEnv(answerString(item))
+			if !slices.Contains(selection, answer) {
+				return nil, fmt.Errorf("answer %q for prompt %q is not one of the available options", answer, id)
+			}
+			selected = append(selected, answer)
+		}
+	}
+
+	if mdl.oneSelectedOnly && len(selected) > 1 {
+		return nil, fmt.Errorf("prompt %q allows only a single item to be selected", id)
+	}
+	if mdl.requireSelection && len(selected) == 0 {
+		return nil, fmt.Errorf("prompt %q requires at least one item to be selected", id)
+	}
+
+	a.print(id, "", strings.Join(selected, ", "))
+	return selected, nil
+}
+
+func (a *AnswersRunner) NewSpinner(done chan struct{}, text string) error {
+	fmt.Fprintln(a.out, text)
+	<-done
+	return nil
+}
+
+func (a *AnswersRunner) NewTimer(timeout time.Duration, title string) error {
+	fmt.Fprintln(a.out, title)
+	time.Sleep(timeout)
+	return nil
+}
+
+var nonIdChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+// maxDerivedIdLength limits the length of ids derived from the prompt labels
+const maxDerivedIdLength = 64
+
+// deriveId creates a prompt id from given label. Used only when prompt does
+// not have an explicit id set.
+func deriveId(label string) string {
+	id := strings.Trim(nonIdChars.ReplaceAllString(strings.ToLower(label), "_"), "_")
+	if len(id) > maxDerivedIdLength {
+		id = strings.TrimRight(id[:maxDerivedIdLength], "_")
+	}
+	return id
+}
+
+func inputId(m inputModel) string {
+	if m.id != "" {
+		return m.id
+	}
+	return "input." + deriveId(m.textInput.Placeholder)
+}
+
+func listId(m listModel) string {
+	if m.id != "" {
+		return m.id
+	}
+	return "list." + deriveId(m.list.Title)
+}
+
+func promptId(m promptModel) string {
+	if m.id != "" {
+		return m.id
+	}
+	return "prompt." + deriveId(m.question)
+}
+
+func selectionId(m selectionModel) string {
+	if m.id != "" {
+		return m.id
+	}
+	return "selection." + deriveId(strings.Join(m.selection, " "))
+}
+
+func isSlice(v any) bool {
+	_, ok := v.([]any)
+	return ok
+}
+
+func answerString(v any) string {
+	if v == nil {
+		return ""
+	}
+	return fmt.Sprint(v)
+}
+
+func answerBool(v any) (bool, error) {
+	switch val := v.(type) {
+	case bool:
+		return val, nil
+	case string:
+		val = strings.ToLower(strings.TrimSpace(os.ExpandEnv(val)))
+		switch val {
+		case "y", "yes":
+			return true, nil
+		case "n", "no":
+			return false, nil
+		}
+		return strconv.ParseBool(val)
+	}
+	return false, fmt.Errorf("expected yes/no answer, got %v", v)
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
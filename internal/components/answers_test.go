@@ -0,0 +1,260 @@
+package components
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestAnswersRunner(t *testing.T, answers string) *AnswersRunner {
+	r, err := NewAnswersRunner([]byte(answers))
+	require.NoError(t, err)
+	r.out = io.Discard
+	return r
+}
+
+func TestAnswersRunnerNewInput(t *testing.T) {
+	tests := []struct {
+		name    string
+		answers string
+		opts    []TextInputOpt
+		want    string
+		wantErr string
+	}{
+		{
+			name:    "answer",
+			answers: "token: abc",
+			opts:    []TextInputOpt{TextInputOptId("token")},
+			want:    "abc",
+		},
+		{
+			name:    "missing answer",
+			answers: "other: abc",
+			opts:    []TextInputOpt{TextInputOptId("token")},
+			wantErr: `no answer provided for prompt "token"`,
+		},
+		{
+			name:    "null accepts default",
+			answers: "fee: ~",
+			opts:    []TextInputOpt{TextInputOptId("fee"), TextInputOptValue("0.06")},
+			want:    "0.06",
+		},
+		{
+			name:    "empty answer denied",
+			answers: "fee: ~",
+			opts:    []TextInputOpt{TextInputOptId("fee"), TextInputOptDenyEmpty()},
+			wantErr: `answer for prompt "fee" must not be empty`,
+		},
+		{
+			name:    "invalid answer",
+			answers: "port: abc",
+			opts: []TextInputOpt{
+				TextInputOptId("port"),
+				TextInputOptValidation(func(s string) bool { return s == "22" }, "must be 22"),
+			},
+			wantErr: `answer for prompt "port" is invalid: must be 22`,
+		},
+		{
+			name:    "id derived from placeholder",
+			answers: "input.rpc_url: https://rpc.example.com",
+			opts:    []TextInputOpt{TextInputOptPlaceholder("RPC url")},
+			want:    "https://rpc.example.com",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := newTestAnswersRunner(t, tt.answers).NewInput(tt.opts...)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestAnswersRunnerRepeatedAnswers(t *testing.T) {
+	r := newTestAnswersRunner(t, "rpc: [https://a, https://b]")
+
+	for _, want := range []string{"https://a", "https://b"} {
+		got, err := r.NewInput(TextInputOptId("rpc"))
+		require.NoError(t, err)
+		assert.Equal(t, want, got)
+	}
+	_, err := r.NewInput(TextInputOptId("rpc"))
+	assert.EqualError(t, err, `all 2 answers for prompt "rpc" were already used`)
+}
+
+func TestAnswersRunnerMaskedInput(t *testing.T) {
+	t.Setenv("D8X_LINODE_TOKEN", "secret-token")
+	r := newTestAnswersRunner(t, "linode.token: ${D8X_LINODE_TOKEN}")
+	out := &strings.Builder{}
+	r.out = out
+
+	got, err := r.NewInput(TextInputOptId("linode.token"), TextInputOptMasked())
+	require.NoError(t, err)
+	assert.Equal(t, "secret-token", got)
+	assert.NotContains(t, out.String(), "secret-token")
+	assert.Contains(t, out.String(), strings.Repeat("*", len("secret-token")))
+}
+
+func TestAnswersRunnerNewList(t *testing.T) {
+	items := []ListItem{{ItemTitle: "linode"}, {ItemTitle: "aws"}}
+	tests := []struct {
+		name    string
+		answers string
+		opts    []ListOpt
+		want    string
+		wantErr string
+	}{
+		{
+			name:    "by value",
+			answers: "provider: aws",
+			want:    "aws",
+		},
+		{
+			name:    "by value of env",
+			answers: "provider: ${D8X_TEST_PROVIDER}",
+			want:    "linode",
+		},
+		{
+			name:    "null accepts default",
+			answers: "provider: ~",
+			opts:    []ListOpt{ListOptSelectedItem(items[1])},
+			want:    "aws",
+		},
+		{
+			name:    "unknown item",
+			answers: "provider: gcp",
+			wantErr: `answer "gcp" for prompt "provider" is not one of the available items`,
+		},
+		{
+			name:    "missing answer",
+			answers: "other: aws",
+			wantErr: `no answer provided for prompt "provider"`,
+		},
+	}
+	t.Setenv("D8X_TEST_PROVIDER", "linode")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := append([]ListOpt{ListOptId("provider")}, tt.opts...)
+			got, err := newTestAnswersRunner(t, tt.answers).NewList(items, "Provider", opts...)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got.ItemTitle)
+		})
+	}
+}
+
+func TestAnswersRunnerNewSelection(t *testing.T) {
+	options := []string{"main", "history", "referral"}
+	tests := []struct {
+		name    string
+		answers string
+		opts    []SelectionOpts
+		want    []string
+		wantErr string
+	}{
+		{
+			name:    "single value",
+			answers: "services: history",
+			want:    []string{"history"},
+		},
+		{
+			name:    "list of values",
+			answers: "services: [main, referral]",
+			want:    []string{"main", "referral"},
+		},
+		{
+			name:    "null accepts default",
+			answers: "services: ~",
+			opts:    []SelectionOpts{SelectionOptSelectedValue("referral")},
+			want:    []string{"referral"},
+		},
+		{
+			name:    "unknown option",
+			answers: "services: [main, api]",
+			wantErr: `answer "api" for prompt "services" is not one of the available options`,
+		},
+		{
+			name:    "single item only",
+			answers: "services: [main, history]",
+			opts:    []SelectionOpts{SelectionOptAllowOnlySingleItem()},
+			wantErr: `prompt "services" allows only a single item to be selected`,
+		},
+		{
+			name:    "selection required",
+			answers: "services: []",
+			opts:    []SelectionOpts{SelectionOptRequireSelection()},
+			wantErr: `prompt "services" requires at least one item to be selected`,
+		},
+		{
+			name:    "missing answer",
+			answers: "other: main",
+			wantErr: `no answer provided for prompt "services"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := append([]SelectionOpts{SelectionOptId("services")}, tt.opts...)
+			got, err := newTestAnswersRunner(t, tt.answers).NewSelection(options, opts...)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestAnswersRunnerRepeatedSelections(t *testing.T) {
+	r := newTestAnswersRunner(t, "services: [[main], [history, referral]]")
+
+	for _, want := range [][]string{{"main"}, {"history", "referral"}} {
+		got, err := r.NewSelection([]string{"main", "history", "referral"}, SelectionOptId("services"))
+		require.NoError(t, err)
+		assert.Equal(t, want, got)
+	}
+}
+
+func TestAnswersRunnerNewPrompt(t *testing.T) {
+	tests := []struct {
+		answers string
+		want    bool
+		wantErr string
+	}{
+		{answers: "deploy: yes", want: true},
+		{answers: "deploy: n", want: false},
+		{answers: `deploy: "true"`, want: true},
+		{answers: "deploy: ~", want: true},
+		{answers: "deploy: maybe", wantErr: `answer for prompt "deploy"`},
+		{answers: "other: yes", wantErr: `no answer provided for prompt "deploy"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.answers, func(t *testing.T) {
+			got, err := newTestAnswersRunner(t, tt.answers).NewPrompt("Deploy?", true, PromptOptId("deploy"))
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestRejectAnswer(t *testing.T) {
+	answers := newTestAnswersRunner(t, "key: invalid\n")
+
+	assert.ErrorContains(t, RejectAnswer(answers, "key", "invalid key"), `answer for prompt "key" was rejected: invalid key`)
+	assert.Error(t, RejectAnswer(NewRecordingRunner(answers), "key", "invalid key"))
+	assert.NoError(t, RejectAnswer(InteractiveRunner{}, "key", "invalid key"))
+}
@@ -13,7 +13,7 @@ type TextInputOpt interface {
 	Apply(*inputModel)
 }
 
-func newInput(opts ...TextInputOpt) (string, error) {
+func newInputModel(opts ...TextInputOpt) inputModel {
 	ti := textinput.New()
 	ti.Focus()
 	mdl := inputModel{
@@ -25,6 +25,12 @@ func newInput(opts ...TextInputOpt) (string, error) {
 		opt.Apply(&mdl)
 	}
 
+	return mdl
+}
+
+func newInput(opts ...TextInputOpt) (string, error) {
+	mdl := newInputModel(opts...)
+
 	p := tea.NewProgram(mdl)
 	m, err := p.Run()
 	if err != nil {
@@ -69,6 +75,9 @@ type inputModel struct {
 	// List of input validation rules to run
 	validators             []textInputOptValidation
 	currentValidationError string
+
+	// Stable prompt id used by non-interactive runners
+	id string
 }
 
 func (m inputModel) Init() tea.Cmd {
@@ -229,3 +238,17 @@ func (t textInputOptValidation) Apply(s *inputModel) {
 func TextInputOptValidation(fn func(string) bool, errMsg string) TextInputOpt {
 	return textInputOptValidation{errorMsg: errMsg, valid: fn}
 }
+
+type textInputOptId struct {
+	id string
+}
+
+func (t textInputOptId) Apply(s *inputModel) {
+	s.id = t.id
+}
+
+// TextInputOptId sets the stable prompt id which is used to look up answers
+// when running non-interactively (see AnswersRunner).
+func TextInputOptId(id string) TextInputOpt {
+	return textInputOptId{id}
+}
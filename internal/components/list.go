@@ -21,6 +21,9 @@ func (i ListItem) FilterValue() string { return i.ItemTitle }
 
 type listModel struct {
 	list list.Model
+
+	// Stable prompt id used by non-interactive runners
+	id string
 }
 
 func (m listModel) Init() tea.Cmd {
@@ -51,7 +54,7 @@ func (m listModel) View() string {
 	return docStyle.Render(m.list.View())
 }
 
-func newList(listItems []ListItem, listTitle string, opts ...ListOpt) (ListItem, error) {
+func newListModel(listItems []ListItem, listTitle string, opts ...ListOpt) listModel {
 	items := make([]list.Item, len(listItems))
 
 	for i, itm := range listItems {
@@ -67,6 +70,12 @@ func newList(listItems []ListItem, listTitle string, opts ...ListOpt) (ListItem,
 		opt(&m)
 	}
 
+	return m
+}
+
+func newList(listItems []ListItem, listTitle string, opts ...ListOpt) (ListItem, error) {
+	m := newListModel(listItems, listTitle, opts...)
+
 	p := tea.NewProgram(m, tea.WithAltScreen())
 
 	mdl, err := p.Run()
@@ -93,3 +102,11 @@ func ListOptSelectedItem(item ListItem) ListOpt {
 		}
 	}
 }
+
+// Set the stable prompt id which is used to look up answers when running
+// non-interactively (see AnswersRunner).
+func ListOptId(id string) ListOpt {
+	return func(model *listModel) {
+		model.id = id
+	}
+}
@@ -8,11 +8,24 @@ import (
 	"github.com/charmbracelet/lipgloss"
 )
 
-func newPrompt(question string, confirmed bool) (bool, error) {
-	p := tea.NewProgram(promptModel{
+// PromptOpt modifies promptModel instance
+type PromptOpt interface {
+	Apply(*promptModel)
+}
+
+func newPromptModel(question string, confirmed bool, opts ...PromptOpt) promptModel {
+	m := promptModel{
 		question:  question,
 		confirmed: confirmed,
-	})
+	}
+	for _, opt := range opts {
+		opt.Apply(&m)
+	}
+	return m
+}
+
+func newPrompt(question string, confirmed bool, opts ...PromptOpt) (bool, error) {
+	p := tea.NewProgram(newPromptModel(question, confirmed, opts...))
 	out, err := p.Run()
 
 	if err != nil {
@@ -33,6 +46,9 @@ var _ tea.Model = (*promptModel)(nil)
 type promptModel struct {
 	question  string
 	confirmed bool
+
+	// Stable prompt id used by non-interactive runners
+	id string
 }
 
 func (m promptModel) View() string {
@@ -71,3 +87,19 @@ func (m promptModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	}
 	return m, nil
 }
+
+var _ PromptOpt = (*promptOptId)(nil)
+
+type promptOptId struct {
+	id string
+}
+
+func (opt promptOptId) Apply(m *promptModel) {
+	m.id = opt.id
+}
+
+// PromptOptId sets the stable prompt id which is used to look up answers when
+// running non-interactively (see AnswersRunner).
+func PromptOptId(id string) PromptOpt {
+	return promptOptId{id}
+}
@@ -0,0 +1,122 @@
+package components
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+var _ (ComponentsRunner) = (*RecordingRunner)(nil)
+
+// RecordingRunner wraps another ComponentsRunner and records every answer
+// given by the user under its prompt id. Recorded session can be saved as an
+// answers file which is accepted by AnswersRunner. Masked inputs are never
+// written to the answers file, an environment variable reference is recorded
+// instead.
+type RecordingRunner struct {
+	Runner ComponentsRunner
+
+	// Recorded answers in order of appearance
+	answers yaml.MapSlice
+	// Number of recorded answers for each prompt id
+	counts map[string]int
+}
+
+func NewRecordingRunner(runner ComponentsRunner) *RecordingRunner {
+	return &RecordingRunner{Runner: runner}
+}
+
+// record stores the answer for given prompt id. Repeated prompts are stored
+// as a list of answers.
+func (r *RecordingRunner) record(id string, value any) {
+	if r.counts == nil {
+		r.counts = map[string]int{}
+	}
+	r.counts[id]++
+
+	for i, item := range r.answers {
+		if item.Key != id {
+			continue
+		}
+		if r.counts[id] == 2 {
+			r.answers[i].Value = []any{item.Value, value}
+		} else {
+			r.answers[i].Value = append(item.Value.([]any), value)
+		}
+		return
+	}
+	r.answers = append(r.answers, yaml.MapItem{Key: id, Value: value})
+}
+
+// Save writes recorded answers to given file path
+func (r *RecordingRunner) Save(path string) error {
+	out, err := yaml.Marshal(r.answers)
+	if err != nil {
+		return fmt.Errorf("marshalling answers: %w", err)
+	}
+	return os.WriteFile(path, out, 0600)
+}
+
+func (r *RecordingRunner) NewConfirmation(text string) error {
+	return r.Runner.NewConfirmation(text)
+}
+
+func (r *RecordingRunner) NewInput(opts ...TextInputOpt) (string, error) {
+	value, err := r.Runner.NewInput(opts...)
+	if err != nil {
+		return value, err
+	}
+
+	mdl := newInputModel(opts...)
+	id := inputId(mdl)
+	if mdl.masked {
+		r.record(id, "${"+answerEnvName(id)+"}")
+	} else {
+		r.record(id, value)
+	}
+
+	return value, nil
+}
+
+func (r *RecordingRunner) NewList(listItems []ListItem, listTitle string, opts ...ListOpt) (ListItem, error) {
+	item, err := r.Runner.NewList(listItems, listTitle, opts...)
+	if err != nil {
+		return item, err
+	}
+	r.record(listId(newListModel(listItems, listTitle, opts...)), item.ItemTitle)
+	return item, nil
+}
+
+func (r *RecordingRunner) NewPrompt(question string, confirmed bool, opts ...PromptOpt) (bool, error) {
+	ok, err := r.Runner.NewPrompt(question, confirmed, opts...)
+	if err != nil {
+		return ok, err
+	}
+	r.record(promptId(newPromptModel(question, confirmed, opts...)), ok)
+	return ok, nil
+}
+
+func (r *RecordingRunner) NewSelection(selection []string, opts ...SelectionOpts) ([]string, error) {
+	selected, err := r.Runner.NewSelection(selection, opts...)
+	if err != nil {
+		return selected, err
+	}
+	r.record(selectionId(newSelectionModel(selection, opts...)), selected)
+	return selected, nil
+}
+
+func (r *RecordingRunner) NewSpinner(done chan struct{}, text string) error {
+	return r.Runner.NewSpinner(done, text)
+}
+
+func (r *RecordingRunner) NewTimer(timeout time.Duration, title string) error {
+	return r.Runner.NewTimer(timeout, title)
+}
+
+// answerEnvName creates environment variable name for given prompt id
+func answerEnvName(id string) string {
+	return "D8X_" + strings.ToUpper(deriveId(id))
+}
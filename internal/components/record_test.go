@@ -0,0 +1,56 @@
+package components
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordTestSession runs the same prompts as an interactive setup would
+func recordTestSession(t *testing.T, r ComponentsRunner) []any {
+	provider, err := r.NewList([]ListItem{{ItemTitle: "linode"}, {ItemTitle: "aws"}}, "Provider", ListOptId("provider"))
+	require.NoError(t, err)
+	token, err := r.NewInput(TextInputOptId("linode.token"), TextInputOptMasked())
+	require.NoError(t, err)
+	rpc1, err := r.NewInput(TextInputOptId("rpc"))
+	require.NoError(t, err)
+	rpc2, err := r.NewInput(TextInputOptId("rpc"))
+	require.NoError(t, err)
+	services, err := r.NewSelection([]string{"main", "history", "referral"}, SelectionOptId("services"))
+	require.NoError(t, err)
+	deploy, err := r.NewPrompt("Deploy?", false, PromptOptId("deploy"))
+	require.NoError(t, err)
+	return []any{provider.ItemTitle, token, rpc1, rpc2, services, deploy}
+}
+
+func TestRecordingRunnerRoundTrip(t *testing.T) {
+	t.Setenv("D8X_LINODE_TOKEN", "secret-token")
+	source := newTestAnswersRunner(t, `
+provider: aws
+linode.token: secret-token
+rpc: [https://a, https://b]
+services: [main, referral]
+deploy: yes
+`)
+	recorder := NewRecordingRunner(source)
+	recorded := recordTestSession(t, recorder)
+
+	path := filepath.Join(t.TempDir(), "answers.yaml")
+	require.NoError(t, recorder.Save(path))
+	contents, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(contents), "secret-token")
+	assert.Contains(t, string(contents), "${D8X_LINODE_TOKEN}")
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	replay, err := NewAnswersRunnerFromFile(path)
+	require.NoError(t, err)
+	replay.out = source.out
+	assert.Equal(t, recorded, recordTestSession(t, replay))
+	assert.Equal(t, []any{"aws", "secret-token", "https://a", "https://b", []string{"main", "referral"}, true}, recorded)
+}
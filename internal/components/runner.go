@@ -12,7 +12,7 @@ type ComponentsRunner interface {
 	NewConfirmation(text string) error
 	NewInput(opts ...TextInputOpt) (string, error)
 	NewList(listItems []ListItem, listTitle string, opts ...ListOpt) (ListItem, error)
-	NewPrompt(question string, confirmed bool) (bool, error)
+	NewPrompt(question string, confirmed bool, opts ...PromptOpt) (bool, error)
 	NewSelection(selection []string, opts ...SelectionOpts) ([]string, error)
 	NewSpinner(done chan struct{}, text string) error
 	NewTimer(timeout time.Duration, title string) error
@@ -35,8 +35,8 @@ func (InteractiveRunner) NewList(listItems []ListItem, listTitle string, opts ..
 	return newList(listItems, listTitle, opts...)
 }
 
-func (InteractiveRunner) NewPrompt(question string, confirmed bool) (bool, error) {
-	return newPrompt(question, confirmed)
+func (InteractiveRunner) NewPrompt(question string, confirmed bool, opts ...PromptOpt) (bool, error) {
+	return newPrompt(question, confirmed, opts...)
 }
 
 func (InteractiveRunner) NewSelection(selection []string, opts ...SelectionOpts) ([]string, error) {
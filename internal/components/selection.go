@@ -18,14 +18,7 @@ type SelectionOpts interface {
 // success. If SelectionOptAllowOnlySingleItem option is passed, returned slice
 // will always contain up to 1 item.
 func newSelection(selection []string, opts ...SelectionOpts) ([]string, error) {
-	s := selectionModel{
-		selection: selection,
-		selected:  make([]bool, len(selection)),
-	}
-
-	for _, opt := range opts {
-		opt.Apply(&s)
-	}
+	s := newSelectionModel(selection, opts...)
 
 	out, err := tea.NewProgram(s).Run()
 
@@ -49,6 +42,19 @@ func newSelection(selection []string, opts ...SelectionOpts) ([]string, error) {
 	return selected, nil
 }
 
+func newSelectionModel(selection []string, opts ...SelectionOpts) selectionModel {
+	s := selectionModel{
+		selection: selection,
+		selected:  make([]bool, len(selection)),
+	}
+
+	for _, opt := range opts {
+		opt.Apply(&s)
+	}
+
+	return s
+}
+
 var _ tea.Model = (*selectionModel)(nil)
 
 type selectionModel struct {
@@ -62,6 +68,9 @@ type selectionModel struct {
 
 	// Requires at least 1 item to be selected
 	requireSelection bool
+
+	// Stable prompt id used by non-interactive runners
+	id string
 }
 
 func (m selectionModel) View() string {
@@ -207,3 +216,18 @@ func (opt selectOptSelectEnter) Apply(s *selectionModel) {
 func SelectOptSelectEnter() SelectionOpts {
 	return selectOptSelectEnter{}
 }
+
+// selectionOptId sets the stable prompt id
+type selectionOptId struct {
+	id string
+}
+
+func (opt selectionOptId) Apply(s *selectionModel) {
+	s.id = opt.id
+}
+
+// SelectionOptId sets the stable prompt id which is used to look up answers
+// when running non-interactively (see AnswersRunner).
+func SelectionOptId(id string) SelectionOpts {
+	return selectionOptId{id}
+}
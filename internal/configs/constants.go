@@ -15,4 +15,36 @@ const (
 
 	// Default ansible hosts.cfg (ini format) file path
 	DEFAULT_HOSTS_FILE = "./hosts.cfg"
+
+	// Default file name of encrypted secrets vault in config directory
+	DEFAULT_SECRETS_VAULT_NAME = "secrets.vault"
+
+	// Environment variable which can be used to provide the secrets vault
+	// passphrase non-interactively
+	VAULT_PASSPHRASE_ENV = "D8X_VAULT_PASSPHRASE"
+
+	// Directory in config directory where release ledger and snapshots of
+	// deployed files are stored
+	DEFAULT_RELEASES_DIR_NAME = "releases"
+
+	// File name of release ledger in releases directory
+	DEFAULT_RELEASES_LEDGER_NAME = "ledger.json"
+
+	// Default file name of d8x managed ssh known_hosts file in config
+	// directory
+	DEFAULT_KNOWN_HOSTS_NAME = "known_hosts"
+
+	// Environment variable which can be used to provide the passphrase of
+	// encrypted ssh key non-interactively
+	SSH_KEY_PASSPHRASE_ENV = "D8X_SSH_KEY_PASSPHRASE"
+
+	// Directory in workspace where named profiles are stored
+	DEFAULT_PROFILES_DIR = "profiles"
+
+	// File in workspace which records the active profile
+	DEFAULT_ACTIVE_PROFILE_FILE = ".d8x-profile"
+
+	// Environment variable which can be used to select the profile instead
+	// of --profile flag
+	PROFILE_ENV = "D8X_PROFILE"
 )
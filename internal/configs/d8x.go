@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/D8-X/d8x-cli/internal/styles"
 )
@@ -38,11 +39,16 @@ var SuggestedSubdomains = map[D8XServiceName]string{
 }
 
 type D8XConfig struct {
+	// Version of config schema, see MigrateConfig
+	SchemaVersion int `json:"schema_version"`
+
 	Services       map[D8XServiceName]D8XService `json:"services"`
 	ServerProvider D8XServerProvider             `json:"server_provider"`
 
-	LinodeConfig *D8XLinodeConfig `json:"linode_config"`
-	AWSConfig    *D8XAWSConfig    `json:"aws_config"`
+	LinodeConfig  *D8XLinodeConfig  `json:"linode_config"`
+	AWSConfig     *D8XAWSConfig     `json:"aws_config"`
+	HetznerConfig *D8XHetznerConfig `json:"hetzner_config"`
+	StaticConfig  *D8XStaticConfig  `json:"static_config"`
 
 	BrokerServerConfig D8XBrokerServerConfig `json:"broker_server_config"`
 
@@ -55,6 +61,9 @@ type D8XConfig struct {
 	HttpRpcList map[string][]string `json:"http_rpc_list"`
 	// List of user provided ws rpc endpoints for chainId
 	WsRpcList map[string][]string `json:"ws_rpc_list"`
+	// How rpcs of HttpRpcList and WsRpcList are distributed to services, nil
+	// deals rpcs round robin
+	RpcDistribution *D8XRpcDistribution `json:"rpc_distribution,omitempty"`
 
 	SwarmRedisPassword string `json:"swarm_redis_password"`
 
@@ -85,6 +94,13 @@ type D8XConfig struct {
 	// MD5 hash of last created ssh private key, empty string initially
 	SSHKeyMD5 string `json:"ssh_key_hash"`
 
+	// Default ssh port of all servers. Port of individual server can be set
+	// via ansible_port in hosts.cfg. 0 means the default port 22.
+	SSHPort int `json:"ssh_port,omitempty"`
+
+	// Scheduled database backups, nil when backups were never scheduled
+	DbBackup *D8XDbBackupConfig `json:"db_backup,omitempty"`
+
 	// Ansible related configuration details
 	ConfigDetails ConfigurationDetails `json:"configuration_details"`
 
@@ -103,6 +119,10 @@ func (c *D8XConfig) GetServersLabel() string {
 		if c.LinodeConfig != nil {
 			return c.LinodeConfig.LabelPrefix
 		}
+	case D8XServerProviderHetzner:
+		if c.HetznerConfig != nil {
+			return c.HetznerConfig.LabelPrefix
+		}
 	}
 	return "d8x-cluster"
 }
@@ -134,6 +154,28 @@ func (d *D8XConfig) ResetDeploymentStatus() {
 	}
 }
 
+// D8XDbBackupConfig holds settings of scheduled database backups (d8x
+// backup-db schedule)
+type D8XDbBackupConfig struct {
+	// systemd OnCalendar expression of backup timer
+	OnCalendar string `json:"on_calendar"`
+	// Number of latest backups kept on manager
+	KeepLocal int `json:"keep_local"`
+	// Backups older than this are removed from the bucket
+	RetentionDays int `json:"retention_days"`
+
+	// S3 compatible bucket where backups are uploaded
+	S3Endpoint  string `json:"s3_endpoint"`
+	S3Region    string `json:"s3_region"`
+	S3Bucket    string `json:"s3_bucket"`
+	S3Prefix    string `json:"s3_prefix"`
+	S3AccessKey string `json:"s3_access_key"`
+	S3SecretKey string `json:"s3_secret_key"`
+
+	// age recipients (age or ssh public keys) which backups are encrypted to
+	PublicKey string `json:"public_key"`
+}
+
 type ReferralConfig struct {
 	// ExecutorAddress     string `json:"executor_address"`
 	BrokerPayoutAddress string `json:"broker_payout_address"`
@@ -146,7 +188,12 @@ func (d *D8XConfig) IsEmpty() bool {
 // GetAnsibleUser returns the default sudo user for initial ansible
 // configuration step
 func (d *D8XConfig) GetAnsibleUser() string {
-	if d.ServerProvider == D8XServerProviderLinode {
+	if d.ServerProvider == D8XServerProviderLinode || d.ServerProvider == D8XServerProviderHetzner {
+		return "root"
+	} else if d.ServerProvider == D8XServerProviderStatic {
+		if d.StaticConfig != nil && d.StaticConfig.SSHUser != "" {
+			return d.StaticConfig.SSHUser
+		}
 		return "root"
 	} else if d.ServerProvider == D8XServerProviderAWS {
 		// In case used image changes - we should also change the user!
@@ -158,10 +205,24 @@ func (d *D8XConfig) GetAnsibleUser() string {
 type D8XServerProvider string
 
 const (
-	D8XServerProviderLinode D8XServerProvider = "linode"
-	D8XServerProviderAWS    D8XServerProvider = "aws"
+	D8XServerProviderLinode  D8XServerProvider = "linode"
+	D8XServerProviderAWS     D8XServerProvider = "aws"
+	D8XServerProviderHetzner D8XServerProvider = "hetzner"
+	// User provided servers, no provisioning is done
+	D8XServerProviderStatic D8XServerProvider = "static"
 )
 
+// HasPublicServers reports whether all servers of the provider are accessed
+// directly via their public ip addresses and are not protected by provider's
+// firewall (unlike AWS, where workers are accessed via manager).
+func (d *D8XConfig) HasPublicServers() bool {
+	switch d.ServerProvider {
+	case D8XServerProviderLinode, D8XServerProviderHetzner, D8XServerProviderStatic:
+		return true
+	}
+	return false
+}
+
 type D8XLinodeConfig struct {
 	Token              string `json:"linode_token"`
 	DbId               string `json:"db_id"`
@@ -213,7 +274,8 @@ type D8XBrokerServerConfig struct {
 
 func NewD8XConfig() *D8XConfig {
 	return &D8XConfig{
-		Services: make(map[D8XServiceName]D8XService),
+		SchemaVersion: CurrentSchemaVersion,
+		Services:      make(map[D8XServiceName]D8XService),
 	}
 }
 
@@ -244,6 +306,12 @@ type d8xConfigFileReadWriter struct {
 	filePath string
 
 	warningShown bool
+
+	// Original contents of config which was migrated on read. Backup of it
+	// is saved before the migrated config is written.
+	preMigration []byte
+	// Schema version of preMigration contents
+	preMigrationVersion int
 }
 
 func (d *d8xConfigFileReadWriter) GetPath() string {
@@ -265,12 +333,20 @@ func (d *d8xConfigFileReadWriter) Read() (*D8XConfig, error) {
 		}
 		return cfg, nil
 	} else {
-		if err := json.Unmarshal(contents, cfg); err != nil {
+		migrated, version, err := MigrateConfig(contents)
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %w", d.filePath, err)
+		}
+		if version != CurrentSchemaVersion && d.preMigration == nil {
+			d.preMigration = contents
+			d.preMigrationVersion = version
+		}
+		if err := json.Unmarshal(migrated, cfg); err != nil {
 			return nil, err
 		}
 	}
 
-	// Make sure we initialize nil-able fields
+	// Configs written from zero value D8XConfig contain null maps
 	if cfg.Services == nil {
 		cfg.Services = make(map[D8XServiceName]D8XService)
 	}
@@ -284,7 +360,31 @@ func (d *d8xConfigFileReadWriter) Read() (*D8XConfig, error) {
 	return cfg, nil
 }
 
+// backupPreMigration saves the original contents of migrated config next to
+// the config file
+func (d *d8xConfigFileReadWriter) backupPreMigration() error {
+	if d.preMigration == nil {
+		return nil
+	}
+	backup := d.filePath + ".backup-" + time.Now().Format("2006-01-02_15:04:05")
+	if err := os.WriteFile(backup, d.preMigration, 0666); err != nil {
+		return fmt.Errorf("saving backup of config before migration: %w", err)
+	}
+	fmt.Printf(
+		"Config was upgraded from schema version %d to %d, backup of the previous config was saved to %s\n",
+		d.preMigrationVersion,
+		CurrentSchemaVersion,
+		backup,
+	)
+	d.preMigration = nil
+	return nil
+}
+
 func (d *d8xConfigFileReadWriter) Write(cfg *D8XConfig) error {
+	if err := d.backupPreMigration(); err != nil {
+		return err
+	}
+	cfg.SchemaVersion = CurrentSchemaVersion
 	if buf, err := json.MarshalIndent(cfg, "", "\t"); err != nil {
 		return err
 	} else {
@@ -316,3 +416,28 @@ func (c *D8XConfig) SuggestSubdomain(svc D8XServiceName, chainName string, chain
 	}
 	return ""
 }
+
+type D8XHetznerConfig struct {
+	Token              string `json:"hetzner_token"`
+	Location           string `json:"location"`
+	LabelPrefix        string `json:"label_prefix"`
+	SwarmServerType    string `json:"swarm_server_type"`
+	BrokerServerType   string `json:"broker_server_type"`
+	CreateBrokerServer bool   `json:"create_broker_server"`
+	DeploySwarm        bool   `json:"deploy_swarm"`
+	// Number of worker servers to deploy in swarm
+	NumWorker int `json:"num_worker"`
+}
+
+type D8XStaticConfig struct {
+	ManagerIp        string   `json:"manager_ip"`
+	ManagerPrivateIp string   `json:"manager_private_ip"`
+	WorkerIps        []string `json:"worker_ips"`
+	WorkerPrivateIps []string `json:"worker_private_ips"`
+	BrokerIp         string   `json:"broker_ip"`
+	BrokerPrivateIp  string   `json:"broker_private_ip"`
+	// Initial user used for servers configuration
+	SSHUser            string `json:"ssh_user"`
+	CreateBrokerServer bool   `json:"create_broker_server"`
+	DeploySwarm        bool   `json:"deploy_swarm"`
+}
@@ -0,0 +1,88 @@
+#!/usr/bin/env bash
+# Managed by d8x-cli (d8x backup-db schedule), changes will be overwritten.
+#
+# Usage:
+#   d8x-db-backup [run]             create, encrypt and upload a new backup,
+#                                   prune old backups
+#   d8x-db-backup list local|remote list stored backups as json
+#   d8x-db-backup fetch <name> <dst> copy backup to dst, from local backups
+#                                   if available, otherwise from the bucket
+set -euo pipefail
+
+set -a
+# shellcheck source=/dev/null
+. /etc/d8x-db-backup/env
+set +a
+
+# rclone remote configured via RCLONE_CONFIG_D8XBACKUP_* variables
+REMOTE="d8xbackup:${S3_BUCKET}/${S3_PREFIX}"
+BACKUP_SUFFIX=".dump.age"
+
+backup() {
+	mkdir -p "${BACKUP_DIR}"
+	chmod 700 "${BACKUP_DIR}"
+
+	name="${BACKUP_NAME_PREFIX}-$(date -u +%Y-%m-%d-%H-%M-%S)${BACKUP_SUFFIX}"
+	partial="${BACKUP_DIR}/.${name}.partial"
+	trap 'rm -f "${partial}"' EXIT
+
+	echo "Creating backup ${name}"
+	pg_dump --format=custom --compress=9 --no-password | age --encrypt -R /etc/d8x-db-backup/recipients -o "${partial}"
+	mv "${partial}" "${BACKUP_DIR}/${name}"
+
+	echo "Uploading ${name} to ${REMOTE}"
+	rclone copyto "${BACKUP_DIR}/${name}" "${REMOTE}/${name}"
+
+	echo "Pruning local backups, keeping ${KEEP_LOCAL} latest"
+	find "${BACKUP_DIR}" -maxdepth 1 -type f -name "*${BACKUP_SUFFIX}" -printf '%T@ %p\n' |
+		sort -rn | tail -n +"$((KEEP_LOCAL + 1))" | cut -d' ' -f2- | xargs -r rm -f --
+
+	echo "Pruning remote backups older than ${RETENTION_DAYS} days"
+	rclone delete --min-age "${RETENTION_DAYS}d" --include "*${BACKUP_SUFFIX}" "${REMOTE}"
+}
+
+list() {
+	case "${1:-}" in
+	local)
+		mkdir -p "${BACKUP_DIR}"
+		rclone lsjson --files-only --include "*${BACKUP_SUFFIX}" "${BACKUP_DIR}"
+		;;
+	remote)
+		rclone lsjson --files-only --include "*${BACKUP_SUFFIX}" "${REMOTE}"
+		;;
+	*)
+		echo "usage: d8x-db-backup list local|remote" >&2
+		exit 1
+		;;
+	esac
+}
+
+fetch() {
+	name="${1:?backup name is required}"
+	dst="${2:?destination is required}"
+	case "${name}" in
+	*/* | .*)
+		echo "invalid backup name ${name}" >&2
+		exit 1
+		;;
+	esac
+
+	if [ -f "${BACKUP_DIR}/${name}" ]; then
+		cp "${BACKUP_DIR}/${name}" "${dst}"
+	else
+		rclone copyto "${REMOTE}/${name}" "${dst}"
+	fi
+	if [ -n "${SUDO_USER:-}" ]; then
+		chown "${SUDO_USER}:" "${dst}"
+	fi
+}
+
+case "${1:-run}" in
+run) backup ;;
+list) list "${2:-}" ;;
+fetch) fetch "${2:-}" "${3:-}" ;;
+*)
+	echo "unknown command ${1}" >&2
+	exit 1
+	;;
+esac
@@ -155,7 +155,11 @@ variable "ssh_jump_host" {
     Hostname %s
     User ubuntu
     IdentityFile ./id_ed25519
-    Port 22
+    Port %d
+    UserKnownHostsFile %s
+    StrictHostKeyChecking yes
+    ServerAliveInterval 30
+    ServerAliveCountMax 3
   EOF
 }
 
@@ -163,7 +167,7 @@ variable "ssh_jump_host" {
 # as a ProxyJump
 resource "local_file" "jump_host_ssh_config" {
   count    = var.create_swarm ? 1 : 0
-  content  = format(var.ssh_jump_host, module.swarm_servers[0].manager.public_ip)
+  content  = format(var.ssh_jump_host, module.swarm_servers[0].manager.public_ip, var.ssh_port, var.known_hosts_file)
   filename = "../${var.ssh_jump_host_cfg_filename}"
 }
 
@@ -71,6 +71,18 @@ variable "ssh_jump_host_cfg_filename" {
   default     = "manager_ssh_jump.conf"
 }
 
+variable "known_hosts_file" {
+  type        = string
+  description = "Path to d8x managed known_hosts file used for jump host"
+  default     = "~/.ssh/known_hosts"
+}
+
+variable "ssh_port" {
+  type        = number
+  description = "ssh port of manager used by jump host config"
+  default     = 22
+}
+
 variable "host_cfg_path" {
   type        = string
   description = "Path to ssh jump host config file"
@@ -0,0 +1,14 @@
+%{if create_swarm}
+[managers]
+${manager_public_ip} manager_private_ip=${manager_private_ip} hostname=manager-1
+
+[workers]
+%{for index, ip in workers_public_ips~}
+${ip} worker_private_ip=${workers_private_ips[index]} hostname=${format("worker-%02d", index + 1)}
+%{endfor~}
+%{endif~}
+
+%{if create_broker_server}
+[broker]
+${broker_public_ip} private_ip=${broker_private_ip}
+%{endif~}
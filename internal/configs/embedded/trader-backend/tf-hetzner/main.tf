@@ -0,0 +1,90 @@
+terraform {
+  required_providers {
+    hcloud = {
+      source = "hetznercloud/hcloud"
+    }
+  }
+}
+
+# Token must be provided via HCLOUD_TOKEN env var
+provider "hcloud" {}
+
+resource "hcloud_ssh_key" "default" {
+  name       = format("%s-%s", var.server_label_prefix, "key")
+  public_key = var.authorized_key
+}
+
+# Private network for communication between cluster servers
+resource "hcloud_network" "private" {
+  name     = format("%s-%s", var.server_label_prefix, "network")
+  ip_range = "10.0.0.0/16"
+}
+
+resource "hcloud_network_subnet" "private" {
+  network_id   = hcloud_network.private.id
+  type         = "cloud"
+  network_zone = var.network_zone
+  ip_range     = "10.0.1.0/24"
+}
+
+resource "hcloud_server" "manager" {
+  count       = var.create_swarm ? 1 : 0
+  name        = format("%s-%s", var.server_label_prefix, "manager")
+  server_type = var.worker_size
+  location    = var.location
+  image       = "ubuntu-22.04"
+  ssh_keys    = [hcloud_ssh_key.default.id]
+}
+
+resource "hcloud_server_network" "manager" {
+  count     = var.create_swarm ? 1 : 0
+  server_id = hcloud_server.manager[count.index].id
+  subnet_id = hcloud_network_subnet.private.id
+}
+
+resource "hcloud_server" "nodes" {
+  count       = var.create_swarm ? var.num_workers : 0
+  name        = format("%s-%s", var.server_label_prefix, "worker-${count.index + 1}")
+  server_type = var.worker_size
+  location    = var.location
+  image       = "ubuntu-22.04"
+  ssh_keys    = [hcloud_ssh_key.default.id]
+}
+
+resource "hcloud_server_network" "nodes" {
+  count     = var.create_swarm ? var.num_workers : 0
+  server_id = hcloud_server.nodes[count.index].id
+  subnet_id = hcloud_network_subnet.private.id
+}
+
+resource "hcloud_server" "broker_server" {
+  count       = var.create_broker_server ? 1 : 0
+  name        = format("%s-%s", var.server_label_prefix, "broker-server")
+  server_type = var.broker_size
+  location    = var.location
+  image       = "ubuntu-22.04"
+  ssh_keys    = [hcloud_ssh_key.default.id]
+}
+
+resource "hcloud_server_network" "broker_server" {
+  count     = var.create_broker_server ? 1 : 0
+  server_id = hcloud_server.broker_server[count.index].id
+  subnet_id = hcloud_network_subnet.private.id
+}
+
+# Geneate ansible inventory
+resource "local_file" "hosts_cfg" {
+  depends_on = [hcloud_server_network.manager, hcloud_server_network.nodes, hcloud_server_network.broker_server]
+  content = templatefile("${path.module}/inventory.tpl", {
+    create_swarm         = var.create_swarm
+    create_broker_server = var.create_broker_server
+    manager_public_ip    = var.create_swarm ? hcloud_server.manager[0].ipv4_address : ""
+    manager_private_ip   = var.create_swarm ? hcloud_server_network.manager[0].ip : ""
+    workers_public_ips   = hcloud_server.nodes.*.ipv4_address
+    workers_private_ips  = hcloud_server_network.nodes.*.ip
+    broker_public_ip     = var.create_broker_server ? hcloud_server.broker_server[0].ipv4_address : ""
+    broker_private_ip    = var.create_broker_server ? hcloud_server_network.broker_server[0].ip : ""
+  })
+
+  filename = "../hosts.cfg"
+}
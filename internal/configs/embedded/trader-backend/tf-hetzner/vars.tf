@@ -0,0 +1,52 @@
+variable "num_workers" {
+  type        = number
+  description = "Number of worker nodes to create"
+  default     = 4
+}
+
+variable "location" {
+  type        = string
+  description = "Cluster location"
+  default     = "fsn1"
+}
+
+variable "network_zone" {
+  type        = string
+  description = "Network zone of the private network subnet, must match the location"
+  default     = "eu-central"
+}
+
+variable "worker_size" {
+  type        = string
+  description = "Manager and worker servers type"
+  default     = "cpx31"
+}
+
+variable "broker_size" {
+  type        = string
+  description = "Broker server type"
+  default     = "cpx31"
+}
+
+variable "authorized_key" {
+  type        = string
+  description = "Ssh public key that will be added to each server"
+}
+
+variable "create_broker_server" {
+  type        = bool
+  description = "Whether broker-server node should be created"
+  default     = true
+}
+
+variable "server_label_prefix" {
+  type        = string
+  description = "Prefix that will be used in server names"
+  default     = "d8x-cluster"
+}
+
+variable "create_swarm" {
+  type        = bool
+  description = "Whether swarm setup should be created (manager, workers)"
+  default     = true
+}
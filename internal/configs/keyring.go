@@ -0,0 +1,61 @@
+package configs
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// KeyringService is the service name under which d8x secrets vault
+// passphrases are stored in the OS keyring
+const KeyringService = "d8x-cli"
+
+// KeyringGet retrieves the secret stored under given account in the OS
+// keyring. macOS keychain (security) and libsecret (secret-tool) on linux are
+// supported.
+func KeyringGet(account string) (string, error) {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("security", "find-generic-password", "-s", KeyringService, "-a", account, "-w")
+	case "linux":
+		cmd = exec.Command("secret-tool", "lookup", "service", KeyringService, "account", account)
+	default:
+		return "", fmt.Errorf("os keyring is not supported on %s", runtime.GOOS)
+	}
+
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("reading secret from os keyring: %w", err)
+	}
+	secret := strings.TrimRight(string(out), "\r\n")
+	if secret == "" {
+		return "", fmt.Errorf("secret for %s was not found in os keyring", account)
+	}
+	return secret, nil
+}
+
+// KeyringSet stores secret under given account in the OS keyring. Existing
+// secret is overwritten.
+func KeyringSet(account, secret string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		// -w without value reads the secret from stdin (entered twice), so it
+		// does not show up in the process list
+		cmd = exec.Command("security", "add-generic-password", "-U", "-s", KeyringService, "-a", account, "-w")
+		cmd.Stdin = bytes.NewBufferString(secret + "\n" + secret + "\n")
+	case "linux":
+		cmd = exec.Command("secret-tool", "store", "--label", "d8x-cli secrets vault passphrase", "service", KeyringService, "account", account)
+		cmd.Stdin = bytes.NewBufferString(secret)
+	default:
+		return fmt.Errorf("os keyring is not supported on %s", runtime.GOOS)
+	}
+
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("storing secret in os keyring: %w: %s", err, string(out))
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package configs
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// configMigration upgrades raw d8x.conf.json contents by one schema version.
+// Raw json is migrated, so that migrations can handle fields which are no
+// longer present in D8XConfig.
+type configMigration struct {
+	description string
+	migrate     func(cfg map[string]any) error
+}
+
+// configMigrations is the ordered registry of config migrations. Migration at
+// index i upgrades schema version i to i+1. New migrations must only be
+// appended.
+var configMigrations = []configMigration{
+	{
+		description: "initialize empty services, rpc lists and configured servers",
+		migrate:     migrateInitCollections,
+	},
+	{
+		description: "set missing service names from services keys",
+		migrate:     migrateServiceNames,
+	},
+}
+
+// CurrentSchemaVersion is the schema_version of configs written by this
+// version of d8x cli. Must be equal to the number of configMigrations.
+const CurrentSchemaVersion = 2
+
+// MigrateConfig upgrades raw d8x.conf.json contents to CurrentSchemaVersion.
+// Schema version of contents before the migration is returned. Contents are
+// returned unchanged when config is already up to date.
+func MigrateConfig(contents []byte) ([]byte, int, error) {
+	raw := map[string]any{}
+	if err := json.Unmarshal(contents, &raw); err != nil {
+		return nil, 0, err
+	}
+
+	version := 0
+	if v, ok := raw["schema_version"]; ok && v != nil {
+		f, ok := v.(float64)
+		if !ok || f < 0 || f != float64(int(f)) {
+			return nil, 0, fmt.Errorf("invalid schema_version %v", v)
+		}
+		version = int(f)
+	}
+	if version > CurrentSchemaVersion {
+		return nil, version, fmt.Errorf("config schema_version %d is newer than supported version %d, please upgrade d8x cli", version, CurrentSchemaVersion)
+	}
+	if version == CurrentSchemaVersion {
+		return contents, version, nil
+	}
+
+	for i, m := range configMigrations[version:] {
+		if err := m.migrate(raw); err != nil {
+			return nil, version, fmt.Errorf("migrating config to schema version %d (%s): %w", version+i+1, m.description, err)
+		}
+	}
+	raw["schema_version"] = CurrentSchemaVersion
+
+	migrated, err := json.MarshalIndent(raw, "", "\t")
+	if err != nil {
+		return nil, version, err
+	}
+	return migrated, version, nil
+}
+
+// rawObject returns json object field of cfg, creating it when it is missing
+// or null
+func rawObject(cfg map[string]any, field string) (map[string]any, error) {
+	switch v := cfg[field].(type) {
+	case map[string]any:
+		return v, nil
+	case nil:
+		obj := map[string]any{}
+		cfg[field] = obj
+		return obj, nil
+	default:
+		return nil, fmt.Errorf("%s must be an object", field)
+	}
+}
+
+// migrateInitCollections replaces missing or null maps and lists with empty
+// ones. Older versions wrote null values which were silently replaced on
+// every read.
+func migrateInitCollections(cfg map[string]any) error {
+	for _, field := range []string{"services", "http_rpc_list", "ws_rpc_list"} {
+		if _, err := rawObject(cfg, field); err != nil {
+			return err
+		}
+	}
+	if cfg["user_supplied_price_feed_endpoints"] == nil {
+		cfg["user_supplied_price_feed_endpoints"] = []any{}
+	}
+
+	details, err := rawObject(cfg, "configuration_details")
+	if err != nil {
+		return err
+	}
+	if details["configured_server_ip_addresses"] == nil {
+		details["configured_server_ip_addresses"] = []any{}
+	}
+	return nil
+}
+
+// migrateServiceNames sets name of services which were stored without it.
+// Services are looked up by map key, but name is used when services are
+// listed.
+func migrateServiceNames(cfg map[string]any) error {
+	services, err := rawObject(cfg, "services")
+	if err != nil {
+		return err
+	}
+	for key, v := range services {
+		svc, ok := v.(map[string]any)
+		if !ok {
+			return fmt.Errorf("services.%s must be an object", key)
+		}
+		if name, _ := svc["name"].(string); name == "" {
+			svc["name"] = key
+		}
+	}
+	return nil
+}
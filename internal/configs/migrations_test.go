@@ -0,0 +1,88 @@
+package configs
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigMigrationsRegistry(t *testing.T) {
+	assert.Equal(t, len(configMigrations), CurrentSchemaVersion)
+}
+
+func TestMigrateConfig(t *testing.T) {
+	legacy := []byte(`{
+	"services": {"main_http": {"https": true, "hostname": "api.example.com"}},
+	"http_rpc_list": null,
+	"configuration_details": {"done": true, "configured_server_ip_addresses": null}
+}`)
+
+	migrated, version, err := MigrateConfig(legacy)
+	require.NoError(t, err)
+	assert.Equal(t, 0, version)
+	assert.JSONEq(t, `{
+	"schema_version": 2,
+	"services": {"main_http": {"name": "main_http", "https": true, "hostname": "api.example.com"}},
+	"http_rpc_list": {},
+	"ws_rpc_list": {},
+	"user_supplied_price_feed_endpoints": [],
+	"configuration_details": {"done": true, "configured_server_ip_addresses": []}
+}`, string(migrated))
+
+	// Up to date configs are not changed
+	again, version, err := MigrateConfig(migrated)
+	require.NoError(t, err)
+	assert.Equal(t, CurrentSchemaVersion, version)
+	assert.Equal(t, migrated, again)
+
+	_, _, err = MigrateConfig([]byte(`{"schema_version": 99}`))
+	assert.EqualError(t, err, "config schema_version 99 is newer than supported version 2, please upgrade d8x cli")
+
+	_, _, err = MigrateConfig([]byte(`{"schema_version": "1"}`))
+	assert.EqualError(t, err, "invalid schema_version 1")
+
+	_, _, err = MigrateConfig([]byte(`{"services": []}`))
+	assert.EqualError(t, err, "migrating config to schema version 1 (initialize empty services, rpc lists and configured servers): services must be an object")
+}
+
+func TestConfigFileMigrationBackup(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, DEFAULT_D8X_CONFIG_NAME)
+	legacy := []byte(`{"server_provider": "linode", "services": null}`)
+	require.NoError(t, os.WriteFile(path, legacy, 0666))
+
+	rw := NewFileBasedD8XConfigRW(path)
+	cfg, err := rw.Read()
+	require.NoError(t, err)
+	assert.Equal(t, CurrentSchemaVersion, cfg.SchemaVersion)
+	assert.NotNil(t, cfg.Services)
+
+	// Reading does not modify the config file
+	contents, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, legacy, contents)
+
+	require.NoError(t, rw.Write(cfg))
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	backup := entries[1].Name()
+	assert.True(t, strings.HasPrefix(backup, DEFAULT_D8X_CONFIG_NAME+".backup-"), backup)
+	contents, err = os.ReadFile(filepath.Join(dir, backup))
+	require.NoError(t, err)
+	assert.Equal(t, legacy, contents)
+
+	// Config written from zero value is stored with current schema version
+	require.NoError(t, rw.Write(&D8XConfig{}))
+	cfg, err = rw.Read()
+	require.NoError(t, err)
+	assert.Equal(t, CurrentSchemaVersion, cfg.SchemaVersion)
+	assert.NotNil(t, cfg.Services)
+	entries, err = os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 2)
+}
@@ -0,0 +1,140 @@
+package configs
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var profileNameRe = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_-]{0,63}$`)
+
+// ValidateProfileName checks whether name can be used as profile (directory)
+// name
+func ValidateProfileName(name string) error {
+	if !profileNameRe.MatchString(name) {
+		return fmt.Errorf("invalid profile name %q, use letters, digits, - and _", name)
+	}
+	return nil
+}
+
+// Workspace manages named profiles of d8x working directory. Each profile is
+// a separate directory in profiles/ with its own d8x config, hosts.cfg,
+// terraform state, ssh key and generated files. When no profile is active the
+// workspace directory itself is used.
+type Workspace struct {
+	dir string
+}
+
+func NewWorkspace(dir string) *Workspace {
+	return &Workspace{dir: dir}
+}
+
+func (w *Workspace) Dir() string {
+	return w.dir
+}
+
+func (w *Workspace) activeProfilePath() string {
+	return filepath.Join(w.dir, DEFAULT_ACTIVE_PROFILE_FILE)
+}
+
+// ProfileDir returns the directory of profile name
+func (w *Workspace) ProfileDir(name string) string {
+	return filepath.Join(w.dir, DEFAULT_PROFILES_DIR, name)
+}
+
+// Exists reports whether profile name was created
+func (w *Workspace) Exists(name string) bool {
+	if ValidateProfileName(name) != nil {
+		return false
+	}
+	fi, err := os.Stat(w.ProfileDir(name))
+	return err == nil && fi.IsDir()
+}
+
+// Profiles returns sorted names of all profiles
+func (w *Workspace) Profiles() ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(w.dir, DEFAULT_PROFILES_DIR))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("reading profiles directory: %w", err)
+	}
+	profiles := []string{}
+	for _, e := range entries {
+		if e.IsDir() && ValidateProfileName(e.Name()) == nil {
+			profiles = append(profiles, e.Name())
+		}
+	}
+	sort.Strings(profiles)
+	return profiles, nil
+}
+
+// Active returns the name of active profile or empty string when no profile
+// is active
+func (w *Workspace) Active() (string, error) {
+	contents, err := os.ReadFile(w.activeProfilePath())
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", nil
+		}
+		return "", fmt.Errorf("reading active profile: %w", err)
+	}
+	name := strings.TrimSpace(string(contents))
+	if name == "" {
+		return "", nil
+	}
+	if err := ValidateProfileName(name); err != nil {
+		return "", fmt.Errorf("%s: %w", w.activeProfilePath(), err)
+	}
+	return name, nil
+}
+
+// SetActive records name as the active profile. Empty name switches back to
+// the workspace directory.
+func (w *Workspace) SetActive(name string) error {
+	if name == "" {
+		if err := os.Remove(w.activeProfilePath()); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("removing active profile: %w", err)
+		}
+		return nil
+	}
+	if !w.Exists(name) {
+		return fmt.Errorf("profile %s does not exist", name)
+	}
+	return os.WriteFile(w.activeProfilePath(), []byte(name+"\n"), 0644)
+}
+
+// Create creates the directory of new profile name
+func (w *Workspace) Create(name string) error {
+	if err := ValidateProfileName(name); err != nil {
+		return err
+	}
+	if w.Exists(name) {
+		return fmt.Errorf("profile %s already exists", name)
+	}
+	return os.MkdirAll(w.ProfileDir(name), 0775)
+}
+
+// Delete removes profile name with all of its files. Active profile record is
+// cleared when name is the active profile.
+func (w *Workspace) Delete(name string) error {
+	if !w.Exists(name) {
+		return fmt.Errorf("profile %s does not exist", name)
+	}
+	active, err := w.Active()
+	if err != nil {
+		return err
+	}
+	if err := os.RemoveAll(w.ProfileDir(name)); err != nil {
+		return fmt.Errorf("removing profile %s: %w", name, err)
+	}
+	if active == name {
+		return w.SetActive("")
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package configs
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWorkspaceProfiles(t *testing.T) {
+	w := NewWorkspace(t.TempDir())
+
+	profiles, err := w.Profiles()
+	require.NoError(t, err)
+	assert.Empty(t, profiles)
+	active, err := w.Active()
+	require.NoError(t, err)
+	assert.Equal(t, "", active)
+
+	require.NoError(t, w.Create("mainnet"))
+	require.NoError(t, w.Create("testnet"))
+	assert.EqualError(t, w.Create("mainnet"), "profile mainnet already exists")
+	assert.EqualError(t, w.Create("../mainnet"), `invalid profile name "../mainnet", use letters, digits, - and _`)
+	assert.EqualError(t, w.SetActive("devnet"), "profile devnet does not exist")
+
+	// Files which are not profile directories are ignored
+	require.NoError(t, os.WriteFile(filepath.Join(w.Dir(), DEFAULT_PROFILES_DIR, "notes.txt"), nil, 0644))
+	profiles, err = w.Profiles()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"mainnet", "testnet"}, profiles)
+
+	require.NoError(t, w.SetActive("mainnet"))
+	active, err = w.Active()
+	require.NoError(t, err)
+	assert.Equal(t, "mainnet", active)
+
+	// Deleting the active profile deactivates it
+	require.NoError(t, w.Delete("mainnet"))
+	assert.NoDirExists(t, w.ProfileDir("mainnet"))
+	active, err = w.Active()
+	require.NoError(t, err)
+	assert.Equal(t, "", active)
+
+	require.NoError(t, w.SetActive("testnet"))
+	require.NoError(t, w.SetActive(""))
+	assert.NoFileExists(t, filepath.Join(w.Dir(), DEFAULT_ACTIVE_PROFILE_FILE))
+}
+
+func TestWorkspaceActiveInvalid(t *testing.T) {
+	w := NewWorkspace(t.TempDir())
+	require.NoError(t, os.WriteFile(filepath.Join(w.Dir(), DEFAULT_ACTIVE_PROFILE_FILE), []byte("../../etc\n"), 0644))
+
+	_, err := w.Active()
+	assert.Error(t, err)
+}
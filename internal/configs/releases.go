@@ -0,0 +1,205 @@
+package configs
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-yaml/yaml"
+)
+
+// Deployment targets of a release
+const (
+	ReleaseTargetSwarm  = "swarm"
+	ReleaseTargetBroker = "broker"
+)
+
+// Release is a single entry of release ledger. It describes what was live
+// after a deployment, update or rollback.
+type Release struct {
+	Id        int       `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	// Command which created the release
+	Source string `json:"source"`
+	// ReleaseTargetSwarm or ReleaseTargetBroker
+	Target string `json:"target"`
+	// Id of release which was redeployed when Source is rollback
+	RollbackOf int `json:"rollback_of,omitempty"`
+
+	// Service name -> image reference resolved to sha256 digest. Images
+	// without repo digest are stored by the reference they were started from.
+	Images map[string]string `json:"images"`
+	// Docker config (or config file) name -> contents hash
+	Configs map[string]string `json:"configs"`
+	// Hash of the docker stack (or compose) file
+	StackFile string `json:"stack_file"`
+	// Hash of the .env file. Empty if no .env was deployed.
+	Env string `json:"env,omitempty"`
+
+	// Remote paths of files stored in release snapshot directory
+	Files []string `json:"files"`
+}
+
+// ReleaseLedger persists releases and snapshots of their deployed files in a
+// directory.
+type ReleaseLedger struct {
+	dir string
+}
+
+func NewReleaseLedger(dir string) *ReleaseLedger {
+	return &ReleaseLedger{dir: dir}
+}
+
+func (l *ReleaseLedger) ledgerPath() string {
+	return filepath.Join(l.dir, DEFAULT_RELEASES_LEDGER_NAME)
+}
+
+func (l *ReleaseLedger) snapshotPath(id int, file string) string {
+	return filepath.Join(l.dir, strconv.Itoa(id), filepath.Clean(strings.TrimPrefix(file, "./")))
+}
+
+// List returns all releases ordered by id
+func (l *ReleaseLedger) List() ([]Release, error) {
+	contents, err := os.ReadFile(l.ledgerPath())
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return []Release{}, nil
+		}
+		return nil, fmt.Errorf("reading release ledger: %w", err)
+	}
+
+	releases := []Release{}
+	if err := json.Unmarshal(contents, &releases); err != nil {
+		return nil, fmt.Errorf("parsing release ledger: %w", err)
+	}
+	sort.Slice(releases, func(i, j int) bool {
+		return releases[i].Id < releases[j].Id
+	})
+	return releases, nil
+}
+
+// Get returns release by id
+func (l *ReleaseLedger) Get(id int) (*Release, error) {
+	releases, err := l.List()
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range releases {
+		if r.Id == id {
+			return &r, nil
+		}
+	}
+	return nil, fmt.Errorf("release %d was not found", id)
+}
+
+// Latest returns the most recent release of target or nil if target was never
+// released
+func (l *ReleaseLedger) Latest(target string) (*Release, error) {
+	releases, err := l.List()
+	if err != nil {
+		return nil, err
+	}
+	for i := len(releases) - 1; i >= 0; i-- {
+		if releases[i].Target == target {
+			return &releases[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// Add assigns the next id to r, stores snapshot of files (remote path ->
+// contents) and appends r to the ledger.
+func (l *ReleaseLedger) Add(r Release, files map[string][]byte) (*Release, error) {
+	releases, err := l.List()
+	if err != nil {
+		return nil, err
+	}
+
+	r.Id = 1
+	if len(releases) > 0 {
+		r.Id = releases[len(releases)-1].Id + 1
+	}
+	if r.CreatedAt.IsZero() {
+		r.CreatedAt = time.Now().UTC()
+	}
+
+	r.Files = []string{}
+	for file, contents := range files {
+		p := l.snapshotPath(r.Id, file)
+		if err := os.MkdirAll(filepath.Dir(p), 0700); err != nil {
+			return nil, fmt.Errorf("creating release snapshot directory: %w", err)
+		}
+		// Snapshots might contain secrets (.env)
+		if err := os.WriteFile(p, contents, 0600); err != nil {
+			return nil, fmt.Errorf("storing release snapshot: %w", err)
+		}
+		r.Files = append(r.Files, file)
+	}
+	sort.Strings(r.Files)
+
+	releases = append(releases, r)
+	buf, err := json.MarshalIndent(releases, "", "\t")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(l.ledgerPath(), buf, 0600); err != nil {
+		return nil, fmt.Errorf("writing release ledger: %w", err)
+	}
+
+	return &r, nil
+}
+
+// ReadFile returns the snapshot contents of file deployed in release id
+func (l *ReleaseLedger) ReadFile(id int, file string) ([]byte, error) {
+	return os.ReadFile(l.snapshotPath(id, file))
+}
+
+// HashContents returns sha256 hash of contents in sha256:<hex> format
+func HashContents(contents []byte) string {
+	h := sha256.Sum256(contents)
+	return "sha256:" + hex.EncodeToString(h[:])
+}
+
+// PinServiceImages replaces the image of each service found in images in the
+// given docker stack or compose file.
+func PinServiceImages(dockerComposeContentsYaml []byte, images map[string]string) ([]byte, error) {
+	doc := yaml.MapSlice{}
+	if err := yaml.Unmarshal(dockerComposeContentsYaml, &doc); err != nil {
+		return nil, err
+	}
+
+	for _, item := range doc {
+		if item.Key != "services" {
+			continue
+		}
+		services, ok := item.Value.(yaml.MapSlice)
+		if !ok {
+			return nil, fmt.Errorf("invalid services section")
+		}
+		for _, svc := range services {
+			img, ok := images[fmt.Sprint(svc.Key)]
+			if !ok {
+				continue
+			}
+			values, ok := svc.Value.(yaml.MapSlice)
+			if !ok {
+				continue
+			}
+			for i := range values {
+				if values[i].Key == "image" {
+					values[i].Value = img
+				}
+			}
+		}
+	}
+
+	return yaml.Marshal(doc)
+}
@@ -0,0 +1,80 @@
+package configs
+
+import (
+	"testing"
+
+	"github.com/go-yaml/yaml"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReleaseLedger(t *testing.T) {
+	ledger := NewReleaseLedger(t.TempDir())
+
+	latest, err := ledger.Latest(ReleaseTargetSwarm)
+	require.NoError(t, err)
+	assert.Nil(t, latest)
+
+	r1, err := ledger.Add(
+		Release{
+			Source: "swarm-deploy",
+			Target: ReleaseTargetSwarm,
+			Images: map[string]string{"api": "ghcr.io/d8-x/d8x-trader-main:main@sha256:aaa"},
+		},
+		map[string][]byte{
+			"./docker-stack.yml":             []byte("services: {}"),
+			"./trader-backend/rpc.main.json": []byte("[]"),
+		},
+	)
+	require.NoError(t, err)
+	assert.Equal(t, 1, r1.Id)
+	assert.Equal(t, []string{"./docker-stack.yml", "./trader-backend/rpc.main.json"}, r1.Files)
+
+	r2, err := ledger.Add(Release{Source: "broker-deploy", Target: ReleaseTargetBroker}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 2, r2.Id)
+
+	latest, err = ledger.Latest(ReleaseTargetSwarm)
+	require.NoError(t, err)
+	assert.Equal(t, 1, latest.Id)
+
+	got, err := ledger.Get(1)
+	require.NoError(t, err)
+	assert.Equal(t, r1.Images, got.Images)
+
+	contents, err := ledger.ReadFile(1, "./trader-backend/rpc.main.json")
+	require.NoError(t, err)
+	assert.Equal(t, "[]", string(contents))
+
+	_, err = ledger.Get(3)
+	assert.Error(t, err)
+}
+
+func TestPinServiceImages(t *testing.T) {
+	stack := `version: "3.8"
+services:
+  api:
+    image: ghcr.io/d8-x/d8x-trader-main:main
+    ports:
+      - "3001:3001"
+  redis:
+    image: redis
+`
+	pinned, err := PinServiceImages([]byte(stack), map[string]string{
+		"api": "ghcr.io/d8-x/d8x-trader-main:main@sha256:aaa",
+	})
+	require.NoError(t, err)
+
+	parsed := struct {
+		Version  string `yaml:"version"`
+		Services map[string]struct {
+			Image string   `yaml:"image"`
+			Ports []string `yaml:"ports"`
+		} `yaml:"services"`
+	}{}
+	require.NoError(t, yaml.Unmarshal(pinned, &parsed))
+	assert.Equal(t, "3.8", parsed.Version)
+	assert.Equal(t, "ghcr.io/d8-x/d8x-trader-main:main@sha256:aaa", parsed.Services["api"].Image)
+	assert.Equal(t, []string{"3001:3001"}, parsed.Services["api"].Ports)
+	assert.Equal(t, "redis", parsed.Services["redis"].Image)
+}
@@ -0,0 +1,80 @@
+package configs
+
+// Services which receive user supplied rpcs, in the order of DistributeRpcs
+// serviceIndex
+var RpcServices = []string{"main", "history", "referral", "broker"}
+
+// Strategies of distributing rpcs to a service
+const (
+	// Rpcs are dealt like cards between round robin services (default)
+	RpcStrategyRoundRobin = "round_robin"
+	// Service receives only listed urls, which are reserved for pinned
+	// services
+	RpcStrategyPinned = "pinned"
+	// Rpcs are dealt between weighted services, rpc with weight n is dealt n
+	// times
+	RpcStrategyWeighted = "weighted"
+	// Service receives all rpcs of its tier, lower priority tiers are used
+	// when there are not enough rpcs
+	RpcStrategyTiered = "tiered"
+)
+
+var RpcStrategies = []string{
+	RpcStrategyRoundRobin,
+	RpcStrategyPinned,
+	RpcStrategyWeighted,
+	RpcStrategyTiered,
+}
+
+// D8XRpcDistribution configures how user supplied rpcs are distributed to
+// services
+type D8XRpcDistribution struct {
+	// Policy of each service of RpcServices. Services without policy use
+	// round robin strategy.
+	Services map[string]D8XRpcServicePolicy `json:"services,omitempty"`
+	// Weights and tiers of rpc urls
+	Endpoints map[string]D8XRpcEndpoint `json:"endpoints,omitempty"`
+}
+
+type D8XRpcServicePolicy struct {
+	Strategy string `json:"strategy"`
+	// Urls of pinned strategy
+	Urls []string `json:"urls,omitempty"`
+	// Tier of tiered strategy
+	Tier int `json:"tier,omitempty"`
+	// Minimum number of rpcs of the service. Missing rpcs are added from rpcs
+	// which are not pinned.
+	MinCount int `json:"min_count,omitempty"`
+}
+
+type D8XRpcEndpoint struct {
+	// Weight of weighted strategy, 0 means 1
+	Weight int `json:"weight,omitempty"`
+	// Tier of tiered strategy, lower tier has higher priority
+	Tier int `json:"tier,omitempty"`
+}
+
+// Policy returns the policy of service, defaulting to round robin strategy
+func (d *D8XRpcDistribution) Policy(service string) D8XRpcServicePolicy {
+	if d != nil {
+		if p, ok := d.Services[service]; ok {
+			if p.Strategy == "" {
+				p.Strategy = RpcStrategyRoundRobin
+			}
+			return p
+		}
+	}
+	return D8XRpcServicePolicy{Strategy: RpcStrategyRoundRobin}
+}
+
+// Endpoint returns weight and tier of rpc url
+func (d *D8XRpcDistribution) Endpoint(url string) D8XRpcEndpoint {
+	e := D8XRpcEndpoint{}
+	if d != nil {
+		e = d.Endpoints[url]
+	}
+	if e.Weight <= 0 {
+		e.Weight = 1
+	}
+	return e
+}
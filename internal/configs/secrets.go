@@ -0,0 +1,435 @@
+package configs
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/scrypt"
+)
+
+// Names of the secrets stored in SecretsStore
+const (
+	SecretLinodeToken         = "linode_token"
+	SecretAWSAccessKey        = "aws_access_key"
+	SecretAWSSecretKey        = "aws_secret_key"
+	SecretHetznerToken        = "hetzner_token"
+	SecretDatabaseDSN         = "database_dsn"
+	SecretSwarmRedisPassword  = "swarm_redis_password"
+	SecretBrokerRedisPassword = "broker_redis_password"
+	// Sudo password of the default cluster user (legacy ./password.txt)
+	SecretUserPassword = "user_password"
+	// Credentials of database backups bucket
+	SecretDbBackupS3AccessKey = "db_backup_s3_access_key"
+	SecretDbBackupS3SecretKey = "db_backup_s3_secret_key"
+)
+
+// SecretsStore stores d8x secrets (api tokens, passwords, database dsn)
+// outside of the plain text d8x.conf.json.
+type SecretsStore interface {
+	// Load returns all stored secrets. If store does not exist yet, an empty
+	// map is returned.
+	Load() (map[string]string, error)
+
+	// Save replaces all stored secrets with the given ones
+	Save(map[string]string) error
+
+	// Exists reports whether the underlying storage was already created
+	Exists() bool
+}
+
+// PassphraseProvider retrieves the passphrase used to encrypt the secrets
+// vault
+type PassphraseProvider func() (string, error)
+
+// Scrypt parameters used to derive the vault encryption key
+const (
+	vaultScryptN = 1 << 15
+	vaultScryptR = 8
+	vaultScryptP = 1
+)
+
+// vaultFile is the on disk representation of encrypted secrets vault. Secrets
+// are encrypted with XChaCha20-Poly1305 using a key derived from user's
+// passphrase via scrypt.
+type vaultFile struct {
+	Version    int    `json:"version"`
+	Kdf        string `json:"kdf"`
+	ScryptN    int    `json:"scrypt_n"`
+	ScryptR    int    `json:"scrypt_r"`
+	ScryptP    int    `json:"scrypt_p"`
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+var _ (SecretsStore) = (*vaultSecretsStore)(nil)
+
+// NewVaultSecretsStore creates passphrase encrypted file based SecretsStore.
+// Passphrase is requested only once, decrypted secrets are kept only in
+// memory.
+func NewVaultSecretsStore(filePath string, passphrase PassphraseProvider) SecretsStore {
+	return &vaultSecretsStore{
+		filePath:   filePath,
+		passphrase: passphrase,
+	}
+}
+
+type vaultSecretsStore struct {
+	filePath   string
+	passphrase PassphraseProvider
+
+	mu sync.Mutex
+	// Derived encryption key and its salt. Cached to avoid running scrypt on
+	// each write.
+	key  []byte
+	salt []byte
+	// Decrypted secrets
+	secrets map[string]string
+}
+
+func (v *vaultSecretsStore) Exists() bool {
+	_, err := os.Stat(v.filePath)
+	return err == nil
+}
+
+func (v *vaultSecretsStore) Load() (map[string]string, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.secrets != nil {
+		return copySecrets(v.secrets), nil
+	}
+
+	contents, err := os.ReadFile(v.filePath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return map[string]string{}, nil
+		}
+		return nil, fmt.Errorf("reading secrets vault: %w", err)
+	}
+
+	vf := vaultFile{}
+	if err := json.Unmarshal(contents, &vf); err != nil {
+		return nil, fmt.Errorf("parsing secrets vault: %w", err)
+	}
+	if vf.Kdf != "scrypt" {
+		return nil, fmt.Errorf("unsupported secrets vault kdf: %s", vf.Kdf)
+	}
+
+	pass, err := v.passphrase()
+	if err != nil {
+		return nil, fmt.Errorf("retrieving vault passphrase: %w", err)
+	}
+	key, err := scrypt.Key([]byte(pass), vf.Salt, vf.ScryptN, vf.ScryptR, vf.ScryptP, chacha20poly1305.KeySize)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := aead.Open(nil, vf.Nonce, vf.Ciphertext, nil)
+	if err != nil {
+		return nil, fmt.Errorf("could not decrypt secrets vault %s, is the passphrase correct?", v.filePath)
+	}
+
+	secrets := map[string]string{}
+	if err := json.Unmarshal(plain, &secrets); err != nil {
+		return nil, fmt.Errorf("parsing decrypted secrets: %w", err)
+	}
+
+	v.key = key
+	v.salt = vf.Salt
+	v.secrets = secrets
+
+	return copySecrets(secrets), nil
+}
+
+func (v *vaultSecretsStore) Save(secrets map[string]string) error {
+	// Make sure existing vault is unlocked so we reuse its key
+	if v.Exists() {
+		if _, err := v.Load(); err != nil {
+			return err
+		}
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.key == nil {
+		pass, err := v.passphrase()
+		if err != nil {
+			return fmt.Errorf("retrieving vault passphrase: %w", err)
+		}
+		salt := make([]byte, 16)
+		if _, err := rand.Read(salt); err != nil {
+			return err
+		}
+		key, err := scrypt.Key([]byte(pass), salt, vaultScryptN, vaultScryptR, vaultScryptP, chacha20poly1305.KeySize)
+		if err != nil {
+			return err
+		}
+		v.key = key
+		v.salt = salt
+	}
+
+	plain, err := json.Marshal(secrets)
+	if err != nil {
+		return err
+	}
+	aead, err := chacha20poly1305.NewX(v.key)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+
+	vf := vaultFile{
+		Version:    1,
+		Kdf:        "scrypt",
+		ScryptN:    vaultScryptN,
+		ScryptR:    vaultScryptR,
+		ScryptP:    vaultScryptP,
+		Salt:       v.salt,
+		Nonce:      nonce,
+		Ciphertext: aead.Seal(nil, nonce, plain, nil),
+	}
+	buf, err := json.MarshalIndent(vf, "", "\t")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(v.filePath, buf, 0600); err != nil {
+		return fmt.Errorf("writing secrets vault: %w", err)
+	}
+
+	v.secrets = copySecrets(secrets)
+	return nil
+}
+
+func copySecrets(s map[string]string) map[string]string {
+	out := make(map[string]string, len(s))
+	for k, v := range s {
+		out[k] = v
+	}
+	return out
+}
+
+// configSecrets lists the secret fields of D8XConfig. Returned pointer is nil
+// when the field's parent struct is not set.
+var configSecrets = map[string]func(*D8XConfig) *string{
+	SecretLinodeToken: func(c *D8XConfig) *string {
+		if c.LinodeConfig == nil {
+			return nil
+		}
+		return &c.LinodeConfig.Token
+	},
+	SecretAWSAccessKey: func(c *D8XConfig) *string {
+		if c.AWSConfig == nil {
+			return nil
+		}
+		return &c.AWSConfig.AccesKey
+	},
+	SecretAWSSecretKey: func(c *D8XConfig) *string {
+		if c.AWSConfig == nil {
+			return nil
+		}
+		return &c.AWSConfig.SecretKey
+	},
+	SecretHetznerToken: func(c *D8XConfig) *string {
+		if c.HetznerConfig == nil {
+			return nil
+		}
+		return &c.HetznerConfig.Token
+	},
+	SecretDatabaseDSN: func(c *D8XConfig) *string {
+		return &c.DatabaseDSN
+	},
+	SecretSwarmRedisPassword: func(c *D8XConfig) *string {
+		return &c.SwarmRedisPassword
+	},
+	SecretBrokerRedisPassword: func(c *D8XConfig) *string {
+		return &c.BrokerServerConfig.RedisPassword
+	},
+	SecretDbBackupS3AccessKey: func(c *D8XConfig) *string {
+		if c.DbBackup == nil {
+			return nil
+		}
+		return &c.DbBackup.S3AccessKey
+	},
+	SecretDbBackupS3SecretKey: func(c *D8XConfig) *string {
+		if c.DbBackup == nil {
+			return nil
+		}
+		return &c.DbBackup.S3SecretKey
+	},
+}
+
+// NewSecretsD8XConfigRW wraps given D8XConfigReadWriter and keeps all secret
+// values of D8XConfig in the provided SecretsStore. Config written by rw never
+// contains secrets, they are filled in from the store on Read.
+func NewSecretsD8XConfigRW(rw D8XConfigReadWriter, store SecretsStore) D8XConfigReadWriter {
+	return &secretsD8XConfigRW{rw: rw, store: store}
+}
+
+var _ (D8XConfigReadWriter) = (*secretsD8XConfigRW)(nil)
+
+type secretsD8XConfigRW struct {
+	rw    D8XConfigReadWriter
+	store SecretsStore
+}
+
+func (s *secretsD8XConfigRW) GetPath() string {
+	return s.rw.GetPath()
+}
+
+func (s *secretsD8XConfigRW) Read() (*D8XConfig, error) {
+	cfg, err := s.rw.Read()
+	if err != nil {
+		return nil, err
+	}
+	secrets, err := s.store.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	for name, field := range configSecrets {
+		// Values found in plain config take precedence, they will be moved to
+		// the store on next write
+		if f := field(cfg); f != nil && *f == "" {
+			*f = secrets[name]
+		}
+	}
+
+	return cfg, nil
+}
+
+func (s *secretsD8XConfigRW) Write(cfg *D8XConfig) error {
+	sanitized, err := s.extractSecrets(cfg)
+	if err != nil {
+		return err
+	}
+	return s.rw.Write(sanitized)
+}
+
+func (s *secretsD8XConfigRW) WriteTo(filePath string, cfg *D8XConfig) error {
+	sanitized, err := s.extractSecrets(cfg)
+	if err != nil {
+		return err
+	}
+	return s.rw.WriteTo(filePath, sanitized)
+}
+
+// extractSecrets stores secret values of cfg in the secrets store and returns
+// a copy of cfg without secrets
+func (s *secretsD8XConfigRW) extractSecrets(cfg *D8XConfig) (*D8XConfig, error) {
+	secrets, err := s.store.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	sanitized := *cfg
+	if cfg.LinodeConfig != nil {
+		l := *cfg.LinodeConfig
+		sanitized.LinodeConfig = &l
+	}
+	if cfg.AWSConfig != nil {
+		a := *cfg.AWSConfig
+		sanitized.AWSConfig = &a
+	}
+	if cfg.HetznerConfig != nil {
+		h := *cfg.HetznerConfig
+		sanitized.HetznerConfig = &h
+	}
+	if cfg.DbBackup != nil {
+		b := *cfg.DbBackup
+		sanitized.DbBackup = &b
+	}
+
+	changed := false
+	for name, field := range configSecrets {
+		f := field(&sanitized)
+		if f == nil {
+			continue
+		}
+		if secrets[name] != *f {
+			changed = true
+			if *f == "" {
+				delete(secrets, name)
+			} else {
+				secrets[name] = *f
+			}
+		}
+		*f = ""
+	}
+
+	if changed || !s.store.Exists() {
+		if err := s.store.Save(secrets); err != nil {
+			return nil, err
+		}
+	}
+
+	return &sanitized, nil
+}
+
+// SecretValues returns all non empty secret values of the config
+func (c *D8XConfig) SecretValues() []string {
+	values := []string{}
+	for _, field := range configSecrets {
+		if f := field(c); f != nil && *f != "" {
+			values = append(values, *f)
+		}
+	}
+	return values
+}
+
+// secretPlaceholder is stored instead of secret name value by MaskSecrets
+func secretPlaceholder(name string) string {
+	return "<d8x-secret:" + name + ">"
+}
+
+// MaskSecrets replaces all non empty secret values of the config found in
+// contents with placeholders which can be resolved by UnmaskSecrets
+func (c *D8XConfig) MaskSecrets(contents []byte) []byte {
+	names := []string{}
+	for name, field := range configSecrets {
+		if f := field(c); f != nil && *f != "" {
+			names = append(names, name)
+		}
+	}
+	// Longer values first, secret might contain another one (dsn contains
+	// the database password)
+	sort.Slice(names, func(i, j int) bool {
+		return len(*configSecrets[names[i]](c)) > len(*configSecrets[names[j]](c))
+	})
+
+	for _, name := range names {
+		contents = bytes.ReplaceAll(contents, []byte(*configSecrets[name](c)), []byte(secretPlaceholder(name)))
+	}
+	return contents
+}
+
+// UnmaskSecrets replaces placeholders created by MaskSecrets with current
+// secret values of the config
+func (c *D8XConfig) UnmaskSecrets(contents []byte) ([]byte, error) {
+	for name, field := range configSecrets {
+		placeholder := []byte(secretPlaceholder(name))
+		if !bytes.Contains(contents, placeholder) {
+			continue
+		}
+		f := field(c)
+		if f == nil || *f == "" {
+			return nil, fmt.Errorf("secret %s is not set", name)
+		}
+		contents = bytes.ReplaceAll(contents, placeholder, []byte(*f))
+	}
+	return contents, nil
+}
@@ -0,0 +1,247 @@
+package configs
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Severity levels of ConfigIssue
+const (
+	ConfigIssueError   = "error"
+	ConfigIssueWarning = "warning"
+)
+
+// ConfigIssue is a missing or inconsistent field of d8x config
+type ConfigIssue struct {
+	Severity string `json:"severity" yaml:"severity"`
+	// json path of the field
+	Field   string `json:"field" yaml:"field"`
+	Message string `json:"message" yaml:"message"`
+}
+
+type configIssues []ConfigIssue
+
+func (i *configIssues) errorf(field, format string, args ...any) {
+	*i = append(*i, ConfigIssue{Severity: ConfigIssueError, Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+func (i *configIssues) warnf(field, format string, args ...any) {
+	*i = append(*i, ConfigIssue{Severity: ConfigIssueWarning, Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// Validate reports missing and inconsistent fields of config. Checks which
+// require data outside of config (chain.json, hosts.cfg) are not performed.
+func (c *D8XConfig) Validate() []ConfigIssue {
+	issues := configIssues{}
+
+	if c.ServerProvider == "" {
+		issues.errorf("server_provider", "server provider is not set, run d8x setup")
+		return issues
+	}
+	c.validateProvider(&issues)
+
+	if c.ChainId == 0 {
+		issues.errorf("chain_id", "chain id is not set")
+	}
+	if c.SSHPort < 0 || c.SSHPort > 65535 {
+		issues.errorf("ssh_port", "invalid ssh port %d", c.SSHPort)
+	}
+
+	c.validateDeploymentStatus(&issues)
+	c.validateServices(&issues)
+	c.validateRpcs(&issues)
+	c.validateRpcDistribution(&issues)
+
+	return issues
+}
+
+func (c *D8XConfig) validateProvider(issues *configIssues) {
+	switch c.ServerProvider {
+	case D8XServerProviderLinode:
+		if c.LinodeConfig == nil {
+			issues.errorf("linode_config", "linode config is missing for server provider linode")
+		}
+	case D8XServerProviderAWS:
+		if c.AWSConfig == nil {
+			issues.errorf("aws_config", "aws config is missing for server provider aws")
+		}
+	case D8XServerProviderHetzner:
+		if c.HetznerConfig == nil {
+			issues.errorf("hetzner_config", "hetzner config is missing for server provider hetzner")
+		}
+	case D8XServerProviderStatic:
+		if c.StaticConfig == nil {
+			issues.errorf("static_config", "static config is missing for server provider static")
+			return
+		}
+		s := c.StaticConfig
+		if s.DeploySwarm && s.ManagerIp == "" {
+			issues.errorf("static_config.manager_ip", "manager ip is required when swarm is deployed")
+		}
+		if len(s.WorkerPrivateIps) > 0 && len(s.WorkerPrivateIps) != len(s.WorkerIps) {
+			issues.errorf("static_config.worker_private_ips", "%d worker private ips do not match %d worker ips", len(s.WorkerPrivateIps), len(s.WorkerIps))
+		}
+		if s.CreateBrokerServer && s.BrokerIp == "" {
+			issues.errorf("static_config.broker_ip", "broker ip is required when broker server is created")
+		}
+	default:
+		issues.errorf("server_provider", "unknown server provider %s", c.ServerProvider)
+	}
+}
+
+func (c *D8XConfig) validateDeploymentStatus(issues *configIssues) {
+	if c.SwarmDeployed {
+		if c.DatabaseDSN == "" {
+			issues.errorf("database_dsn", "database dsn is required when swarm is deployed")
+		}
+		if c.SwarmRedisPassword == "" {
+			issues.errorf("swarm_redis_password", "swarm redis password is required when swarm is deployed")
+		}
+		if !c.ConfigDetails.Done {
+			issues.warnf("configuration_details.done", "swarm is deployed but servers configuration was not recorded as done")
+		}
+	}
+	if c.SwarmNginxDeployed && !c.SwarmDeployed {
+		issues.warnf("swarm_nginx_deployed", "swarm nginx is deployed but swarm is not")
+	}
+	if c.SwarmCertbotDeployed && !c.SwarmNginxDeployed {
+		issues.warnf("swarm_certbot_deployed", "swarm certbot is deployed but swarm nginx is not")
+	}
+
+	if c.BrokerDeployed && c.BrokerServerConfig.RedisPassword == "" {
+		issues.errorf("broker_server_config.redis_password", "broker redis password is required when broker server is deployed")
+	}
+	if c.BrokerNginxDeployed && !c.BrokerDeployed {
+		issues.warnf("broker_nginx_deployed", "broker nginx is deployed but broker server is not")
+	}
+	if c.BrokerCertbotDeployed && !c.BrokerNginxDeployed {
+		issues.warnf("broker_certbot_deployed", "broker certbot is deployed but broker nginx is not")
+	}
+}
+
+func (c *D8XConfig) validateServices(issues *configIssues) {
+	usesHTTPS := false
+	names := make([]string, 0, len(c.Services))
+	for name := range c.Services {
+		names = append(names, string(name))
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		svc := c.Services[D8XServiceName(name)]
+		field := "services." + name
+		if _, known := SuggestedSubdomains[D8XServiceName(name)]; !known {
+			issues.warnf(field, "unknown service %s", name)
+		}
+		if svc.Name != D8XServiceName(name) {
+			issues.errorf(field+".name", "service name %q does not match its key %s", svc.Name, name)
+		}
+		if svc.HostName == "" {
+			issues.errorf(field+".hostname", "hostname of service %s is not set", name)
+		}
+		if !svc.UsesHTTPS {
+			continue
+		}
+		usesHTTPS = true
+
+		// Certificates are issued by certbot only after nginx is deployed
+		if D8XServiceName(name) == D8XServiceBrokerServer {
+			if c.BrokerNginxDeployed && !c.BrokerCertbotDeployed {
+				issues.errorf(field+".https", "service %s uses https but certbot was not deployed on broker server", name)
+			}
+		} else if c.SwarmNginxDeployed && !c.SwarmCertbotDeployed {
+			issues.errorf(field+".https", "service %s uses https but certbot was not deployed on manager", name)
+		}
+	}
+
+	if usesHTTPS && c.CertbotEmail == "" {
+		issues.errorf("certbot_email", "certbot email is required when services use https")
+	}
+}
+
+func (c *D8XConfig) validateRpcs(issues *configIssues) {
+	if c.ChainId == 0 {
+		return
+	}
+	chainId := fmt.Sprintf("%d", c.ChainId)
+	if c.SwarmDeployed || c.BrokerDeployed {
+		if len(c.HttpRpcList[chainId]) == 0 {
+			issues.errorf("http_rpc_list."+chainId, "no http rpc endpoints for chain %s", chainId)
+		}
+		if len(c.WsRpcList[chainId]) == 0 {
+			issues.warnf("ws_rpc_list."+chainId, "no websocket rpc endpoints for chain %s", chainId)
+		}
+	}
+
+	for _, id := range sortedKeys(c.HttpRpcList) {
+		for _, rpc := range c.HttpRpcList[id] {
+			if !strings.HasPrefix(rpc, "http://") && !strings.HasPrefix(rpc, "https://") {
+				issues.errorf("http_rpc_list."+id, "invalid http rpc endpoint %s", rpc)
+			}
+		}
+	}
+	for _, id := range sortedKeys(c.WsRpcList) {
+		for _, rpc := range c.WsRpcList[id] {
+			if !strings.HasPrefix(rpc, "ws://") && !strings.HasPrefix(rpc, "wss://") {
+				issues.errorf("ws_rpc_list."+id, "invalid websocket rpc endpoint %s", rpc)
+			}
+		}
+	}
+}
+
+func (c *D8XConfig) validateRpcDistribution(issues *configIssues) {
+	d := c.RpcDistribution
+	if d == nil {
+		return
+	}
+	configured := func(url string) bool {
+		chainId := fmt.Sprintf("%d", c.ChainId)
+		return slices.Contains(c.HttpRpcList[chainId], url) || slices.Contains(c.WsRpcList[chainId], url)
+	}
+
+	for _, name := range sortedKeys(d.Services) {
+		p := d.Services[name]
+		field := "rpc_distribution.services." + name
+		if !slices.Contains(RpcServices, name) {
+			issues.errorf(field, "unknown service %s, rpcs are distributed to %s", name, strings.Join(RpcServices, ", "))
+		}
+		if p.Strategy != "" && !slices.Contains(RpcStrategies, p.Strategy) {
+			issues.errorf(field+".strategy", "unknown strategy %s, supported strategies: %s", p.Strategy, strings.Join(RpcStrategies, ", "))
+		}
+		if p.MinCount < 0 {
+			issues.errorf(field+".min_count", "min count must not be negative")
+		}
+		if p.Strategy == RpcStrategyPinned {
+			if len(p.Urls) == 0 {
+				issues.errorf(field+".urls", "pinned strategy requires at least one url")
+			}
+			for _, url := range p.Urls {
+				if c.ChainId != 0 && !configured(url) {
+					issues.warnf(field+".urls", "pinned url %s is not in http or ws rpc list of chain %d", url, c.ChainId)
+				}
+			}
+		} else if len(p.Urls) > 0 {
+			issues.warnf(field+".urls", "urls are only used by pinned strategy")
+		}
+	}
+
+	for _, url := range sortedKeys(d.Endpoints) {
+		if d.Endpoints[url].Weight < 0 {
+			issues.errorf("rpc_distribution.endpoints."+url+".weight", "weight must not be negative")
+		}
+		if c.ChainId != 0 && !configured(url) {
+			issues.warnf("rpc_distribution.endpoints."+url, "url %s is not in http or ws rpc list of chain %d", url, c.ChainId)
+		}
+	}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
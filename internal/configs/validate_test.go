@@ -0,0 +1,143 @@
+package configs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func validTestConfig() *D8XConfig {
+	return &D8XConfig{
+		ServerProvider: D8XServerProviderHetzner,
+		HetznerConfig:  &D8XHetznerConfig{},
+		ChainId:        1101,
+		Services: map[D8XServiceName]D8XService{
+			D8XServiceMainHTTP:     {Name: D8XServiceMainHTTP, HostName: "api.example.com", UsesHTTPS: true},
+			D8XServiceBrokerServer: {Name: D8XServiceBrokerServer, HostName: "broker.example.com", UsesHTTPS: true},
+		},
+		HttpRpcList:           map[string][]string{"1101": {"https://rpc.example.com"}},
+		WsRpcList:             map[string][]string{"1101": {"wss://rpc.example.com"}},
+		DatabaseDSN:           "postgres://d8x:pwd@db:5432/d8x",
+		SwarmRedisPassword:    "redis",
+		CertbotEmail:          "ops@example.com",
+		SwarmDeployed:         true,
+		SwarmNginxDeployed:    true,
+		SwarmCertbotDeployed:  true,
+		BrokerDeployed:        true,
+		BrokerNginxDeployed:   true,
+		BrokerCertbotDeployed: true,
+		BrokerServerConfig:    D8XBrokerServerConfig{RedisPassword: "redis"},
+		ConfigDetails:         ConfigurationDetails{Done: true},
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(*D8XConfig)
+		want   []ConfigIssue
+	}{
+		{
+			name:   "valid",
+			modify: func(*D8XConfig) {},
+			want:   []ConfigIssue{},
+		},
+		{
+			name:   "empty",
+			modify: func(c *D8XConfig) { *c = D8XConfig{} },
+			want: []ConfigIssue{
+				{Severity: ConfigIssueError, Field: "server_provider", Message: "server provider is not set, run d8x setup"},
+			},
+		},
+		{
+			name: "https without certbot",
+			modify: func(c *D8XConfig) {
+				c.SwarmCertbotDeployed = false
+				c.CertbotEmail = ""
+			},
+			want: []ConfigIssue{
+				{Severity: ConfigIssueError, Field: "services.main_http.https", Message: "service main_http uses https but certbot was not deployed on manager"},
+				{Severity: ConfigIssueError, Field: "certbot_email", Message: "certbot email is required when services use https"},
+			},
+		},
+		{
+			name: "swarm deployed without configuration",
+			modify: func(c *D8XConfig) {
+				c.SwarmDeployed = false
+				c.ConfigDetails.Done = false
+				c.DatabaseDSN = ""
+			},
+			want: []ConfigIssue{
+				{Severity: ConfigIssueWarning, Field: "swarm_nginx_deployed", Message: "swarm nginx is deployed but swarm is not"},
+			},
+		},
+		{
+			name: "static provider",
+			modify: func(c *D8XConfig) {
+				c.ServerProvider = D8XServerProviderStatic
+				c.StaticConfig = &D8XStaticConfig{
+					DeploySwarm:      true,
+					WorkerIps:        []string{"10.0.0.1", "10.0.0.2"},
+					WorkerPrivateIps: []string{"192.168.0.1"},
+				}
+			},
+			want: []ConfigIssue{
+				{Severity: ConfigIssueError, Field: "static_config.manager_ip", Message: "manager ip is required when swarm is deployed"},
+				{Severity: ConfigIssueError, Field: "static_config.worker_private_ips", Message: "1 worker private ips do not match 2 worker ips"},
+			},
+		},
+		{
+			name: "services and rpcs",
+			modify: func(c *D8XConfig) {
+				c.Services["history"] = D8XService{Name: "referral"}
+				c.HttpRpcList = map[string][]string{"1": {"rpc.example.com"}}
+				c.WsRpcList["1101"] = []string{"https://rpc.example.com"}
+				c.SSHPort = 70000
+			},
+			want: []ConfigIssue{
+				{Severity: ConfigIssueError, Field: "ssh_port", Message: "invalid ssh port 70000"},
+				{Severity: ConfigIssueError, Field: "services.history.name", Message: `service name "referral" does not match its key history`},
+				{Severity: ConfigIssueError, Field: "services.history.hostname", Message: "hostname of service history is not set"},
+				{Severity: ConfigIssueError, Field: "http_rpc_list.1101", Message: "no http rpc endpoints for chain 1101"},
+				{Severity: ConfigIssueError, Field: "http_rpc_list.1", Message: "invalid http rpc endpoint rpc.example.com"},
+				{Severity: ConfigIssueError, Field: "ws_rpc_list.1101", Message: "invalid websocket rpc endpoint https://rpc.example.com"},
+			},
+		},
+		{
+			name: "rpc distribution",
+			modify: func(c *D8XConfig) {
+				c.RpcDistribution = &D8XRpcDistribution{
+					Services: map[string]D8XRpcServicePolicy{
+						"main":     {Strategy: RpcStrategyPinned, Urls: []string{"https://rpc.example.com", "https://other.example.com"}},
+						"history":  {Strategy: "random", MinCount: -1},
+						"referral": {Strategy: RpcStrategyPinned},
+						"broker":   {Strategy: RpcStrategyWeighted, Urls: []string{"https://rpc.example.com"}},
+						"candles":  {Strategy: RpcStrategyRoundRobin},
+					},
+					Endpoints: map[string]D8XRpcEndpoint{
+						"wss://rpc.example.com":     {Weight: -1},
+						"https://other.example.com": {Tier: 1},
+					},
+				}
+			},
+			want: []ConfigIssue{
+				{Severity: ConfigIssueWarning, Field: "rpc_distribution.services.broker.urls", Message: "urls are only used by pinned strategy"},
+				{Severity: ConfigIssueError, Field: "rpc_distribution.services.candles", Message: "unknown service candles, rpcs are distributed to main, history, referral, broker"},
+				{Severity: ConfigIssueError, Field: "rpc_distribution.services.history.strategy", Message: "unknown strategy random, supported strategies: round_robin, pinned, weighted, tiered"},
+				{Severity: ConfigIssueError, Field: "rpc_distribution.services.history.min_count", Message: "min count must not be negative"},
+				{Severity: ConfigIssueWarning, Field: "rpc_distribution.services.main.urls", Message: "pinned url https://other.example.com is not in http or ws rpc list of chain 1101"},
+				{Severity: ConfigIssueError, Field: "rpc_distribution.services.referral.urls", Message: "pinned strategy requires at least one url"},
+				{Severity: ConfigIssueWarning, Field: "rpc_distribution.endpoints.https://other.example.com", Message: "url https://other.example.com is not in http or ws rpc list of chain 1101"},
+				{Severity: ConfigIssueError, Field: "rpc_distribution.endpoints.wss://rpc.example.com.weight", Message: "weight must not be negative"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validTestConfig()
+			tt.modify(cfg)
+			assert.Equal(t, tt.want, cfg.Validate())
+		})
+	}
+}
@@ -0,0 +1,213 @@
+package conn
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os/exec"
+	"regexp"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// Kinds of recorded plan steps
+const (
+	PlanStepExec  = "exec"
+	PlanStepCopy  = "copy"
+	PlanStepLocal = "local"
+)
+
+// PlanStep is a single recorded action of dry run
+type PlanStep struct {
+	// Host on which the action would be performed
+	Host string
+	Kind string
+	// Command or copied file description
+	Detail string
+}
+
+// Plan records remote commands, sftp copies and local commands instead of
+// executing them. Used for --dry-run mode.
+type Plan struct {
+	mu      sync.Mutex
+	steps   []PlanStep
+	secrets []string
+}
+
+func NewPlan() *Plan {
+	return &Plan{}
+}
+
+// Record appends a new step to the plan
+func (p *Plan) Record(host, kind, detail string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.steps = append(p.steps, PlanStep{Host: host, Kind: kind, Detail: detail})
+}
+
+// AddSecrets registers secret values which will be masked when plan is
+// printed
+func (p *Plan) AddSecrets(secrets ...string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, s := range secrets {
+		if s != "" {
+			p.secrets = append(p.secrets, s)
+		}
+	}
+}
+
+// Steps returns the recorded steps in order
+func (p *Plan) Steps() []PlanStep {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]PlanStep{}, p.steps...)
+}
+
+// secretAssignments matches common inline secrets in shell commands which are
+// not registered via AddSecrets
+var secretAssignments = regexp.MustCompile(`(?i)((?:password|passwd|_pass|secret|token|private_key|pk)['"]?\s*[=:]\s*['"]?)([^\s'"]+)`)
+
+// Mask replaces all registered secrets and inline secret assignments in s
+func (p *Plan) Mask(s string) string {
+	p.mu.Lock()
+	secrets := append([]string{}, p.secrets...)
+	p.mu.Unlock()
+
+	for _, secret := range secrets {
+		s = strings.ReplaceAll(s, secret, "******")
+	}
+	return secretAssignments.ReplaceAllString(s, "${1}******")
+}
+
+// Print writes ordered plan with masked secrets to w
+func (p *Plan) Print(w io.Writer) {
+	steps := p.Steps()
+	if len(steps) == 0 {
+		fmt.Fprintln(w, "Dry run: no remote commands, file copies or local commands would be executed")
+		return
+	}
+
+	fmt.Fprintf(w, "Dry run plan (%d steps):\n", len(steps))
+	for i, step := range steps {
+		fmt.Fprintf(w, "%3d. [%s] %-5s %s\n", i+1, step.Host, step.Kind, p.Mask(step.Detail))
+	}
+}
+
+// SSHConnectionEstablisher returns establisher of recording connections
+func (p *Plan) SSHConnectionEstablisher() SSHConnectionEstablisher {
+	return func(serverIp, user, idFilePath string) (SSHConnection, error) {
+		return &dryRunConnection{plan: p, host: user + "@" + serverIp}, nil
+	}
+}
+
+// SSHConnectionWithBastionEstablisher returns establisher of recording
+// connections which are made via bastion
+func (p *Plan) SSHConnectionWithBastionEstablisher() SSHConnectionWithBastionEstablisher {
+	return func(bastion SSHConnection, serverIp, user, idFilePath string) (SSHConnection, error) {
+		host := user + "@" + serverIp
+		if b, ok := bastion.(*dryRunConnection); ok {
+			host = b.host + " -> " + host
+		}
+		return &dryRunConnection{plan: p, host: host}, nil
+	}
+}
+
+// RunCmd records local command instead of running it
+func (p *Plan) RunCmd(cmd *exec.Cmd) error {
+	p.Record("localhost", PlanStepLocal, strings.Join(cmd.Args, " "))
+	return nil
+}
+
+var _ (SSHConnection) = (*dryRunConnection)(nil)
+
+// dryRunConnection records all commands and copies in plan
+type dryRunConnection struct {
+	plan *Plan
+	host string
+
+	clientOnce sync.Once
+	client     *ssh.Client
+}
+
+func (d *dryRunConnection) ExecCommand(cmd string) ([]byte, error) {
+	d.plan.Record(d.host, PlanStepExec, cmd)
+	return []byte{}, nil
+}
+
+func (d *dryRunConnection) ExecCommandPiped(cmd string) error {
+	d.plan.Record(d.host, PlanStepExec, cmd)
+	return nil
+}
+
+func (d *dryRunConnection) ExecCommandStream(ctx context.Context, cmd string, stdout, stderr io.Writer) error {
+	d.plan.Record(d.host, PlanStepExec, cmd)
+	return nil
+}
+
+func (d *dryRunConnection) CopyFilesOverSftp(srcDst ...SftpCopySrcDest) error {
+	for _, cp := range srcDst {
+		d.plan.Record(d.host, PlanStepCopy, cp.Src+" -> "+cp.Dst)
+	}
+	return nil
+}
+
+func (d *dryRunConnection) Reconnect() error {
+	return nil
+}
+
+// GetClient returns a client without an actual connection. Opening channels
+// (dialing through the connection, sessions, sftp) on it fails with
+// errDryRunClient.
+func (d *dryRunConnection) GetClient() *ssh.Client {
+	d.clientOnce.Do(func() {
+		chans := make(chan ssh.NewChannel)
+		reqs := make(chan *ssh.Request)
+		close(chans)
+		close(reqs)
+		d.client = ssh.NewClient(&dryRunSSHConn{host: d.host}, chans, reqs)
+	})
+	return d.client
+}
+
+var errDryRunClient = errors.New("dry run does not connect to servers")
+
+var _ (ssh.Conn) = (*dryRunSSHConn)(nil)
+
+// dryRunSSHConn is ssh.Conn of dry run clients which refuses to open any
+// channel
+type dryRunSSHConn struct {
+	host string
+}
+
+func (d *dryRunSSHConn) User() string          { return "" }
+func (d *dryRunSSHConn) SessionID() []byte     { return nil }
+func (d *dryRunSSHConn) ClientVersion() []byte { return nil }
+func (d *dryRunSSHConn) ServerVersion() []byte { return nil }
+func (d *dryRunSSHConn) RemoteAddr() net.Addr  { return dryRunAddr(d.host) }
+func (d *dryRunSSHConn) LocalAddr() net.Addr   { return dryRunAddr("localhost") }
+
+func (d *dryRunSSHConn) SendRequest(name string, wantReply bool, payload []byte) (bool, []byte, error) {
+	return true, nil, nil
+}
+
+func (d *dryRunSSHConn) OpenChannel(name string, data []byte) (ssh.Channel, <-chan *ssh.Request, error) {
+	return nil, nil, fmt.Errorf("%s: %w", d.host, errDryRunClient)
+}
+
+func (d *dryRunSSHConn) Close() error {
+	return nil
+}
+
+func (d *dryRunSSHConn) Wait() error {
+	return errDryRunClient
+}
+
+type dryRunAddr string
+
+func (a dryRunAddr) Network() string { return "dry-run" }
+func (a dryRunAddr) String() string  { return string(a) }
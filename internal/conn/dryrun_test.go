@@ -0,0 +1,56 @@
+package conn
+
+import (
+	"bytes"
+	"os/exec"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlan(t *testing.T) {
+	plan := NewPlan()
+	plan.AddSecrets("hunter2")
+
+	manager, err := plan.SSHConnectionEstablisher()("10.0.0.1", "d8xtrader", "./id_ed25519")
+	require.NoError(t, err)
+	worker, err := plan.SSHConnectionWithBastionEstablisher()(manager, "10.0.0.2", "d8xtrader", "./id_ed25519")
+	require.NoError(t, err)
+
+	_, err = manager.ExecCommand(`echo 'hunter2' | sudo -S docker stack ls`)
+	assert.NoError(t, err)
+	assert.NoError(t, manager.CopyFilesOverSftp(SftpCopySrcDest{Src: "./.env", Dst: "./trader-backend/.env"}))
+	assert.NoError(t, worker.ExecCommandPiped("docker volume ls"))
+	assert.NoError(t, plan.RunCmd(exec.Command("ansible-playbook", "--extra-vars", "ansible_become_pass='secret123'")))
+
+	assert.Equal(t,
+		[]PlanStep{
+			{Host: "d8xtrader@10.0.0.1", Kind: PlanStepExec, Detail: `echo 'hunter2' | sudo -S docker stack ls`},
+			{Host: "d8xtrader@10.0.0.1", Kind: PlanStepCopy, Detail: "./.env -> ./trader-backend/.env"},
+			{Host: "d8xtrader@10.0.0.1 -> d8xtrader@10.0.0.2", Kind: PlanStepExec, Detail: "docker volume ls"},
+			{Host: "localhost", Kind: PlanStepLocal, Detail: "ansible-playbook --extra-vars ansible_become_pass='secret123'"},
+		},
+		plan.Steps(),
+	)
+
+	out := bytes.NewBuffer(nil)
+	plan.Print(out)
+	assert.NotContains(t, out.String(), "hunter2")
+	assert.NotContains(t, out.String(), "secret123")
+}
+
+func TestDryRunClient(t *testing.T) {
+	manager, err := NewPlan().SSHConnectionEstablisher()("10.0.0.1", "d8xtrader", "./id_ed25519")
+	require.NoError(t, err)
+
+	client := manager.GetClient()
+	require.NotNil(t, client)
+	assert.Same(t, client, manager.GetClient())
+
+	_, err = client.Dial("tcp", "127.0.0.1:5432")
+	assert.ErrorIs(t, err, errDryRunClient)
+	_, err = client.NewSession()
+	assert.ErrorIs(t, err, errDryRunClient)
+	assert.True(t, healthy(manager))
+}
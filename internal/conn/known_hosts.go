@@ -0,0 +1,315 @@
+package conn
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// Host key algorithms offered to servers which are not yet present in known
+// hosts file. Ed25519 keys are preferred.
+var defaultHostKeyAlgorithms = []string{
+	ssh.KeyAlgoED25519,
+	ssh.KeyAlgoECDSA256,
+	ssh.KeyAlgoECDSA384,
+	ssh.KeyAlgoECDSA521,
+	ssh.KeyAlgoRSASHA512,
+	ssh.KeyAlgoRSASHA256,
+	ssh.KeyAlgoRSA,
+}
+
+// HostKeyMismatchError is returned when server presents a host key which
+// differs from the one recorded in known hosts file
+type HostKeyMismatchError struct {
+	Host           string
+	KnownHostsFile string
+	Fingerprint    string
+}
+
+func (e *HostKeyMismatchError) Error() string {
+	return fmt.Sprintf(
+		"host key verification failed for %s: server presented key %s which does not match the key recorded in %s. If the server was rebuilt, run d8x hosts rekey <node> to trust the new key",
+		e.Host, e.Fingerprint, e.KnownHostsFile,
+	)
+}
+
+// KnownHosts is a d8x managed known_hosts file. Host keys are pinned on first
+// use: keys of unknown hosts are recorded on the first connection and every
+// subsequent connection must present the recorded key.
+type KnownHosts struct {
+	path string
+	mu   sync.Mutex
+}
+
+func NewKnownHosts(path string) *KnownHosts {
+	return &KnownHosts{path: path}
+}
+
+// Path returns the known hosts file path
+func (k *KnownHosts) Path() string {
+	return k.path
+}
+
+// HostKeyCallback returns ssh.HostKeyCallback which verifies host keys against
+// the known hosts file and records keys of unknown hosts.
+func (k *KnownHosts) HostKeyCallback() ssh.HostKeyCallback {
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		k.mu.Lock()
+		defer k.mu.Unlock()
+
+		err := k.check(hostname, remote, key)
+		var keyErr *knownhosts.KeyError
+		if errors.As(err, &keyErr) {
+			if len(keyErr.Want) > 0 {
+				return &HostKeyMismatchError{
+					Host:           knownhosts.Normalize(hostname),
+					KnownHostsFile: k.path,
+					Fingerprint:    ssh.FingerprintSHA256(key),
+				}
+			}
+			// Trust on first use
+			return k.appendLine(knownhosts.Line([]string{knownhosts.Normalize(hostname)}, key))
+		}
+		return err
+	}
+}
+
+// check verifies key against known hosts file. Missing file is treated as an
+// empty one.
+func (k *KnownHosts) check(hostname string, remote net.Addr, key ssh.PublicKey) error {
+	if _, err := os.Stat(k.path); errors.Is(err, os.ErrNotExist) {
+		return &knownhosts.KeyError{}
+	}
+	cb, err := knownhosts.New(k.path)
+	if err != nil {
+		return fmt.Errorf("reading known hosts file %s: %w", k.path, err)
+	}
+	return cb(hostname, remote, key)
+}
+
+// HostKeyAlgorithms returns host key algorithms which should be negotiated with
+// addr. For known hosts only the algorithms of recorded keys are returned,
+// otherwise a different key type might be negotiated and reported as
+// mismatch.
+func (k *KnownHosts) HostKeyAlgorithms(addr string) []string {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	keys, err := k.hostKeys(addr)
+	if err != nil || len(keys) == 0 {
+		return defaultHostKeyAlgorithms
+	}
+
+	algos := []string{}
+	for _, key := range keys {
+		switch key.Type() {
+		case ssh.KeyAlgoRSA:
+			algos = append(algos, ssh.KeyAlgoRSASHA512, ssh.KeyAlgoRSASHA256, ssh.KeyAlgoRSA)
+		default:
+			algos = append(algos, key.Type())
+		}
+	}
+	return algos
+}
+
+// Has reports whether addr has any key recorded in known hosts file
+func (k *KnownHosts) Has(addr string) (bool, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	keys, err := k.hostKeys(addr)
+	return len(keys) > 0, err
+}
+
+// hostKeys returns all keys recorded for addr
+func (k *KnownHosts) hostKeys(addr string) ([]ssh.PublicKey, error) {
+	lines, err := k.readLines()
+	if err != nil {
+		return nil, err
+	}
+	host := knownhosts.Normalize(addr)
+	keys := []ssh.PublicKey{}
+	for _, line := range lines {
+		_, hosts, key, _, _, err := ssh.ParseKnownHosts([]byte(line))
+		if err != nil {
+			continue
+		}
+		for _, h := range hosts {
+			if h == host {
+				keys = append(keys, key)
+				break
+			}
+		}
+	}
+	return keys, nil
+}
+
+// Pin replaces all recorded keys of addr with key
+func (k *KnownHosts) Pin(addr string, key ssh.PublicKey) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if _, err := k.remove(addr); err != nil {
+		return err
+	}
+	return k.appendLine(knownhosts.Line([]string{knownhosts.Normalize(addr)}, key))
+}
+
+// Remove removes all recorded keys of addr and returns the number of removed
+// entries
+func (k *KnownHosts) Remove(addr string) (int, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	return k.remove(addr)
+}
+
+func (k *KnownHosts) remove(addr string) (int, error) {
+	lines, err := k.readLines()
+	if err != nil {
+		return 0, err
+	}
+
+	host := knownhosts.Normalize(addr)
+	kept := []string{}
+	removed := 0
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) > 0 && !strings.HasPrefix(fields[0], "#") {
+			hosts := strings.Split(fields[0], ",")
+			if containsHost(hosts, host) {
+				removed++
+				continue
+			}
+		}
+		kept = append(kept, line)
+	}
+	if removed == 0 {
+		return 0, nil
+	}
+
+	contents := strings.Join(kept, "\n")
+	if len(kept) > 0 {
+		contents += "\n"
+	}
+	if err := os.WriteFile(k.path, []byte(contents), 0600); err != nil {
+		return 0, fmt.Errorf("writing known hosts file %s: %w", k.path, err)
+	}
+	return removed, nil
+}
+
+func containsHost(hosts []string, host string) bool {
+	for _, h := range hosts {
+		if h == host {
+			return true
+		}
+	}
+	return false
+}
+
+func (k *KnownHosts) readLines() ([]string, error) {
+	f, err := os.Open(k.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading known hosts file %s: %w", k.path, err)
+	}
+	defer f.Close()
+
+	lines := []string{}
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		if strings.TrimSpace(s.Text()) != "" {
+			lines = append(lines, s.Text())
+		}
+	}
+	return lines, s.Err()
+}
+
+func (k *KnownHosts) appendLine(line string) error {
+	if err := os.MkdirAll(filepath.Dir(k.path), 0700); err != nil {
+		return fmt.Errorf("creating known hosts directory: %w", err)
+	}
+	f, err := os.OpenFile(k.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("opening known hosts file %s: %w", k.path, err)
+	}
+	defer f.Close()
+	_, err = f.WriteString(line + "\n")
+	return err
+}
+
+// clientConfig returns ssh client config which verifies host key of addr
+// against known hosts file
+func (k *KnownHosts) clientConfig(addr, user string, auth ...ssh.AuthMethod) *ssh.ClientConfig {
+	return &ssh.ClientConfig{
+		User:              user,
+		HostKeyCallback:   k.HostKeyCallback(),
+		HostKeyAlgorithms: k.HostKeyAlgorithms(addr),
+		Auth:              auth,
+		Timeout:           time.Second * 10,
+	}
+}
+
+// errHostKeyScanned aborts the handshake once host key is retrieved
+var errHostKeyScanned = errors.New("host key scanned")
+
+// ScanHostKey retrieves the host key of server at addr (host:port) without
+// authenticating. When bastion is not nil, connection is established via
+// bastion.
+func ScanHostKey(bastion *ssh.Client, addr string) (ssh.PublicKey, error) {
+	var hostKey ssh.PublicKey
+	config := &ssh.ClientConfig{
+		User: "d8x",
+		HostKeyCallback: func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+			hostKey = key
+			return errHostKeyScanned
+		},
+		HostKeyAlgorithms: defaultHostKeyAlgorithms,
+		Timeout:           time.Second * 10,
+	}
+
+	var (
+		netConn net.Conn
+		err     error
+	)
+	if bastion != nil {
+		netConn, err = bastion.Dial("tcp", addr)
+	} else {
+		netConn, err = net.DialTimeout("tcp", addr, config.Timeout)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer netConn.Close()
+
+	_, _, _, err = ssh.NewClientConn(netConn, addr, config)
+	if hostKey != nil {
+		return hostKey, nil
+	}
+	if err == nil {
+		err = fmt.Errorf("server did not present a host key")
+	}
+	return nil, err
+}
+
+// Known hosts file used by all ssh connections
+var knownHosts *KnownHosts
+
+// UseKnownHosts sets the known hosts file which all subsequent ssh connections
+// are verified against
+func UseKnownHosts(k *KnownHosts) {
+	knownHosts = k
+}
+
+var errKnownHostsNotSet = errors.New("known hosts file is not set, refusing to connect without host key verification")
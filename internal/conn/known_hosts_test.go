@@ -0,0 +1,92 @@
+package conn
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"net"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+)
+
+func newTestHostKey(t *testing.T) ssh.Signer {
+	_, pk, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	signer, err := ssh.NewSignerFromKey(pk)
+	require.NoError(t, err)
+	return signer
+}
+
+func TestKnownHostsTrustOnFirstUse(t *testing.T) {
+	k := NewKnownHosts(filepath.Join(t.TempDir(), "known_hosts"))
+	cb := k.HostKeyCallback()
+	remote := &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 22}
+
+	key := newTestHostKey(t).PublicKey()
+	otherKey := newTestHostKey(t).PublicKey()
+
+	assert.Equal(t, defaultHostKeyAlgorithms, k.HostKeyAlgorithms("10.0.0.1:22"))
+
+	// Unknown host is recorded
+	require.NoError(t, cb("10.0.0.1:22", remote, key))
+	has, err := k.Has("10.0.0.1:22")
+	require.NoError(t, err)
+	assert.True(t, has)
+	assert.Equal(t, []string{ssh.KeyAlgoED25519}, k.HostKeyAlgorithms("10.0.0.1:22"))
+
+	// Same key is accepted
+	require.NoError(t, cb("10.0.0.1:22", remote, key))
+
+	// Different key is rejected
+	err = cb("10.0.0.1:22", remote, otherKey)
+	var mismatch *HostKeyMismatchError
+	require.ErrorAs(t, err, &mismatch)
+	assert.Equal(t, "10.0.0.1", mismatch.Host)
+	assert.Equal(t, ssh.FingerprintSHA256(otherKey), mismatch.Fingerprint)
+
+	// Pinning replaces the recorded key
+	require.NoError(t, k.Pin("10.0.0.1:22", otherKey))
+	require.NoError(t, cb("10.0.0.1:22", remote, otherKey))
+	require.ErrorAs(t, cb("10.0.0.1:22", remote, key), &mismatch)
+
+	// Other hosts are not affected by removal
+	require.NoError(t, cb("10.0.0.2:22", remote, key))
+	removed, err := k.Remove("10.0.0.1")
+	require.NoError(t, err)
+	assert.Equal(t, 1, removed)
+	has, err = k.Has("10.0.0.1")
+	require.NoError(t, err)
+	assert.False(t, has)
+	has, err = k.Has("10.0.0.2")
+	require.NoError(t, err)
+	assert.True(t, has)
+}
+
+func TestScanHostKey(t *testing.T) {
+	hostKey := newTestHostKey(t)
+	serverCfg := &ssh.ServerConfig{NoClientAuth: true}
+	serverCfg.AddHostKey(hostKey)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer c.Close()
+				_, _, _, _ = ssh.NewServerConn(c, serverCfg)
+			}()
+		}
+	}()
+
+	key, err := ScanHostKey(nil, l.Addr().String())
+	require.NoError(t, err)
+	assert.Equal(t, hostKey.PublicKey().Marshal(), key.Marshal())
+}
@@ -0,0 +1,165 @@
+package conn
+
+import (
+	"sync"
+	"time"
+)
+
+// Timeout of connection health check
+var poolHealthCheckTimeout = time.Second * 10
+
+// Pool caches one SSHConnection per user@host and bastion chain, so that
+// connections are reused during a command run. Cached connections are health
+// checked and reconnected when handed out again. Pool is safe for concurrent
+// use.
+type Pool struct {
+	create            SSHConnectionEstablisher
+	createWithBastion SSHConnectionWithBastionEstablisher
+
+	mu      sync.Mutex
+	entries map[string]*poolEntry
+	// Pool keys of handed out connections, used to build bastion chain keys
+	keys map[SSHConnection]string
+	// Keys in order of creation, connections are closed in reverse order
+	order []string
+}
+
+type poolEntry struct {
+	mu   sync.Mutex
+	conn SSHConnection
+}
+
+// NewPool creates a new connection pool which establishes connections with
+// create and createWithBastion
+func NewPool(create SSHConnectionEstablisher, createWithBastion SSHConnectionWithBastionEstablisher) *Pool {
+	return &Pool{
+		create:            create,
+		createWithBastion: createWithBastion,
+		entries:           map[string]*poolEntry{},
+		keys:              map[SSHConnection]string{},
+	}
+}
+
+var _ (SSHConnectionEstablisher) = (*Pool)(nil).Get
+var _ (SSHConnectionWithBastionEstablisher) = (*Pool)(nil).GetViaBastion
+
+// Get returns cached connection to serverIp or establishes a new one
+func (p *Pool) Get(serverIp, user, idFilePath string) (SSHConnection, error) {
+	return p.get(user+"@"+serverIp, func() (SSHConnection, error) {
+		return p.create(serverIp, user, idFilePath)
+	})
+}
+
+// GetViaBastion returns cached connection to serverIp via bastion or
+// establishes a new one. Connections via bastions which were not created by
+// the pool are not cached.
+func (p *Pool) GetViaBastion(bastion SSHConnection, serverIp, user, idFilePath string) (SSHConnection, error) {
+	create := func() (SSHConnection, error) {
+		return p.createWithBastion(bastion, serverIp, user, idFilePath)
+	}
+
+	p.mu.Lock()
+	bastionKey, ok := p.keys[bastion]
+	p.mu.Unlock()
+	if !ok {
+		return create()
+	}
+	return p.get(bastionKey+" -> "+user+"@"+serverIp, create)
+}
+
+func (p *Pool) get(key string, create func() (SSHConnection, error)) (SSHConnection, error) {
+	p.mu.Lock()
+	e, ok := p.entries[key]
+	if !ok {
+		e = &poolEntry{}
+		p.entries[key] = e
+	}
+	p.mu.Unlock()
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.conn != nil {
+		if healthy(e.conn) {
+			return e.conn, nil
+		}
+		if err := e.conn.Reconnect(); err == nil {
+			return e.conn, nil
+		}
+		// Establish a completely new connection
+		closeConnection(e.conn)
+		p.forget(e.conn)
+		e.conn = nil
+	}
+
+	c, err := create()
+	if err != nil {
+		return nil, err
+	}
+	e.conn = c
+
+	p.mu.Lock()
+	p.keys[c] = key
+	p.order = append(p.order, key)
+	p.mu.Unlock()
+
+	return c, nil
+}
+
+func (p *Pool) forget(c SSHConnection) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.keys, c)
+}
+
+// Close closes all cached connections. Connections via bastions are closed
+// before their bastions.
+func (p *Pool) Close() {
+	p.mu.Lock()
+	entries := make([]*poolEntry, 0, len(p.order))
+	for i := len(p.order) - 1; i >= 0; i-- {
+		if e, ok := p.entries[p.order[i]]; ok {
+			entries = append(entries, e)
+			delete(p.entries, p.order[i])
+		}
+	}
+	p.order = nil
+	p.keys = map[SSHConnection]string{}
+	p.mu.Unlock()
+
+	for _, e := range entries {
+		e.mu.Lock()
+		if e.conn != nil {
+			closeConnection(e.conn)
+			e.conn = nil
+		}
+		e.mu.Unlock()
+	}
+}
+
+// healthy checks whether connection is still alive. Connections without
+// underlying client are always healthy.
+func healthy(c SSHConnection) bool {
+	client := c.GetClient()
+	if client == nil {
+		return true
+	}
+
+	replied := make(chan error, 1)
+	go func() {
+		_, _, err := client.SendRequest("keepalive@openssh.com", true, nil)
+		replied <- err
+	}()
+	select {
+	case err := <-replied:
+		return err == nil
+	case <-time.After(poolHealthCheckTimeout):
+		return false
+	}
+}
+
+func closeConnection(c SSHConnection) {
+	if client := c.GetClient(); client != nil {
+		client.Close()
+	}
+}
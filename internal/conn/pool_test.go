@@ -0,0 +1,127 @@
+package conn
+
+import (
+	"net"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+)
+
+// fakeConnection is an SSHConnection without underlying client
+type fakeConnection struct {
+	SSHConnection
+	host string
+}
+
+func (f *fakeConnection) GetClient() *ssh.Client {
+	return nil
+}
+
+func TestPoolCachesConnections(t *testing.T) {
+	var created atomic.Int32
+	p := NewPool(
+		func(serverIp, user, idFilePath string) (SSHConnection, error) {
+			created.Add(1)
+			return &fakeConnection{host: user + "@" + serverIp}, nil
+		},
+		func(bastion SSHConnection, serverIp, user, idFilePath string) (SSHConnection, error) {
+			created.Add(1)
+			return &fakeConnection{host: bastion.(*fakeConnection).host + " -> " + user + "@" + serverIp}, nil
+		},
+	)
+
+	manager, err := p.Get("10.0.0.1", "d8x", "key")
+	require.NoError(t, err)
+	managerAgain, err := p.Get("10.0.0.1", "d8x", "key")
+	require.NoError(t, err)
+	assert.Same(t, manager, managerAgain)
+
+	// Different user is a different connection
+	root, err := p.Get("10.0.0.1", "root", "key")
+	require.NoError(t, err)
+	assert.NotSame(t, manager, root)
+
+	// Concurrent retrieval of workers via the same bastion
+	wg := sync.WaitGroup{}
+	workers := make([]SSHConnection, 10)
+	for i := range workers {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			w, err := p.GetViaBastion(manager, "10.0.1.1", "d8x", "key")
+			assert.NoError(t, err)
+			workers[i] = w
+		}(i)
+	}
+	wg.Wait()
+	for _, w := range workers {
+		assert.Same(t, workers[0], w)
+	}
+	assert.Equal(t, "d8x@10.0.0.1 -> d8x@10.0.1.1", workers[0].(*fakeConnection).host)
+
+	// Bastion chains are cached separately
+	viaRoot, err := p.GetViaBastion(root, "10.0.1.1", "d8x", "key")
+	require.NoError(t, err)
+	assert.NotSame(t, workers[0], viaRoot)
+
+	// Bastions not created by the pool are not cached
+	external := &fakeConnection{host: "external"}
+	_, err = p.GetViaBastion(external, "10.0.1.1", "d8x", "key")
+	require.NoError(t, err)
+	_, err = p.GetViaBastion(external, "10.0.1.1", "d8x", "key")
+	require.NoError(t, err)
+
+	assert.Equal(t, int32(6), created.Load())
+
+	// Closed pool establishes new connections
+	p.Close()
+	managerAfterClose, err := p.Get("10.0.0.1", "d8x", "key")
+	require.NoError(t, err)
+	assert.NotSame(t, manager, managerAfterClose)
+}
+
+func TestPoolReconnectsBrokenConnections(t *testing.T) {
+	keyPath, pk := writeTestKey(t, "")
+	pub, err := ssh.NewPublicKey(pk.Public())
+	require.NoError(t, err)
+
+	serverAddr := startTestSSHServer(t, pub)
+	host, port, err := net.SplitHostPort(serverAddr)
+	require.NoError(t, err)
+
+	prevTransport, prevKnownHosts := transport, knownHosts
+	t.Cleanup(func() {
+		UseTransport(prevTransport)
+		UseKnownHosts(prevKnownHosts)
+	})
+	UseKnownHosts(NewKnownHosts(filepath.Join(t.TempDir(), "known_hosts")))
+	UseTransport(Transport{
+		Port: func(string) int {
+			p, _ := net.LookupPort("tcp", port)
+			return p
+		},
+	})
+
+	p := NewPool(NewSSHConnection, NewSSHConnectionViaBastion)
+	defer p.Close()
+
+	c, err := p.Get(host, "d8x", keyPath)
+	require.NoError(t, err)
+	client := c.GetClient()
+
+	// Broken connection is reconnected when handed out again
+	require.NoError(t, client.Close())
+	cAgain, err := p.Get(host, "d8x", keyPath)
+	require.NoError(t, err)
+	assert.Same(t, c, cAgain)
+	assert.NotSame(t, client, cAgain.GetClient())
+	assert.True(t, healthy(cAgain))
+
+	p.Close()
+	assert.False(t, healthy(c))
+}
@@ -13,6 +13,9 @@ type SftpCopySrcDest struct {
 	Src string
 	// Remote destination
 	Dst string
+	// Permissions of remote file, set before contents are written. Default
+	// sftp permissions are used when 0.
+	Mode os.FileMode
 }
 
 // CopyFilesOverSftp copies the list of srcDst to remote conn.
@@ -43,6 +46,12 @@ func CopyFilesOverSftp(
 		if err != nil {
 			return err
 		}
+		if cp.Mode != 0 {
+			if err := dstFd.Chmod(cp.Mode); err != nil {
+				dstFd.Close()
+				return err
+			}
+		}
 
 		// Write
 		_, err = dstFd.Write(srcFileContents)
@@ -1,10 +1,11 @@
 package conn
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"os"
-	"time"
+	"sync"
 
 	"golang.org/x/crypto/ssh"
 )
@@ -19,89 +20,109 @@ type SSHConnection interface {
 	// stdin/out/err
 	ExecCommandPiped(cmd string) error
 
+	// ExecCommandStream executes cmd and streams its stdout and stderr to the
+	// given writers until the command exits or ctx is cancelled. stdout and
+	// stderr can be the same writer.
+	ExecCommandStream(ctx context.Context, cmd string, stdout, stderr io.Writer) error
+
 	CopyFilesOverSftp(srcDst ...SftpCopySrcDest) error
 
 	GetClient() *ssh.Client
+
+	// Reconnect closes the current client and establishes a new connection
+	// to the same server
+	Reconnect() error
 }
 
 type SSHConnectionEstablisher func(serverIp, user, idFilePath string) (SSHConnection, error)
 
 var _ (SSHConnectionEstablisher) = NewSSHConnection
 
-// NewSSHClient attempts to connect to server via ssh on default port 22
-func NewSSHConnection(serverIp, user, idFilePath string) (SSHConnection, error) {
-	pk, err := os.ReadFile(idFilePath)
-	if err != nil {
-		return nil, err
-	}
-
-	signer, err := ssh.ParsePrivateKey(pk)
-	if err != nil {
-		return nil, fmt.Errorf("parsing private key %s: %v", idFilePath, err)
-	}
+type SSHConnectionWithBastionEstablisher func(bastion SSHConnection, serverIp, user, idFilePath string) (SSHConnection, error)
 
-	config := &ssh.ClientConfig{
-		User:            user,
-		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
-		Auth:            []ssh.AuthMethod{ssh.PublicKeys(signer)},
-		Timeout:         time.Second * 10,
-	}
+var _ (SSHConnectionWithBastionEstablisher) = NewSSHConnectionViaBastion
 
-	// TODO pass port as parameter
-	c, err := ssh.Dial("tcp", serverIp+":22", config)
-	if err != nil {
-		return nil, err
-	}
-	return &sshConnection{c: c}, nil
+// NewSSHConnection attempts to connect to server via ssh on the port
+// configured for serverIp in current Transport
+func NewSSHConnection(serverIp, user, idFilePath string) (SSHConnection, error) {
+	return newSSHConnection(func() (*ssh.Client, error) {
+		return transport.dial(nil, serverIp, user, idFilePath)
+	})
 }
 
 func NewSSHConnectionWithBastion(bastion *ssh.Client, serverIp, user, idFilePath string) (SSHConnection, error) {
-	pk, err := os.ReadFile(idFilePath)
-	if err != nil {
-		return nil, err
-	}
-
-	signer, err := ssh.ParsePrivateKey(pk)
-	if err != nil {
-		return nil, fmt.Errorf("parsing private key %s: %v", idFilePath, err)
-	}
+	return newSSHConnection(func() (*ssh.Client, error) {
+		return transport.dial(bastion, serverIp, user, idFilePath)
+	})
+}
 
-	config := &ssh.ClientConfig{
-		User:            user,
-		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
-		Auth:            []ssh.AuthMethod{ssh.PublicKeys(signer)},
-		Timeout:         time.Second * 10,
-	}
+// NewSSHConnectionViaBastion connects to serverIp via already established
+// bastion connection. Reconnecting uses the current bastion client, so
+// reconnected bastion can be reused.
+func NewSSHConnectionViaBastion(bastion SSHConnection, serverIp, user, idFilePath string) (SSHConnection, error) {
+	return newSSHConnection(func() (*ssh.Client, error) {
+		return transport.dial(bastion.GetClient(), serverIp, user, idFilePath)
+	})
+}
 
-	targetConn, err := bastion.Dial("tcp", serverIp+":22")
-	if err != nil {
-		return nil, fmt.Errorf("dialing to target via bastion: %w", err)
-	}
-	a, b, c, err := ssh.NewClientConn(targetConn, ":22", config)
-	if err != nil {
+func newSSHConnection(dial func() (*ssh.Client, error)) (*sshConnection, error) {
+	s := &sshConnection{dial: dial}
+	if err := s.Reconnect(); err != nil {
 		return nil, err
 	}
-
-	return &sshConnection{c: ssh.NewClient(a, b, c)}, nil
+	return s, nil
 }
 
 var _ (SSHConnection) = (*sshConnection)(nil)
 
 type sshConnection struct {
-	c *ssh.Client
+	mu   sync.Mutex
+	c    *ssh.Client
+	dial func() (*ssh.Client, error)
 }
 
 func (s *sshConnection) GetClient() *ssh.Client {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	return s.c
 }
 
+func (s *sshConnection) Reconnect() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.c != nil {
+		s.c.Close()
+	}
+	c, err := s.dial()
+	if err != nil {
+		return err
+	}
+	s.c = c
+	go transport.keepAlive(c)
+	return nil
+}
+
+// newSession opens a new session, reconnecting once when the connection was
+// lost
+func (s *sshConnection) newSession() (*ssh.Session, error) {
+	session, err := s.GetClient().NewSession()
+	if err == nil {
+		return session, nil
+	}
+	if err := s.Reconnect(); err != nil {
+		return nil, fmt.Errorf("reconnecting: %w", err)
+	}
+	return s.GetClient().NewSession()
+}
+
 func (conn *sshConnection) ExecCommand(cmd string) ([]byte, error) {
 	// Print out the cmd for debugging
 	if _, ok := os.LookupEnv("DEBUG"); ok {
 		fmt.Printf("[CMD]: %s\n", cmd)
 	}
 
-	s, err := conn.c.NewSession()
+	s, err := conn.newSession()
 	if err != nil {
 		return nil, err
 	}
@@ -115,7 +136,7 @@ func (conn *sshConnection) ExecCommandPiped(cmd string) error {
 		fmt.Printf("[CMD]: %s\n", cmd)
 	}
 
-	s, err := conn.c.NewSession()
+	s, err := conn.newSession()
 	if err != nil {
 		return err
 	}
@@ -149,6 +170,60 @@ func (conn *sshConnection) ExecCommandPiped(cmd string) error {
 
 }
 
+func (conn *sshConnection) ExecCommandStream(ctx context.Context, cmd string, stdout, stderr io.Writer) error {
+	// Print out the cmd for debugging
+	if _, ok := os.LookupEnv("DEBUG"); ok {
+		fmt.Printf("[CMD]: %s\n", cmd)
+	}
+
+	s, err := conn.newSession()
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	// Stdout and stderr are copied from separate goroutines
+	if stdout == stderr {
+		sw := &syncWriter{w: stdout}
+		s.Stdout = sw
+		s.Stderr = sw
+	} else {
+		s.Stdout = stdout
+		s.Stderr = stderr
+	}
+
+	if err := s.Start(cmd); err != nil {
+		return err
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- s.Wait()
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		// Not every server supports signals, closing the session terminates
+		// the stream either way
+		s.Signal(ssh.SIGTERM)
+		s.Close()
+		return ctx.Err()
+	}
+}
+
+type syncWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (s *syncWriter) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.w.Write(p)
+}
+
 func (conn *sshConnection) CopyFilesOverSftp(srcDst ...SftpCopySrcDest) error {
-	return CopyFilesOverSftp(conn.c, srcDst...)
+	return CopyFilesOverSftp(conn.GetClient(), srcDst...)
 }
@@ -0,0 +1,289 @@
+package conn
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+const DefaultSSHPort = 22
+
+// Transport configures how ssh connections are established
+type Transport struct {
+	// Port returns the ssh port of host. DefaultSSHPort is used when Port is
+	// nil or returns 0.
+	Port func(host string) int
+
+	// Path of ssh-agent socket (SSH_AUTH_SOCK). Agent keys are tried before
+	// the key file.
+	AgentSocket string
+
+	// Passphrase retrieves the passphrase of encrypted key file
+	Passphrase func(keyPath string) ([]byte, error)
+
+	// Interval of keepalive requests, 0 disables keepalives
+	KeepAliveInterval time.Duration
+	// Number of unanswered keepalive requests after which connection is
+	// considered broken and closed
+	KeepAliveMaxMissed int
+}
+
+// DefaultTransport uses default ssh port, ssh-agent from environment and
+// sends keepalives every 30 seconds
+func DefaultTransport() Transport {
+	return Transport{
+		AgentSocket:        os.Getenv("SSH_AUTH_SOCK"),
+		KeepAliveInterval:  time.Second * 30,
+		KeepAliveMaxMissed: 3,
+	}
+}
+
+// Transport used by all ssh connections
+var transport = DefaultTransport()
+
+// UseTransport sets the transport which all subsequent ssh connections use
+func UseTransport(t Transport) {
+	transport = t
+}
+
+// HostAddr returns host:port ssh address of host
+func HostAddr(host string) string {
+	return transport.addr(host)
+}
+
+func (t Transport) addr(host string) string {
+	port := 0
+	if t.Port != nil {
+		port = t.Port(host)
+	}
+	if port == 0 {
+		port = DefaultSSHPort
+	}
+	return net.JoinHostPort(host, strconv.Itoa(port))
+}
+
+// authMethods returns ssh-agent and key file authentication methods. Returned
+// close func must be called once authentication is done.
+func (t Transport) authMethods(idFilePath string) ([]ssh.AuthMethod, func(), error) {
+	signers := []ssh.Signer{}
+	closeAgent := func() {}
+
+	if t.AgentSocket != "" {
+		agentConn, err := net.Dial("unix", t.AgentSocket)
+		if err != nil {
+			return nil, nil, fmt.Errorf("connecting to ssh-agent: %w", err)
+		}
+		closeAgent = func() { agentConn.Close() }
+		agentSigners, err := agent.NewClient(agentConn).Signers()
+		if err != nil {
+			closeAgent()
+			return nil, nil, fmt.Errorf("retrieving ssh-agent keys: %w", err)
+		}
+		signers = append(signers, agentSigners...)
+	}
+
+	keySigner, err := t.keyFileSigner(idFilePath, len(signers) > 0)
+	if err != nil {
+		closeAgent()
+		return nil, nil, err
+	}
+	if keySigner != nil {
+		signers = append(signers, keySigner)
+	}
+
+	return []ssh.AuthMethod{ssh.PublicKeys(signers...)}, closeAgent, nil
+}
+
+// keyFileSigner parses the key file at idFilePath. When agent keys are
+// available, missing key files are skipped. Passphrase of encrypted key file
+// is only requested once the server accepts its public key, so agent keys are
+// tried first without prompting.
+func (t Transport) keyFileSigner(idFilePath string, hasAgentKeys bool) (ssh.Signer, error) {
+	pk, err := os.ReadFile(idFilePath)
+	if err != nil {
+		if hasAgentKeys && errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	signer, err := ssh.ParsePrivateKey(pk)
+	var passErr *ssh.PassphraseMissingError
+	if !errors.As(err, &passErr) {
+		if err != nil {
+			return nil, fmt.Errorf("parsing private key %s: %v", idFilePath, err)
+		}
+		return signer, nil
+	}
+
+	if t.Passphrase == nil {
+		if hasAgentKeys {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("private key %s is encrypted, add it to ssh-agent or provide the passphrase", idFilePath)
+	}
+
+	decrypt := func() (ssh.Signer, error) {
+		passphrase, err := t.Passphrase(idFilePath)
+		if err != nil {
+			return nil, err
+		}
+		signer, err := ssh.ParsePrivateKeyWithPassphrase(pk, passphrase)
+		if err != nil {
+			return nil, fmt.Errorf("parsing encrypted private key %s: %v", idFilePath, err)
+		}
+		return signer, nil
+	}
+
+	// Legacy PEM encrypted keys do not expose the public key
+	pub := passErr.PublicKey
+	if pub == nil {
+		if contents, err := os.ReadFile(idFilePath + ".pub"); err == nil {
+			pub, _, _, _, _ = ssh.ParseAuthorizedKey(contents)
+		}
+	}
+	if pub == nil {
+		return decrypt()
+	}
+	return &lazySigner{pub: pub, decrypt: decrypt}, nil
+}
+
+var _ (ssh.AlgorithmSigner) = (*lazySigner)(nil)
+
+// lazySigner decrypts the private key on first signature
+type lazySigner struct {
+	pub     ssh.PublicKey
+	decrypt func() (ssh.Signer, error)
+
+	mu     sync.Mutex
+	signer ssh.Signer
+}
+
+func (l *lazySigner) load() (ssh.Signer, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.signer == nil {
+		signer, err := l.decrypt()
+		if err != nil {
+			return nil, err
+		}
+		l.signer = signer
+	}
+	return l.signer, nil
+}
+
+func (l *lazySigner) PublicKey() ssh.PublicKey {
+	return l.pub
+}
+
+func (l *lazySigner) Sign(rand io.Reader, data []byte) (*ssh.Signature, error) {
+	signer, err := l.load()
+	if err != nil {
+		return nil, err
+	}
+	return signer.Sign(rand, data)
+}
+
+func (l *lazySigner) SignWithAlgorithm(rand io.Reader, data []byte, algorithm string) (*ssh.Signature, error) {
+	signer, err := l.load()
+	if err != nil {
+		return nil, err
+	}
+	if as, ok := signer.(ssh.AlgorithmSigner); ok {
+		return as.SignWithAlgorithm(rand, data, algorithm)
+	}
+	return signer.Sign(rand, data)
+}
+
+// dial establishes ssh client connection to host. When bastion is not nil,
+// connection is established via bastion.
+func (t Transport) dial(bastion *ssh.Client, host, user, idFilePath string) (*ssh.Client, error) {
+	if knownHosts == nil {
+		return nil, errKnownHostsNotSet
+	}
+
+	auth, closeAgent, err := t.authMethods(idFilePath)
+	if err != nil {
+		return nil, err
+	}
+	defer closeAgent()
+
+	addr := t.addr(host)
+	config := knownHosts.clientConfig(addr, user, auth...)
+
+	if bastion == nil {
+		return ssh.Dial("tcp", addr, config)
+	}
+
+	targetConn, err := bastion.Dial("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("dialing to target via bastion: %w", err)
+	}
+	// Target address must be passed for host key verification
+	a, b, c, err := ssh.NewClientConn(targetConn, addr, config)
+	if err != nil {
+		targetConn.Close()
+		return nil, err
+	}
+	return ssh.NewClient(a, b, c), nil
+}
+
+// keepAlive sends keepalive requests on client until it is closed. Client is
+// closed when KeepAliveMaxMissed requests in a row are not answered.
+func (t Transport) keepAlive(client *ssh.Client) {
+	if t.KeepAliveInterval <= 0 {
+		return
+	}
+
+	done := make(chan struct{})
+	go func() {
+		client.Wait()
+		close(done)
+	}()
+
+	ticker := time.NewTicker(t.KeepAliveInterval)
+	defer ticker.Stop()
+
+	maxMissed := t.KeepAliveMaxMissed
+	if maxMissed < 1 {
+		maxMissed = 1
+	}
+	missed := 0
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+
+		replied := make(chan error, 1)
+		go func() {
+			_, _, err := client.SendRequest("keepalive@openssh.com", true, nil)
+			replied <- err
+		}()
+
+		select {
+		case err := <-replied:
+			if err != nil {
+				missed = maxMissed
+			} else {
+				missed = 0
+			}
+		case <-time.After(t.KeepAliveInterval):
+			missed++
+		}
+
+		if missed >= maxMissed {
+			client.Close()
+			return
+		}
+	}
+}
@@ -0,0 +1,264 @@
+package conn
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+func TestTransportAddr(t *testing.T) {
+	tr := Transport{}
+	assert.Equal(t, "10.0.0.1:22", tr.addr("10.0.0.1"))
+
+	tr.Port = func(host string) int {
+		if host == "10.0.0.2" {
+			return 2222
+		}
+		return 0
+	}
+	assert.Equal(t, "10.0.0.1:22", tr.addr("10.0.0.1"))
+	assert.Equal(t, "10.0.0.2:2222", tr.addr("10.0.0.2"))
+}
+
+// writeTestKey writes ed25519 private key to a temp file, encrypted when
+// passphrase is not empty
+func writeTestKey(t *testing.T, passphrase string) (string, ed25519.PrivateKey) {
+	_, pk, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	var block *pem.Block
+	if passphrase != "" {
+		block, err = ssh.MarshalPrivateKeyWithPassphrase(pk, "", []byte(passphrase))
+	} else {
+		block, err = ssh.MarshalPrivateKey(pk, "")
+	}
+	require.NoError(t, err)
+
+	keyPath := filepath.Join(t.TempDir(), "id_ed25519")
+	require.NoError(t, os.WriteFile(keyPath, pem.EncodeToMemory(block), 0600))
+	return keyPath, pk
+}
+
+// startTestAgent serves ssh-agent holding keys on a unix socket
+func startTestAgent(t *testing.T, keys ...ed25519.PrivateKey) string {
+	keyring := agent.NewKeyring()
+	for _, k := range keys {
+		require.NoError(t, keyring.Add(agent.AddedKey{PrivateKey: k}))
+	}
+
+	sock := filepath.Join(t.TempDir(), "agent.sock")
+	l, err := net.Listen("unix", sock)
+	require.NoError(t, err)
+	t.Cleanup(func() { l.Close() })
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go agent.ServeAgent(keyring, c)
+		}
+	}()
+	return sock
+}
+
+func TestTransportKeyFileSigner(t *testing.T) {
+	plainKey, _ := writeTestKey(t, "")
+	encryptedKey, pk := writeTestKey(t, "secret")
+
+	tests := []struct {
+		name         string
+		keyPath      string
+		passphrase   string
+		hasAgentKeys bool
+		wantSigner   bool
+		wantErr      string
+	}{
+		{
+			name:       "plain key",
+			keyPath:    plainKey,
+			wantSigner: true,
+		},
+		{
+			name:       "encrypted key with passphrase",
+			keyPath:    encryptedKey,
+			passphrase: "secret",
+			wantSigner: true,
+		},
+		{
+			name:    "encrypted key without passphrase",
+			keyPath: encryptedKey,
+			wantErr: "private key " + encryptedKey + " is encrypted, add it to ssh-agent or provide the passphrase",
+		},
+		{
+			name:         "encrypted key is used with agent keys",
+			keyPath:      encryptedKey,
+			passphrase:   "secret",
+			hasAgentKeys: true,
+			wantSigner:   true,
+		},
+		{
+			name:         "encrypted key without passphrase is skipped when agent has keys",
+			keyPath:      encryptedKey,
+			hasAgentKeys: true,
+		},
+		{
+			name:         "missing key is skipped when agent has keys",
+			keyPath:      filepath.Join(t.TempDir(), "missing"),
+			hasAgentKeys: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := Transport{}
+			if tt.passphrase != "" {
+				tr.Passphrase = func(string) ([]byte, error) {
+					return []byte(tt.passphrase), nil
+				}
+			}
+
+			signer, err := tr.keyFileSigner(tt.keyPath, tt.hasAgentKeys)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantSigner, signer != nil)
+			if tt.keyPath == encryptedKey && signer != nil {
+				pub, err := ssh.NewPublicKey(pk.Public())
+				require.NoError(t, err)
+				assert.Equal(t, pub.Marshal(), signer.PublicKey().Marshal())
+			}
+		})
+	}
+}
+
+// startTestSSHServer starts ssh server which accepts authorizedKey and
+// answers exec requests with "ok" output
+func startTestSSHServer(t *testing.T, authorizedKey ssh.PublicKey) string {
+	serverCfg := &ssh.ServerConfig{
+		PublicKeyCallback: func(c ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if string(key.Marshal()) == string(authorizedKey.Marshal()) {
+				return nil, nil
+			}
+			return nil, assert.AnError
+		},
+	}
+	serverCfg.AddHostKey(newTestHostKey(t))
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { l.Close() })
+
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer c.Close()
+				_, chans, reqs, err := ssh.NewServerConn(c, serverCfg)
+				if err != nil {
+					return
+				}
+				go ssh.DiscardRequests(reqs)
+				for newCh := range chans {
+					ch, chReqs, err := newCh.Accept()
+					if err != nil {
+						continue
+					}
+					go func() {
+						for req := range chReqs {
+							req.Reply(req.Type == "exec", nil)
+							if req.Type == "exec" {
+								ch.Write([]byte("ok"))
+								ch.SendRequest("exit-status", false, ssh.Marshal(struct{ Status uint32 }{0}))
+								ch.Close()
+							}
+						}
+					}()
+				}
+			}()
+		}
+	}()
+
+	return l.Addr().String()
+}
+
+func TestSSHConnectionAgentAuthAndReconnect(t *testing.T) {
+	// Key file is encrypted and only the agent can be used
+	keyPath, pk := writeTestKey(t, "secret")
+	pub, err := ssh.NewPublicKey(pk.Public())
+	require.NoError(t, err)
+
+	serverAddr := startTestSSHServer(t, pub)
+	host, port, err := net.SplitHostPort(serverAddr)
+	require.NoError(t, err)
+
+	prevTransport, prevKnownHosts := transport, knownHosts
+	t.Cleanup(func() {
+		UseTransport(prevTransport)
+		UseKnownHosts(prevKnownHosts)
+	})
+	UseKnownHosts(NewKnownHosts(filepath.Join(t.TempDir(), "known_hosts")))
+	UseTransport(Transport{
+		Port: func(string) int {
+			p, _ := net.LookupPort("tcp", port)
+			return p
+		},
+		AgentSocket: startTestAgent(t, pk),
+	})
+
+	c, err := NewSSHConnection(host, "d8x", keyPath)
+	require.NoError(t, err)
+	out, err := c.ExecCommand("echo ok")
+	require.NoError(t, err)
+	assert.Equal(t, "ok", string(out))
+
+	// Lost connection is reestablished when a new session is opened
+	require.NoError(t, c.GetClient().Close())
+	out, err = c.ExecCommand("echo ok")
+	require.NoError(t, err)
+	assert.Equal(t, "ok", string(out))
+
+	streamed := &bytes.Buffer{}
+	require.NoError(t, c.ExecCommandStream(context.Background(), "echo ok", streamed, streamed))
+	assert.Equal(t, "ok", streamed.String())
+}
+
+func TestTransportKeyFileSignerAsksPassphraseLazily(t *testing.T) {
+	encryptedKey, _ := writeTestKey(t, "secret")
+
+	asked := 0
+	tr := Transport{
+		Passphrase: func(string) ([]byte, error) {
+			asked++
+			return []byte("secret"), nil
+		},
+	}
+	signer, err := tr.keyFileSigner(encryptedKey, true)
+	require.NoError(t, err)
+	require.NotNil(t, signer)
+	assert.Equal(t, 0, asked)
+
+	data := []byte("data")
+	sig, err := signer.Sign(rand.Reader, data)
+	require.NoError(t, err)
+	assert.NoError(t, signer.PublicKey().Verify(data, sig))
+	_, err = signer.Sign(rand.Reader, data)
+	require.NoError(t, err)
+	assert.Equal(t, 1, asked)
+}
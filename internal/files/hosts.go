@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"regexp"
+	"strconv"
 	"strings"
 )
 
@@ -16,11 +18,19 @@ type HostsFileInteractor interface {
 	GetWorkerPrivateIps() ([]string, error)
 	GetAllPublicIps() []string
 
+	// GetSSHPort returns ansible_port of host, of its group vars or of
+	// [all:vars]. 0 is returned when port is not set.
+	GetSSHPort(host string) int
+
 	// GetLines retrieves all lines from hosts file
 	GetLines() ([]string, error)
 
 	// WriteLines writes the provided lines to hosts file
 	WriteLines([]string) error
+
+	// ClearCache drops the cached hosts file contents. Must be called when
+	// hosts file is changed externally (i.e. by terraform).
+	ClearCache()
 }
 
 func NewFSHostsFileInteractor(filePath string) HostsFileInteractor {
@@ -96,6 +106,17 @@ func (f *fsHostFileInteractor) GetWorkerPrivateIps() ([]string, error) {
 	return f.cached.GetWorkerPrivateIps()
 }
 
+func (f *fsHostFileInteractor) GetSSHPort(host string) int {
+	if err := f.ensureFileLoaded(); err != nil {
+		return 0
+	}
+	return f.cached.GetSSHPort(host)
+}
+
+func (f *fsHostFileInteractor) ClearCache() {
+	f.cached = nil
+}
+
 func (f *fsHostFileInteractor) GetLines() ([]string, error) {
 	if err := f.ensureFileLoaded(); err != nil {
 		return nil, err
@@ -259,3 +280,69 @@ func (h *HostsFile) FindAllIps(of string) ([]string, error) {
 	}
 	return ret, nil
 }
+
+// GetSSHPort returns ansible_port variable of host line, of [<group>:vars] of
+// a group of host or of [all:vars], in this order of precedence. 0 is returned
+// when port is not set.
+func (h *HostsFile) GetSSHPort(host string) int {
+	hostPort := 0
+	hostGroups := []string{}
+	groupPorts := map[string]int{}
+	section := ""
+	for _, l := range h.lines {
+		l = strings.TrimSpace(l)
+		if l == "" || strings.HasPrefix(l, "#") || strings.HasPrefix(l, ";") {
+			continue
+		}
+		if strings.HasPrefix(l, "[") && strings.HasSuffix(l, "]") {
+			section = strings.TrimSpace(l[1 : len(l)-1])
+			continue
+		}
+
+		if group, ok := strings.CutSuffix(section, ":vars"); ok {
+			if port, ok := parseAnsiblePort(l); ok {
+				groupPorts[group] = port
+			}
+			continue
+		}
+		if strings.HasSuffix(section, ":children") {
+			continue
+		}
+		fields := strings.Fields(l)
+		if fields[0] != host {
+			continue
+		}
+		hostGroups = append(hostGroups, section)
+		if port, ok := parseAnsiblePort(strings.TrimPrefix(l, fields[0])); ok {
+			hostPort = port
+		}
+	}
+
+	if hostPort != 0 {
+		return hostPort
+	}
+	for _, g := range hostGroups {
+		if port, ok := groupPorts[g]; ok {
+			return port
+		}
+	}
+	return groupPorts["all"]
+}
+
+// Matches ansible_port=<port> variable, with optional whitespace around = as
+// allowed in vars sections
+var ansiblePortVar = regexp.MustCompile(`(?:^|\s)ansible_port\s*=\s*["']?([0-9]+)["']?(?:\s|$)`)
+
+// parseAnsiblePort parses ansible_port variable of host vars or group vars
+// line
+func parseAnsiblePort(line string) (int, bool) {
+	m := ansiblePortVar.FindStringSubmatch(line)
+	if m == nil {
+		return 0, false
+	}
+	port, err := strconv.Atoi(m[1])
+	if err != nil {
+		return 0, false
+	}
+	return port, true
+}
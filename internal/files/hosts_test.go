@@ -0,0 +1,110 @@
+package files
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHostsFileGetSSHPort(t *testing.T) {
+	tests := []struct {
+		name  string
+		hosts string
+		host  string
+		want  int
+	}{
+		{
+			name:  "not set",
+			hosts: "[managers]\n10.0.0.1 manager_private_ip=192.168.0.1\n",
+			host:  "10.0.0.1",
+			want:  0,
+		},
+		{
+			name:  "host line",
+			hosts: "[managers]\n10.0.0.1 manager_private_ip=192.168.0.1 ansible_port=2200 hostname=manager-1\n",
+			host:  "10.0.0.1",
+			want:  2200,
+		},
+		{
+			name:  "host line with whitespace",
+			hosts: "[managers]\n10.0.0.1 ansible_port = 2200\n",
+			host:  "10.0.0.1",
+			want:  2200,
+		},
+		{
+			name:  "other host",
+			hosts: "[managers]\n10.0.0.1 ansible_port=2200\n10.0.0.2\n",
+			host:  "10.0.0.2",
+			want:  0,
+		},
+		{
+			name:  "all vars",
+			hosts: "[managers]\n10.0.0.1\n\n[all:vars]\nansible_port=2222\n",
+			host:  "10.0.0.1",
+			want:  2222,
+		},
+		{
+			name:  "all vars with whitespace",
+			hosts: "[all:vars]\nansible_user = d8xtrader\nansible_port = 2222\n\n[managers]\n10.0.0.1\n",
+			host:  "10.0.0.1",
+			want:  2222,
+		},
+		{
+			name:  "group vars",
+			hosts: "[workers]\n192.168.0.2\n\n[workers:vars]\nansible_port=2300\n\n[all:vars]\nansible_port=2222\n",
+			host:  "192.168.0.2",
+			want:  2300,
+		},
+		{
+			name:  "vars of other group",
+			hosts: "[managers]\n10.0.0.1\n[workers]\n192.168.0.2\n[workers:vars]\nansible_port=2300\n[all:vars]\nansible_port=2222\n",
+			host:  "10.0.0.1",
+			want:  2222,
+		},
+		{
+			name:  "host over group",
+			hosts: "[workers]\n192.168.0.2 ansible_port=2400\n[workers:vars]\nansible_port=2300\n[all:vars]\nansible_port=2222\n",
+			host:  "192.168.0.2",
+			want:  2400,
+		},
+		{
+			name:  "commented out",
+			hosts: "[managers]\n10.0.0.1\n[all:vars]\n# ansible_port=2222\n",
+			host:  "10.0.0.1",
+			want:  0,
+		},
+		{
+			name:  "other variable suffix",
+			hosts: "[managers]\n10.0.0.1 my_ansible_port=2222\n",
+			host:  "10.0.0.1",
+			want:  0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &HostsFile{lines: strings.Split(tt.hosts, "\n")}
+			assert.Equal(t, tt.want, h.GetSSHPort(tt.host))
+		})
+	}
+}
+
+func TestFSHostsFileInteractorGetSSHPort(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hosts.cfg")
+	require.NoError(t, os.WriteFile(path, []byte("[managers]\n10.0.0.1 ansible_port=2200\n"), 0644))
+
+	h := NewFSHostsFileInteractor(path)
+	assert.Equal(t, 2200, h.GetSSHPort("10.0.0.1"))
+
+	// Changed file is read again only after cache is cleared
+	require.NoError(t, os.WriteFile(path, []byte("[managers]\n10.0.0.1 ansible_port=2300\n"), 0644))
+	assert.Equal(t, 2200, h.GetSSHPort("10.0.0.1"))
+	h.ClearCache()
+	assert.Equal(t, 2300, h.GetSSHPort("10.0.0.1"))
+
+	// Missing file
+	assert.Equal(t, 0, NewFSHostsFileInteractor(filepath.Join(t.TempDir(), "missing")).GetSSHPort("10.0.0.1"))
+}
@@ -8,4 +8,9 @@ const (
 	ConfigDir      = "config-directory"
 	PrivateKeyPath = "ssh-key-path"
 	PgCertPath     = "pg-cert"
+	Answers        = "answers"
+	RecordAnswers  = "record-answers"
+	DryRun         = "dry-run"
+	Output         = "output"
+	Profile        = "profile"
 )
@@ -89,18 +89,23 @@ func (mr *MockComponentsRunnerMockRecorder) NewList(arg0, arg1 interface{}, arg2
 }
 
 // NewPrompt mocks base method.
-func (m *MockComponentsRunner) NewPrompt(arg0 string, arg1 bool) (bool, error) {
+func (m *MockComponentsRunner) NewPrompt(arg0 string, arg1 bool, arg2 ...components.PromptOpt) (bool, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "NewPrompt", arg0, arg1)
+	varargs := []interface{}{arg0, arg1}
+	for _, a := range arg2 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "NewPrompt", varargs...)
 	ret0, _ := ret[0].(bool)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // NewPrompt indicates an expected call of NewPrompt.
-func (mr *MockComponentsRunnerMockRecorder) NewPrompt(arg0, arg1 interface{}) *gomock.Call {
+func (mr *MockComponentsRunnerMockRecorder) NewPrompt(arg0, arg1 interface{}, arg2 ...interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NewPrompt", reflect.TypeOf((*MockComponentsRunner)(nil).NewPrompt), arg0, arg1)
+	varargs := append([]interface{}{arg0, arg1}, arg2...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NewPrompt", reflect.TypeOf((*MockComponentsRunner)(nil).NewPrompt), varargs...)
 }
 
 // NewSelection mocks base method.
@@ -5,6 +5,8 @@
 package mocks
 
 import (
+	context "context"
+	io "io"
 	reflect "reflect"
 
 	conn "github.com/D8-X/d8x-cli/internal/conn"
@@ -82,6 +84,20 @@ func (mr *MockSSHConnectionMockRecorder) ExecCommandPiped(arg0 interface{}) *gom
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExecCommandPiped", reflect.TypeOf((*MockSSHConnection)(nil).ExecCommandPiped), arg0)
 }
 
+// ExecCommandStream mocks base method.
+func (m *MockSSHConnection) ExecCommandStream(arg0 context.Context, arg1 string, arg2, arg3 io.Writer) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ExecCommandStream", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ExecCommandStream indicates an expected call of ExecCommandStream.
+func (mr *MockSSHConnectionMockRecorder) ExecCommandStream(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExecCommandStream", reflect.TypeOf((*MockSSHConnection)(nil).ExecCommandStream), arg0, arg1, arg2, arg3)
+}
+
 // GetClient mocks base method.
 func (m *MockSSHConnection) GetClient() *ssh.Client {
 	m.ctrl.T.Helper()
@@ -95,3 +111,17 @@ func (mr *MockSSHConnectionMockRecorder) GetClient() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetClient", reflect.TypeOf((*MockSSHConnection)(nil).GetClient))
 }
+
+// Reconnect mocks base method.
+func (m *MockSSHConnection) Reconnect() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Reconnect")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Reconnect indicates an expected call of Reconnect.
+func (mr *MockSSHConnectionMockRecorder) Reconnect() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Reconnect", reflect.TypeOf((*MockSSHConnection)(nil).Reconnect))
+}
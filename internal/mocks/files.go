@@ -97,6 +97,18 @@ func (m *MockHostsFileInteractor) EXPECT() *MockHostsFileInteractorMockRecorder
 	return m.recorder
 }
 
+// ClearCache mocks base method.
+func (m *MockHostsFileInteractor) ClearCache() {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "ClearCache")
+}
+
+// ClearCache indicates an expected call of ClearCache.
+func (mr *MockHostsFileInteractorMockRecorder) ClearCache() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClearCache", reflect.TypeOf((*MockHostsFileInteractor)(nil).ClearCache))
+}
+
 // GetAllPublicIps mocks base method.
 func (m *MockHostsFileInteractor) GetAllPublicIps() []string {
 	m.ctrl.T.Helper()
@@ -171,6 +183,20 @@ func (mr *MockHostsFileInteractorMockRecorder) GetMangerPublicIp() *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMangerPublicIp", reflect.TypeOf((*MockHostsFileInteractor)(nil).GetMangerPublicIp))
 }
 
+// GetSSHPort mocks base method.
+func (m *MockHostsFileInteractor) GetSSHPort(arg0 string) int {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSSHPort", arg0)
+	ret0, _ := ret[0].(int)
+	return ret0
+}
+
+// GetSSHPort indicates an expected call of GetSSHPort.
+func (mr *MockHostsFileInteractorMockRecorder) GetSSHPort(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSSHPort", reflect.TypeOf((*MockHostsFileInteractor)(nil).GetSSHPort), arg0)
+}
+
 // GetWorkerIps mocks base method.
 func (m *MockHostsFileInteractor) GetWorkerIps() ([]string, error) {
 	m.ctrl.T.Helper()
@@ -9,6 +9,18 @@ import (
 // Colors
 var (
 	D8XPurple = lipgloss.Color("#664adf")
+
+	// Colors used to distinguish prefixes of streamed log lines
+	LogPrefixColors = []lipgloss.Color{
+		lipgloss.Color("#4ea8de"),
+		lipgloss.Color("#f4a261"),
+		lipgloss.Color("#2a9d8f"),
+		lipgloss.Color("#e76f51"),
+		lipgloss.Color("#a78bfa"),
+		lipgloss.Color("#e9c46a"),
+		lipgloss.Color("#f28482"),
+		lipgloss.Color("#84a59d"),
+	}
 )
 
 // Texts